@@ -10,8 +10,16 @@ import (
 	"backend/internal/api/handler_fiber"
 	"backend/internal/api/middleware"
 	"backend/internal/database"
+	"backend/internal/service/catalog"
+	"backend/internal/service/darwincore"
 	"backend/internal/service/file"
+	"backend/internal/service/oaipmh"
+	"backend/internal/service/signing"
+	"backend/internal/service/slug"
+	"backend/internal/service/storagecheck"
 	"backend/internal/service/translation"
+	"backend/internal/service/trash"
+	"backend/internal/service/upload"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -19,8 +27,8 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"os"
-	"path/filepath"
 	"strings"
+	"time"
 )
 
 func main() {
@@ -37,6 +45,12 @@ func main() {
 	}
 	defer db.DB.Close()
 
+	if filled, err := db.BackfillMineralSlugs(slug.Make); err != nil {
+		log.Printf("Warning: не удалось создать адреса минералов: %v", err)
+	} else if filled > 0 {
+		log.Printf("Созданы адреса для минералов: %d", filled)
+	}
+
 	storagePath := "/app/storage"
 	log.Printf("Storage директория: %s", storagePath)
 
@@ -45,6 +59,30 @@ func main() {
 		log.Fatal("Ошибка инициализации файлового сервиса: ", err)
 	}
 
+	uploadService, err := upload.NewUploadService(upload.UploadsPath, file.MaxFileSize, upload.DefaultExpiry)
+	if err != nil {
+		log.Fatal("Ошибка инициализации сервиса загрузок: ", err)
+	}
+	uploadService.StartCleanup(upload.CleanupInterval)
+
+	storageChecker := storagecheck.NewChecker(db, fileService, durationEnv("STORAGE_GC_GRACE", storagecheck.DefaultGracePeriod))
+	if interval := durationEnv("STORAGE_GC_INTERVAL", 0); interval > 0 {
+		dryRun := os.Getenv("STORAGE_GC_DRY_RUN") == "true"
+		log.Printf("Плановая очистка хранилища каждые %s (dry run: %t)", interval, dryRun)
+		storageChecker.StartSchedule(interval, dryRun)
+	}
+
+	trashPurger := trash.NewPurger(db, fileService, durationEnv("TRASH_RETENTION", trash.DefaultRetention))
+	if interval := durationEnv("TRASH_PURGE_INTERVAL", trash.DefaultPurgeInterval); interval > 0 {
+		log.Printf("Минералы хранятся в корзине %s, проверка каждые %s", trashPurger.Retention(), interval)
+		trashPurger.StartSchedule(interval)
+	}
+
+	urlSigner := signing.NewURLSignerFromEnv()
+	if urlSigner.Enabled() {
+		log.Printf("Подписанные ссылки требуются для: %s", os.Getenv("STORAGE_SIGNED_DIRS"))
+	}
+
 	baseURL := "http://translate:3000"
 	if os.Getenv("DOCKER_ENV") != "true" {
 		baseURL = "http://localhost:5050"
@@ -60,6 +98,12 @@ func main() {
 		log.Printf("Translation service is available at %s", baseURL)
 	}
 
+	dwcMapping, err := darwincore.MappingFromEnv()
+	if err != nil {
+		log.Fatal("Ошибка загрузки сопоставления Darwin Core: ", err)
+	}
+	oaiProvider := oaipmh.NewProvider(db, dwcMapping, oaipmh.ConfigFromEnv())
+
 	app := fiber.New(fiber.Config{
 		AppName:           "GazlinGO Api",
 		StrictRouting:     true,
@@ -67,12 +111,18 @@ func main() {
 		BodyLimit:         100 * 1024 * 1024,
 	})
 
+	h := handler_fiber.New(db, fileService, translationService, uploadService, urlSigner, storageChecker, trashPurger, catalog.NewImporter(db, fileService), catalog.NewExporter(db, fileService), darwincore.NewPublisher(db, dwcMapping), oaiProvider)
+
 	app.Use(func(c *fiber.Ctx) error {
 		c.Set("Access-Control-Allow-Origin", "http://localhost:5173")
-		c.Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-		c.Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,HEAD,DELETE,OPTIONS")
+		c.Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata, Upload-Checksum")
+		c.Set("Access-Control-Expose-Headers", "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Tus-Checksum-Algorithm, Upload-Offset, Upload-Length, Upload-Expires")
 
 		if c.Method() == "OPTIONS" {
+			if strings.HasPrefix(c.Path(), "/api/v1/admin/uploads") {
+				return h.TusOptions(c)
+			}
 			return c.SendStatus(fiber.StatusOK)
 		}
 		return c.Next()
@@ -84,20 +134,8 @@ func main() {
 		Output: os.Stdout,
 	}))
 
-	app.Use("/storage", func(c *fiber.Ctx) error {
-		requestedPath := strings.TrimPrefix(c.Path(), "/storage")
-		fullPath := filepath.Join("/app/storage", requestedPath)
-		log.Printf("Requested static file: %s (full path: %s)", requestedPath, fullPath)
-
-		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-			log.Printf("File not found: %s", fullPath)
-			return c.Status(404).SendString("File not found")
-		}
-
-		return c.SendFile(fullPath)
-	})
-
-	h := handler_fiber.New(db, fileService, translationService)
+	storageServer := middleware.NewStorageServer(storagePath).WithSigner(urlSigner)
+	app.Use("/storage", storageServer.Handler("/storage"))
 
 	app.Get("/", func(c *fiber.Ctx) error {
 		return c.JSON(fiber.Map{
@@ -110,10 +148,38 @@ func main() {
 	v1 := api.Group("/v1")
 
 	v1.Get("/minerals", h.GetAllMinerals)
+	v1.Get("/minerals/by-slug/:slug", h.GetMineralBySlug)
 	v1.Get("/minerals/:id", h.GetMineralByID)
+	v1.Get("/minerals/:id/assets", h.GetMineralAssets)
+	v1.Get("/minerals/:id/specimens", h.GetMineralSpecimens)
+	v1.Get("/specimens/:id", h.GetSpecimenByID)
+	v1.Get("/specimens/:id/assets", h.GetSpecimenAssets)
+	v1.Get("/minerals/:id/localities", h.GetMineralLocalities)
+	v1.Get("/localities", h.GetLocalities)
+	v1.Get("/localities/:id", h.GetLocalityByID)
+	v1.Get("/elements", h.GetElements)
+	v1.Get("/elements/:symbol/minerals", h.GetElementMinerals)
+	v1.Get("/classification", h.GetClassificationTree)
+	v1.Get("/classification/:id", h.GetClassificationNode)
+	v1.Get("/classification/:id/minerals", h.GetClassificationMinerals)
+	v1.Get("/minerals/:id/structures", h.GetMineralStructures)
+	v1.Get("/structures/:id", h.GetStructureByID)
+	v1.Get("/minerals/:id/xrd", h.GetMineralXRDPeaks)
+	v1.Post("/identify/xrd", h.IdentifyXRD)
+	v1.Get("/minerals/:id/spectra", h.GetMineralSpectra)
+	v1.Get("/spectra/:id", h.GetSpectrumByID)
+	v1.Get("/spectra/:id/data", h.GetSpectrumData)
+	v1.Post("/spectra/compare", h.CompareSpectrum)
+	v1.Get("/minerals/:id/names", h.GetMineralNames)
+	v1.Get("/minerals/:id/related", h.GetMineralRelated)
 	v1.Get("/languages", h.GetAvailableLanguages)
+	v1.Get("/tags", h.GetTags)
+	v1.Get("/tags/cloud", h.GetTagCloud)
 	v1.Get("/minerals-translated", h.GetAllTranslatedMinerals)
 	v1.Get("/minerals-translated/:id", h.GetTranslatedMineral)
+	v1.Get("/dwc/archive", h.DarwinCoreArchive)
+	v1.Get("/oai", h.OAIPMH)
+	v1.Post("/oai", h.OAIPMH)
 
 	v1.Post("/login", h.Login)
 	v1.Post("/register", h.Register)
@@ -121,11 +187,73 @@ func main() {
 	v1.Get("/find-minerals", middleware.AuthMiddleware(), h.SearchMineral)
 
 	admin := v1.Group("/admin", middleware.AuthMiddleware(), middleware.AdminOnly())
+	admin.Get("/minerals", h.GetAdminMinerals)
+	admin.Get("/minerals/:id", h.GetMineralByID)
 	admin.Post("/minerals", h.CreateMineral)
 	admin.Put("/minerals/:id", h.UpdateMineral)
 	admin.Delete("/minerals/:id", h.DeleteMineral)
 	admin.Post("/upload/model", h.UploadModel)
 	admin.Post("/upload/preview", h.UploadPreview)
+	admin.Post("/minerals/:id/preview", h.RegeneratePreview)
+	admin.Post("/minerals/:id/assets", h.CreateMineralAsset)
+	admin.Put("/minerals/:id/assets/order", h.ReorderMineralAssets)
+	admin.Patch("/minerals/:id/assets/:assetId", h.UpdateMineralAsset)
+	admin.Delete("/minerals/:id/assets/:assetId", h.DeleteMineralAsset)
+	admin.Post("/minerals/:id/specimens", h.CreateSpecimen)
+	admin.Put("/specimens/:id", h.UpdateSpecimen)
+	admin.Delete("/specimens/:id", h.DeleteSpecimen)
+	admin.Post("/specimens/:id/assets", h.CreateSpecimenAsset)
+	admin.Put("/specimens/:id/assets/order", h.ReorderSpecimenAssets)
+	admin.Patch("/specimens/:id/assets/:assetId", h.UpdateSpecimenAsset)
+	admin.Delete("/specimens/:id/assets/:assetId", h.DeleteSpecimenAsset)
+	admin.Put("/minerals/:id/localities", h.SetMineralLocalities)
+	admin.Post("/localities", h.CreateLocality)
+	admin.Put("/localities/:id", h.UpdateLocality)
+	admin.Delete("/localities/:id", h.DeleteLocality)
+	admin.Put("/minerals/:id/classification", h.SetMineralClassification)
+	admin.Post("/classification", h.CreateClassificationNode)
+	admin.Put("/classification/:id", h.UpdateClassificationNode)
+	admin.Delete("/classification/:id", h.DeleteClassificationNode)
+	admin.Post("/minerals/:id/structures", h.CreateMineralStructure)
+	admin.Delete("/structures/:id", h.DeleteStructure)
+	admin.Put("/minerals/:id/xrd", h.SetMineralXRDPeaks)
+	admin.Post("/minerals/:id/spectra", h.CreateMineralSpectrum)
+	admin.Delete("/spectra/:id", h.DeleteSpectrum)
+	admin.Put("/minerals/:id/tags", h.SetMineralTags)
+	admin.Post("/tags", h.CreateTag)
+	admin.Put("/tags/:id", h.UpdateTag)
+	admin.Delete("/tags/:id", h.DeleteTag)
+	admin.Get("/minerals/:id/slugs", h.GetMineralSlugs)
+	admin.Put("/minerals/:id/slugs/:lang", h.SetMineralSlug)
+	admin.Post("/minerals/:id/names", h.CreateMineralName)
+	admin.Delete("/names/:id", h.DeleteMineralName)
+	admin.Post("/minerals/:id/relations", h.CreateMineralRelation)
+	admin.Delete("/relations/:id", h.DeleteMineralRelation)
+	admin.Get("/minerals/:id/revisions", h.GetMineralRevisions)
+	admin.Get("/minerals/:id/revisions/diff", h.DiffMineralRevisions)
+	admin.Get("/minerals/:id/revisions/:revision", h.GetMineralRevision)
+	admin.Post("/minerals/:id/revisions/:revision/restore", h.RestoreMineralRevision)
+	admin.Post("/minerals/:id/submit", h.SubmitMineral)
+	admin.Post("/minerals/:id/approve", h.ApproveMineral)
+	admin.Post("/minerals/:id/reject", h.RejectMineral)
+	admin.Post("/minerals/:id/archive", h.ArchiveMineral)
+	admin.Post("/minerals/:id/reopen", h.ReopenMineral)
+	admin.Get("/minerals/:id/reviews", h.GetMineralReviews)
+	admin.Post("/minerals/:id/reviews", h.CreateMineralReview)
+	admin.Get("/trash", h.GetTrash)
+	admin.Delete("/trash", h.EmptyTrash)
+	admin.Post("/trash/:id/restore", h.RestoreMineral)
+	admin.Delete("/trash/:id", h.PurgeMineral)
+	admin.Post("/import", h.ImportMinerals)
+	admin.Get("/export/minerals", h.ExportMinerals)
+	admin.Get("/export/archive", h.ExportArchive)
+	admin.Post("/uploads", h.CreateUpload)
+	admin.Head("/uploads/:id", h.GetUploadOffset)
+	admin.Patch("/uploads/:id", h.PatchUpload)
+	admin.Delete("/uploads/:id", h.DeleteUpload)
+	admin.Post("/uploads/:id/finalize", h.FinalizeUpload)
+	admin.Get("/storage/check", h.CheckStorage)
+	admin.Post("/storage/gc", h.CollectStorageGarbage)
 
 	protected := v1.Group("", middleware.AuthMiddleware())
 	protected.Post("/favorites/:id", h.AddToFavorites)
@@ -151,3 +279,16 @@ func main() {
 		log.Fatal("Ошибка запуска сервера: ", err)
 	}
 }
+
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %s", key, raw, fallback)
+		return fallback
+	}
+	return value
+}
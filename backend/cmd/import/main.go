@@ -0,0 +1,99 @@
+// Command-line bulk import of minerals, for catalogues too large to send through the admin endpoint.
+// Reads a CSV or JSON manifest and an optional ZIP archive of files, or an exported archive with its own manifest, prints the import report as JSON
+// and only writes to the database and the storage when -apply is given. Exits with status 1 when a row has errors.
+//
+//	import -manifest minerals.csv -archive files.zip [-apply]
+//	import -archive export.zip [-apply]         # the manifest is read from the archive
+
+package main
+
+import (
+	"backend/internal/database"
+	"backend/internal/service/catalog"
+	"backend/internal/service/file"
+	"encoding/json"
+	"flag"
+	"github.com/joho/godotenv"
+	"log"
+	"os"
+)
+
+func main() {
+	manifestPath := flag.String("manifest", "", "CSV, JSON или NDJSON манифест; без него манифест берётся из архива")
+	archivePath := flag.String("archive", "", "ZIP-архив с файлами моделей и превью")
+	apply := flag.Bool("apply", false, "выполнить импорт; без флага выводится только отчёт")
+	author := flag.String("author", "import", "имя автора ревизий")
+	flag.Parse()
+	if *manifestPath == "" && *archivePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: No .env file found")
+	}
+
+	var archive *catalog.Archive
+	if *archivePath != "" {
+		f, err := os.Open(*archivePath)
+		if err != nil {
+			log.Fatal("Ошибка открытия архива: ", err)
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil {
+			log.Fatal("Ошибка открытия архива: ", err)
+		}
+		if archive, err = catalog.OpenArchive(f, info.Size()); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var records []catalog.Record
+	if *manifestPath != "" {
+		manifest, err := os.Open(*manifestPath)
+		if err != nil {
+			log.Fatal("Ошибка открытия манифеста: ", err)
+		}
+		records, err = catalog.ParseManifest(*manifestPath, manifest)
+		manifest.Close()
+		if err != nil {
+			log.Fatal("Ошибка чтения манифеста: ", err)
+		}
+	} else {
+		var err error
+		if records, err = archive.Manifest(); err != nil {
+			log.Fatal("Ошибка чтения манифеста: ", err)
+		}
+	}
+
+	db, err := database.NewDatabase()
+	if err != nil {
+		log.Fatal("Ошибка подключения к БД: ", err)
+	}
+	defer db.DB.Close()
+
+	fileService, err := file.NewFileService("/app")
+	if err != nil {
+		log.Fatal("Ошибка инициализации файлового сервиса: ", err)
+	}
+
+	report, err := catalog.NewImporter(db, fileService).Run(records, archive, catalog.Options{
+		DryRun:     !*apply,
+		AuthorName: *author,
+	})
+	if err != nil {
+		log.Fatal("Ошибка импорта: ", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Записей: %d, создать: %d, обновить: %d, ошибок: %d, применено: %t",
+		report.Rows, report.Creates, report.Updates, report.Errors, report.Applied)
+	if report.Errors > 0 {
+		os.Exit(1)
+	}
+}
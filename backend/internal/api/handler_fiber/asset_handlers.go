@@ -0,0 +1,328 @@
+// HTTP handlers for the media assets of a mineral or a specimen: listing, upload, caption and primary flag changes, reordering and deletion.
+// Mineral and specimen routes share the same implementation and differ only in how the owning record is resolved.
+// Uploads are validated by the file service and stored through a staging area, so a failed insert never leaves files behind.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"encoding/json"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+)
+
+type updateAssetRequest struct {
+	Captions  map[string]string `json:"captions"`
+	IsPrimary *bool             `json:"is_primary"`
+}
+
+type reorderAssetsRequest struct {
+	AssetIDs []int `json:"asset_ids"`
+}
+
+// assetOwnerResolver finds the record addressed by the :id route parameter.
+type assetOwnerResolver func(c *fiber.Ctx) (database.AssetOwner, *errors.APIError)
+
+func (h *Handler) GetMineralAssets(c *fiber.Ctx) error {
+	return h.listAssets(c, h.mineralOwner)
+}
+
+func (h *Handler) CreateMineralAsset(c *fiber.Ctx) error {
+	return h.createAsset(c, h.mineralOwner)
+}
+
+func (h *Handler) UpdateMineralAsset(c *fiber.Ctx) error {
+	return h.updateAsset(c, h.mineralOwner)
+}
+
+func (h *Handler) ReorderMineralAssets(c *fiber.Ctx) error {
+	return h.reorderAssets(c, h.mineralOwner)
+}
+
+func (h *Handler) DeleteMineralAsset(c *fiber.Ctx) error {
+	return h.deleteAsset(c, h.mineralOwner)
+}
+
+func (h *Handler) GetSpecimenAssets(c *fiber.Ctx) error {
+	return h.listAssets(c, h.specimenOwner)
+}
+
+func (h *Handler) CreateSpecimenAsset(c *fiber.Ctx) error {
+	return h.createAsset(c, h.specimenOwner)
+}
+
+func (h *Handler) UpdateSpecimenAsset(c *fiber.Ctx) error {
+	return h.updateAsset(c, h.specimenOwner)
+}
+
+func (h *Handler) ReorderSpecimenAssets(c *fiber.Ctx) error {
+	return h.reorderAssets(c, h.specimenOwner)
+}
+
+func (h *Handler) DeleteSpecimenAsset(c *fiber.Ctx) error {
+	return h.deleteAsset(c, h.specimenOwner)
+}
+
+func (h *Handler) mineralOwner(c *fiber.Ctx) (database.AssetOwner, *errors.APIError) {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return database.AssetOwner{}, apiErr
+	}
+	if _, apiErr := h.visibleMineral(c, id); apiErr != nil {
+		return database.AssetOwner{}, apiErr
+	}
+	return database.MineralOwner(id), nil
+}
+
+func (h *Handler) specimenOwner(c *fiber.Ctx) (database.AssetOwner, *errors.APIError) {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return database.AssetOwner{}, errors.ErrInvalidInput("некорректный id образца")
+	}
+	specimen, err := h.db.GetSpecimenByID(id)
+	if err != nil {
+		if err == database.ErrSpecimenNotFound {
+			return database.AssetOwner{}, errors.ErrNotFound("образец не найден")
+		}
+		return database.AssetOwner{}, errors.ErrServerError
+	}
+	if _, apiErr := h.visibleMineral(c, specimen.MineralID); apiErr != nil {
+		return database.AssetOwner{}, errors.ErrNotFound("образец не найден")
+	}
+	return database.AssetOwner{MineralID: specimen.MineralID, SpecimenID: specimen.ID}, nil
+}
+
+// lockAssetOwner locks the owning row, which serializes concurrent changes to the owner's assets and keeps positions unique.
+func lockAssetOwner(tx *database.Tx, owner database.AssetOwner) error {
+	if owner.SpecimenID != 0 {
+		_, err := tx.GetSpecimenForUpdate(owner.SpecimenID)
+		return err
+	}
+	_, err := tx.GetMineralForUpdate(owner.MineralID)
+	return err
+}
+
+func (h *Handler) listAssets(c *fiber.Ctx, resolve assetOwnerResolver) error {
+	owner, apiErr := resolve(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	assets, err := h.db.GetAssets(owner)
+	if err != nil {
+		log.Printf("Ошибка при получении ресурсов %+v: %v", owner, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	if lang := c.Query("lang"); lang != "" {
+		for i := range assets {
+			assets[i].LocalizeCaption(lang)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signAssets(assets),
+	})
+}
+
+// createAsset accepts a multipart form with file, type, optional captions (a JSON object keyed by language) and is_primary.
+func (h *Handler) createAsset(c *fiber.Ctx, resolve assetOwnerResolver) error {
+	assetFile, err := c.FormFile("file")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("файл не найден в запросе"))
+	}
+
+	asset := models.MineralAsset{
+		Type:      c.FormValue("type"),
+		Captions:  map[string]string{},
+		IsPrimary: c.FormValue("is_primary") == "true",
+	}
+	if raw := c.FormValue("captions"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &asset.Captions); err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput("captions должен быть JSON-объектом вида {\"ru\": \"...\"}"))
+		}
+	}
+	if err := asset.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	owner, apiErr := resolve(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	asset.MineralID = owner.MineralID
+	asset.SpecimenID = owner.SpecimenID
+
+	staging, err := h.fileService.NewStaging()
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+	defer staging.Rollback()
+
+	saved, err := h.fileService.WithStaging(staging).SaveAsset(assetFile, asset.Type)
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+	asset.Path = saved.Path
+	asset.SourcePath = saved.SourcePath
+
+	var created *models.MineralAsset
+	err = h.commitStaged(staging, func(tx *database.Tx) error {
+		if err := lockAssetOwner(tx, owner); err != nil {
+			return err
+		}
+		created, err = tx.CreateAsset(asset)
+		return err
+	})
+	if err != nil {
+		log.Printf("Ошибка при добавлении ресурса %+v: %v", owner, err)
+		return sendAssetError(c, err)
+	}
+
+	log.Printf("Добавлен ресурс %d (%s) для %+v: %s", created.ID, created.Type, owner, created.Path)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signAsset(*created),
+	})
+}
+
+func (h *Handler) updateAsset(c *fiber.Ctx, resolve assetOwnerResolver) error {
+	assetID, err := c.ParamsInt("assetId")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id ресурса"))
+	}
+
+	var req updateAssetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	if err := models.ValidateCaptions(req.Captions); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	owner, apiErr := resolve(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	asset, err := tx.GetAssetForUpdate(owner, assetID)
+	if err != nil {
+		return sendAssetError(c, err)
+	}
+	if req.Captions != nil {
+		asset.Captions = req.Captions
+	}
+	if req.IsPrimary != nil {
+		asset.IsPrimary = *req.IsPrimary
+	}
+
+	updated, err := tx.UpdateAsset(*asset)
+	if err != nil {
+		return sendAssetError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при обновлении ресурса %d: %v", assetID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signAsset(*updated),
+	})
+}
+
+// reorderAssets sets the display order. The body lists every asset id of the owner: {"asset_ids": [3, 1, 2]}.
+func (h *Handler) reorderAssets(c *fiber.Ctx, resolve assetOwnerResolver) error {
+	var req reorderAssetsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+
+	owner, apiErr := resolve(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	if err := lockAssetOwner(tx, owner); err != nil {
+		return sendAssetError(c, err)
+	}
+	assets, err := tx.ReorderAssets(owner, req.AssetIDs)
+	if err != nil {
+		return sendAssetError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при изменении порядка ресурсов %+v: %v", owner, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signAssets(assets),
+	})
+}
+
+func (h *Handler) deleteAsset(c *fiber.Ctx, resolve assetOwnerResolver) error {
+	assetID, err := c.ParamsInt("assetId")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id ресурса"))
+	}
+
+	owner, apiErr := resolve(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	asset, err := tx.GetAssetForUpdate(owner, assetID)
+	if err != nil {
+		return sendAssetError(c, err)
+	}
+	if err := tx.DeleteAsset(assetID); err != nil {
+		return sendAssetError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при удалении ресурса %d: %v", assetID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	h.removeUnreferencedFiles(asset.Path, asset.SourcePath)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func sendAssetError(c *fiber.Ctx, err error) error {
+	var apiErr *errors.APIError
+	switch {
+	case stderrors.As(err, &apiErr):
+		return errors.SendError(c, apiErr)
+	case stderrors.Is(err, database.ErrMineralNotFound):
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	case stderrors.Is(err, database.ErrSpecimenNotFound):
+		return errors.SendError(c, errors.ErrNotFound("образец не найден"))
+	case stderrors.Is(err, database.ErrAssetNotFound):
+		return errors.SendError(c, errors.ErrNotFound("ресурс не найден"))
+	case stderrors.Is(err, database.ErrAssetOrderMismatch):
+		return errors.SendError(c, errors.ErrInvalidInput("asset_ids должен содержать все ресурсы ровно по одному разу"))
+	}
+	log.Printf("Ошибка при работе с ресурсами: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
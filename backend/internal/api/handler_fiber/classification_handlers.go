@@ -0,0 +1,238 @@
+// HTTP handlers for browsing the Strunz/Dana classification: the whole tree with mineral counts per node,
+// a single node with its ancestors and children, and the minerals of any subtree. Administrators maintain the tree
+// and place minerals in it. Node names are localized with ?lang=, falling back to Russian.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+)
+
+type mineralClassificationRequest struct {
+	ClassificationID *int   `json:"classification_id"`
+	DanaCode         string `json:"dana_code"`
+}
+
+// GetClassificationTree returns the nested tree; every node carries mineral_count and total_count for its subtree.
+func (h *Handler) GetClassificationTree(c *fiber.Ctx) error {
+	tree, err := h.classificationTree(c.Query("lang"))
+	if err != nil {
+		log.Printf("Ошибка при получении классификации: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   tree,
+	})
+}
+
+// GetClassificationNode returns a node with its children and the path from its class, for breadcrumbs.
+func (h *Handler) GetClassificationNode(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id узла классификации"))
+	}
+
+	lang := c.Query("lang")
+	tree, err := h.classificationTree(lang)
+	if err != nil {
+		log.Printf("Ошибка при получении классификации: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	node := models.FindClassificationNode(tree, id)
+	if node == nil {
+		return errors.SendError(c, errors.ErrNotFound("узел классификации не найден"))
+	}
+
+	path, err := h.db.GetClassificationPath(id)
+	if err != nil {
+		log.Printf("Ошибка при получении пути узла классификации %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	for i := range path {
+		path[i].Localize(lang)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data": fiber.Map{
+			"node": node,
+			"path": path,
+		},
+	})
+}
+
+// GetClassificationMinerals lists the minerals placed in a node or anywhere in its subtree.
+func (h *Handler) GetClassificationMinerals(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id узла классификации"))
+	}
+
+	if _, err := h.db.GetClassificationNode(id); err != nil {
+		return sendClassificationError(c, err)
+	}
+	minerals, err := h.db.GetClassificationMinerals(id)
+	if err != nil {
+		log.Printf("Ошибка при получении минералов узла классификации %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signMinerals(minerals),
+	})
+}
+
+func (h *Handler) CreateClassificationNode(c *fiber.Ctx) error {
+	var node models.ClassificationNode
+	if err := c.BodyParser(&node); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+
+	var parent *models.ClassificationNode
+	if node.ParentID != nil {
+		var err error
+		parent, err = h.db.GetClassificationNode(*node.ParentID)
+		if err != nil {
+			if err == database.ErrClassificationNotFound {
+				return errors.SendError(c, errors.ErrInvalidInput("родительский узел не найден"))
+			}
+			return sendClassificationError(c, err)
+		}
+	}
+	if err := node.Validate(parent); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	created, err := h.db.CreateClassificationNode(node)
+	if err != nil {
+		return sendClassificationError(c, err)
+	}
+	created.Localize(c.Query("lang"))
+
+	log.Printf("Добавлен узел классификации %s (%s)", created.StrunzCode, created.Level)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   created,
+	})
+}
+
+// UpdateClassificationNode changes the names and Dana code of a node; level, code and parent are fixed.
+func (h *Handler) UpdateClassificationNode(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id узла классификации"))
+	}
+
+	var node models.ClassificationNode
+	if err := c.BodyParser(&node); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	node.ID = id
+	if err := node.ValidateDetails(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	updated, err := h.db.UpdateClassificationNode(node)
+	if err != nil {
+		return sendClassificationError(c, err)
+	}
+	updated.Localize(c.Query("lang"))
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   updated,
+	})
+}
+
+func (h *Handler) DeleteClassificationNode(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id узла классификации"))
+	}
+
+	if err := h.db.DeleteClassificationNode(id); err != nil {
+		return sendClassificationError(c, err)
+	}
+
+	log.Printf("Удален узел классификации %d", id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SetMineralClassification places a mineral in the tree: {"classification_id": 12, "dana_code": "2.8.1.1"}.
+// A null classification_id removes the mineral from the tree.
+func (h *Handler) SetMineralClassification(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	var req mineralClassificationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	if err := models.ValidateDanaCode(req.DanaCode); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	previous, err := tx.GetMineralForUpdate(id)
+	if err != nil {
+		return sendClassificationError(c, err)
+	}
+	mineral, err := tx.SetMineralClassification(id, req.ClassificationID, req.DanaCode)
+	if err != nil {
+		return sendClassificationError(c, err)
+	}
+	if err := h.recordRevision(c, tx, models.RevisionUpdate, previous, mineral, nil); err != nil {
+		return sendClassificationError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при изменении классификации минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signMineral(mineral),
+	})
+}
+
+func (h *Handler) classificationTree(lang string) ([]models.ClassificationNode, error) {
+	nodes, err := h.db.GetClassificationNodes()
+	if err != nil {
+		return nil, err
+	}
+	tree := models.BuildClassificationTree(nodes)
+	for i := range tree {
+		tree[i].Localize(lang)
+	}
+	return tree, nil
+}
+
+func sendClassificationError(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, database.ErrClassificationNotFound):
+		return errors.SendError(c, errors.ErrNotFound("узел классификации не найден"))
+	case stderrors.Is(err, database.ErrMineralNotFound):
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	case stderrors.Is(err, database.ErrClassificationCodeExists):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, "Узел с таким кодом уже существует", ""))
+	case stderrors.Is(err, database.ErrClassificationHasChildren):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, "Нельзя удалить узел, у которого есть дочерние узлы", ""))
+	}
+	log.Printf("Ошибка при работе с классификацией: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
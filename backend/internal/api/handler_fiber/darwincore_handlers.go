@@ -0,0 +1,56 @@
+// HTTP handlers for museum interoperability: the Darwin Core Archive of the visible specimens and the OAI-PMH endpoint
+// harvesters use to pull the records changed since a date. Both are public, as they only expose what visitors can see.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"bufio"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"net/url"
+	"time"
+)
+
+// DarwinCoreArchive streams a Darwin Core Archive (meta.xml and occurrence.txt) of the specimens.
+func (h *Handler) DarwinCoreArchive(c *fiber.Ctx) error {
+	filename := "dwca-" + time.Now().Format("20060102") + ".zip"
+	c.Attachment(filename)
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		count, err := h.darwinCore.WriteArchive(w)
+		if err != nil {
+			log.Printf("Ошибка экспорта Darwin Core после %d образцов: %v", count, err)
+		} else {
+			log.Printf("Экспорт Darwin Core: %d образцов", count)
+		}
+		if err := w.Flush(); err != nil {
+			log.Printf("Ошибка отправки архива Darwin Core: %v", err)
+		}
+	})
+	return nil
+}
+
+// OAIPMH answers OAI-PMH requests, with the arguments in the query string (GET) or a urlencoded form (POST).
+func (h *Handler) OAIPMH(c *fiber.Ctx) error {
+	raw := string(c.Request().URI().QueryString())
+	if c.Method() == fiber.MethodPost {
+		raw = string(c.Body())
+	}
+	args, err := url.ParseQuery(raw)
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректные аргументы запроса"))
+	}
+
+	baseURL := h.oaiProvider.BaseURL()
+	if baseURL == "" {
+		baseURL = c.BaseURL() + c.Path()
+	}
+	body, err := h.oaiProvider.Handle(baseURL, args)
+	if err != nil {
+		log.Printf("Ошибка ответа OAI-PMH: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	c.Set(fiber.HeaderContentType, "text/xml; charset=utf-8")
+	return c.Send(body)
+}
@@ -0,0 +1,63 @@
+// HTTP handlers for the periodic table: every element with its position, localized names and the number of
+// minerals in the collection that contain it, and the list of those minerals for a single element.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/chemistry"
+	"github.com/gofiber/fiber/v2"
+	"log"
+)
+
+type elementEntry struct {
+	chemistry.Element
+	Name         string `json:"name"`
+	MineralCount int    `json:"mineral_count"`
+}
+
+// GetElements returns the periodic table ordered by atomic number. ?lang= selects the language of name.
+func (h *Handler) GetElements(c *fiber.Ctx) error {
+	counts, err := h.db.GetElementMineralCounts()
+	if err != nil {
+		log.Printf("Ошибка при подсчете минералов по элементам: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	lang := c.Query("lang", models.DefaultLanguage)
+	table := chemistry.Elements()
+	entries := make([]elementEntry, 0, len(table))
+	for _, element := range table {
+		entries = append(entries, elementEntry{
+			Element:      element,
+			Name:         element.LocalizedName(lang),
+			MineralCount: counts[element.Symbol],
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   entries,
+	})
+}
+
+// GetElementMinerals lists the minerals whose formula contains the element, e.g. /elements/cu/minerals.
+func (h *Handler) GetElementMinerals(c *fiber.Ctx) error {
+	symbol, ok := chemistry.NormalizeSymbol(c.Params("symbol"))
+	if !ok {
+		return errors.SendError(c, errors.ErrNotFound("химический элемент не найден"))
+	}
+
+	minerals, err := h.db.FindMinerals(database.MineralFilter{Elements: []string{symbol}})
+	if err != nil {
+		log.Printf("Ошибка при получении минералов с элементом %s: %v", symbol, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signMinerals(minerals),
+	})
+}
@@ -0,0 +1,52 @@
+// HTTP handlers for the catalogue export: every mineral outside the trash as NDJSON or CSV, and a ZIP archive
+// with the model and preview files and a manifest that POST /admin/import accepts as it is.
+// Responses are streamed while the minerals are read, so an error midway can only be logged and ends the download early.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"bufio"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"log"
+	"time"
+)
+
+// ExportMinerals streams the catalogue, ?format=ndjson (default) or ?format=csv.
+func (h *Handler) ExportMinerals(c *fiber.Ctx) error {
+	var write func(w io.Writer) (int, error)
+	var contentType string
+	format := c.Query("format", "ndjson")
+	switch format {
+	case "ndjson":
+		write, contentType = h.exporter.WriteNDJSON, "application/x-ndjson"
+	case "csv":
+		write, contentType = h.exporter.WriteCSV, "text/csv; charset=utf-8"
+	default:
+		return errors.SendError(c, errors.ErrInvalidInput("format должен быть ndjson или csv"))
+	}
+	return h.streamExport(c, "minerals-"+time.Now().Format("20060102")+"."+format, contentType, write)
+}
+
+// ExportArchive streams a ZIP archive of the catalogue with its files.
+func (h *Handler) ExportArchive(c *fiber.Ctx) error {
+	return h.streamExport(c, "minerals-"+time.Now().Format("20060102")+".zip", "application/zip", h.exporter.WriteArchive)
+}
+
+func (h *Handler) streamExport(c *fiber.Ctx, filename, contentType string, write func(w io.Writer) (int, error)) error {
+	c.Attachment(filename)
+	c.Set(fiber.HeaderContentType, contentType)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		count, err := write(w)
+		if err != nil {
+			log.Printf("Ошибка экспорта %s после %d минералов: %v", filename, count, err)
+		} else {
+			log.Printf("Экспорт %s: %d минералов", filename, count)
+		}
+		if err := w.Flush(); err != nil {
+			log.Printf("Ошибка отправки экспорта %s: %v", filename, err)
+		}
+	})
+	return nil
+}
@@ -6,6 +6,9 @@ package handler_fiber
 
 import (
 	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/slug"
 	"github.com/gofiber/fiber/v2"
 	"log"
 )
@@ -18,14 +21,15 @@ func (h *Handler) UploadModel(c *fiber.Ctx) error {
 	}
 	log.Printf("Размер загружаемого файла: %d байт", file.Size)
 
-	modelPath, err := h.fileService.SaveModel(file)
+	savedModel, err := h.fileService.SaveModel(file)
 	if err != nil {
 		return errors.SendError(c, err.(*errors.APIError))
 	}
 
 	return c.JSON(fiber.Map{
-		"status": "success",
-		"path":   modelPath,
+		"status":      "success",
+		"path":        savedModel.Path,
+		"source_path": savedModel.SourcePath,
 	})
 }
 
@@ -48,3 +52,73 @@ func (h *Handler) UploadPreview(c *fiber.Ctx) error {
 		"path":   previewPath,
 	})
 }
+
+// RegeneratePreview re-renders the preview of an existing mineral from its stored model.
+// Pass sprite=true to additionally render a turntable sprite sheet with the given number of frames.
+func (h *Handler) RegeneratePreview(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	mineral, err := h.db.GetMineralByID(id)
+	if err != nil {
+		if err == database.ErrMineralNotFound {
+			return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+		}
+		log.Printf("Ошибка при получении минерала: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	staging, err := h.fileService.NewStaging()
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+	defer staging.Rollback()
+	files := h.fileService.WithStaging(staging)
+
+	previewPath, err := files.RenderPreview(mineral.ModelPath)
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+
+	response := fiber.Map{
+		"status": "success",
+		"path":   previewPath,
+	}
+
+	if c.QueryBool("sprite") {
+		turntable, err := files.RenderTurntable(mineral.ModelPath, c.QueryInt("frames"))
+		if err != nil {
+			return errors.SendError(c, err.(*errors.APIError))
+		}
+		response["sprite"] = turntable
+	}
+
+	var previous, updated *models.Mineral
+	err = h.commitStaged(staging, func(tx *database.Tx) error {
+		current, err := tx.GetMineralForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if current.PreviewImagePath == previewPath {
+			return nil
+		}
+		snapshot := *current
+		previous = &snapshot
+		current.PreviewImagePath = previewPath
+		updated, err = tx.UpdateMineral(*current, slug.Make(current.Title))
+		if err != nil {
+			return err
+		}
+		return h.recordRevision(c, tx, models.RevisionUpdate, previous, updated, nil)
+	})
+	if err != nil {
+		log.Printf("Ошибка при обновлении превью минерала %d: %v", id, err)
+		return sendStagedError(c, err)
+	}
+	h.removeReplacedFiles(previous, updated)
+
+	log.Printf("Превью минерала %d перегенерировано: %s", id, previewPath)
+	return c.JSON(response)
+}
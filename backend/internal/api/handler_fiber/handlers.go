@@ -11,15 +11,21 @@ import (
 	"backend/internal/api/middleware"
 	"backend/internal/database"
 	"backend/internal/models"
+	"backend/internal/service/catalog"
+	"backend/internal/service/darwincore"
 	"backend/internal/service/file"
+	"backend/internal/service/oaipmh"
+	"backend/internal/service/signing"
+	"backend/internal/service/slug"
+	"backend/internal/service/storagecheck"
 	"backend/internal/service/translation"
+	"backend/internal/service/trash"
+	"backend/internal/service/upload"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/golang-jwt/jwt/v4"
 	"log"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 )
@@ -28,13 +34,29 @@ type Handler struct {
 	db                 *database.Database
 	fileService        *file.FileService
 	translationService *translation.TranslationService
+	uploadService      *upload.UploadService
+	urlSigner          *signing.URLSigner
+	storageChecker     *storagecheck.Checker
+	trashPurger        *trash.Purger
+	importer           *catalog.Importer
+	exporter           *catalog.Exporter
+	darwinCore         *darwincore.Publisher
+	oaiProvider        *oaipmh.Provider
 }
 
-func New(db *database.Database, fileService *file.FileService, translationService *translation.TranslationService) *Handler {
+func New(db *database.Database, fileService *file.FileService, translationService *translation.TranslationService, uploadService *upload.UploadService, urlSigner *signing.URLSigner, storageChecker *storagecheck.Checker, trashPurger *trash.Purger, importer *catalog.Importer, exporter *catalog.Exporter, darwinCore *darwincore.Publisher, oaiProvider *oaipmh.Provider) *Handler {
 	return &Handler{
 		db:                 db,
 		fileService:        fileService,
 		translationService: translationService,
+		uploadService:      uploadService,
+		urlSigner:          urlSigner,
+		storageChecker:     storageChecker,
+		trashPurger:        trashPurger,
+		importer:           importer,
+		exporter:           exporter,
+		darwinCore:         darwinCore,
+		oaiProvider:        oaiProvider,
 	}
 }
 
@@ -84,7 +106,15 @@ func (h *Handler) Routes(app *fiber.App) {
 }
 
 func (h *Handler) GetAllMinerals(c *fiber.Ctx) error {
-	minerals, err := h.db.GetAllMinerals()
+	filter, apiErr := parseMineralFilter(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	if !filter.IsEmpty() {
+		return h.findMinerals(c, filter)
+	}
+
+	minerals, err := h.db.GetPublishedMinerals()
 	if err != nil {
 		log.Printf("Ошибка при получении минералов: %v", err)
 		return errors.SendError(c, errors.ErrServerError)
@@ -96,43 +126,108 @@ func (h *Handler) GetAllMinerals(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"status": "success",
-		"data":   minerals,
+		"data":   h.signMinerals(minerals),
 	})
 }
 
+// GetMineralByID accepts the numeric id or the UUID of a mineral.
 func (h *Handler) GetMineralByID(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("id")
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	mineral, apiErr := h.visibleMineral(c, id)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	return h.sendMineralDetails(c, mineral)
+}
+
+// sendMineralDetails responds with a mineral together with everything attached to it.
+func (h *Handler) sendMineralDetails(c *fiber.Ctx, mineral *models.Mineral) error {
+	id := mineral.ID
+	var err error
+	mineral.Assets, err = h.db.GetAssets(database.MineralOwner(id))
 	if err != nil {
-		return errors.SendError(c, errors.ErrInvalidInput("некорректный id минерала"))
+		log.Printf("Ошибка при получении ресурсов минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
 	}
 
-	mineral, err := h.db.GetMineralByID(id)
+	mineral.Specimens, err = h.db.GetMineralSpecimens(id)
 	if err != nil {
-		if err == database.ErrMineralNotFound {
-			return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
-		}
+		log.Printf("Ошибка при получении образцов минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	mineral.Specimens = visibleSpecimens(c, mineral.Specimens)
+
+	mineral.Localities, err = h.db.GetMineralLocalities(id)
+	if err != nil {
+		log.Printf("Ошибка при получении местонахождений минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	mineral.Composition, err = h.db.GetMineralComposition(id)
+	if err != nil {
+		log.Printf("Ошибка при получении состава минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	mineral.Structures, err = h.db.GetMineralStructures(id)
+	if err != nil {
+		log.Printf("Ошибка при получении структур минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	mineral.XRDPeaks, err = h.db.GetMineralXRDPeaks(id)
+	if err != nil {
+		log.Printf("Ошибка при получении дифрактограммы минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	mineral.Spectra, err = h.db.GetMineralSpectra(id, "")
+	if err != nil {
+		log.Printf("Ошибка при получении спектров минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	mineral.Names, err = h.db.GetMineralNames(id)
+	if err != nil {
+		log.Printf("Ошибка при получении синонимов минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	mineral.Slugs, err = h.db.GetCurrentMineralSlugs(id)
+	if err != nil {
+		log.Printf("Ошибка при получении адресов минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	mineral.Tags, err = h.db.GetMineralTags(id)
+	if err != nil {
+		log.Printf("Ошибка при получении тегов минерала %d: %v", id, err)
 		return errors.SendError(c, errors.ErrServerError)
 	}
+	localizeTags(mineral.Tags, c.Query("lang"))
+
+	if mineral.ClassificationID != nil {
+		mineral.Classification, err = h.db.GetClassificationPath(*mineral.ClassificationID)
+		if err != nil {
+			log.Printf("Ошибка при получении классификации минерала %d: %v", id, err)
+			return errors.SendError(c, errors.ErrServerError)
+		}
+		for i := range mineral.Classification {
+			mineral.Classification[i].Localize(c.Query("lang"))
+		}
+	}
 
 	return c.JSON(fiber.Map{
 		"status": "success",
-		"data":   mineral,
+		"data":   h.signMineral(mineral),
 	})
 }
 
 func (h *Handler) CreateMineral(c *fiber.Ctx) error {
-	modelDir := "/app/storage/models"
-	previewDir := "/app/storage/previews"
-
-	if err := os.MkdirAll(modelDir, os.ModePerm); err != nil {
-		log.Printf("Ошибка при создании директории модели: %v", err)
-		return errors.SendError(c, errors.ErrFileOperation("Не удалось создать директорию для модели"))
-	}
-	if err := os.MkdirAll(previewDir, os.ModePerm); err != nil {
-		log.Printf("Ошибка при создании директории превью: %v", err)
-		return errors.SendError(c, errors.ErrFileOperation("Не удалось создать директорию для превью"))
-	}
-
 	modelFile, err := c.FormFile("model")
 	if err != nil {
 		return errors.SendError(c, errors.ErrInvalidInput("Ошибка при загрузке файла модели"))
@@ -140,11 +235,14 @@ func (h *Handler) CreateMineral(c *fiber.Ctx) error {
 
 	previewFile, err := c.FormFile("preview")
 	if err != nil {
-		return errors.SendError(c, errors.ErrInvalidInput("Ошибка при загрузке файла превью"))
+		previewFile = nil
 	}
 
-	modelPath := "/storage/models/" + modelFile.Filename
-	previewImagePath := "/storage/previews/" + previewFile.Filename
+	modelPath := "/storage/models/" + file.ModelFileName(modelFile.Filename)
+	previewImagePath := ""
+	if previewFile != nil {
+		previewImagePath = "/storage/previews/" + previewFile.Filename
+	}
 
 	log.Println("Model URL path:", modelPath)
 	log.Println("Preview URL path:", previewImagePath)
@@ -157,51 +255,90 @@ func (h *Handler) CreateMineral(c *fiber.Ctx) error {
 		Description:      description,
 		ModelPath:        modelPath,
 		PreviewImagePath: previewImagePath,
+		CrystalSystem:    c.FormValue("crystal_system"),
+		Status:           models.StatusDraft,
 		CreatedAt:        time.Now(),
 	}
 
+	composition, apiErr := parseFormulaValue(c.FormValue("formula"))
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	applyComposition(mineral, composition)
+
 	log.Printf("Mineral: %+v\n", mineral)
 
 	if err := mineral.Validate(); err != nil {
 		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
 	}
 
-	fullModelPath := filepath.Join(modelDir, modelFile.Filename)
-	log.Println("Saving model file to:", fullModelPath)
-	if err := c.SaveFile(modelFile, fullModelPath); err != nil {
+	if !file.IsAllowedModelFile(modelFile.Filename) {
+		return errors.SendError(c, errors.ErrInvalidTypeFile("можно загружать только файлы "+file.AllowedSourceExts))
+	}
+
+	staging, err := h.fileService.NewStaging()
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+	defer staging.Rollback()
+	files := h.fileService.WithStaging(staging)
+
+	savedModel, err := files.SaveModel(modelFile)
+	if err != nil {
 		log.Printf("Ошибка при сохранении файла модели: %v", err)
-		return errors.SendError(c, errors.ErrFileOperation("Не удалось сохранить файл модели"))
+		return errors.SendError(c, err.(*errors.APIError))
 	}
-	log.Println("Модель успешно сохранена по пути: ", fullModelPath)
+	mineral.ModelPath = savedModel.Path
+	mineral.SourceModelPath = savedModel.SourcePath
+	log.Println("Модель подготовлена по пути: ", savedModel.Path)
 
-	fullPreviewPath := filepath.Join(previewDir, previewFile.Filename)
-	log.Println("Saving preview file to:", fullPreviewPath)
-	if err := c.SaveFile(previewFile, fullPreviewPath); err != nil {
-		log.Printf("Ошибка при сохранении файла превью: %v", err)
-		return errors.SendError(c, errors.ErrFileOperation("Не удалось сохранить файл превью"))
+	if previewFile != nil {
+		savedPreview, err := files.SavePreview(previewFile)
+		if err != nil {
+			log.Printf("Ошибка при сохранении файла превью: %v", err)
+			return errors.SendError(c, err.(*errors.APIError))
+		}
+		mineral.PreviewImagePath = savedPreview
+		log.Println("Превью подготовлено ", savedPreview)
+	} else {
+		log.Println("Превью не передано, рендеринг по модели:", savedModel.Path)
+		renderedPath, err := files.RenderPreview(savedModel.Path)
+		if err != nil {
+			log.Printf("Ошибка при рендеринге превью: %v", err)
+			return errors.SendError(c, err.(*errors.APIError))
+		}
+		mineral.PreviewImagePath = renderedPath
 	}
-	log.Println("Превью было успешно добавлено ", fullPreviewPath)
 
-	newMineral, err := h.db.CreateMineral(*mineral)
+	var newMineral *models.Mineral
+	err = h.commitStaged(staging, func(tx *database.Tx) error {
+		newMineral, err = tx.CreateMineral(*mineral, slug.Make(mineral.Title))
+		if err != nil {
+			return err
+		}
+		if err := tx.SetMineralComposition(newMineral.ID, compositionElements(composition)); err != nil {
+			return err
+		}
+		return h.recordRevision(c, tx, models.RevisionCreate, nil, newMineral, nil)
+	})
 	if err != nil {
 		log.Printf("Ошибка при создании минерала: %v", err)
-		return errors.SendError(c, errors.ErrServerError)
+		return sendStagedError(c, err)
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"status": "success",
-		"data":   newMineral,
+		"data":   h.signMineral(newMineral),
 	})
 }
 
 func (h *Handler) UpdateMineral(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("id")
-	if err != nil {
-		return errors.SendError(c, errors.ErrInvalidInput("некорректный id"))
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
 	}
 
-	currentMineral, err := h.db.GetMineralByID(id)
-	if err != nil {
+	if _, err := h.db.GetMineralByID(id); err != nil {
 		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
 	}
 
@@ -211,53 +348,113 @@ func (h *Handler) UpdateMineral(c *fiber.Ctx) error {
 	log.Printf("Получены данные для обновления минерала %d: title=%s, description=%s",
 		id, title, description)
 
-	if title != "" {
-		currentMineral.Title = title
+	composition, apiErr := parseFormulaValue(c.FormValue("formula"))
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
 	}
-	if description != "" {
-		currentMineral.Description = description
+
+	crystalSystem := c.FormValue("crystal_system")
+	if apiErr := h.checkStructureSystems(id, crystalSystem); apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	staging, err := h.fileService.NewStaging()
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
 	}
+	defer staging.Rollback()
+	files := h.fileService.WithStaging(staging)
 
+	var savedModel *file.SavedModel
 	if modelFile, err := c.FormFile("model"); err == nil {
-		modelPath, err := h.fileService.SaveModel(modelFile)
+		savedModel, err = files.SaveModel(modelFile)
 		if err != nil {
 			return errors.SendError(c, err.(*errors.APIError))
 		}
-		currentMineral.ModelPath = modelPath
 	}
 
+	previewPath := ""
 	if previewFile, err := c.FormFile("preview"); err == nil {
-		previewPath, err := h.fileService.SavePreview(previewFile)
+		previewPath, err = files.SavePreview(previewFile)
 		if err != nil {
 			return errors.SendError(c, err.(*errors.APIError))
 		}
-		currentMineral.PreviewImagePath = previewPath
 	}
 
-	if err := currentMineral.Validate(); err != nil {
-		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
-	}
+	var previous, updatedMineral *models.Mineral
+	err = h.commitStaged(staging, func(tx *database.Tx) error {
+		currentMineral, err := tx.GetMineralForUpdate(id)
+		if err != nil {
+			return err
+		}
+		snapshot := *currentMineral
+		previous = &snapshot
+
+		if title != "" {
+			currentMineral.Title = title
+		}
+		if description != "" {
+			currentMineral.Description = description
+		}
+		if savedModel != nil {
+			currentMineral.ModelPath = savedModel.Path
+			currentMineral.SourceModelPath = savedModel.SourcePath
+		}
+		if previewPath != "" {
+			currentMineral.PreviewImagePath = previewPath
+		}
+
+		if composition != nil {
+			applyComposition(currentMineral, composition)
+		}
+		if crystalSystem != "" {
+			currentMineral.CrystalSystem = crystalSystem
+		}
 
-	updatedMineral, err := h.db.UpdateMineral(*currentMineral)
+		if err := currentMineral.Validate(); err != nil {
+			return errors.ErrInvalidInput(err.Error())
+		}
+
+		updatedMineral, err = tx.UpdateMineral(*currentMineral, slug.Make(currentMineral.Title))
+		if err != nil {
+			return err
+		}
+		if composition != nil {
+			if err := tx.SetMineralComposition(id, composition.Elements); err != nil {
+				return err
+			}
+		}
+		return h.recordRevision(c, tx, models.RevisionUpdate, previous, updatedMineral, nil)
+	})
 	if err != nil {
-		log.Printf("Ошибка при обновлении минерала в БД: %v", err)
-		return errors.SendError(c, errors.ErrServerError)
+		log.Printf("Ошибка при обновлении минерала %d: %v", id, err)
+		return sendStagedError(c, err)
 	}
 
+	h.removeReplacedFiles(previous, updatedMineral)
+
 	log.Printf("Минерал %d успешно обновлен", id)
 	return c.JSON(fiber.Map{
 		"status": "success",
-		"data":   updatedMineral,
+		"data":   h.signMineral(updatedMineral),
 	})
 
 }
+// DeleteMineral moves a mineral to the trash; its files are removed only when it is purged.
 func (h *Handler) DeleteMineral(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("id")
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	tx, err := h.db.Begin()
 	if err != nil {
-		return errors.SendError(c, errors.ErrInvalidInput("некорректный id"))
+		log.Printf("Ошибка при открытии транзакции: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
 	}
+	defer tx.Rollback()
 
-	mineral, err := h.db.GetMineralByID(id)
+	mineral, err := tx.GetMineralForUpdate(id)
 	if err != nil {
 		if err == database.ErrMineralNotFound {
 			return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
@@ -266,27 +463,20 @@ func (h *Handler) DeleteMineral(c *fiber.Ctx) error {
 		return errors.SendError(c, errors.ErrServerError)
 	}
 
-	baseDir := "/app/storage"
-	modelFilePath := filepath.Join(baseDir, strings.TrimPrefix(mineral.ModelPath, "/storage"))
-	previewFilePath := filepath.Join(baseDir, strings.TrimPrefix(mineral.PreviewImagePath, "/storage"))
-
-	if err := h.db.DeleteMineral(id); err != nil {
-		log.Printf("Ошибка при удалении минерала из БД: %v", err)
+	if err := h.recordRevision(c, tx, models.RevisionDelete, nil, mineral, nil); err != nil {
+		log.Printf("Ошибка при сохранении ревизии минерала %d: %v", id, err)
 		return errors.SendError(c, errors.ErrServerError)
 	}
-
-	log.Printf("Удаление файла модели: %s", modelFilePath)
-	if err := os.Remove(modelFilePath); err != nil {
-		log.Printf("Ошибка при удалении файла модели: %v", err)
-
+	if err := tx.DeleteMineral(id); err != nil {
+		log.Printf("Ошибка при удалении минерала из БД: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
 	}
-
-	log.Printf("Удаление файла превью: %s", previewFilePath)
-	if err := os.Remove(previewFilePath); err != nil {
-		log.Printf("Ошибка при удалении файла превью: %v", err)
-
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при фиксации удаления минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
 	}
 
+	log.Printf("Минерал %d перемещён в корзину", id)
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -301,38 +491,50 @@ func (h *Handler) SearchMineral(c *fiber.Ctx) error {
 		})
 	}
 
-	minerals, err := h.db.GetAllMinerals()
+	minerals, err := h.db.GetPublishedMinerals()
 	if err != nil {
 		log.Printf("Ошибка при получении минералов: %v", err)
 		return errors.SendError(c, errors.ErrServerError)
 	}
 
+	// Synonyms and varieties are matched in any language, so "amethyst" finds quartz.
+	nameMatches, err := h.db.FindMineralsByName(query)
+	if err != nil {
+		log.Printf("Ошибка при поиске по синонимам: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
 	var searchResults []models.Mineral
 	for _, mineral := range minerals {
 		translatedTitle, err := h.translationService.Translate(mineral.Title, "ru", targetLang)
-		if err == nil {
-
-			if strings.HasPrefix(
-				strings.ToLower(translatedTitle),
-				strings.ToLower(query),
-			) {
-
-				translatedMineral := mineral
-				translatedMineral.Title = translatedTitle
+		titleMatches := err == nil && strings.HasPrefix(
+			strings.ToLower(translatedTitle),
+			strings.ToLower(query),
+		)
+		matchedName, byName := nameMatches[mineral.ID]
+		if !titleMatches && !byName {
+			continue
+		}
 
-				translatedDesc, err := h.translationService.Translate(mineral.Description, "ru", targetLang)
-				if err == nil {
-					translatedMineral.Description = translatedDesc
-				}
+		translatedMineral := mineral
+		if err == nil {
+			translatedMineral.Title = translatedTitle
+		}
+		if !titleMatches {
+			translatedMineral.MatchedName = matchedName
+		}
 
-				searchResults = append(searchResults, translatedMineral)
-			}
+		translatedDesc, err := h.translationService.Translate(mineral.Description, "ru", targetLang)
+		if err == nil {
+			translatedMineral.Description = translatedDesc
 		}
+
+		searchResults = append(searchResults, translatedMineral)
 	}
 
 	return c.JSON(fiber.Map{
 		"status": "success",
-		"data":   searchResults,
+		"data":   h.signMinerals(searchResults),
 	})
 }
 func (h *Handler) AddToFavorites(c *fiber.Ctx) error {
@@ -0,0 +1,81 @@
+// HTTP handler for the bulk import of minerals: a CSV or JSON manifest with an optional ZIP archive of
+// model and preview files. The import is a dry run unless ?dry_run=false is given; either way the response
+// reports the action taken for every row, and an import with row errors changes nothing.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/service/catalog"
+	"backend/internal/service/file"
+	"bytes"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+)
+
+// ImportMinerals checks or applies an import. Form fields: manifest (.csv, .json, .ndjson) and archive (.zip).
+// Without a manifest field the manifest is read from the archive, as in an archive made by GET /admin/export/archive.
+func (h *Handler) ImportMinerals(c *fiber.Ctx) error {
+	var archive *catalog.Archive
+	if archiveFile, err := c.FormFile("archive"); err == nil {
+		src, err := archiveFile.Open()
+		if err != nil {
+			log.Printf("Ошибка открытия архива импорта: %v", err)
+			return errors.SendError(c, errors.ErrFileOperation("не удалось открыть архив"))
+		}
+		defer src.Close()
+		if archive, err = catalog.OpenArchive(src, archiveFile.Size); err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+		}
+	}
+
+	var records []catalog.Record
+	manifestFile, err := c.FormFile("manifest")
+	switch {
+	case err == nil:
+		data, err := file.ReadUpload(manifestFile, catalog.MaxManifestSize)
+		if err != nil {
+			return errors.SendError(c, err.(*errors.APIError))
+		}
+		records, err = catalog.ParseManifest(manifestFile.Filename, bytes.NewReader(data))
+		if err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+		}
+	case archive != nil:
+		if records, err = archive.Manifest(); err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+		}
+	default:
+		return errors.SendError(c, errors.ErrInvalidInput("нужен файл манифеста (manifest) или архив с манифестом (archive)"))
+	}
+
+	authorID, authorName := revisionAuthor(c)
+	report, err := h.importer.Run(records, archive, catalog.Options{
+		DryRun:     c.QueryBool("dry_run", true),
+		AuthorID:   authorID,
+		AuthorName: authorName,
+	})
+	if err != nil {
+		log.Printf("Ошибка импорта минералов: %v", err)
+		var apiErr *errors.APIError
+		if stderrors.As(err, &apiErr) {
+			return errors.SendError(c, errors.NewAPIError(apiErr.StatusCode, apiErr.Message, err.Error()))
+		}
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	if report.Errors > 0 && !report.DryRun {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "импорт не выполнен: в манифесте есть ошибки",
+			"data":  report,
+		})
+	}
+	if report.Applied {
+		log.Printf("Импорт минералов: создано %d, обновлено %d", report.Creates, report.Updates)
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   report,
+	})
+}
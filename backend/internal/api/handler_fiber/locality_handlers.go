@@ -0,0 +1,226 @@
+// HTTP handlers for geographic localities: listing with bounding-box and radius filters, CRUD for administrators,
+// and linking localities to minerals. List endpoints return GeoJSON FeatureCollections with ?format=geojson
+// so the frontend can draw occurrence maps directly.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/geo"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+)
+
+type mineralLocalitiesRequest struct {
+	LocalityIDs []int `json:"locality_ids"`
+}
+
+// GetLocalities lists localities, optionally filtered by ?bbox=minLon,minLat,maxLon,maxLat or ?near=lat,lon&radius_km=.
+func (h *Handler) GetLocalities(c *fiber.Ctx) error {
+	var (
+		localities []models.Locality
+		err        error
+	)
+	switch {
+	case c.Query("bbox") != "" && c.Query("near") != "":
+		return errors.SendError(c, errors.ErrInvalidInput("параметры bbox и near нельзя использовать одновременно"))
+	case c.Query("bbox") != "":
+		box, parseErr := geo.ParseBBox(c.Query("bbox"))
+		if parseErr != nil {
+			return errors.SendError(c, errors.ErrInvalidInput(parseErr.Error()))
+		}
+		localities, err = h.db.GetLocalitiesInBBox(box)
+	case c.Query("near") != "":
+		near, apiErr := parseNear(c)
+		if apiErr != nil {
+			return errors.SendError(c, apiErr)
+		}
+		localities, err = h.db.GetLocalitiesNear(*near)
+	default:
+		localities, err = h.db.GetAllLocalities()
+	}
+	if err != nil {
+		log.Printf("Ошибка при получении местонахождений: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return sendLocalities(c, localities)
+}
+
+func (h *Handler) GetLocalityByID(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id местонахождения"))
+	}
+
+	locality, err := h.db.GetLocalityByID(id)
+	if err != nil {
+		return sendLocalityError(c, err)
+	}
+
+	if c.Query("format") == "geojson" {
+		return c.JSON(geo.LocalityFeature(*locality), geo.ContentType)
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   locality,
+	})
+}
+
+func (h *Handler) CreateLocality(c *fiber.Ctx) error {
+	var locality models.Locality
+	if err := c.BodyParser(&locality); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	if err := locality.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	created, err := h.db.CreateLocality(locality, geo.Bounds(locality.Latitude, locality.Longitude, locality.Polygon))
+	if err != nil {
+		return sendLocalityError(c, err)
+	}
+
+	log.Printf("Добавлено местонахождение %d: %s", created.ID, created.Name)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   created,
+	})
+}
+
+func (h *Handler) UpdateLocality(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id местонахождения"))
+	}
+
+	var locality models.Locality
+	if err := c.BodyParser(&locality); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	locality.ID = id
+	if err := locality.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	updated, err := h.db.UpdateLocality(locality, geo.Bounds(locality.Latitude, locality.Longitude, locality.Polygon))
+	if err != nil {
+		return sendLocalityError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   updated,
+	})
+}
+
+func (h *Handler) DeleteLocality(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id местонахождения"))
+	}
+
+	if err := h.db.DeleteLocality(id); err != nil {
+		return sendLocalityError(c, err)
+	}
+
+	log.Printf("Удалено местонахождение %d", id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetMineralLocalities lists where a mineral occurs: linked localities and those of its specimens.
+func (h *Handler) GetMineralLocalities(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	localities, err := h.db.GetMineralLocalities(owner.MineralID)
+	if err != nil {
+		log.Printf("Ошибка при получении местонахождений минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return sendLocalities(c, localities)
+}
+
+// SetMineralLocalities replaces the localities linked to a mineral: {"locality_ids": [1, 2]}.
+func (h *Handler) SetMineralLocalities(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	var req mineralLocalitiesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.GetMineralForUpdate(id); err != nil {
+		return sendLocalityError(c, err)
+	}
+	if err := tx.SetMineralLocalities(id, req.LocalityIDs); err != nil {
+		return sendLocalityError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при обновлении местонахождений минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	localities, err := h.db.GetMineralLocalities(id)
+	if err != nil {
+		log.Printf("Ошибка при получении местонахождений минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   localities,
+	})
+}
+
+func parseNear(c *fiber.Ctx) (*database.NearFilter, *errors.APIError) {
+	lat, lon, err := geo.ParsePoint(c.Query("near"))
+	if err != nil {
+		return nil, errors.ErrInvalidInput(err.Error())
+	}
+	radius, err := geo.ParseRadius(c.Query("radius_km"))
+	if err != nil {
+		return nil, errors.ErrInvalidInput(err.Error())
+	}
+	return &database.NearFilter{Lat: lat, Lon: lon, RadiusKM: radius}, nil
+}
+
+// sendLocalities responds with the usual envelope, or with a GeoJSON FeatureCollection for ?format=geojson.
+func sendLocalities(c *fiber.Ctx, localities []models.Locality) error {
+	if c.Query("format") == "geojson" {
+		features := make([]geo.Feature, 0, len(localities))
+		for i := range localities {
+			features = append(features, geo.LocalityFeature(localities[i]))
+		}
+		return c.JSON(geo.NewFeatureCollection(features), geo.ContentType)
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   localities,
+	})
+}
+
+func sendLocalityError(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, database.ErrLocalityNotFound):
+		return errors.SendError(c, errors.ErrNotFound("местонахождение не найдено"))
+	case stderrors.Is(err, database.ErrMineralNotFound):
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	}
+	log.Printf("Ошибка при работе с местонахождениями: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
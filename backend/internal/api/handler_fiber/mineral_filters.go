@@ -0,0 +1,127 @@
+// Query-string filters for the mineral list: ?near=lat,lon&radius_km= for occurrences near a point and
+// ?elements=Cu,S&exclude=Fe for the chemical composition and ?tags=fluorescent,gemstone for tags. Filters combine with AND.
+// Also parses the formula form field of mineral create and update requests into the stored composition.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/chemistry"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"strings"
+)
+
+func parseMineralFilter(c *fiber.Ctx) (database.MineralFilter, *errors.APIError) {
+	var filter database.MineralFilter
+	if c.Query("near") != "" {
+		near, apiErr := parseNear(c)
+		if apiErr != nil {
+			return filter, apiErr
+		}
+		filter.Near = near
+	}
+
+	var apiErr *errors.APIError
+	if filter.Elements, apiErr = parseElementList(c.Query("elements")); apiErr != nil {
+		return filter, apiErr
+	}
+	if filter.ExcludeElements, apiErr = parseElementList(c.Query("exclude")); apiErr != nil {
+		return filter, apiErr
+	}
+	for _, included := range filter.Elements {
+		for _, excluded := range filter.ExcludeElements {
+			if included == excluded {
+				return filter, errors.ErrInvalidInput("элемент " + included + " указан и в elements, и в exclude")
+			}
+		}
+	}
+	if filter.Tags, apiErr = parseTagList(c.Query("tags")); apiErr != nil {
+		return filter, apiErr
+	}
+	return filter, nil
+}
+
+// parseTagList parses a comma-separated list of tag slugs, dropping duplicates.
+func parseTagList(raw string) ([]string, *errors.APIError) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var tags []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		tag := strings.ToLower(strings.TrimSpace(part))
+		if !models.ValidSlug(tag) {
+			return nil, errors.ErrInvalidInput("некорректный тег: " + strings.TrimSpace(part))
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+// parseElementList parses a comma-separated list of element symbols in any case, dropping duplicates.
+func parseElementList(raw string) ([]string, *errors.APIError) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var symbols []string
+	seen := map[string]bool{}
+	for _, part := range strings.Split(raw, ",") {
+		symbol, ok := chemistry.NormalizeSymbol(part)
+		if !ok {
+			return nil, errors.ErrInvalidInput("неизвестный химический элемент: " + strings.TrimSpace(part))
+		}
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols, nil
+}
+
+func (h *Handler) findMinerals(c *fiber.Ctx, filter database.MineralFilter) error {
+	minerals, err := h.db.FindMinerals(filter)
+	if err != nil {
+		log.Printf("Ошибка при поиске минералов: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signMinerals(minerals),
+	})
+}
+
+// parseFormulaValue parses the optional formula form field; an empty value yields no composition.
+func parseFormulaValue(formula string) (*chemistry.Composition, *errors.APIError) {
+	if strings.TrimSpace(formula) == "" {
+		return nil, nil
+	}
+	composition, err := chemistry.ParseFormula(formula)
+	if err != nil {
+		return nil, errors.ErrInvalidInput(err.Error())
+	}
+	return composition, nil
+}
+
+// compositionElements returns the element rows of a parsed formula, nil when the mineral has no formula.
+func compositionElements(composition *chemistry.Composition) []models.ElementAmount {
+	if composition == nil {
+		return nil
+	}
+	return composition.Elements
+}
+
+func applyComposition(mineral *models.Mineral, composition *chemistry.Composition) {
+	if composition == nil {
+		return
+	}
+	molarMass := composition.MolarMass
+	mineral.Formula = composition.Formula
+	mineral.MolarMass = &molarMass
+}
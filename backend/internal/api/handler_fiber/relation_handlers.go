@@ -0,0 +1,176 @@
+// HTTP handlers for synonyms and varieties of minerals and for the graph of related species.
+// GET /minerals/:id/related walks the relations up to ?depth= steps away and returns the nodes and edges,
+// ready to be drawn as a graph.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"strconv"
+)
+
+const (
+	DefaultRelationDepth = 1
+	MaxRelationDepth     = 3
+)
+
+func (h *Handler) GetMineralNames(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	names, err := h.db.GetMineralNames(owner.MineralID)
+	if err != nil {
+		log.Printf("Ошибка при получении синонимов минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   names,
+	})
+}
+
+// CreateMineralName adds a synonym or variety: {"name": "amethyst", "lang": "en", "kind": "variety", "description": "..."}.
+func (h *Handler) CreateMineralName(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	var name models.MineralName
+	if err := c.BodyParser(&name); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	name.MineralID = id
+	if err := name.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	created, err := h.db.CreateMineralName(name)
+	if err != nil {
+		return sendRelationError(c, err)
+	}
+
+	log.Printf("Добавлено название %q (%s) минерала %d", created.Name, created.Kind, id)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   created,
+	})
+}
+
+func (h *Handler) DeleteMineralName(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id названия"))
+	}
+
+	if err := h.db.DeleteMineralName(id); err != nil {
+		return sendRelationError(c, err)
+	}
+
+	log.Printf("Удалено название %d", id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetMineralRelated returns the relation graph around a mineral, ?depth=1..3 steps deep.
+func (h *Handler) GetMineralRelated(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	depth := DefaultRelationDepth
+	if raw := c.Query("depth"); raw != "" {
+		var err error
+		depth, err = strconv.Atoi(raw)
+		if err != nil || depth < 1 || depth > MaxRelationDepth {
+			return errors.SendError(c, errors.ErrInvalidInput("depth должен быть от 1 до "+strconv.Itoa(MaxRelationDepth)))
+		}
+	}
+
+	graph, err := h.db.GetRelationGraph(owner.MineralID, depth, canSeeUnpublished(c))
+	if err != nil {
+		log.Printf("Ошибка при получении связей минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	if h.urlSigner != nil && h.urlSigner.Enabled() {
+		for i := range graph.Nodes {
+			graph.Nodes[i].PreviewImagePath = h.urlSigner.Sign(graph.Nodes[i].PreviewImagePath)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   graph,
+	})
+}
+
+// CreateMineralRelation links two minerals: {"related_id": 12, "type": "polymorph_of", "note": "..."}.
+// For pseudomorph_after the mineral in the path is the pseudomorph and related_id the replaced mineral.
+func (h *Handler) CreateMineralRelation(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	var req struct {
+		RelatedID int    `json:"related_id"`
+		Type      string `json:"type"`
+		Note      string `json:"note"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	relation := models.MineralRelation{MineralID: id, RelatedID: req.RelatedID, Type: req.Type, Note: req.Note}
+	if err := relation.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	created, err := h.db.CreateMineralRelation(relation)
+	if err != nil {
+		return sendRelationError(c, err)
+	}
+
+	log.Printf("Добавлена связь %d: %d %s %d", created.ID, created.MineralID, created.Type, created.RelatedID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   created,
+	})
+}
+
+func (h *Handler) DeleteMineralRelation(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id связи"))
+	}
+
+	if err := h.db.DeleteMineralRelation(id); err != nil {
+		return sendRelationError(c, err)
+	}
+
+	log.Printf("Удалена связь %d", id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func sendRelationError(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, database.ErrMineralNotFound):
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	case stderrors.Is(err, database.ErrMineralNameNotFound):
+		return errors.SendError(c, errors.ErrNotFound("название не найдено"))
+	case stderrors.Is(err, database.ErrRelationNotFound):
+		return errors.SendError(c, errors.ErrNotFound("связь не найдена"))
+	case stderrors.Is(err, database.ErrMineralNameExists):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, "Такое название уже есть у минерала", err.Error()))
+	case stderrors.Is(err, database.ErrRelationExists):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, "Такая связь уже существует", err.Error()))
+	}
+	log.Printf("Ошибка при работе с названиями и связями минералов: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
@@ -0,0 +1,237 @@
+// HTTP handlers for the revision history of minerals: listing, a field-level and word-level diff between two revisions,
+// and restoring an earlier revision. Restoring writes a new revision, so it can itself be undone.
+// recordRevision is called by every handler that changes a mineral record, inside the same transaction.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/slug"
+	"backend/internal/service/textdiff"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"log"
+	"strconv"
+)
+
+// revisionAuthor reads the author of a change from the JWT claims set by the auth middleware.
+func revisionAuthor(c *fiber.Ctx) (*int, string) {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	if !ok {
+		return nil, ""
+	}
+	var authorID *int
+	if id, ok := claims["id"].(float64); ok {
+		value := int(id)
+		authorID = &value
+	}
+	username, _ := claims["username"].(string)
+	return authorID, username
+}
+
+// recordRevision stores the state of mineral after a change. For updates, previous is the state before it:
+// a mineral without history first gets a baseline revision of that state.
+func (h *Handler) recordRevision(c *fiber.Ctx, tx *database.Tx, action string, previous, mineral *models.Mineral, restoredFrom *int) error {
+	if previous != nil {
+		if err := tx.EnsureBaselineRevision(previous); err != nil {
+			return err
+		}
+	}
+	authorID, authorName := revisionAuthor(c)
+	_, err := tx.CreateMineralRevision(models.MineralRevision{
+		MineralID:    mineral.ID,
+		Action:       action,
+		AuthorID:     authorID,
+		AuthorName:   authorName,
+		RestoredFrom: restoredFrom,
+		Snapshot:     models.SnapshotOf(mineral),
+	})
+	return err
+}
+
+// GetMineralRevisions lists the revisions of a mineral, newest first. The history of a deleted mineral stays readable.
+func (h *Handler) GetMineralRevisions(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	revisions, err := h.db.GetMineralRevisions(id)
+	if err != nil {
+		log.Printf("Ошибка при получении истории минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	if len(revisions) == 0 {
+		if _, err := h.db.GetMineralByID(id); err != nil {
+			return sendRevisionError(c, err)
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   revisions,
+	})
+}
+
+func (h *Handler) GetMineralRevision(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	number, err := c.ParamsInt("revision")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный номер ревизии"))
+	}
+
+	revision, err := h.db.GetMineralRevision(id, number)
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   revision,
+	})
+}
+
+// DiffMineralRevisions compares two revisions: ?from=3&to=5. Without to, the latest revision is used.
+func (h *Handler) DiffMineralRevisions(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	fromNumber, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("укажите номер ревизии from"))
+	}
+
+	from, err := h.db.GetMineralRevision(id, fromNumber)
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	var to *models.MineralRevision
+	if raw := c.Query("to"); raw != "" {
+		toNumber, err := strconv.Atoi(raw)
+		if err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput("некорректный номер ревизии to"))
+		}
+		to, err = h.db.GetMineralRevision(id, toNumber)
+		if err != nil {
+			return sendRevisionError(c, err)
+		}
+	} else {
+		revisions, err := h.db.GetMineralRevisions(id)
+		if err != nil {
+			log.Printf("Ошибка при получении истории минерала %d: %v", id, err)
+			return errors.SendError(c, errors.ErrServerError)
+		}
+		to = &revisions[0]
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data": fiber.Map{
+			"from":    from,
+			"to":      to,
+			"changes": snapshotChanges(from.Snapshot, to.Snapshot),
+		},
+	})
+}
+
+// RestoreMineralRevision writes the snapshot of an earlier revision back into the mineral.
+// Files named in revisions are kept while the mineral exists, so restored paths point at existing files.
+func (h *Handler) RestoreMineralRevision(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	number, err := c.ParamsInt("revision")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный номер ревизии"))
+	}
+
+	revision, err := h.db.GetMineralRevision(id, number)
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	snapshot := revision.Snapshot
+	composition, apiErr := parseFormulaValue(snapshot.Formula)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	if apiErr := h.checkStructureSystems(id, snapshot.CrystalSystem); apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	current, err := tx.GetMineralForUpdate(id)
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	previous := *current
+	snapshot.ApplyTo(current)
+	applyComposition(current, composition)
+	if err := current.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	updated, err := tx.UpdateMineral(*current, slug.Make(current.Title))
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	if err := tx.SetMineralComposition(id, compositionElements(composition)); err != nil {
+		return sendRevisionError(c, err)
+	}
+	updated, err = tx.SetMineralClassification(id, snapshot.ClassificationID, snapshot.DanaCode)
+	if err != nil {
+		return sendRevisionError(c, err)
+	}
+	if err := h.recordRevision(c, tx, models.RevisionRestore, &previous, updated, &revision.Revision); err != nil {
+		return sendRevisionError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при восстановлении ревизии %d минерала %d: %v", number, id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	h.removeReplacedFiles(&previous, updated)
+
+	log.Printf("Минерал %d восстановлен из ревизии %d", id, number)
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signMineral(updated),
+	})
+}
+
+// snapshotChanges lists the changed fields, with a word-level diff for the text ones.
+func snapshotChanges(from, to models.MineralSnapshot) []models.FieldChange {
+	changes := models.DiffSnapshots(from, to)
+	for i, change := range changes {
+		if change.IsText() {
+			changes[i].Text = textdiff.Words(change.From.(string), change.To.(string))
+		}
+	}
+	return changes
+}
+
+func sendRevisionError(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, database.ErrRevisionNotFound):
+		return errors.SendError(c, errors.ErrNotFound("ревизия не найдена"))
+	case stderrors.Is(err, database.ErrMineralNotFound):
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	case stderrors.Is(err, database.ErrClassificationNotFound):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict,
+			"Узел классификации из ревизии удален", err.Error()))
+	}
+	log.Printf("Ошибка при работе с историей минерала: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
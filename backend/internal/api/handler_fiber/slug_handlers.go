@@ -0,0 +1,165 @@
+// HTTP handlers for human-readable and stable mineral addresses: lookup by slug with redirects from replaced slugs,
+// the slugs of a mineral in every language, and setting the slug of a language by hand or from a translated title.
+// mineralIDParam lets mineral routes take the UUID of a mineral wherever they take its numeric id.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/slug"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+type slugRequest struct {
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+}
+
+// mineralIDParam reads the :id route parameter as a numeric id or a mineral UUID.
+func (h *Handler) mineralIDParam(c *fiber.Ctx) (int, *errors.APIError) {
+	if id, err := c.ParamsInt("id"); err == nil {
+		return id, nil
+	}
+	raw := c.Params("id")
+	if !uuidPattern.MatchString(raw) {
+		return 0, errors.ErrInvalidInput("некорректный id минерала")
+	}
+	id, err := h.db.GetMineralIDByUUID(strings.ToLower(raw))
+	if err != nil {
+		if err == database.ErrMineralNotFound {
+			return 0, errors.ErrNotFound("минерал не найден")
+		}
+		log.Printf("Ошибка при поиске минерала по UUID %s: %v", raw, err)
+		return 0, errors.ErrServerError
+	}
+	return id, nil
+}
+
+// GetMineralBySlug finds a mineral by its slug, in the language from ?lang= or in any language.
+// A slug replaced after a rename answers with a permanent redirect to the current one.
+func (h *Handler) GetMineralBySlug(c *fiber.Ctx) error {
+	value := strings.ToLower(c.Params("slug"))
+	lang := strings.ToLower(c.Query("lang"))
+
+	found, err := h.db.FindMineralSlug(value, lang)
+	if err != nil {
+		return sendSlugError(c, err)
+	}
+	mineral, apiErr := h.visibleMineral(c, found.MineralID)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	if !found.Current {
+		current, err := h.db.GetCurrentMineralSlug(found.MineralID, found.Lang)
+		if err != nil {
+			return sendSlugError(c, err)
+		}
+		target := strings.TrimSuffix(c.Path(), c.Params("slug")) + url.PathEscape(current)
+		if query := c.Context().QueryArgs().String(); query != "" {
+			target += "?" + query
+		}
+		return c.Redirect(target, fiber.StatusMovedPermanently)
+	}
+
+	return h.sendMineralDetails(c, mineral)
+}
+
+// GetMineralSlugs lists the current and replaced slugs of a mineral.
+func (h *Handler) GetMineralSlugs(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	slugs, err := h.db.GetMineralSlugs(owner.MineralID)
+	if err != nil {
+		log.Printf("Ошибка при получении адресов минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   slugs,
+	})
+}
+
+// SetMineralSlug sets the slug of a language: {"slug": "rauchquarz"} is used as is,
+// while {"title": "Rauchquarz"} is turned into a slug that gets a numeric suffix if it is taken.
+func (h *Handler) SetMineralSlug(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	lang := strings.ToLower(strings.TrimSpace(c.Params("lang")))
+	if len(lang) < 2 || len(lang) > 8 {
+		return errors.SendError(c, errors.ErrInvalidInput(models.ErrInvalidLanguage.Error()))
+	}
+
+	var req slugRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	req.Slug = strings.TrimSpace(req.Slug)
+	req.Title = strings.TrimSpace(req.Title)
+	value, exact := req.Slug, true
+	switch {
+	case req.Slug != "":
+		if !models.ValidSlug(req.Slug) {
+			return errors.SendError(c, errors.ErrInvalidInput(models.ErrInvalidSlug.Error()))
+		}
+	case req.Title != "":
+		value, exact = slug.Make(req.Title), false
+	default:
+		return errors.SendError(c, errors.ErrInvalidInput(models.ErrSlugRequired.Error()))
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.GetMineralForUpdate(owner.MineralID); err != nil {
+		return sendSlugError(c, err)
+	}
+	chosen, err := tx.SetMineralSlug(owner.MineralID, lang, value, exact)
+	if err != nil {
+		return sendSlugError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при сохранении адреса минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	slugs, err := h.db.GetCurrentMineralSlugs(owner.MineralID)
+	if err != nil {
+		log.Printf("Ошибка при получении адресов минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	log.Printf("Минерал %d: адрес %s/%s", owner.MineralID, lang, chosen)
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   slugs,
+	})
+}
+
+func sendSlugError(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, database.ErrSlugNotFound), stderrors.Is(err, database.ErrMineralNotFound):
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	case stderrors.Is(err, database.ErrSlugExists):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, "этот адрес уже занят другим минералом", ""))
+	}
+	log.Printf("Ошибка при работе с адресами минерала: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
@@ -0,0 +1,186 @@
+// HTTP handlers for the specimen inventory: physical samples of a mineral species with their catalog data.
+// Specimens are listed on the mineral detail endpoint; their scans and photos are managed through the asset endpoints.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+)
+
+func (h *Handler) GetMineralSpecimens(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	specimens, err := h.db.GetMineralSpecimens(owner.MineralID)
+	if err != nil {
+		log.Printf("Ошибка при получении образцов минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   visibleSpecimens(c, specimens),
+	})
+}
+
+// visibleSpecimens leaves out the inventory fields of specimens unless the caller is an administrator.
+func visibleSpecimens(c *fiber.Ctx, specimens []models.Specimen) []models.Specimen {
+	if canSeeUnpublished(c) {
+		return specimens
+	}
+	for i := range specimens {
+		specimens[i] = specimens[i].Public()
+	}
+	return specimens
+}
+
+func (h *Handler) GetSpecimenByID(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id образца"))
+	}
+
+	specimen, err := h.db.GetSpecimenByID(id)
+	if err != nil {
+		return sendSpecimenError(c, err)
+	}
+	if _, apiErr := h.visibleMineral(c, specimen.MineralID); apiErr != nil {
+		return sendSpecimenError(c, database.ErrSpecimenNotFound)
+	}
+
+	specimen.Assets, err = h.db.GetAssets(database.AssetOwner{MineralID: specimen.MineralID, SpecimenID: specimen.ID})
+	if err != nil {
+		log.Printf("Ошибка при получении ресурсов образца %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	if lang := c.Query("lang"); lang != "" {
+		for i := range specimen.Assets {
+			specimen.Assets[i].LocalizeCaption(lang)
+		}
+	}
+	specimen.Assets = h.signAssets(specimen.Assets)
+	if !canSeeUnpublished(c) {
+		*specimen = specimen.Public()
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   specimen,
+	})
+}
+
+func (h *Handler) CreateSpecimen(c *fiber.Ctx) error {
+	mineralID, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	var specimen models.Specimen
+	if err := c.BodyParser(&specimen); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	specimen.MineralID = mineralID
+	if err := specimen.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	created, err := h.db.CreateSpecimen(specimen)
+	if err != nil {
+		return sendSpecimenError(c, err)
+	}
+
+	log.Printf("Минералу %d добавлен образец %s", mineralID, created.CatalogNumber)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   created,
+	})
+}
+
+// UpdateSpecimen replaces the catalog data of a specimen. The specimen stays attached to its mineral.
+func (h *Handler) UpdateSpecimen(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id образца"))
+	}
+
+	current, err := h.db.GetSpecimenByID(id)
+	if err != nil {
+		return sendSpecimenError(c, err)
+	}
+
+	var specimen models.Specimen
+	if err := c.BodyParser(&specimen); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	specimen.ID = current.ID
+	specimen.MineralID = current.MineralID
+	if err := specimen.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	updated, err := h.db.UpdateSpecimen(specimen)
+	if err != nil {
+		return sendSpecimenError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   updated,
+	})
+}
+
+func (h *Handler) DeleteSpecimen(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id образца"))
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.GetSpecimenForUpdate(id); err != nil {
+		return sendSpecimenError(c, err)
+	}
+	assets, err := tx.GetSpecimenAssetsForUpdate(id)
+	if err != nil {
+		log.Printf("Ошибка при получении ресурсов образца %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	if err := tx.DeleteSpecimen(id); err != nil {
+		return sendSpecimenError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при удалении образца %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	for _, asset := range assets {
+		h.removeUnreferencedFiles(asset.Path, asset.SourcePath)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func sendSpecimenError(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, database.ErrSpecimenNotFound):
+		return errors.SendError(c, errors.ErrNotFound("образец не найден"))
+	case stderrors.Is(err, database.ErrMineralNotFound):
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	case stderrors.Is(err, database.ErrLocalityNotFound):
+		return errors.SendError(c, errors.ErrInvalidInput("местонахождение не найдено"))
+	case stderrors.Is(err, database.ErrCatalogNumberExists):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, "Каталожный номер уже используется", ""))
+	}
+	log.Printf("Ошибка при работе с образцами: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
@@ -0,0 +1,292 @@
+// HTTP handlers for reference spectra: upload of CSV or JCAMP-DX files for a mineral, listing, data for plotting
+// with optional downsampling, deletion, and comparison of a user spectrum with the stored references.
+// Form fields of an upload override the metadata found in the file.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/file"
+	"backend/internal/service/spectroscopy"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultSpectrumMatches = 10
+	MaxSpectrumMatches     = 50
+)
+
+type compareSpectrumRequest struct {
+	Type   string                 `json:"type"`
+	Points []models.SpectrumPoint `json:"points"`
+	Limit  int                    `json:"limit"`
+}
+
+type spectrumMatch struct {
+	Spectrum models.Spectrum `json:"spectrum"`
+	Mineral  *models.Mineral `json:"mineral"`
+	spectroscopy.Comparison
+}
+
+func (h *Handler) GetMineralSpectra(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	spectrumType := c.Query("type")
+	if spectrumType != "" {
+		if err := models.ValidateSpectrumType(spectrumType); err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+		}
+	}
+
+	spectra, err := h.db.GetMineralSpectra(owner.MineralID, spectrumType)
+	if err != nil {
+		log.Printf("Ошибка при получении спектров минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signSpectra(spectra),
+	})
+}
+
+func (h *Handler) GetSpectrumByID(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id спектра"))
+	}
+
+	spectrum, err := h.db.GetSpectrumByID(id, false)
+	if err != nil {
+		return sendSpectrumError(c, err)
+	}
+	if _, apiErr := h.visibleMineral(c, spectrum.MineralID); apiErr != nil {
+		return sendSpectrumError(c, database.ErrSpectrumNotFound)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signSpectrum(*spectrum),
+	})
+}
+
+// GetSpectrumData returns the series for plotting; ?points=N reduces it to at most N points.
+func (h *Handler) GetSpectrumData(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id спектра"))
+	}
+	threshold := 0
+	if raw := c.Query("points"); raw != "" {
+		threshold, err = strconv.Atoi(raw)
+		if err != nil || threshold < 3 {
+			return errors.SendError(c, errors.ErrInvalidInput("points должно быть целым числом не меньше 3"))
+		}
+	}
+
+	spectrum, err := h.db.GetSpectrumByID(id, true)
+	if err != nil {
+		return sendSpectrumError(c, err)
+	}
+	if _, apiErr := h.visibleMineral(c, spectrum.MineralID); apiErr != nil {
+		return sendSpectrumError(c, database.ErrSpectrumNotFound)
+	}
+	if threshold > 0 {
+		spectrum.Points = spectroscopy.Downsample(spectrum.Points, threshold)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signSpectrum(*spectrum),
+	})
+}
+
+// CreateMineralSpectrum accepts a multipart form with the file and optional type, title, instrument,
+// wavelength, x_units and y_units fields. The type is required unless the JCAMP-DX header names it.
+func (h *Handler) CreateMineralSpectrum(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	spectrumFile, err := c.FormFile("file")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("файл не найден в запросе"))
+	}
+
+	data, err := h.fileService.ReadSpectrum(spectrumFile)
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+	parsed, err := spectroscopy.Parse(data)
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	spectrum := models.Spectrum{
+		MineralID:  id,
+		Type:       formValueOr(c, "type", parsed.Type),
+		Title:      formValueOr(c, "title", parsed.Title),
+		Instrument: formValueOr(c, "instrument", parsed.Instrument),
+		XUnits:     formValueOr(c, "x_units", parsed.XUnits),
+		YUnits:     formValueOr(c, "y_units", parsed.YUnits),
+	}
+	wavelength := parsed.LaserWavelength
+	if raw := c.FormValue("wavelength"); raw != "" {
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput("некорректная длина волны"))
+		}
+		wavelength = &value
+	}
+	if spectrum.Type != models.SpectrumRaman && c.FormValue("wavelength") == "" {
+		// A laser wavelength read from the file only applies to Raman spectra.
+		wavelength = nil
+	}
+	spectrum.SetWavelength(wavelength)
+	spectrum.SetPoints(parsed.Points)
+	if err := spectrum.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	staging, err := h.fileService.NewStaging()
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+	defer staging.Rollback()
+
+	spectrum.SourcePath, err = h.fileService.WithStaging(staging).SaveSpectrum(data, spectrumFile.Filename)
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+
+	var created *models.Spectrum
+	err = h.commitStaged(staging, func(tx *database.Tx) error {
+		if _, err := tx.GetMineralForUpdate(id); err != nil {
+			return err
+		}
+		created, err = tx.CreateSpectrum(spectrum)
+		return err
+	})
+	if err != nil {
+		log.Printf("Ошибка при добавлении спектра минерала %d: %v", id, err)
+		return sendStagedError(c, err)
+	}
+
+	log.Printf("Добавлен спектр %d (%s, %d точек) минерала %d", created.ID, created.Type, created.PointCount, id)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signSpectrum(*created),
+	})
+}
+
+func (h *Handler) DeleteSpectrum(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id спектра"))
+	}
+
+	sourcePath, err := h.db.DeleteSpectrum(id)
+	if err != nil {
+		return sendSpectrumError(c, err)
+	}
+	h.removeUnreferencedFiles(sourcePath)
+
+	log.Printf("Удален спектр %d", id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// CompareSpectrum scores a user spectrum against the stored references of the same type.
+// Accepts JSON {"type": "raman", "points": [[x, y], ...], "limit": 10} or a multipart form with file, type and limit.
+func (h *Handler) CompareSpectrum(c *fiber.Ctx) error {
+	var req compareSpectrumRequest
+	if spectrumFile, err := c.FormFile("file"); err == nil {
+		data, err := file.ReadUpload(spectrumFile, file.MaxSpectrumSize)
+		if err != nil {
+			return errors.SendError(c, err.(*errors.APIError))
+		}
+		parsed, err := spectroscopy.Parse(data)
+		if err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+		}
+		req.Type = formValueOr(c, "type", parsed.Type)
+		req.Points = parsed.Points
+		req.Limit, _ = strconv.Atoi(c.FormValue("limit"))
+	} else {
+		if err := c.BodyParser(&req); err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+		}
+		if len(req.Points) > spectroscopy.MaxPoints {
+			return errors.SendError(c, errors.ErrInvalidInput("слишком много точек"))
+		}
+		if req.Points, err = spectroscopy.SortPoints(req.Points); err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+		}
+	}
+	if err := models.ValidateSpectrumType(req.Type); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+	if req.Limit <= 0 || req.Limit > MaxSpectrumMatches {
+		req.Limit = DefaultSpectrumMatches
+	}
+
+	references, err := h.db.GetReferenceSpectra(req.Type)
+	if err != nil {
+		log.Printf("Ошибка при загрузке эталонных спектров: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	byID := make(map[int]models.Spectrum, len(references))
+	series := make(map[int][]models.SpectrumPoint, len(references))
+	for _, reference := range references {
+		series[reference.ID] = reference.Points
+		reference.Points = nil
+		byID[reference.ID] = reference
+	}
+
+	ranked := spectroscopy.Rank(req.Points, series, req.Limit)
+	matches := make([]spectrumMatch, 0, len(ranked))
+	for _, match := range ranked {
+		reference := byID[match.SpectrumID]
+		mineral, err := h.db.GetMineralByID(reference.MineralID)
+		if err == database.ErrMineralNotFound {
+			continue
+		}
+		if err != nil {
+			log.Printf("Ошибка при получении минерала %d: %v", reference.MineralID, err)
+			return errors.SendError(c, errors.ErrServerError)
+		}
+		matches = append(matches, spectrumMatch{
+			Spectrum:   h.signSpectrum(reference),
+			Mineral:    h.signMineral(mineral),
+			Comparison: match.Comparison,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   matches,
+	})
+}
+
+// formValueOr returns the trimmed form value, or fallback when the field is empty.
+func formValueOr(c *fiber.Ctx, field, fallback string) string {
+	if value := strings.TrimSpace(c.FormValue(field)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func sendSpectrumError(c *fiber.Ctx, err error) error {
+	if stderrors.Is(err, database.ErrSpectrumNotFound) {
+		return errors.SendError(c, errors.ErrNotFound("спектр не найден"))
+	}
+	log.Printf("Ошибка при работе со спектрами: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
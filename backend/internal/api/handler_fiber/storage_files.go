@@ -0,0 +1,187 @@
+// Helpers for the stored files referenced by minerals.
+// Mineral paths are kept unsigned in the database and signed only when a response is built, so links to protected assets always carry a fresh expiry.
+// Changes that write files go through a staging area and a database transaction, so storage and Postgres commit or roll back together.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/file"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"os"
+)
+
+// signMineral returns a copy of the mineral whose asset paths are replaced with signed URLs where required.
+func (h *Handler) signMineral(mineral *models.Mineral) *models.Mineral {
+	if mineral == nil || h.urlSigner == nil || !h.urlSigner.Enabled() {
+		return mineral
+	}
+	signed := *mineral
+	signed.ModelPath = h.urlSigner.Sign(mineral.ModelPath)
+	signed.PreviewImagePath = h.urlSigner.Sign(mineral.PreviewImagePath)
+	signed.SourceModelPath = h.urlSigner.Sign(mineral.SourceModelPath)
+	if mineral.Assets != nil {
+		signed.Assets = h.signAssets(mineral.Assets)
+	}
+	if mineral.Structures != nil {
+		signed.Structures = h.signStructures(mineral.Structures)
+	}
+	if mineral.Spectra != nil {
+		signed.Spectra = h.signSpectra(mineral.Spectra)
+	}
+	return &signed
+}
+
+func (h *Handler) signMinerals(minerals []models.Mineral) []models.Mineral {
+	if h.urlSigner == nil || !h.urlSigner.Enabled() {
+		return minerals
+	}
+	signed := make([]models.Mineral, len(minerals))
+	for i := range minerals {
+		signed[i] = *h.signMineral(&minerals[i])
+	}
+	return signed
+}
+
+func (h *Handler) signAsset(asset models.MineralAsset) models.MineralAsset {
+	if h.urlSigner == nil || !h.urlSigner.Enabled() {
+		return asset
+	}
+	asset.Path = h.urlSigner.Sign(asset.Path)
+	asset.SourcePath = h.urlSigner.Sign(asset.SourcePath)
+	return asset
+}
+
+func (h *Handler) signAssets(assets []models.MineralAsset) []models.MineralAsset {
+	if h.urlSigner == nil || !h.urlSigner.Enabled() {
+		return assets
+	}
+	signed := make([]models.MineralAsset, len(assets))
+	for i, asset := range assets {
+		signed[i] = h.signAsset(asset)
+	}
+	return signed
+}
+
+func (h *Handler) signStructure(structure models.CrystalStructure) models.CrystalStructure {
+	if h.urlSigner == nil || !h.urlSigner.Enabled() {
+		return structure
+	}
+	structure.CIFPath = h.urlSigner.Sign(structure.CIFPath)
+	return structure
+}
+
+func (h *Handler) signStructures(structures []models.CrystalStructure) []models.CrystalStructure {
+	if h.urlSigner == nil || !h.urlSigner.Enabled() {
+		return structures
+	}
+	signed := make([]models.CrystalStructure, len(structures))
+	for i, structure := range structures {
+		signed[i] = h.signStructure(structure)
+	}
+	return signed
+}
+
+func (h *Handler) signSpectrum(spectrum models.Spectrum) models.Spectrum {
+	if h.urlSigner == nil || !h.urlSigner.Enabled() {
+		return spectrum
+	}
+	spectrum.SourcePath = h.urlSigner.Sign(spectrum.SourcePath)
+	return spectrum
+}
+
+func (h *Handler) signSpectra(spectra []models.Spectrum) []models.Spectrum {
+	if h.urlSigner == nil || !h.urlSigner.Enabled() {
+		return spectra
+	}
+	signed := make([]models.Spectrum, len(spectra))
+	for i, spectrum := range spectra {
+		signed[i] = h.signSpectrum(spectrum)
+	}
+	return signed
+}
+
+// commitStaged applies a database change in a transaction and publishes the staged files with it.
+// Files are moved into place before the transaction commits, so a committed record never points at a missing file;
+// if anything fails, both the transaction and the file moves are rolled back.
+func (h *Handler) commitStaged(staging *file.Staging, apply func(tx *database.Tx) error) error {
+	tx, err := h.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := apply(tx); err != nil {
+		return err
+	}
+	if err := staging.Commit(); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		staging.Rollback()
+		return err
+	}
+	staging.Release()
+	return nil
+}
+
+// sendStagedError maps an error returned by commitStaged to an API response.
+func sendStagedError(c *fiber.Ctx, err error) error {
+	var apiErr *errors.APIError
+	if stderrors.As(err, &apiErr) {
+		return errors.SendError(c, apiErr)
+	}
+	if stderrors.Is(err, database.ErrMineralNotFound) {
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	}
+	return errors.SendError(c, errors.ErrServerError)
+}
+
+// removeReplacedFiles deletes the files of previous that updated no longer uses.
+func (h *Handler) removeReplacedFiles(previous, updated *models.Mineral) {
+	if previous == nil || updated == nil {
+		return
+	}
+	var replaced []string
+	if previous.ModelPath != updated.ModelPath {
+		replaced = append(replaced, previous.ModelPath)
+	}
+	if previous.SourceModelPath != updated.SourceModelPath {
+		replaced = append(replaced, previous.SourceModelPath)
+	}
+	if previous.PreviewImagePath != updated.PreviewImagePath {
+		replaced = append(replaced, previous.PreviewImagePath)
+	}
+	h.removeUnreferencedFiles(replaced...)
+}
+
+// removeUnreferencedFiles deletes files that were replaced or belonged to a deleted mineral, skipping paths
+// that another record still uses. Failures are only logged: the storage checker reports and collects leftovers.
+func (h *Handler) removeUnreferencedFiles(publicPaths ...string) {
+	for _, publicPath := range publicPaths {
+		if publicPath == "" {
+			continue
+		}
+		referenced, err := h.db.IsStoragePathReferenced(publicPath)
+		if err != nil {
+			log.Printf("Ошибка проверки ссылок на файл %s: %v", publicPath, err)
+			continue
+		}
+		if referenced {
+			continue
+		}
+		fullPath, err := h.fileService.FullPath(publicPath)
+		if err != nil {
+			log.Printf("Некорректный путь к файлу %q: %v", publicPath, err)
+			continue
+		}
+		log.Printf("Удаление файла: %s", fullPath)
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Ошибка при удалении файла %s: %v", fullPath, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Admin endpoints for the storage consistency checker.
+// A check only reports orphaned and missing files; garbage collection deletes orphans older than the grace period
+// and runs as a dry run unless dry_run=false is passed explicitly.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"time"
+)
+
+func (h *Handler) CheckStorage(c *fiber.Ctx) error {
+	grace, apiErr := h.gracePeriod(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	report, err := h.storageChecker.Run(true, grace)
+	if err != nil {
+		log.Printf("Ошибка проверки хранилища: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   report,
+	})
+}
+
+func (h *Handler) CollectStorageGarbage(c *fiber.Ctx) error {
+	grace, apiErr := h.gracePeriod(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	dryRun := c.QueryBool("dry_run", true)
+
+	report, err := h.storageChecker.Run(dryRun, grace)
+	if err != nil {
+		log.Printf("Ошибка очистки хранилища: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	if !dryRun {
+		log.Printf("Очистка хранилища: удалено %d файлов, освобождено %d байт", report.DeletedFiles, report.ReclaimedSize)
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   report,
+	})
+}
+
+// gracePeriod reads the optional grace query parameter (a Go duration such as "48h"), defaulting to the configured value.
+func (h *Handler) gracePeriod(c *fiber.Ctx) (time.Duration, *errors.APIError) {
+	raw := c.Query("grace")
+	if raw == "" {
+		return h.storageChecker.GracePeriod(), nil
+	}
+	grace, err := time.ParseDuration(raw)
+	if err != nil || grace < 0 {
+		return 0, errors.ErrInvalidInput("некорректный параметр grace")
+	}
+	return grace, nil
+}
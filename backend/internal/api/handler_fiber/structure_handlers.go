@@ -0,0 +1,165 @@
+// HTTP handlers for crystal structures: CIF upload for a mineral, listing, lookup and deletion.
+// The file is parsed before it is stored, and its crystal system must agree with the one declared for the mineral;
+// a mineral without a declared system takes it from its first structure.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/crystallography"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+)
+
+func (h *Handler) GetMineralStructures(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	structures, err := h.db.GetMineralStructures(owner.MineralID)
+	if err != nil {
+		log.Printf("Ошибка при получении структур минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signStructures(structures),
+	})
+}
+
+func (h *Handler) GetStructureByID(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id структуры"))
+	}
+
+	structure, err := h.db.GetStructureByID(id)
+	if err != nil {
+		return sendStructureError(c, err)
+	}
+	if _, apiErr := h.visibleMineral(c, structure.MineralID); apiErr != nil {
+		return sendStructureError(c, database.ErrStructureNotFound)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signStructure(*structure),
+	})
+}
+
+// CreateMineralStructure accepts a multipart form with the CIF in the file field.
+func (h *Handler) CreateMineralStructure(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	cifFile, err := c.FormFile("file")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("файл не найден в запросе"))
+	}
+
+	data, err := h.fileService.ReadStructure(cifFile)
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+	parsed, err := crystallography.ParseCIF(data)
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+	structure := *parsed
+	structure.MineralID = id
+
+	staging, err := h.fileService.NewStaging()
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+	defer staging.Rollback()
+
+	structure.CIFPath, err = h.fileService.WithStaging(staging).SaveStructure(data, cifFile.Filename)
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+
+	var created *models.CrystalStructure
+	err = h.commitStaged(staging, func(tx *database.Tx) error {
+		mineral, err := tx.GetMineralForUpdate(id)
+		if err != nil {
+			return err
+		}
+		if err := structure.CheckCrystalSystem(mineral.CrystalSystem); err != nil {
+			return errors.ErrInvalidInput(err.Error())
+		}
+		if mineral.CrystalSystem == "" {
+			if err := tx.SetMineralCrystalSystem(id, structure.CrystalSystem); err != nil {
+				return err
+			}
+			previous := *mineral
+			mineral.CrystalSystem = structure.CrystalSystem
+			if err := h.recordRevision(c, tx, models.RevisionUpdate, &previous, mineral, nil); err != nil {
+				return err
+			}
+		}
+		created, err = tx.CreateStructure(structure)
+		return err
+	})
+	if err != nil {
+		log.Printf("Ошибка при добавлении структуры минерала %d: %v", id, err)
+		return sendStagedError(c, err)
+	}
+
+	log.Printf("Добавлена структура %d минерала %d: %s, %s", created.ID, id, created.SpaceGroup, created.CIFPath)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signStructure(*created),
+	})
+}
+
+func (h *Handler) DeleteStructure(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id структуры"))
+	}
+
+	cifPath, err := h.db.DeleteStructure(id)
+	if err != nil {
+		return sendStructureError(c, err)
+	}
+	h.removeUnreferencedFiles(cifPath)
+
+	log.Printf("Удалена структура %d", id)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// checkStructureSystems rejects a declared crystal system that disagrees with structures already attached to the mineral.
+func (h *Handler) checkStructureSystems(mineralID int, system string) *errors.APIError {
+	if system == "" {
+		return nil
+	}
+	if err := models.ValidateCrystalSystem(system); err != nil {
+		return errors.ErrInvalidInput(err.Error())
+	}
+	structures, err := h.db.GetMineralStructures(mineralID)
+	if err != nil {
+		log.Printf("Ошибка при получении структур минерала %d: %v", mineralID, err)
+		return errors.ErrServerError
+	}
+	for _, structure := range structures {
+		if err := structure.CheckCrystalSystem(system); err != nil {
+			return errors.ErrInvalidInput(err.Error())
+		}
+	}
+	return nil
+}
+
+func sendStructureError(c *fiber.Ctx, err error) error {
+	if stderrors.Is(err, database.ErrStructureNotFound) {
+		return errors.SendError(c, errors.ErrNotFound("структура не найдена"))
+	}
+	log.Printf("Ошибка при работе со структурами: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
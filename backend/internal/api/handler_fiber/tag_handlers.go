@@ -0,0 +1,196 @@
+// HTTP handlers for tags: the tag list and tag cloud with mineral counts for visitors, and tag maintenance
+// and assignment for administrators. Minerals are browsed by tag through GET /minerals?tags=.
+// Tag names are localized with ?lang=, falling back to Russian.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/slug"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultTagCloudSize = 50
+	MaxTagCloudSize     = 200
+)
+
+type mineralTagsRequest struct {
+	TagIDs []int `json:"tag_ids"`
+}
+
+func (h *Handler) GetTags(c *fiber.Ctx) error {
+	tags, err := h.db.GetTags()
+	if err != nil {
+		log.Printf("Ошибка при получении тегов: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	localizeTags(tags, c.Query("lang"))
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   tags,
+	})
+}
+
+// GetTagCloud returns the most used tags with their counts and a weight from 1 to 5 for sizing, ?limit=50.
+func (h *Handler) GetTagCloud(c *fiber.Ctx) error {
+	limit := DefaultTagCloudSize
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > MaxTagCloudSize {
+			return errors.SendError(c, errors.ErrInvalidInput("limit должен быть от 1 до "+strconv.Itoa(MaxTagCloudSize)))
+		}
+	}
+
+	tags, err := h.db.GetTagCloud(limit)
+	if err != nil {
+		log.Printf("Ошибка при получении облака тегов: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	localizeTags(tags, c.Query("lang"))
+	models.AssignCloudWeights(tags)
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   tags,
+	})
+}
+
+// CreateTag adds a tag: {"slug": "fluorescent", "names": {"ru": "Флуоресцентные", "en": "Fluorescent"}}.
+// Without a slug one is made from the Russian name.
+func (h *Handler) CreateTag(c *fiber.Ctx) error {
+	var tag models.Tag
+	if err := c.BodyParser(&tag); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	fillTagSlug(&tag)
+	if err := tag.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	created, err := h.db.CreateTag(tag)
+	if err != nil {
+		return sendTagError(c, err)
+	}
+	created.Localize(c.Query("lang"))
+
+	log.Printf("Добавлен тег %s", created.Slug)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   created,
+	})
+}
+
+// UpdateTag changes the slug and names of a tag. Links with the old slug in ?tags= stop matching.
+func (h *Handler) UpdateTag(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id тега"))
+	}
+	var tag models.Tag
+	if err := c.BodyParser(&tag); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	tag.ID = id
+	fillTagSlug(&tag)
+	if err := tag.Validate(); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+
+	updated, err := h.db.UpdateTag(tag)
+	if err != nil {
+		return sendTagError(c, err)
+	}
+	updated.Localize(c.Query("lang"))
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   updated,
+	})
+}
+
+func (h *Handler) DeleteTag(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id тега"))
+	}
+	if err := h.db.DeleteTag(id); err != nil {
+		return sendTagError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// SetMineralTags replaces the tags of a mineral: {"tag_ids": [3, 7]}.
+func (h *Handler) SetMineralTags(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	var req mineralTagsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.GetMineralForUpdate(id); err != nil {
+		return sendTagError(c, err)
+	}
+	if err := tx.SetMineralTags(id, req.TagIDs); err != nil {
+		return sendTagError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при обновлении тегов минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	tags, err := h.db.GetMineralTags(id)
+	if err != nil {
+		log.Printf("Ошибка при получении тегов минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	localizeTags(tags, c.Query("lang"))
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   tags,
+	})
+}
+
+// fillTagSlug makes a slug from the default-language name when the request gives none.
+func fillTagSlug(tag *models.Tag) {
+	if strings.TrimSpace(tag.Slug) == "" {
+		tag.Slug = slug.Make(tag.Names[models.DefaultLanguage])
+	}
+}
+
+func localizeTags(tags []models.Tag, lang string) {
+	for i := range tags {
+		tags[i].Localize(lang)
+	}
+}
+
+func sendTagError(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, database.ErrMineralNotFound):
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	case stderrors.Is(err, database.ErrTagNotFound):
+		return errors.SendError(c, errors.ErrNotFound("тег не найден"))
+	case stderrors.Is(err, database.ErrTagExists):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, "тег с таким кодом уже существует", ""))
+	}
+	log.Printf("Ошибка при работе с тегами: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
@@ -6,6 +6,7 @@ package handler_fiber
 
 import (
 	"backend/internal/api/errors"
+	"backend/internal/database"
 	"backend/internal/models"
 	"backend/internal/service/translation"
 	stderrors "errors"
@@ -25,9 +26,9 @@ func (h *Handler) GetAvailableLanguages(c *fiber.Ctx) error {
 }
 
 func (h *Handler) GetTranslatedMineral(c *fiber.Ctx) error {
-	id, err := c.ParamsInt("id")
-	if err != nil {
-		return errors.SendError(c, errors.ErrInvalidInput("некорректный id"))
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
 	}
 
 	targetLang := c.Query("lang")
@@ -45,9 +46,27 @@ func (h *Handler) GetTranslatedMineral(c *fiber.Ctx) error {
 		return errors.SendError(c, errors.ErrInvalidInput("язык не поддерживается"))
 	}
 
-	mineral, err := h.db.GetMineralByID(id)
+	mineral, apiErr := h.visibleMineral(c, id)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	var err error
+	mineral.Assets, err = h.db.GetAssets(database.MineralOwner(id))
 	if err != nil {
-		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+		log.Printf("Ошибка при получении ресурсов минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	for i := range mineral.Assets {
+		mineral.Assets[i].LocalizeCaption(targetLang)
+	}
+
+	// The slug in the target language, when the mineral has one; otherwise the default-language slug.
+	if localized, err := h.db.GetCurrentMineralSlug(id, targetLang); err == nil {
+		mineral.Slug = localized
+	} else if err != database.ErrSlugNotFound {
+		log.Printf("Ошибка при получении адреса минерала %d на языке %s: %v", id, targetLang, err)
+		return errors.SendError(c, errors.ErrServerError)
 	}
 
 	translatedTitle, err := h.translationService.Translate(mineral.Title, sourceLang, targetLang)
@@ -71,7 +90,7 @@ func (h *Handler) GetTranslatedMineral(c *fiber.Ctx) error {
 			log.Printf("Пустой текст для перевода: %v", err)
 			return c.JSON(fiber.Map{
 				"status": "success",
-				"data":   mineral,
+				"data":   h.signMineral(mineral),
 			})
 		default:
 			log.Printf("Неизвестная ошибка при переводе: %v", err)
@@ -106,16 +125,22 @@ func (h *Handler) GetTranslatedMineral(c *fiber.Ctx) error {
 
 	translatedMineral := models.Mineral{
 		ID:               mineral.ID,
+		UUID:             mineral.UUID,
 		Title:            translatedTitle,
+		Slug:             mineral.Slug,
 		Description:      translatedDescription,
 		ModelPath:        mineral.ModelPath,
 		PreviewImagePath: mineral.PreviewImagePath,
+		SourceModelPath:  mineral.SourceModelPath,
+		Status:           mineral.Status,
+		PublishedAt:      mineral.PublishedAt,
 		CreatedAt:        mineral.CreatedAt,
+		Assets:           mineral.Assets,
 	}
 
 	return c.JSON(fiber.Map{
 		"status": "success",
-		"data":   translatedMineral,
+		"data":   h.signMineral(&translatedMineral),
 	})
 }
 
@@ -135,7 +160,7 @@ func (h *Handler) GetAllTranslatedMinerals(c *fiber.Ctx) error {
 		return errors.SendError(c, errors.ErrInvalidInput("язык не поддерживается"))
 	}
 
-	minerals, err := h.db.GetAllMinerals()
+	minerals, err := h.db.GetPublishedMinerals()
 	if err != nil {
 		log.Printf("Ошибка при получении минералов: %v", err)
 		return errors.SendError(c, errors.ErrServerError)
@@ -178,6 +203,7 @@ func (h *Handler) GetAllTranslatedMinerals(c *fiber.Ctx) error {
 			Description:      translatedDescription,
 			ModelPath:        mineral.ModelPath,
 			PreviewImagePath: mineral.PreviewImagePath,
+			SourceModelPath:  mineral.SourceModelPath,
 			CreatedAt:        mineral.CreatedAt,
 		}
 	}
@@ -188,7 +214,7 @@ func (h *Handler) GetAllTranslatedMinerals(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"status": "success",
-		"data":   translatedMinerals,
+		"data":   h.signMinerals(translatedMinerals),
 	})
 }
 
@@ -253,12 +279,13 @@ func (h *Handler) SearchTranslatedMinerals(c *fiber.Ctx) error {
 			Description:      translatedDescription,
 			ModelPath:        mineral.ModelPath,
 			PreviewImagePath: mineral.PreviewImagePath,
+			SourceModelPath:  mineral.SourceModelPath,
 			CreatedAt:        mineral.CreatedAt,
 		})
 	}
 
 	return c.JSON(fiber.Map{
 		"status": "success",
-		"data":   translatedMinerals,
+		"data":   h.signMinerals(translatedMinerals),
 	})
 }
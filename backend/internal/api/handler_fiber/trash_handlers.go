@@ -0,0 +1,135 @@
+// HTTP handlers for the trash: listing deleted minerals, restoring them and purging them permanently.
+// Deleted minerals are hidden everywhere else; purging removes the record and its files.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"time"
+)
+
+// trashedMineral adds the time a deleted mineral will be purged automatically.
+type trashedMineral struct {
+	models.Mineral
+	PurgeAt *time.Time `json:"purge_at,omitempty"`
+}
+
+func (h *Handler) GetTrash(c *fiber.Ctx) error {
+	minerals, err := h.db.GetDeletedMinerals()
+	if err != nil {
+		log.Printf("Ошибка при получении корзины: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	minerals = h.signMinerals(minerals)
+	items := make([]trashedMineral, 0, len(minerals))
+	for _, mineral := range minerals {
+		item := trashedMineral{Mineral: mineral}
+		if mineral.DeletedAt != nil {
+			purgeAt := mineral.DeletedAt.Add(h.trashPurger.Retention())
+			item.PurgeAt = &purgeAt
+		}
+		items = append(items, item)
+	}
+
+	return c.JSON(fiber.Map{
+		"status":    "success",
+		"data":      items,
+		"retention": h.trashPurger.Retention().String(),
+	})
+}
+
+// RestoreMineral takes a mineral out of the trash with its previous status; the restore is recorded as a revision.
+func (h *Handler) RestoreMineral(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id"))
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		log.Printf("Ошибка при открытии транзакции: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	restored, err := tx.RestoreMineral(id)
+	if err != nil {
+		return sendTrashError(c, err)
+	}
+	if err := h.recordRevision(c, tx, models.RevisionRestore, nil, restored, nil); err != nil {
+		log.Printf("Ошибка при сохранении ревизии минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при восстановлении минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	log.Printf("Минерал %d восстановлен из корзины", id)
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signMineral(restored),
+	})
+}
+
+func (h *Handler) PurgeMineral(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректный id"))
+	}
+
+	if err := h.trashPurger.Purge(id); err != nil {
+		return sendTrashError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// EmptyTrash purges the minerals whose retention period has passed; ?all=true purges the whole trash.
+func (h *Handler) EmptyTrash(c *fiber.Ctx) error {
+	if c.Query("all") != "true" {
+		purged, err := h.trashPurger.PurgeExpired()
+		if err != nil {
+			log.Printf("Ошибка очистки корзины: %v", err)
+			return errors.SendError(c, errors.ErrServerError)
+		}
+		return c.JSON(fiber.Map{
+			"status": "success",
+			"purged": purged,
+		})
+	}
+
+	minerals, err := h.db.GetDeletedMinerals()
+	if err != nil {
+		log.Printf("Ошибка при получении корзины: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	purged := 0
+	for _, mineral := range minerals {
+		if err := h.trashPurger.Purge(mineral.ID); err != nil {
+			if err == database.ErrMineralNotFound {
+				continue
+			}
+			log.Printf("Ошибка удаления минерала %d из корзины: %v", mineral.ID, err)
+			return errors.SendError(c, errors.ErrServerError)
+		}
+		purged++
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"purged": purged,
+	})
+}
+
+func sendTrashError(c *fiber.Ctx, err error) error {
+	if err == database.ErrMineralNotFound {
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден в корзине"))
+	}
+	log.Printf("Ошибка при работе с корзиной: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
@@ -0,0 +1,216 @@
+// HTTP handlers for resumable model uploads following the tus 1.0 protocol (core, creation, expiration, checksum and termination).
+// Clients create an upload, query its offset after a dropped connection and continue sending chunks from there.
+// A finalize step validates the assembled file through the file service and optionally attaches it to an existing mineral.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/slug"
+	"backend/internal/service/upload"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,checksum,termination"
+	tusOffsetType = "application/offset+octet-stream"
+
+	// StatusChecksumMismatch is the tus-specific status for a chunk whose checksum does not match.
+	StatusChecksumMismatch = 460
+)
+
+// TusOptions answers tus capability discovery requests.
+func (h *Handler) TusOptions(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	c.Set("Tus-Version", tusVersion)
+	c.Set("Tus-Extension", tusExtensions)
+	c.Set("Tus-Max-Size", strconv.FormatInt(h.uploadService.MaxSize(), 10))
+	c.Set("Tus-Checksum-Algorithm", upload.ChecksumAlgorithms)
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *Handler) CreateUpload(c *fiber.Ctx) error {
+	if apiErr := checkTusVersion(c); apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return errors.SendError(c, errors.ErrInvalidInput("заголовок Upload-Length обязателен"))
+	}
+
+	metadata, err := upload.ParseMetadata(c.Get("Upload-Metadata"))
+	if err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+	filename := metadata["filename"]
+	if filename == "" {
+		return errors.SendError(c, errors.ErrInvalidInput("в Upload-Metadata требуется filename"))
+	}
+
+	created, err := h.uploadService.Create(length, filename, metadata)
+	if err != nil {
+		return sendUploadError(c, err)
+	}
+
+	log.Printf("Создана загрузка %s: %s, %d байт", created.ID, created.Filename, created.Length)
+	c.Set("Location", c.BaseURL()+"/api/v1/admin/uploads/"+created.ID)
+	c.Set("Upload-Expires", created.ExpiresAt.UTC().Format(http.TimeFormat))
+	return c.SendStatus(fiber.StatusCreated)
+}
+
+func (h *Handler) GetUploadOffset(c *fiber.Ctx) error {
+	if apiErr := checkTusVersion(c); apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	current, err := h.uploadService.Get(c.Params("id"))
+	if err != nil {
+		return sendUploadError(c, err)
+	}
+
+	c.Set("Cache-Control", "no-store")
+	c.Set("Upload-Offset", strconv.FormatInt(current.Offset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(current.Length, 10))
+	c.Set("Upload-Expires", current.ExpiresAt.UTC().Format(http.TimeFormat))
+	return c.SendStatus(fiber.StatusOK)
+}
+
+func (h *Handler) PatchUpload(c *fiber.Ctx) error {
+	if apiErr := checkTusVersion(c); apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	if c.Get(fiber.HeaderContentType) != tusOffsetType {
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusUnsupportedMediaType,
+			"Некорректный тип содержимого", "ожидается "+tusOffsetType))
+	}
+
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return errors.SendError(c, errors.ErrInvalidInput("заголовок Upload-Offset обязателен"))
+	}
+
+	updated, err := h.uploadService.Append(c.Params("id"), offset, c.Body(), c.Get("Upload-Checksum"))
+	if err != nil {
+		return sendUploadError(c, err)
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(updated.Offset, 10))
+	c.Set("Upload-Expires", updated.ExpiresAt.UTC().Format(http.TimeFormat))
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+func (h *Handler) DeleteUpload(c *fiber.Ctx) error {
+	if apiErr := checkTusVersion(c); apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	if err := h.uploadService.Delete(c.Params("id")); err != nil {
+		return sendUploadError(c, err)
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// FinalizeUpload stores a completed upload as a model. With mineral_id the model of that mineral is replaced.
+func (h *Handler) FinalizeUpload(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", tusVersion)
+	id := c.Params("id")
+
+	current, err := h.uploadService.Get(id)
+	if err != nil {
+		return sendUploadError(c, err)
+	}
+	dataPath, err := h.uploadService.DataPath(id)
+	if err != nil {
+		return sendUploadError(c, err)
+	}
+
+	staging, err := h.fileService.NewStaging()
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+	defer staging.Rollback()
+
+	savedModel, err := h.fileService.WithStaging(staging).SaveModelFile(current.Filename, dataPath)
+	if err != nil {
+		return errors.SendError(c, err.(*errors.APIError))
+	}
+
+	response := fiber.Map{
+		"status":      "success",
+		"path":        savedModel.Path,
+		"source_path": savedModel.SourcePath,
+	}
+
+	if mineralID := c.QueryInt("mineral_id"); mineralID > 0 {
+		var previous, updated *models.Mineral
+		err := h.commitStaged(staging, func(tx *database.Tx) error {
+			mineral, err := tx.GetMineralForUpdate(mineralID)
+			if err != nil {
+				return err
+			}
+			snapshot := *mineral
+			previous = &snapshot
+			mineral.ModelPath = savedModel.Path
+			mineral.SourceModelPath = savedModel.SourcePath
+			if updated, err = tx.UpdateMineral(*mineral, slug.Make(mineral.Title)); err != nil {
+				return err
+			}
+			return h.recordRevision(c, tx, models.RevisionUpdate, previous, updated, nil)
+		})
+		if err != nil {
+			log.Printf("Ошибка при обновлении модели минерала %d: %v", mineralID, err)
+			return sendStagedError(c, err)
+		}
+		h.removeReplacedFiles(previous, updated)
+		response["data"] = h.signMineral(updated)
+	} else {
+		if err := staging.Commit(); err != nil {
+			return errors.SendError(c, err.(*errors.APIError))
+		}
+		staging.Release()
+	}
+
+	if err := h.uploadService.Delete(id); err != nil {
+		log.Printf("Ошибка при удалении завершённой загрузки %s: %v", id, err)
+	}
+
+	log.Printf("Загрузка %s завершена: %s", id, savedModel.Path)
+	return c.JSON(response)
+}
+
+func checkTusVersion(c *fiber.Ctx) *errors.APIError {
+	c.Set("Tus-Resumable", tusVersion)
+	if version := c.Get("Tus-Resumable"); version != "" && version != tusVersion {
+		c.Set("Tus-Version", tusVersion)
+		return errors.NewAPIError(fiber.StatusPreconditionFailed, "Неподдерживаемая версия протокола tus", version)
+	}
+	return nil
+}
+
+func sendUploadError(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, upload.ErrUploadNotFound):
+		return errors.SendError(c, errors.ErrNotFound("загрузка не найдена"))
+	case stderrors.Is(err, upload.ErrUploadExpired):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusGone, "Срок действия загрузки истёк", ""))
+	case stderrors.Is(err, upload.ErrOffsetMismatch):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, "Смещение не совпадает", err.Error()))
+	case stderrors.Is(err, upload.ErrChecksumMismatch):
+		return errors.SendError(c, errors.NewAPIError(StatusChecksumMismatch, "Контрольная сумма не совпадает", ""))
+	case stderrors.Is(err, upload.ErrUnsupportedChecksum):
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	case stderrors.Is(err, upload.ErrUploadTooLarge):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusRequestEntityTooLarge, "Размер файла слишком большой", err.Error()))
+	case stderrors.Is(err, upload.ErrUploadIncomplete):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, "Загрузка не завершена", err.Error()))
+	}
+	log.Printf("Ошибка загрузки: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
@@ -0,0 +1,225 @@
+// HTTP handlers for the publication workflow: submitting a draft for review, approval with an optional publish time,
+// rejection with a comment, archiving and reopening, the review log, and the administrators' list of minerals in any status.
+// visibleMineral is the single check that hides unpublished minerals from visitors on public endpoints.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	stderrors "errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v4"
+	"log"
+	"strings"
+	"time"
+)
+
+type workflowRequest struct {
+	Comment   string     `json:"comment"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// canSeeUnpublished reports whether the request comes from an administrator, who sees minerals in every status.
+// Public routes do not run the auth middleware, so there it is always false.
+func canSeeUnpublished(c *fiber.Ctx) bool {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	return ok && claims["role"] == string(models.RoleAdmin)
+}
+
+// visibleMineral loads a mineral and reports it as not found when the caller may not see it yet.
+func (h *Handler) visibleMineral(c *fiber.Ctx, id int) (*models.Mineral, *errors.APIError) {
+	mineral, err := h.db.GetMineralByID(id)
+	if err != nil {
+		if err == database.ErrMineralNotFound {
+			return nil, errors.ErrNotFound("минерал не найден")
+		}
+		log.Printf("Ошибка при получении минерала %d: %v", id, err)
+		return nil, errors.ErrServerError
+	}
+	if !canSeeUnpublished(c) && !mineral.IsPublic(time.Now()) {
+		return nil, errors.ErrNotFound("минерал не найден")
+	}
+	return mineral, nil
+}
+
+// GetAdminMinerals lists minerals in any status for administrators: ?status=draft,in_review plus the public filters.
+func (h *Handler) GetAdminMinerals(c *fiber.Ctx) error {
+	filter, apiErr := parseMineralFilter(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	filter.IncludeUnpublished = true
+	if raw := strings.TrimSpace(c.Query("status")); raw != "" {
+		for _, status := range strings.Split(raw, ",") {
+			status = strings.TrimSpace(status)
+			if err := models.ValidateStatus(status); err != nil {
+				return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	return h.findMinerals(c, filter)
+}
+
+func (h *Handler) SubmitMineral(c *fiber.Ctx) error {
+	return h.transitionMineral(c, models.ReviewSubmit)
+}
+
+// ApproveMineral publishes a mineral under review. A future publish_at schedules it: the mineral stays hidden until then.
+func (h *Handler) ApproveMineral(c *fiber.Ctx) error {
+	return h.transitionMineral(c, models.ReviewApprove)
+}
+
+// RejectMineral returns a mineral under review to its editor as a draft; the comment is required.
+func (h *Handler) RejectMineral(c *fiber.Ctx) error {
+	return h.transitionMineral(c, models.ReviewReject)
+}
+
+func (h *Handler) ArchiveMineral(c *fiber.Ctx) error {
+	return h.transitionMineral(c, models.ReviewArchive)
+}
+
+func (h *Handler) ReopenMineral(c *fiber.Ctx) error {
+	return h.transitionMineral(c, models.ReviewReopen)
+}
+
+func (h *Handler) transitionMineral(c *fiber.Ctx, action string) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	var req workflowRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+		}
+	}
+	if req.PublishAt != nil && action != models.ReviewApprove {
+		return errors.SendError(c, errors.ErrInvalidInput("publish_at указывается только при одобрении"))
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	mineral, err := tx.GetMineralForUpdate(id)
+	if err != nil {
+		return sendWorkflowError(c, err)
+	}
+	status, err := models.NextStatus(action, mineral.Status)
+	if err != nil {
+		return sendWorkflowError(c, err)
+	}
+	entry, apiErr := h.newReviewEntry(c, mineral, action, status, req.Comment)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	updated, err := tx.SetMineralStatus(id, status, req.PublishAt)
+	if err != nil {
+		return sendWorkflowError(c, err)
+	}
+	if _, err := tx.CreateReviewEntry(*entry); err != nil {
+		return sendWorkflowError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при смене статуса минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	log.Printf("Минерал %d: %s → %s (%s)", id, mineral.Status, status, entry.AuthorName)
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   h.signMineral(updated),
+	})
+}
+
+func (h *Handler) GetMineralReviews(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	entries, err := h.db.GetMineralReviews(owner.MineralID)
+	if err != nil {
+		log.Printf("Ошибка при получении рецензий минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   entries,
+	})
+}
+
+// CreateMineralReview adds a reviewer comment without changing the status: {"comment": "..."}.
+func (h *Handler) CreateMineralReview(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	var req workflowRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	mineral, err := tx.GetMineralForUpdate(id)
+	if err != nil {
+		return sendWorkflowError(c, err)
+	}
+	entry, apiErr := h.newReviewEntry(c, mineral, models.ReviewComment, mineral.Status, req.Comment)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+	created, err := tx.CreateReviewEntry(*entry)
+	if err != nil {
+		return sendWorkflowError(c, err)
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("Ошибка при добавлении рецензии минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"status": "success",
+		"data":   created,
+	})
+}
+
+func (h *Handler) newReviewEntry(c *fiber.Ctx, mineral *models.Mineral, action, status, comment string) (*models.ReviewEntry, *errors.APIError) {
+	authorID, authorName := revisionAuthor(c)
+	entry := &models.ReviewEntry{
+		MineralID:  mineral.ID,
+		AuthorID:   authorID,
+		AuthorName: authorName,
+		Action:     action,
+		FromStatus: mineral.Status,
+		ToStatus:   status,
+		Comment:    comment,
+	}
+	if err := entry.Validate(); err != nil {
+		return nil, errors.ErrInvalidInput(err.Error())
+	}
+	return entry, nil
+}
+
+func sendWorkflowError(c *fiber.Ctx, err error) error {
+	switch {
+	case stderrors.Is(err, database.ErrMineralNotFound):
+		return errors.SendError(c, errors.ErrNotFound("минерал не найден"))
+	case stderrors.Is(err, models.ErrInvalidTransition):
+		return errors.SendError(c, errors.NewAPIError(fiber.StatusConflict, err.Error(), ""))
+	}
+	log.Printf("Ошибка при смене статуса минерала: %v", err)
+	return errors.SendError(c, errors.ErrServerError)
+}
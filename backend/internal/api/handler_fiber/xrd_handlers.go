@@ -0,0 +1,250 @@
+// HTTP handlers for powder X-ray diffraction: reference patterns of minerals and identification of an unknown sample.
+// Peaks are given as d-spacings or as 2θ angles together with the wavelength; a measured profile can also be uploaded
+// as a two-column 2θ/intensity file, from which peaks are picked before the search-match.
+
+package handler_fiber
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/diffraction"
+	"backend/internal/service/file"
+	"encoding/json"
+	"github.com/gofiber/fiber/v2"
+	"log"
+	"math"
+	"mime/multipart"
+	"strconv"
+)
+
+const (
+	MaxPatternFileSize   = 5 << 20
+	DefaultXRDCandidates = 10
+	MaxXRDCandidates     = 50
+)
+
+// wavelengthValue accepts a wavelength as a JSON number in ångströms or as a string such as "CuKa".
+type wavelengthValue string
+
+func (w *wavelengthValue) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*w = wavelengthValue(text)
+		return nil
+	}
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return err
+	}
+	*w = wavelengthValue(number.String())
+	return nil
+}
+
+type xrdPeakInput struct {
+	D         *float64 `json:"d"`
+	TwoTheta  *float64 `json:"two_theta"`
+	Intensity float64  `json:"intensity"`
+	HKL       string   `json:"hkl"`
+}
+
+type xrdPeaksRequest struct {
+	Peaks      []xrdPeakInput  `json:"peaks"`
+	Wavelength wavelengthValue `json:"wavelength"`
+	Tolerance  float64         `json:"tolerance"`
+	Limit      int             `json:"limit"`
+}
+
+type xrdCandidate struct {
+	Mineral *models.Mineral `json:"mineral"`
+	diffraction.MatchResult
+}
+
+// toPeaks converts the input to d-spacings with relative intensities. The wavelength is only required when a peak is given by its 2θ angle.
+func (r *xrdPeaksRequest) toPeaks() ([]models.XRDPeak, *errors.APIError) {
+	var wavelength float64
+	peaks := make([]models.XRDPeak, 0, len(r.Peaks))
+	for _, input := range r.Peaks {
+		peak := models.XRDPeak{Intensity: input.Intensity, HKL: input.HKL}
+		switch {
+		case input.D != nil:
+			peak.D = *input.D
+		case input.TwoTheta != nil:
+			if wavelength == 0 {
+				var err error
+				if wavelength, err = diffraction.ParseWavelength(string(r.Wavelength)); err != nil {
+					return nil, errors.ErrInvalidInput(err.Error())
+				}
+			}
+			d, err := diffraction.DSpacing(*input.TwoTheta, wavelength)
+			if err != nil {
+				return nil, errors.ErrInvalidInput(err.Error())
+			}
+			peak.D = math.Round(d*1e5) / 1e5
+		default:
+			return nil, errors.ErrInvalidInput("для каждого пика нужно указать d или two_theta")
+		}
+		peaks = append(peaks, peak)
+	}
+	if err := diffraction.ValidatePeaks(peaks); err != nil {
+		return nil, errors.ErrInvalidInput(err.Error())
+	}
+	return diffraction.Normalize(peaks), nil
+}
+
+func (h *Handler) GetMineralXRDPeaks(c *fiber.Ctx) error {
+	owner, apiErr := h.mineralOwner(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	peaks, err := h.db.GetMineralXRDPeaks(owner.MineralID)
+	if err != nil {
+		log.Printf("Ошибка при получении дифрактограммы минерала %d: %v", owner.MineralID, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   peaks,
+	})
+}
+
+// SetMineralXRDPeaks replaces the reference pattern: {"peaks": [{"d": 3.343, "intensity": 100, "hkl": "101"}]}.
+// Intensities are rescaled so that the strongest peak is 100; an empty list removes the pattern.
+func (h *Handler) SetMineralXRDPeaks(c *fiber.Ctx) error {
+	id, apiErr := h.mineralIDParam(c)
+	if apiErr != nil {
+		return errors.SendError(c, apiErr)
+	}
+
+	var req xrdPeaksRequest
+	if err := c.BodyParser(&req); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+	}
+	var peaks []models.XRDPeak
+	if len(req.Peaks) > 0 {
+		var apiErr *errors.APIError
+		if peaks, apiErr = req.toPeaks(); apiErr != nil {
+			return errors.SendError(c, apiErr)
+		}
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.GetMineralForUpdate(id); err != nil {
+		return sendStagedError(c, err)
+	}
+	if err := tx.SetMineralXRDPeaks(id, peaks); err != nil {
+		log.Printf("Ошибка при сохранении дифрактограммы минерала %d: %v", id, err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	log.Printf("Сохранена дифрактограмма минерала %d: %d пиков", id, len(peaks))
+	if peaks == nil {
+		peaks = []models.XRDPeak{}
+	}
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data":   peaks,
+	})
+}
+
+// IdentifyXRD ranks minerals by how well their reference patterns match a measured one.
+// Accepts JSON {"peaks": [...], "wavelength": "CuKa", "tolerance": 0.005, "limit": 10} or a multipart form
+// with a 2θ/intensity file and wavelength, tolerance and limit fields.
+func (h *Handler) IdentifyXRD(c *fiber.Ctx) error {
+	var req xrdPeaksRequest
+	var measured []models.XRDPeak
+	if patternFile, err := c.FormFile("file"); err == nil {
+		measured, req, err = readPatternForm(c, patternFile)
+		if err != nil {
+			return errors.SendError(c, err.(*errors.APIError))
+		}
+	} else {
+		if err := c.BodyParser(&req); err != nil {
+			return errors.SendError(c, errors.ErrInvalidInput("некорректное тело запроса"))
+		}
+		var apiErr *errors.APIError
+		if measured, apiErr = req.toPeaks(); apiErr != nil {
+			return errors.SendError(c, apiErr)
+		}
+	}
+
+	if req.Tolerance == 0 {
+		req.Tolerance = diffraction.DefaultTolerance
+	}
+	if err := diffraction.ValidateTolerance(req.Tolerance); err != nil {
+		return errors.SendError(c, errors.ErrInvalidInput(err.Error()))
+	}
+	if req.Limit <= 0 || req.Limit > MaxXRDCandidates {
+		req.Limit = DefaultXRDCandidates
+	}
+
+	references, err := h.db.GetXRDReferences()
+	if err != nil {
+		log.Printf("Ошибка при загрузке эталонных дифрактограмм: %v", err)
+		return errors.SendError(c, errors.ErrServerError)
+	}
+
+	ranked := diffraction.Rank(measured, references, req.Tolerance, req.Limit)
+	candidates := make([]xrdCandidate, 0, len(ranked))
+	for _, candidate := range ranked {
+		mineral, err := h.db.GetMineralByID(candidate.MineralID)
+		if err == database.ErrMineralNotFound {
+			continue
+		}
+		if err != nil {
+			log.Printf("Ошибка при получении минерала %d: %v", candidate.MineralID, err)
+			return errors.SendError(c, errors.ErrServerError)
+		}
+		candidates = append(candidates, xrdCandidate{Mineral: h.signMineral(mineral), MatchResult: candidate.MatchResult})
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "success",
+		"data": fiber.Map{
+			"peaks":      measured,
+			"tolerance":  req.Tolerance,
+			"candidates": candidates,
+		},
+	})
+}
+
+// readPatternForm picks peaks from an uploaded profile and reads the remaining options from the form.
+func readPatternForm(c *fiber.Ctx, patternFile *multipart.FileHeader) ([]models.XRDPeak, xrdPeaksRequest, error) {
+	req := xrdPeaksRequest{Wavelength: wavelengthValue(c.FormValue("wavelength"))}
+	if raw := c.FormValue("tolerance"); raw != "" {
+		tolerance, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, req, errors.ErrInvalidInput("некорректное значение tolerance")
+		}
+		req.Tolerance = tolerance
+	}
+	req.Limit, _ = strconv.Atoi(c.FormValue("limit"))
+
+	wavelength, err := diffraction.ParseWavelength(string(req.Wavelength))
+	if err != nil {
+		return nil, req, errors.ErrInvalidInput(err.Error())
+	}
+	data, err := file.ReadUpload(patternFile, MaxPatternFileSize)
+	if err != nil {
+		return nil, req, err
+	}
+	points, err := diffraction.ParsePattern(data)
+	if err != nil {
+		return nil, req, errors.ErrInvalidInput(err.Error())
+	}
+	peaks, err := diffraction.FindPeaks(points, wavelength)
+	if err != nil {
+		return nil, req, errors.ErrInvalidInput(err.Error())
+	}
+	return peaks, req, nil
+}
@@ -0,0 +1,359 @@
+// A static file server for uploaded assets under /storage with full HTTP caching semantics.
+// Generates strong ETags from file contents (cached by size and modification time) and honours If-None-Match, If-Modified-Since and If-Range.
+// Serves single byte ranges so 3D viewers can fetch parts of large GLB files, and marks content-hashed file names as immutable.
+// Sets correct MIME types for 3D formats that are missing from the system MIME table.
+// Request paths are canonicalized and confined to the storage root, including symlink targets, and hidden files are never served.
+// Directories configured on the URL signer are only served with a valid, unexpired HMAC signature.
+
+package middleware
+
+import (
+	"backend/internal/service/signing"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ImmutableCacheControl   = "public, max-age=31536000, immutable"
+	RevalidateCacheControl  = "public, no-cache"
+	SignedCacheControl      = "private, no-cache"
+	storageNotFoundResponse = "File not found"
+	storageForbidden        = "Forbidden"
+)
+
+var (
+	// contentHashPattern matches names produced by the file service, such as "quartz.3fa9c2d41b07e855.glb".
+	contentHashPattern = regexp.MustCompile(`\.[0-9a-f]{16,64}\.[A-Za-z0-9]+$`)
+
+	storageContentTypes = map[string]string{
+		".glb":  "model/gltf-binary",
+		".gltf": "model/gltf+json",
+		".obj":  "model/obj",
+		".mtl":  "model/mtl",
+		".stl":  "model/stl",
+		".ply":  "application/x-ply",
+		".zip":  "application/zip",
+		".png":  "image/png",
+		".jpg":  "image/jpeg",
+		".jpeg": "image/jpeg",
+		".mp4":  "video/mp4",
+		".webm": "video/webm",
+		".mov":  "video/quicktime",
+		".pdf":  "application/pdf",
+	}
+)
+
+type etagEntry struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+type StorageServer struct {
+	root   string
+	signer *signing.URLSigner
+	mu     sync.RWMutex
+	etags  map[string]etagEntry
+}
+
+func NewStorageServer(root string) *StorageServer {
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	return &StorageServer{root: filepath.Clean(root), etags: make(map[string]etagEntry)}
+}
+
+// WithSigner enables signed-URL enforcement for the directories protected by signer.
+func (s *StorageServer) WithSigner(signer *signing.URLSigner) *StorageServer {
+	s.signer = signer
+	return s
+}
+
+// Handler serves files below root for requests mounted under prefix.
+func (s *StorageServer) Handler(prefix string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return c.Next()
+		}
+
+		relPath, ok := cleanStoragePath(strings.TrimPrefix(string(c.Request().URI().PathOriginal()), prefix))
+		if !ok {
+			return c.Status(fiber.StatusNotFound).SendString(storageNotFoundResponse)
+		}
+
+		signed := false
+		if s.signer != nil && s.signer.Requires(relPath) {
+			err := s.signer.Verify(prefix+relPath, c.Query(signing.ExpiresParam), c.Query(signing.SignatureParam))
+			if err != nil {
+				return c.Status(fiber.StatusForbidden).SendString(storageForbidden)
+			}
+			signed = true
+		}
+
+		fullPath, ok := s.confine(relPath)
+		if !ok {
+			return c.Status(fiber.StatusNotFound).SendString(storageNotFoundResponse)
+		}
+		if err := s.Serve(c, fullPath); err != nil {
+			return err
+		}
+		if signed {
+			c.Set(fiber.HeaderCacheControl, SignedCacheControl)
+		}
+		return nil
+	}
+}
+
+// cleanStoragePath decodes and canonicalizes a request path relative to the storage root.
+// Paths with traversal segments, hidden segments, backslashes or NUL bytes are rejected rather than rewritten.
+func cleanStoragePath(rawPath string) (string, bool) {
+	decoded, err := url.PathUnescape(rawPath)
+	if err != nil || strings.ContainsAny(decoded, "\\\x00") {
+		return "", false
+	}
+	for _, segment := range strings.Split(decoded, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return "", false
+		}
+	}
+	cleaned := path.Clean("/" + decoded)
+	if cleaned == "/" {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// confine maps a clean relative path to disk and checks that the file, after resolving symlinks, is still inside root.
+func (s *StorageServer) confine(relPath string) (string, bool) {
+	fullPath := filepath.Join(s.root, filepath.FromSlash(relPath))
+	resolved, err := filepath.EvalSymlinks(fullPath)
+	if err != nil {
+		return "", false
+	}
+	if !strings.HasPrefix(resolved, s.root+string(filepath.Separator)) {
+		return "", false
+	}
+	return resolved, true
+}
+
+// Serve writes the file at fullPath to the response, applying conditional and range request handling.
+func (s *StorageServer) Serve(c *fiber.Ctx, fullPath string) error {
+	info, err := os.Stat(fullPath)
+	if err != nil || info.IsDir() {
+		return c.Status(fiber.StatusNotFound).SendString(storageNotFoundResponse)
+	}
+
+	etag, err := s.etag(fullPath, info)
+	if err != nil {
+		log.Printf("Ошибка вычисления ETag для %s: %v", fullPath, err)
+		return c.Status(fiber.StatusInternalServerError).SendString("Internal Server Error")
+	}
+	modTime := info.ModTime().UTC().Truncate(time.Second)
+
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderLastModified, modTime.Format(http.TimeFormat))
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderCacheControl, cacheControl(fullPath))
+
+	if notModified(c, etag, modTime) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	size := info.Size()
+	start, length := int64(0), size
+	status := fiber.StatusOK
+
+	if rangeHeader := c.Get(fiber.HeaderRange); rangeHeader != "" && rangeApplies(c, etag, modTime) {
+		rangeStart, rangeLength, ok := parseRange(rangeHeader, size)
+		if !ok {
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes */%d", size))
+			return c.SendStatus(fiber.StatusRequestedRangeNotSatisfiable)
+		}
+		if rangeLength >= 0 {
+			start, length = rangeStart, rangeLength
+			status = fiber.StatusPartialContent
+			c.Set(fiber.HeaderContentRange, fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size))
+		}
+	}
+
+	c.Status(status)
+	c.Set(fiber.HeaderContentType, contentType(fullPath))
+	if c.Method() == fiber.MethodHead {
+		c.Context().Response.Header.SetContentLength(int(length))
+		c.Context().Response.SkipBody = true
+		return nil
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		log.Printf("Ошибка открытия файла %s: %v", fullPath, err)
+		return c.Status(fiber.StatusNotFound).SendString(storageNotFoundResponse)
+	}
+	c.Context().SetBodyStream(&sectionReadCloser{Reader: io.NewSectionReader(f, start, length), file: f}, int(length))
+	return nil
+}
+
+// Forget drops the cached ETag of a file, for example after it has been replaced or deleted.
+func (s *StorageServer) Forget(fullPath string) {
+	s.mu.Lock()
+	delete(s.etags, fullPath)
+	s.mu.Unlock()
+}
+
+func (s *StorageServer) etag(fullPath string, info os.FileInfo) (string, error) {
+	s.mu.RLock()
+	entry, ok := s.etags[fullPath]
+	s.mu.RUnlock()
+	if ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.etag, nil
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil))[:32] + `"`
+
+	s.mu.Lock()
+	s.etags[fullPath] = etagEntry{size: info.Size(), modTime: info.ModTime(), etag: etag}
+	s.mu.Unlock()
+	return etag, nil
+}
+
+type sectionReadCloser struct {
+	io.Reader
+	file *os.File
+}
+
+func (r *sectionReadCloser) Close() error {
+	return r.file.Close()
+}
+
+func cacheControl(fullPath string) string {
+	if contentHashPattern.MatchString(filepath.Base(fullPath)) {
+		return ImmutableCacheControl
+	}
+	return RevalidateCacheControl
+}
+
+func contentType(fullPath string) string {
+	ext := strings.ToLower(filepath.Ext(fullPath))
+	if known, ok := storageContentTypes[ext]; ok {
+		return known
+	}
+	if byExt := mime.TypeByExtension(ext); byExt != "" {
+		return byExt
+	}
+	return "application/octet-stream"
+}
+
+// notModified evaluates If-None-Match and, when it is absent, If-Modified-Since.
+func notModified(c *fiber.Ctx, etag string, modTime time.Time) bool {
+	if inm := c.Get(fiber.HeaderIfNoneMatch); inm != "" {
+		return etagListMatches(inm, etag)
+	}
+	if ims := c.Get(fiber.HeaderIfModifiedSince); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !modTime.After(t)
+		}
+	}
+	return false
+}
+
+// rangeApplies evaluates If-Range: the range is only served when the validator still matches.
+func rangeApplies(c *fiber.Ctx, etag string, modTime time.Time) bool {
+	ifRange := c.Get(fiber.HeaderIfRange)
+	if ifRange == "" {
+		return true
+	}
+	if strings.HasPrefix(ifRange, `"`) {
+		return ifRange == etag
+	}
+	if t, err := http.ParseTime(ifRange); err == nil {
+		return modTime.Equal(t)
+	}
+	return false
+}
+
+// etagListMatches compares an If-None-Match list against an ETag using weak comparison, which ignores the W/ prefix.
+func etagListMatches(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRange parses a single "bytes=" range. Multiple ranges are not supported and are answered with the full body,
+// which is signalled by a negative length. ok is false when the range cannot be satisfied.
+func parseRange(header string, size int64) (start, length int64, ok bool) {
+	if !strings.HasPrefix(header, "bytes=") {
+		return 0, -1, true
+	}
+	spec := strings.TrimSpace(strings.TrimPrefix(header, "bytes="))
+	if strings.Contains(spec, ",") {
+		return 0, -1, true
+	}
+
+	dash := strings.Index(spec, "-")
+	if dash < 0 {
+		return 0, -1, true
+	}
+	first, last := strings.TrimSpace(spec[:dash]), strings.TrimSpace(spec[dash+1:])
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, false
+		}
+		if suffix > size {
+			suffix = size
+		}
+		if size == 0 {
+			return 0, 0, false
+		}
+		return size - suffix, suffix, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end - start + 1, true
+}
@@ -0,0 +1,248 @@
+// Queries for media assets attached to minerals and their specimens.
+// An asset belongs either to a mineral itself or to one of its specimens; ordering and the primary flag are scoped to that owner.
+// Captions are stored as a JSONB object keyed by language code. Ordering is kept in a position column,
+// and a partial unique index guarantees at most one primary asset per type and owner.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"encoding/json"
+)
+
+const assetColumns = `id, mineral_id, COALESCE(specimen_id, 0), asset_type, path, COALESCE(source_path, ''),
+        captions, position, is_primary, created_at`
+
+// ownerCondition selects the assets of one owner; $1 is the mineral id and $2 the specimen id, or 0 for the mineral itself.
+const ownerCondition = `mineral_id = $1 AND specimen_id IS NOT DISTINCT FROM NULLIF($2, 0)`
+
+// AssetOwner identifies what an asset is attached to. SpecimenID is 0 for assets of the mineral itself.
+type AssetOwner struct {
+	MineralID  int
+	SpecimenID int
+}
+
+func MineralOwner(mineralID int) AssetOwner {
+	return AssetOwner{MineralID: mineralID}
+}
+
+func scanAsset(row rowScanner, a *models.MineralAsset) error {
+	var captions []byte
+	err := row.Scan(
+		&a.ID,
+		&a.MineralID,
+		&a.SpecimenID,
+		&a.Type,
+		&a.Path,
+		&a.SourcePath,
+		&captions,
+		&a.Position,
+		&a.IsPrimary,
+		&a.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	a.Captions = map[string]string{}
+	if len(captions) > 0 {
+		return json.Unmarshal(captions, &a.Captions)
+	}
+	return nil
+}
+
+// GetAssets lists the assets of one owner in display order.
+func (db *Database) GetAssets(owner AssetOwner) ([]models.MineralAsset, error) {
+	return getAssets(db.DB, owner, false)
+}
+
+// GetAllMineralAssetsForUpdate loads and locks every asset of a mineral, including those of its specimens,
+// for example before the mineral is deleted.
+func (t *Tx) GetAllMineralAssetsForUpdate(mineralID int) ([]models.MineralAsset, error) {
+	query := `
+        SELECT ` + assetColumns + `
+        FROM mineral_assets
+        WHERE mineral_id = $1
+        ORDER BY id
+        FOR UPDATE
+    `
+	return queryAssets(t.tx, query, mineralID)
+}
+
+// GetSpecimenAssetsForUpdate loads and locks the assets of a specimen.
+func (t *Tx) GetSpecimenAssetsForUpdate(specimenID int) ([]models.MineralAsset, error) {
+	query := `
+        SELECT ` + assetColumns + `
+        FROM mineral_assets
+        WHERE specimen_id = $1
+        ORDER BY id
+        FOR UPDATE
+    `
+	return queryAssets(t.tx, query, specimenID)
+}
+
+func getAssets(q querier, owner AssetOwner, forUpdate bool) ([]models.MineralAsset, error) {
+	query := `
+        SELECT ` + assetColumns + `
+        FROM mineral_assets
+        WHERE ` + ownerCondition + `
+        ORDER BY position, id
+    `
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	return queryAssets(q, query, owner.MineralID, owner.SpecimenID)
+}
+
+func queryAssets(q querier, query string, args ...interface{}) ([]models.MineralAsset, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assets := []models.MineralAsset{}
+	for rows.Next() {
+		var a models.MineralAsset
+		if err := scanAsset(rows, &a); err != nil {
+			return nil, err
+		}
+		assets = append(assets, a)
+	}
+	return assets, rows.Err()
+}
+
+func (t *Tx) GetAssetForUpdate(owner AssetOwner, assetID int) (*models.MineralAsset, error) {
+	query := `
+        SELECT ` + assetColumns + `
+        FROM mineral_assets
+        WHERE ` + ownerCondition + ` AND id = $3
+        FOR UPDATE
+    `
+	var asset models.MineralAsset
+	err := scanAsset(t.tx.QueryRow(query, owner.MineralID, owner.SpecimenID, assetID), &asset)
+	if err == sql.ErrNoRows {
+		return nil, ErrAssetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// CreateAsset appends an asset after the existing ones. A primary asset replaces the previous primary asset of its type.
+func (t *Tx) CreateAsset(asset models.MineralAsset) (*models.MineralAsset, error) {
+	captions, err := json.Marshal(asset.Captions)
+	if err != nil {
+		return nil, err
+	}
+	owner := AssetOwner{MineralID: asset.MineralID, SpecimenID: asset.SpecimenID}
+	if asset.IsPrimary {
+		if err := t.clearPrimary(owner, asset.Type); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+        INSERT INTO mineral_assets (mineral_id, specimen_id, asset_type, path, source_path, captions, position, is_primary)
+        VALUES ($1, NULLIF($2, 0), $3, $4, NULLIF($5, ''), $6,
+                (SELECT COALESCE(MAX(position) + 1, 0) FROM mineral_assets WHERE ` + ownerCondition + `), $7)
+        RETURNING ` + assetColumns + `
+    `
+	var created models.MineralAsset
+	err = scanAsset(t.tx.QueryRow(
+		query,
+		asset.MineralID,
+		asset.SpecimenID,
+		asset.Type,
+		asset.Path,
+		asset.SourcePath,
+		captions,
+		asset.IsPrimary,
+	), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateAsset changes the captions and primary flag of an asset.
+func (t *Tx) UpdateAsset(asset models.MineralAsset) (*models.MineralAsset, error) {
+	captions, err := json.Marshal(asset.Captions)
+	if err != nil {
+		return nil, err
+	}
+	if asset.IsPrimary {
+		owner := AssetOwner{MineralID: asset.MineralID, SpecimenID: asset.SpecimenID}
+		if err := t.clearPrimary(owner, asset.Type); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+        UPDATE mineral_assets
+        SET captions = $1, is_primary = $2
+        WHERE id = $3
+        RETURNING ` + assetColumns + `
+    `
+	var updated models.MineralAsset
+	err = scanAsset(t.tx.QueryRow(query, captions, asset.IsPrimary, asset.ID), &updated)
+	if err == sql.ErrNoRows {
+		return nil, ErrAssetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (t *Tx) DeleteAsset(assetID int) error {
+	result, err := t.tx.Exec(`DELETE FROM mineral_assets WHERE id = $1`, assetID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrAssetNotFound
+	}
+	return nil
+}
+
+// ReorderAssets assigns positions in the given order. assetIDs must list every asset of the owner exactly once.
+func (t *Tx) ReorderAssets(owner AssetOwner, assetIDs []int) ([]models.MineralAsset, error) {
+	assets, err := getAssets(t.tx, owner, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(assets) != len(assetIDs) {
+		return nil, ErrAssetOrderMismatch
+	}
+	existing := make(map[int]bool, len(assets))
+	for _, a := range assets {
+		existing[a.ID] = true
+	}
+	for _, id := range assetIDs {
+		if !existing[id] {
+			return nil, ErrAssetOrderMismatch
+		}
+		delete(existing, id)
+	}
+
+	for position, id := range assetIDs {
+		if _, err := t.tx.Exec(`UPDATE mineral_assets SET position = $1 WHERE id = $2`, position, id); err != nil {
+			return nil, err
+		}
+	}
+	return getAssets(t.tx, owner, false)
+}
+
+func (t *Tx) clearPrimary(owner AssetOwner, assetType string) error {
+	_, err := t.tx.Exec(
+		`UPDATE mineral_assets SET is_primary = FALSE WHERE `+ownerCondition+` AND asset_type = $3 AND is_primary`,
+		owner.MineralID, owner.SpecimenID, assetType,
+	)
+	return err
+}
@@ -0,0 +1,250 @@
+// Queries for the Strunz/Dana classification tree and the placement of minerals in it.
+// The tree is small, so it is loaded whole with per-node mineral counts and assembled in Go;
+// subtree and ancestor lookups use recursive CTEs over parent_id.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"encoding/json"
+	"github.com/lib/pq"
+)
+
+const classificationColumns = `n.id, n.parent_id, n.level, n.strunz_code, COALESCE(n.dana_code, ''), n.names, n.created_at`
+
+// subtreeQuery selects the ids of node $1 and all of its descendants.
+const subtreeQuery = `
+        WITH RECURSIVE subtree AS (
+            SELECT id FROM classification_nodes WHERE id = $1
+            UNION ALL
+            SELECT child.id FROM classification_nodes child JOIN subtree ON child.parent_id = subtree.id
+        )`
+
+func scanClassificationNode(row rowScanner, n *models.ClassificationNode, extra ...interface{}) error {
+	var parentID sql.NullInt64
+	var names []byte
+	dest := []interface{}{
+		&n.ID,
+		&parentID,
+		&n.Level,
+		&n.StrunzCode,
+		&n.DanaCode,
+		&names,
+		&n.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	n.ParentID = nullableInt(parentID)
+	n.Names = map[string]string{}
+	if len(names) > 0 {
+		return json.Unmarshal(names, &n.Names)
+	}
+	return nil
+}
+
+// GetClassificationNodes returns every node with the number of minerals placed directly in it.
+func (db *Database) GetClassificationNodes() ([]models.ClassificationNode, error) {
+	query := `
+        SELECT ` + classificationColumns + `, COUNT(m.id)
+        FROM classification_nodes n
+        LEFT JOIN minerals m ON m.classification_id = n.id AND ` + publishedCondition("m") + `
+        GROUP BY n.id
+    `
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes := []models.ClassificationNode{}
+	for rows.Next() {
+		var n models.ClassificationNode
+		if err := scanClassificationNode(rows, &n, &n.MineralCount); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
+
+func (db *Database) GetClassificationNode(id int) (*models.ClassificationNode, error) {
+	query := `
+        SELECT ` + classificationColumns + `
+        FROM classification_nodes n
+        WHERE n.id = $1
+    `
+	var node models.ClassificationNode
+	err := scanClassificationNode(db.DB.QueryRow(query, id), &node)
+	if err == sql.ErrNoRows {
+		return nil, ErrClassificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// GetClassificationPath returns the ancestors of a node and the node itself, from the class down.
+func (db *Database) GetClassificationPath(id int) ([]models.ClassificationNode, error) {
+	query := `
+        WITH RECURSIVE path AS (
+            SELECT id, parent_id, 0 AS depth FROM classification_nodes WHERE id = $1
+            UNION ALL
+            SELECT parent.id, parent.parent_id, path.depth + 1
+            FROM classification_nodes parent JOIN path ON parent.id = path.parent_id
+        )
+        SELECT ` + classificationColumns + `
+        FROM classification_nodes n
+        JOIN path ON path.id = n.id
+        ORDER BY path.depth DESC
+    `
+	rows, err := db.DB.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	path := []models.ClassificationNode{}
+	for rows.Next() {
+		var n models.ClassificationNode
+		if err := scanClassificationNode(rows, &n); err != nil {
+			return nil, err
+		}
+		path = append(path, n)
+	}
+	return path, rows.Err()
+}
+
+// GetClassificationMinerals lists the minerals placed in a node or anywhere below it.
+func (db *Database) GetClassificationMinerals(id int) ([]models.Mineral, error) {
+	query := subtreeQuery + `
+        SELECT ` + mineralColumns + `
+        FROM minerals
+        WHERE classification_id IN (SELECT id FROM subtree) AND ` + publishedCondition("minerals") + `
+        ORDER BY title, id
+    `
+	rows, err := db.DB.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	minerals := []models.Mineral{}
+	for rows.Next() {
+		var m models.Mineral
+		if err := scanMineral(rows, &m); err != nil {
+			return nil, err
+		}
+		minerals = append(minerals, m)
+	}
+	return minerals, rows.Err()
+}
+
+func (db *Database) CreateClassificationNode(node models.ClassificationNode) (*models.ClassificationNode, error) {
+	names, err := json.Marshal(node.Names)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        INSERT INTO classification_nodes AS n (parent_id, level, strunz_code, dana_code, names)
+        VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+        RETURNING ` + classificationColumns + `
+    `
+	var created models.ClassificationNode
+	err = scanClassificationNode(db.DB.QueryRow(
+		query,
+		node.ParentID,
+		node.Level,
+		node.StrunzCode,
+		node.DanaCode,
+		names,
+	), &created)
+	if err != nil {
+		return nil, classificationError(err)
+	}
+	return &created, nil
+}
+
+// UpdateClassificationNode changes names and the Dana code. Level, code and parent are fixed once created,
+// because the codes of the whole subtree depend on them.
+func (db *Database) UpdateClassificationNode(node models.ClassificationNode) (*models.ClassificationNode, error) {
+	names, err := json.Marshal(node.Names)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        UPDATE classification_nodes AS n
+        SET names = $1, dana_code = NULLIF($2, '')
+        WHERE n.id = $3
+        RETURNING ` + classificationColumns + `
+    `
+	var updated models.ClassificationNode
+	err = scanClassificationNode(db.DB.QueryRow(query, names, node.DanaCode, node.ID), &updated)
+	if err == sql.ErrNoRows {
+		return nil, ErrClassificationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteClassificationNode removes a leaf node; minerals placed in it become unclassified.
+func (db *Database) DeleteClassificationNode(id int) error {
+	result, err := db.DB.Exec(`DELETE FROM classification_nodes WHERE id = $1`, id)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == foreignKeyViolation {
+		return ErrClassificationHasChildren
+	}
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrClassificationNotFound
+	}
+	return nil
+}
+
+// SetMineralClassification places a mineral in a node (nil removes it from the tree) and sets its Dana code.
+func (db *Database) SetMineralClassification(mineralID int, classificationID *int, danaCode string) (*models.Mineral, error) {
+	return setMineralClassification(db.DB, mineralID, classificationID, danaCode)
+}
+
+func (t *Tx) SetMineralClassification(mineralID int, classificationID *int, danaCode string) (*models.Mineral, error) {
+	return setMineralClassification(t.tx, mineralID, classificationID, danaCode)
+}
+
+func setMineralClassification(q querier, mineralID int, classificationID *int, danaCode string) (*models.Mineral, error) {
+	query := `
+        UPDATE minerals
+        SET classification_id = $1, dana_code = NULLIF($2, ''), updated_at = CURRENT_TIMESTAMP
+        WHERE id = $3
+        RETURNING ` + mineralColumns + `
+    `
+	var updated models.Mineral
+	err := scanMineral(q.QueryRow(query, classificationID, danaCode, mineralID), &updated)
+	if err == sql.ErrNoRows {
+		return nil, ErrMineralNotFound
+	}
+	if err != nil {
+		return nil, classificationError(err)
+	}
+	return &updated, nil
+}
+
+func classificationError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case uniqueViolation:
+			return ErrClassificationCodeExists
+		case foreignKeyViolation:
+			return ErrClassificationNotFound
+		}
+	}
+	return err
+}
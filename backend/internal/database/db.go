@@ -14,10 +14,29 @@ import (
 )
 
 var (
-	ErrMineralNotFound   = errors.New("mineral not found")
-	ErrUserNotFound      = errors.New("user not found")
-	ErrInvalidPassword   = errors.New("invalid password")
-	ErrUserAlreadyExists = errors.New("user already exists")
+	ErrMineralNotFound           = errors.New("mineral not found")
+	ErrUserNotFound              = errors.New("user not found")
+	ErrInvalidPassword           = errors.New("invalid password")
+	ErrUserAlreadyExists         = errors.New("user already exists")
+	ErrAssetNotFound             = errors.New("asset not found")
+	ErrAssetOrderMismatch        = errors.New("asset order must list every asset of the mineral exactly once")
+	ErrSpecimenNotFound          = errors.New("specimen not found")
+	ErrCatalogNumberExists       = errors.New("catalog number already exists")
+	ErrLocalityNotFound          = errors.New("locality not found")
+	ErrClassificationNotFound    = errors.New("classification node not found")
+	ErrClassificationCodeExists  = errors.New("classification code already exists")
+	ErrClassificationHasChildren = errors.New("classification node has children")
+	ErrStructureNotFound         = errors.New("crystal structure not found")
+	ErrSpectrumNotFound          = errors.New("spectrum not found")
+	ErrMineralNameNotFound       = errors.New("mineral name not found")
+	ErrMineralNameExists         = errors.New("mineral name already exists")
+	ErrRelationNotFound          = errors.New("mineral relation not found")
+	ErrRelationExists            = errors.New("mineral relation already exists")
+	ErrRevisionNotFound          = errors.New("mineral revision not found")
+	ErrSlugNotFound              = errors.New("mineral slug not found")
+	ErrSlugExists                = errors.New("mineral slug already exists")
+	ErrTagNotFound               = errors.New("tag not found")
+	ErrTagExists                 = errors.New("tag already exists")
 )
 
 type Config struct {
@@ -0,0 +1,75 @@
+// Queries for the elemental composition of minerals, stored as one row per element of the parsed formula.
+// The rows are rewritten together with the formula, so element searches and the per-element mineral counts of the periodic table always match the stored formula.
+
+package database
+
+import (
+	"backend/internal/models"
+)
+
+func (db *Database) GetMineralComposition(mineralID int) ([]models.ElementAmount, error) {
+	query := `
+        SELECT element, atom_count, weight_percent
+        FROM mineral_elements
+        WHERE mineral_id = $1
+        ORDER BY position
+    `
+	rows, err := db.DB.Query(query, mineralID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	composition := []models.ElementAmount{}
+	for rows.Next() {
+		var e models.ElementAmount
+		if err := rows.Scan(&e.Symbol, &e.Count, &e.WeightPercent); err != nil {
+			return nil, err
+		}
+		composition = append(composition, e)
+	}
+	return composition, rows.Err()
+}
+
+// SetMineralComposition replaces the element rows of a mineral with the elements in formula order; nil removes them.
+func (t *Tx) SetMineralComposition(mineralID int, elements []models.ElementAmount) error {
+	if _, err := t.tx.Exec(`DELETE FROM mineral_elements WHERE mineral_id = $1`, mineralID); err != nil {
+		return err
+	}
+	for position, e := range elements {
+		_, err := t.tx.Exec(`
+            INSERT INTO mineral_elements (mineral_id, element, atom_count, weight_percent, position)
+            VALUES ($1, $2, $3, $4, $5)
+        `, mineralID, e.Symbol, e.Count, e.WeightPercent, position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetElementMineralCounts returns how many minerals contain each element, keyed by symbol.
+func (db *Database) GetElementMineralCounts() (map[string]int, error) {
+	rows, err := db.DB.Query(`
+        SELECT e.element, COUNT(*)
+        FROM mineral_elements e
+        JOIN minerals m ON m.id = e.mineral_id
+        WHERE ` + publishedCondition("m") + `
+        GROUP BY e.element
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var symbol string
+		var count int
+		if err := rows.Scan(&symbol, &count); err != nil {
+			return nil, err
+		}
+		counts[symbol] = count
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,168 @@
+// Queries for the catalogue export. Minerals are read in batches by id, and the tags, slugs, names and composition
+// of a batch are loaded with one query each, so an export of the whole catalogue holds a single batch in memory at a time.
+
+package database
+
+import (
+	"backend/internal/models"
+	"github.com/lib/pq"
+)
+
+const ExportBatchSize = 200
+
+// ExportMineral is a mineral with everything the export writes about it.
+type ExportMineral struct {
+	models.Mineral
+	StrunzCode string
+}
+
+// GetExportBatch returns up to limit minerals outside the trash with ids above afterID, in id order.
+func (db *Database) GetExportBatch(afterID, limit int) ([]ExportMineral, error) {
+	minerals, err := queryMinerals(db.DB, `
+        SELECT `+mineralColumns+`
+        FROM minerals m
+        WHERE m.id > $1 AND `+notDeletedCondition("m")+`
+        ORDER BY m.id
+        LIMIT $2
+    `, afterID, limit)
+	if err != nil || len(minerals) == 0 {
+		return nil, err
+	}
+
+	batch := make([]ExportMineral, len(minerals))
+	index := make(map[int]*ExportMineral, len(minerals))
+	ids := make([]int, len(minerals))
+	for i := range minerals {
+		batch[i].Mineral = minerals[i]
+		batch[i].Slugs = map[string]string{}
+		index[minerals[i].ID] = &batch[i]
+		ids[i] = minerals[i].ID
+	}
+
+	if err := db.loadExportTags(ids, index); err != nil {
+		return nil, err
+	}
+	if err := db.loadExportSlugs(ids, index); err != nil {
+		return nil, err
+	}
+	if err := db.loadExportNames(ids, index); err != nil {
+		return nil, err
+	}
+	if err := db.loadExportComposition(ids, index); err != nil {
+		return nil, err
+	}
+	if err := db.loadExportStrunzCodes(ids, index); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func (db *Database) loadExportTags(ids []int, index map[int]*ExportMineral) error {
+	rows, err := db.DB.Query(`
+        SELECT `+tagColumns+`, mt.mineral_id
+        FROM tags t
+        JOIN mineral_tags mt ON mt.tag_id = t.id
+        WHERE mt.mineral_id = ANY($1)
+        ORDER BY mt.mineral_id, t.slug
+    `, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag models.Tag
+		var mineralID int
+		if err := scanTag(rows, &tag, &mineralID); err != nil {
+			return err
+		}
+		index[mineralID].Tags = append(index[mineralID].Tags, tag)
+	}
+	return rows.Err()
+}
+
+func (db *Database) loadExportSlugs(ids []int, index map[int]*ExportMineral) error {
+	rows, err := db.DB.Query(`SELECT mineral_id, lang, slug FROM mineral_slugs WHERE mineral_id = ANY($1) AND is_current`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mineralID int
+		var lang, value string
+		if err := rows.Scan(&mineralID, &lang, &value); err != nil {
+			return err
+		}
+		index[mineralID].Slugs[lang] = value
+	}
+	return rows.Err()
+}
+
+func (db *Database) loadExportNames(ids []int, index map[int]*ExportMineral) error {
+	rows, err := db.DB.Query(`
+        SELECT `+mineralNameColumns+`
+        FROM mineral_names
+        WHERE mineral_id = ANY($1)
+        ORDER BY mineral_id, kind, lang, name
+    `, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n models.MineralName
+		if err := scanMineralName(rows, &n); err != nil {
+			return err
+		}
+		index[n.MineralID].Names = append(index[n.MineralID].Names, n)
+	}
+	return rows.Err()
+}
+
+func (db *Database) loadExportComposition(ids []int, index map[int]*ExportMineral) error {
+	rows, err := db.DB.Query(`
+        SELECT mineral_id, element, atom_count, weight_percent
+        FROM mineral_elements
+        WHERE mineral_id = ANY($1)
+        ORDER BY mineral_id, position
+    `, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mineralID int
+		var e models.ElementAmount
+		if err := rows.Scan(&mineralID, &e.Symbol, &e.Count, &e.WeightPercent); err != nil {
+			return err
+		}
+		index[mineralID].Composition = append(index[mineralID].Composition, e)
+	}
+	return rows.Err()
+}
+
+func (db *Database) loadExportStrunzCodes(ids []int, index map[int]*ExportMineral) error {
+	rows, err := db.DB.Query(`
+        SELECT m.id, n.strunz_code
+        FROM minerals m
+        JOIN classification_nodes n ON n.id = m.classification_id
+        WHERE m.id = ANY($1)
+    `, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mineralID int
+		var code string
+		if err := rows.Scan(&mineralID, &code); err != nil {
+			return err
+		}
+		index[mineralID].StrunzCode = code
+	}
+	return rows.Err()
+}
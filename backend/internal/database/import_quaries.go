@@ -0,0 +1,96 @@
+// Queries used by the bulk import: finding the existing minerals an imported row refers to,
+// and creating the tags an import mentions that do not exist yet.
+
+package database
+
+import (
+	"backend/internal/models"
+	"encoding/json"
+	"github.com/lib/pq"
+)
+
+const (
+	MatchByExternalID = "external_id"
+	MatchByUUID       = "uuid"
+	MatchByTitle      = "title"
+)
+
+// FindImportCandidates returns the minerals an imported row may refer to and the key that matched them:
+// the mineral with the external ID, else the one with the UUID, else those with the title regardless of case.
+// Minerals in the trash are included, so the caller can report them instead of creating a duplicate.
+func (db *Database) FindImportCandidates(externalID, uuid, title string) ([]models.Mineral, string, error) {
+	lookups := []struct {
+		matchedBy string
+		value     string
+		condition string
+	}{
+		{MatchByExternalID, externalID, `external_id = $1`},
+		{MatchByUUID, uuid, `uuid = $1::uuid`},
+		{MatchByTitle, title, `LOWER(title) = LOWER($1)`},
+	}
+	for _, lookup := range lookups {
+		if lookup.value == "" {
+			continue
+		}
+		minerals, err := queryMinerals(db.DB, `SELECT `+mineralColumns+` FROM minerals WHERE `+lookup.condition+` ORDER BY id`, lookup.value)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(minerals) > 0 {
+			return minerals, lookup.matchedBy, nil
+		}
+	}
+	return nil, "", nil
+}
+
+func queryMinerals(q querier, query string, args ...interface{}) ([]models.Mineral, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	minerals := []models.Mineral{}
+	for rows.Next() {
+		var m models.Mineral
+		if err := scanMineral(rows, &m); err != nil {
+			return nil, err
+		}
+		minerals = append(minerals, m)
+	}
+	return minerals, rows.Err()
+}
+
+// EnsureTags returns the ids of the given tags, creating those whose slug does not exist yet.
+func (t *Tx) EnsureTags(tags []models.Tag) ([]int, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	slugs := make([]string, len(tags))
+	for i, tag := range tags {
+		names, err := json.Marshal(tag.Names)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := t.tx.Exec(`INSERT INTO tags (slug, names) VALUES ($1, $2) ON CONFLICT (slug) DO NOTHING`, tag.Slug, names); err != nil {
+			return nil, err
+		}
+		slugs[i] = tag.Slug
+	}
+
+	rows, err := t.tx.Query(`SELECT id FROM tags WHERE slug = ANY($1) ORDER BY id`, pq.Array(slugs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
@@ -0,0 +1,317 @@
+// Queries for geographic localities and their links to minerals.
+// Spatial filtering is plain SQL: bounding-box queries compare the bounds stored with every locality,
+// and radius queries pre-filter by latitude before computing the haversine distance to the reference point.
+// Polygons are stored as GeoJSON coordinates in a JSONB column and are only used for display and bounds.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"encoding/json"
+	"github.com/lib/pq"
+)
+
+const localityColumns = `id, name, COALESCE(country, ''), COALESCE(region, ''), latitude, longitude, polygon,
+        created_at, updated_at`
+
+// distanceExpr is the haversine distance in kilometres from the point (lat, lon) to the locality reference point.
+func distanceExpr(lat, lon string) string {
+	return `2 * 6371.0 * ASIN(SQRT(LEAST(1,
+            POWER(SIN(RADIANS(latitude - ` + lat + `::float8) / 2), 2) +
+            COS(RADIANS(` + lat + `::float8)) * COS(RADIANS(latitude)) *
+            POWER(SIN(RADIANS(longitude - ` + lon + `::float8) / 2), 2))))`
+}
+
+// nearCondition keeps localities whose reference point lies within the radius of the point.
+// The latitude pre-filter, the degrees of latitude the radius covers, lets the planner use the latitude index
+// before the distance is computed.
+func nearCondition(args *queryArgs, near NearFilter) string {
+	lat := args.add(near.Lat)
+	lon := args.add(near.Lon)
+	radius := args.add(near.RadiusKM)
+	span := `DEGREES(` + radius + `::float8 / 6371.0)`
+	return `latitude BETWEEN ` + lat + `::float8 - ` + span + ` AND ` + lat + `::float8 + ` + span + `
+            AND ` + distanceExpr(lat, lon) + ` <= ` + radius + `::float8`
+}
+
+func scanLocality(row rowScanner, l *models.Locality, extra ...interface{}) error {
+	var polygon []byte
+	dest := []interface{}{
+		&l.ID,
+		&l.Name,
+		&l.Country,
+		&l.Region,
+		&l.Latitude,
+		&l.Longitude,
+		&polygon,
+		&l.CreatedAt,
+		&l.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	if len(polygon) > 0 {
+		return json.Unmarshal(polygon, &l.Polygon)
+	}
+	return nil
+}
+
+func queryLocalities(q querier, query string, args ...interface{}) ([]models.Locality, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	localities := []models.Locality{}
+	for rows.Next() {
+		var l models.Locality
+		if err := scanLocality(rows, &l); err != nil {
+			return nil, err
+		}
+		localities = append(localities, l)
+	}
+	return localities, rows.Err()
+}
+
+func (db *Database) GetAllLocalities() ([]models.Locality, error) {
+	query := `
+        SELECT ` + localityColumns + `
+        FROM localities
+        ORDER BY name, id
+    `
+	return queryLocalities(db.DB, query)
+}
+
+// GetLocalitiesInBBox returns localities whose point or outline intersects the box.
+func (db *Database) GetLocalitiesInBBox(box models.BBox) ([]models.Locality, error) {
+	condition, args := bboxCondition(box)
+	query := `
+        SELECT ` + localityColumns + `
+        FROM localities
+        WHERE ` + condition + `
+        ORDER BY name, id
+    `
+	return queryLocalities(db.DB, query, args...)
+}
+
+// GetLocalitiesNear returns localities within the radius of the point, nearest first, with DistanceKM set.
+func (db *Database) GetLocalitiesNear(near NearFilter) ([]models.Locality, error) {
+	var args queryArgs
+	condition := nearCondition(&args, near)
+	query := `
+        SELECT ` + localityColumns + `, ` + distanceExpr("$1", "$2") + ` AS distance_km
+        FROM localities
+        WHERE ` + condition + `
+        ORDER BY distance_km, id
+    `
+	rows, err := db.DB.Query(query, args.values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	localities := []models.Locality{}
+	for rows.Next() {
+		var l models.Locality
+		var distance float64
+		if err := scanLocality(rows, &l, &distance); err != nil {
+			return nil, err
+		}
+		l.DistanceKM = &distance
+		localities = append(localities, l)
+	}
+	return localities, rows.Err()
+}
+
+// bboxCondition builds the intersection test for a box. A box crossing the antimeridian matches either side of it.
+func bboxCondition(box models.BBox) (string, []interface{}) {
+	condition := `max_lat >= $1 AND min_lat <= $2 AND `
+	if box.CrossesAntimeridian() {
+		condition += `(max_lon >= $3 OR min_lon <= $4)`
+	} else {
+		condition += `max_lon >= $3 AND min_lon <= $4`
+	}
+	return condition, []interface{}{box.MinLat, box.MaxLat, box.MinLon, box.MaxLon}
+}
+
+func (db *Database) GetLocalityByID(id int) (*models.Locality, error) {
+	query := `
+        SELECT ` + localityColumns + `
+        FROM localities
+        WHERE id = $1
+    `
+	var locality models.Locality
+	err := scanLocality(db.DB.QueryRow(query, id), &locality)
+	if err == sql.ErrNoRows {
+		return nil, ErrLocalityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &locality, nil
+}
+
+// CreateLocality stores a locality with the bounds of its point and outline, which bounding-box queries compare.
+func (db *Database) CreateLocality(locality models.Locality, bounds models.BBox) (*models.Locality, error) {
+	polygon, err := localityPolygon(locality)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        INSERT INTO localities (name, country, region, latitude, longitude, polygon, min_lat, max_lat, min_lon, max_lon)
+        VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $10)
+        RETURNING ` + localityColumns + `
+    `
+	var created models.Locality
+	err = scanLocality(db.DB.QueryRow(
+		query,
+		locality.Name,
+		locality.Country,
+		locality.Region,
+		locality.Latitude,
+		locality.Longitude,
+		polygon,
+		bounds.MinLat,
+		bounds.MaxLat,
+		bounds.MinLon,
+		bounds.MaxLon,
+	), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (db *Database) UpdateLocality(locality models.Locality, bounds models.BBox) (*models.Locality, error) {
+	polygon, err := localityPolygon(locality)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        UPDATE localities
+        SET name = $1, country = NULLIF($2, ''), region = NULLIF($3, ''), latitude = $4, longitude = $5,
+            polygon = $6, min_lat = $7, max_lat = $8, min_lon = $9, max_lon = $10, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $11
+        RETURNING ` + localityColumns + `
+    `
+	var updated models.Locality
+	err = scanLocality(db.DB.QueryRow(
+		query,
+		locality.Name,
+		locality.Country,
+		locality.Region,
+		locality.Latitude,
+		locality.Longitude,
+		polygon,
+		bounds.MinLat,
+		bounds.MaxLat,
+		bounds.MinLon,
+		bounds.MaxLon,
+		locality.ID,
+	), &updated)
+	if err == sql.ErrNoRows {
+		return nil, ErrLocalityNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteLocality removes a locality; mineral links are dropped and specimens keep their free-text locality.
+func (db *Database) DeleteLocality(id int) error {
+	result, err := db.DB.Exec(`DELETE FROM localities WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrLocalityNotFound
+	}
+	return nil
+}
+
+func localityPolygon(locality models.Locality) (interface{}, error) {
+	if len(locality.Polygon) == 0 {
+		return nil, nil
+	}
+	polygon, err := json.Marshal(locality.Polygon)
+	if err != nil {
+		return nil, err
+	}
+	return polygon, nil
+}
+
+// GetMineralLocalities lists the localities linked to a mineral directly or through its specimens.
+func (db *Database) GetMineralLocalities(mineralID int) ([]models.Locality, error) {
+	query := `
+        SELECT ` + localityColumns + `
+        FROM localities
+        WHERE id IN (
+            SELECT locality_id FROM mineral_localities WHERE mineral_id = $1
+            UNION
+            SELECT locality_id FROM specimens WHERE mineral_id = $1 AND locality_id IS NOT NULL
+        )
+        ORDER BY name, id
+    `
+	return queryLocalities(db.DB, query, mineralID)
+}
+
+// SetMineralLocalities replaces the localities a mineral is linked to. Every id must exist.
+func (t *Tx) SetMineralLocalities(mineralID int, localityIDs []int) error {
+	localityIDs = uniqueIDs(localityIDs)
+	if _, err := t.tx.Exec(`DELETE FROM mineral_localities WHERE mineral_id = $1`, mineralID); err != nil {
+		return err
+	}
+	if len(localityIDs) == 0 {
+		return nil
+	}
+	result, err := t.tx.Exec(`
+        INSERT INTO mineral_localities (mineral_id, locality_id)
+        SELECT $1, id FROM localities WHERE id = ANY($2)
+    `, mineralID, pq.Array(localityIDs))
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(inserted) != len(localityIDs) {
+		return ErrLocalityNotFound
+	}
+	return nil
+}
+
+// mineralsNearQuery selects the ids of minerals occurring within the radius of the point,
+// either through a linked locality or through a specimen collected there.
+func mineralsNearQuery(args *queryArgs, near NearFilter) string {
+	condition := nearCondition(args, near)
+	return `
+            SELECT ml.mineral_id
+            FROM mineral_localities ml
+            JOIN localities ON localities.id = ml.locality_id
+            WHERE ` + condition + `
+            UNION
+            SELECT s.mineral_id
+            FROM specimens s
+            JOIN localities ON localities.id = s.locality_id
+            WHERE ` + condition
+}
+
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,111 @@
+// Filtered mineral listing for GET /minerals and the administrators' list. Each filter contributes an SQL condition with its own numbered
+// arguments, so filters combine freely: ?near=55.7,37.6&radius_km=50&elements=Cu,S&exclude=Fe&tags=fluorescent.
+// Visitors only ever see published minerals whose publish time has come; minerals in the trash never match.
+
+package database
+
+import (
+	"backend/internal/models"
+	"github.com/lib/pq"
+	"strconv"
+	"strings"
+)
+
+// NearFilter selects what lies within RadiusKM of a point.
+type NearFilter struct {
+	Lat      float64
+	Lon      float64
+	RadiusKM float64
+}
+
+type MineralFilter struct {
+	Near *NearFilter
+	// Elements must all be present in the parsed formula; ExcludeElements must all be absent.
+	// Minerals without a parsed formula never match an element filter.
+	Elements        []string
+	ExcludeElements []string
+	// Tags are tag slugs; a mineral must carry all of them.
+	Tags []string
+	// Without IncludeUnpublished only minerals visible to visitors match.
+	// With it, Statuses limits the workflow statuses; empty means any status.
+	IncludeUnpublished bool
+	Statuses           []string
+}
+
+func (f MineralFilter) IsEmpty() bool {
+	return f.Near == nil && len(f.Elements) == 0 && len(f.ExcludeElements) == 0 && len(f.Tags) == 0 &&
+		!f.IncludeUnpublished && len(f.Statuses) == 0
+}
+
+// queryArgs collects positional arguments while a query is assembled.
+type queryArgs struct {
+	values []interface{}
+}
+
+// add appends a value and returns its placeholder.
+func (a *queryArgs) add(value interface{}) string {
+	a.values = append(a.values, value)
+	return "$" + strconv.Itoa(len(a.values))
+}
+
+func (db *Database) FindMinerals(filter MineralFilter) ([]models.Mineral, error) {
+	var args queryArgs
+	conditions := []string{notDeletedCondition("minerals")}
+	switch {
+	case !filter.IncludeUnpublished:
+		conditions = append(conditions, publishedCondition("minerals"))
+	case len(filter.Statuses) > 0:
+		conditions = append(conditions, `status = ANY(`+args.add(pq.Array(filter.Statuses))+`)`)
+	}
+	if filter.Near != nil {
+		conditions = append(conditions, `id IN (`+mineralsNearQuery(&args, *filter.Near)+`)`)
+	}
+	if len(filter.Elements) > 0 {
+		conditions = append(conditions, `id IN (
+            SELECT mineral_id FROM mineral_elements
+            WHERE element = ANY(`+args.add(pq.Array(filter.Elements))+`)
+            GROUP BY mineral_id
+            HAVING COUNT(*) = `+args.add(len(filter.Elements))+`
+        )`)
+	}
+	if len(filter.ExcludeElements) > 0 {
+		conditions = append(conditions,
+			`EXISTS (SELECT 1 FROM mineral_elements e WHERE e.mineral_id = minerals.id)`,
+			`NOT EXISTS (
+            SELECT 1 FROM mineral_elements e
+            WHERE e.mineral_id = minerals.id AND e.element = ANY(`+args.add(pq.Array(filter.ExcludeElements))+`)
+        )`)
+	}
+
+	if len(filter.Tags) > 0 {
+		conditions = append(conditions, `id IN (
+            SELECT mt.mineral_id FROM mineral_tags mt
+            JOIN tags t ON t.id = mt.tag_id
+            WHERE t.slug = ANY(`+args.add(pq.Array(filter.Tags))+`)
+            GROUP BY mt.mineral_id
+            HAVING COUNT(*) = `+args.add(len(filter.Tags))+`
+        )`)
+	}
+
+	query := `
+        SELECT ` + mineralColumns + `
+        FROM minerals
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY id
+    `
+	rows, err := db.DB.Query(query, args.values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	minerals := []models.Mineral{}
+	for rows.Next() {
+		var m models.Mineral
+		if err := scanMineral(rows, &m); err != nil {
+			return nil, err
+		}
+		minerals = append(minerals, m)
+	}
+	return minerals, rows.Err()
+}
@@ -8,14 +8,70 @@ package database
 import (
 	"backend/internal/models"
 	"database/sql"
-	"errors"
 	"log"
 )
 
-func (db *Database) GetAllMinerals() ([]models.Mineral, error) {
+const mineralColumns = `id, uuid, COALESCE(external_id, ''), title, COALESCE(slug, ''), description, model_path, preview_image_path,
+        COALESCE(source_model_path, ''), classification_id, COALESCE(dana_code, ''),
+        COALESCE(formula, ''), molar_mass, COALESCE(crystal_system, ''), status, publish_at, published_at, deleted_at, created_at`
+
+// publishedCondition selects the minerals visitors may see; alias is the name the query gives the minerals table.
+func publishedCondition(alias string) string {
+	return alias + `.status = 'published' AND (` + alias + `.publish_at IS NULL OR ` + alias + `.publish_at <= NOW()) AND ` +
+		notDeletedCondition(alias)
+}
+
+// notDeletedCondition leaves out minerals in the trash.
+func notDeletedCondition(alias string) string {
+	return alias + `.deleted_at IS NULL`
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanMineral(row rowScanner, m *models.Mineral) error {
+	var classificationID sql.NullInt64
+	var molarMass sql.NullFloat64
+	var publishAt, publishedAt, deletedAt sql.NullTime
+	err := row.Scan(
+		&m.ID,
+		&m.UUID,
+		&m.ExternalID,
+		&m.Title,
+		&m.Slug,
+		&m.Description,
+		&m.ModelPath,
+		&m.PreviewImagePath,
+		&m.SourceModelPath,
+		&classificationID,
+		&m.DanaCode,
+		&m.Formula,
+		&molarMass,
+		&m.CrystalSystem,
+		&m.Status,
+		&publishAt,
+		&publishedAt,
+		&deletedAt,
+		&m.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	m.ClassificationID = nullableInt(classificationID)
+	m.MolarMass = nullableFloat(molarMass)
+	m.PublishAt = nullableTime(publishAt)
+	m.PublishedAt = nullableTime(publishedAt)
+	m.DeletedAt = nullableTime(deletedAt)
+	return nil
+}
+
+// GetPublishedMinerals lists the minerals visible to visitors.
+func (db *Database) GetPublishedMinerals() ([]models.Mineral, error) {
 	query := `
-        SELECT id, title, description, model_path, preview_image_path, created_at
+        SELECT ` + mineralColumns + `
         FROM minerals
+        WHERE ` + publishedCondition("minerals") + `
         ORDER BY id
     `
 
@@ -28,14 +84,7 @@ func (db *Database) GetAllMinerals() ([]models.Mineral, error) {
 	var minerals []models.Mineral
 	for rows.Next() {
 		var m models.Mineral
-		err := rows.Scan(
-			&m.ID,
-			&m.Title,
-			&m.Description,
-			&m.ModelPath,
-			&m.PreviewImagePath,
-			&m.CreatedAt,
-		)
+		err := scanMineral(rows, &m)
 		if err != nil {
 			return nil, err
 		}
@@ -49,22 +98,28 @@ func (db *Database) GetAllMinerals() ([]models.Mineral, error) {
 	return minerals, nil
 }
 
+// GetMineralByID loads a mineral that is not in the trash.
 func (db *Database) GetMineralByID(id int) (*models.Mineral, error) {
+	return getMineralByID(db.DB, id, false)
+}
+
+// GetMineralForUpdate loads a mineral and locks its row until the transaction ends.
+func (t *Tx) GetMineralForUpdate(id int) (*models.Mineral, error) {
+	return getMineralByID(t.tx, id, true)
+}
+
+func getMineralByID(q querier, id int, forUpdate bool) (*models.Mineral, error) {
 	query := `
-        SELECT id, title, description, model_path, preview_image_path, created_at 
-        FROM minerals 
-        WHERE id = $1
+        SELECT ` + mineralColumns + `
+        FROM minerals
+        WHERE id = $1 AND deleted_at IS NULL
     `
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
 
 	var mineral models.Mineral
-	err := db.DB.QueryRow(query, id).Scan(
-		&mineral.ID,
-		&mineral.Title,
-		&mineral.Description,
-		&mineral.ModelPath,
-		&mineral.PreviewImagePath,
-		&mineral.CreatedAt,
-	)
+	err := scanMineral(q.QueryRow(query, id), &mineral)
 
 	if err == sql.ErrNoRows {
 		return nil, ErrMineralNotFound
@@ -75,60 +130,127 @@ func (db *Database) GetMineralByID(id int) (*models.Mineral, error) {
 	return &mineral, nil
 }
 
-func (db *Database) CreateMineral(mineral models.Mineral) (*models.Mineral, error) {
+func (db *Database) CreateMineral(mineral models.Mineral, slugBase string) (*models.Mineral, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	created, err := tx.CreateMineral(mineral, slugBase)
+	if err != nil {
+		return nil, err
+	}
+	return created, tx.Commit()
+}
+
+// CreateMineral inserts a mineral and gives it a slug made from slugBase, the slug of its title.
+func (t *Tx) CreateMineral(mineral models.Mineral, slugBase string) (*models.Mineral, error) {
+	created, err := createMineral(t.tx, mineral)
+	if err != nil {
+		return nil, err
+	}
+	created.Slug, err = setMineralSlug(t.tx, created.ID, models.DefaultLanguage, slugBase, false)
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func createMineral(q querier, mineral models.Mineral) (*models.Mineral, error) {
 	query := `
-        INSERT INTO minerals (title, description, model_path, preview_image_path)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id, title, description, model_path, preview_image_path, created_at
+        INSERT INTO minerals (title, description, model_path, preview_image_path, source_model_path, formula, molar_mass, crystal_system, status,
+            uuid, external_id)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), $7, NULLIF($8, ''), $9,
+            COALESCE(NULLIF($10, '')::uuid, gen_random_uuid()), NULLIF($11, ''))
+        RETURNING ` + mineralColumns + `
     `
 	var created models.Mineral
-	err := db.DB.QueryRow(
+	err := scanMineral(q.QueryRow(
 		query,
 		mineral.Title,
 		mineral.Description,
 		mineral.ModelPath,
 		mineral.PreviewImagePath,
-	).Scan(
-		&created.ID,
-		&created.Title,
-		&created.Description,
-		&created.ModelPath,
-		&created.PreviewImagePath,
-		&created.CreatedAt,
-	)
+		mineral.SourceModelPath,
+		mineral.Formula,
+		mineral.MolarMass,
+		mineral.CrystalSystem,
+		mineral.Status,
+		mineral.UUID,
+		mineral.ExternalID,
+	), &created)
 	if err != nil {
 		return nil, err
 	}
 	return &created, nil
 }
 
-func (db *Database) UpdateMineral(mineral models.Mineral) (*models.Mineral, error) {
+func (db *Database) UpdateMineral(mineral models.Mineral, slugBase string) (*models.Mineral, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	updated, err := tx.UpdateMineral(mineral, slugBase)
+	if err != nil {
+		return nil, err
+	}
+	return updated, tx.Commit()
+}
+
+// UpdateMineral saves a mineral. A new title gives it a new slug made from slugBase, the slug of the title;
+// the old one keeps redirecting. Without a rename the current slug stays, so a slug set by hand survives unrelated edits.
+func (t *Tx) UpdateMineral(mineral models.Mineral, slugBase string) (*models.Mineral, error) {
+	var previousTitle string
+	err := t.tx.QueryRow(`SELECT title FROM minerals WHERE id = $1 FOR UPDATE`, mineral.ID).Scan(&previousTitle)
+	if err == sql.ErrNoRows {
+		return nil, ErrMineralNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := updateMineral(t.tx, mineral)
+	if err != nil {
+		return nil, err
+	}
+	if updated.Title == previousTitle && updated.Slug != "" {
+		return updated, nil
+	}
+	updated.Slug, err = setMineralSlug(t.tx, updated.ID, models.DefaultLanguage, slugBase, false)
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func updateMineral(q querier, mineral models.Mineral) (*models.Mineral, error) {
 	query := `
         UPDATE minerals
-        SET title = $1, description = $2, model_path = $3, preview_image_path = $4
+        SET title = $1, description = $2, model_path = $3, preview_image_path = $4,
+            source_model_path = NULLIF($6, ''), formula = NULLIF($7, ''), molar_mass = $8,
+            crystal_system = NULLIF($9, ''), external_id = NULLIF($10, ''), updated_at = CURRENT_TIMESTAMP
         WHERE id = $5
-        RETURNING id, title, description, model_path, preview_image_path, created_at
+        RETURNING ` + mineralColumns + `
     `
 	log.Printf("Received update request for mineral %d with title: %s, description: %s", mineral.ID, mineral.Title, mineral.Description)
 	var updated models.Mineral
-	err := db.DB.QueryRow(
+	err := scanMineral(q.QueryRow(
 		query,
 		mineral.Title,
 		mineral.Description,
 		mineral.ModelPath,
 		mineral.PreviewImagePath,
 		mineral.ID,
-	).Scan(
-		&updated.ID,
-		&updated.Title,
-		&updated.Description,
-		&updated.ModelPath,
-		&updated.PreviewImagePath,
-		&updated.CreatedAt,
-	)
+		mineral.SourceModelPath,
+		mineral.Formula,
+		mineral.MolarMass,
+		mineral.CrystalSystem,
+		mineral.ExternalID,
+	), &updated)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("минерал не найден")
+		return nil, ErrMineralNotFound
 	}
 	if err != nil {
 		return nil, err
@@ -137,9 +259,10 @@ func (db *Database) UpdateMineral(mineral models.Mineral) (*models.Mineral, erro
 
 }
 
-func (db *Database) DeleteMineral(id int) error {
-	query := `DELETE FROM minerals WHERE id = $1`
-	result, err := db.DB.Exec(query, id)
+// DeleteMineral moves a mineral to the trash. Its files and related records stay until it is purged.
+func (t *Tx) DeleteMineral(id int) error {
+	query := `UPDATE minerals SET deleted_at = NOW(), updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL`
+	result, err := t.tx.Exec(query, id)
 	if err != nil {
 		return err
 	}
@@ -156,9 +279,11 @@ func (db *Database) DeleteMineral(id int) error {
 
 func (db *Database) SearchMineralByTitle(query string) ([]models.Mineral, error) {
 	sqlQuery := `
-        SELECT id, title, description, model_path, preview_image_path, created_at
+        SELECT ` + mineralColumns + `
         FROM minerals
-        WHERE LOWER(title) LIKE LOWER($1)
+        WHERE (LOWER(title) LIKE LOWER($1)
+           OR id IN (SELECT mineral_id FROM mineral_names WHERE LOWER(name) LIKE LOWER($1)))
+          AND ` + publishedCondition("minerals") + `
         ORDER BY title ASC
     `
 
@@ -173,14 +298,7 @@ func (db *Database) SearchMineralByTitle(query string) ([]models.Mineral, error)
 	var minerals []models.Mineral
 	for rows.Next() {
 		var m models.Mineral
-		err := rows.Scan(
-			&m.ID,
-			&m.Title,
-			&m.Description,
-			&m.ModelPath,
-			&m.PreviewImagePath,
-			&m.CreatedAt,
-		)
+		err := scanMineral(rows, &m)
 		if err != nil {
 			return nil, err
 		}
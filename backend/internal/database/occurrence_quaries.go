@@ -0,0 +1,181 @@
+// Queries for the Darwin Core export and the OAI-PMH provider: every specimen as an occurrence record with its mineral and locality.
+// The datestamp of a record is the latest change of the specimen, its mineral or its locality, or the moment the mineral was published,
+// so a harvester asking for changes since a date also receives the specimens whose mineral was hidden or trashed in the meantime.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Occurrence is a specimen with the mineral and the locality a Darwin Core record is made from.
+type Occurrence struct {
+	models.Specimen
+	MineralUUID   string
+	MineralTitle  string
+	Formula       string
+	CrystalSystem string
+	DanaCode      string
+	StrunzCode    string
+	// Place is the geographic locality the specimen links to, nil when it only has the free-text locality.
+	Place *models.Locality
+	// Modified is the datestamp of the record.
+	Modified time.Time
+	// Visible is false for the specimens of minerals visitors can no longer see.
+	Visible bool
+}
+
+type OccurrenceFilter struct {
+	AfterID int
+	From    *time.Time
+	Until   *time.Time
+	// IncludeHidden adds the specimens of minerals that were published once and are no longer visible.
+	IncludeHidden bool
+	Limit         int
+}
+
+// A scheduled mineral has published_at in the future; that moment counts only once it has passed,
+// so no record carries a datestamp a harvester has not reached yet.
+const occurrenceModified = `GREATEST(s.updated_at::timestamptz, m.updated_at::timestamptz, l.updated_at::timestamptz,
+            CASE WHEN m.published_at <= NOW() THEN m.published_at END,
+            CASE WHEN m.publish_at <= NOW() THEN m.publish_at END)`
+
+const occurrenceColumns = `s.id, s.mineral_id, s.catalog_number, COALESCE(s.locality, ''), s.locality_id, s.length_mm, s.width_mm, s.height_mm, s.mass_g,
+        COALESCE(to_char(s.acquired_on, 'YYYY-MM-DD'), ''), COALESCE(s.acquisition_source, ''),
+        COALESCE(s.storage_location, ''), COALESCE(s.notes, ''), s.created_at, s.updated_at,
+        m.uuid, m.title, COALESCE(m.formula, ''), COALESCE(m.crystal_system, ''), COALESCE(m.dana_code, ''), COALESCE(n.strunz_code, ''),
+        COALESCE(l.name, ''), COALESCE(l.country, ''), COALESCE(l.region, ''), l.latitude, l.longitude,
+        ` + occurrenceModified
+
+const occurrenceTables = `
+        FROM specimens s
+        JOIN minerals m ON m.id = s.mineral_id
+        LEFT JOIN localities l ON l.id = s.locality_id
+        LEFT JOIN classification_nodes n ON n.id = m.classification_id`
+
+func scanOccurrence(row rowScanner, o *Occurrence) error {
+	var length, width, height, mass, latitude, longitude sql.NullFloat64
+	var localityID sql.NullInt64
+	var place models.Locality
+	err := row.Scan(
+		&o.ID,
+		&o.MineralID,
+		&o.CatalogNumber,
+		&o.Locality,
+		&localityID,
+		&length,
+		&width,
+		&height,
+		&mass,
+		&o.AcquiredOn,
+		&o.AcquisitionSource,
+		&o.StorageLocation,
+		&o.Notes,
+		&o.CreatedAt,
+		&o.UpdatedAt,
+		&o.MineralUUID,
+		&o.MineralTitle,
+		&o.Formula,
+		&o.CrystalSystem,
+		&o.DanaCode,
+		&o.StrunzCode,
+		&place.Name,
+		&place.Country,
+		&place.Region,
+		&latitude,
+		&longitude,
+		&o.Modified,
+		&o.Visible,
+	)
+	if err != nil {
+		return err
+	}
+	o.LocalityID = nullableInt(localityID)
+	o.LengthMM = nullableFloat(length)
+	o.WidthMM = nullableFloat(width)
+	o.HeightMM = nullableFloat(height)
+	o.MassG = nullableFloat(mass)
+	if o.LocalityID != nil {
+		place.ID = *o.LocalityID
+		place.Latitude = latitude.Float64
+		place.Longitude = longitude.Float64
+		o.Place = &place
+	}
+	return nil
+}
+
+// occurrenceConditions keeps the visible specimens, and with includeHidden also those of minerals that were published once.
+// A mineral still waiting for its publish date has never been public, so its specimens are not reported as deleted.
+func occurrenceConditions(includeHidden bool) []string {
+	if includeHidden {
+		return []string{`(` + publishedCondition("m") + ` OR m.published_at <= NOW())`}
+	}
+	return []string{publishedCondition("m")}
+}
+
+// GetOccurrences returns up to filter.Limit occurrences with specimen ids above filter.AfterID whose datestamp
+// lies between From and Until, in specimen id order.
+func (db *Database) GetOccurrences(filter OccurrenceFilter) ([]Occurrence, error) {
+	var args queryArgs
+	conditions := append(occurrenceConditions(filter.IncludeHidden), `s.id > `+args.add(filter.AfterID))
+	if filter.From != nil {
+		conditions = append(conditions, occurrenceModified+` >= `+args.add(*filter.From))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, occurrenceModified+` <= `+args.add(*filter.Until))
+	}
+
+	query := `
+        SELECT ` + occurrenceColumns + `, ` + publishedCondition("m") + occurrenceTables + `
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY s.id
+        LIMIT ` + args.add(filter.Limit)
+	rows, err := db.DB.Query(query, args.values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	occurrences := []Occurrence{}
+	for rows.Next() {
+		var o Occurrence
+		if err := scanOccurrence(rows, &o); err != nil {
+			return nil, err
+		}
+		occurrences = append(occurrences, o)
+	}
+	return occurrences, rows.Err()
+}
+
+// GetOccurrence returns the occurrence of a specimen, including a hidden one whose mineral was published once.
+func (db *Database) GetOccurrence(specimenID int) (*Occurrence, error) {
+	query := `
+        SELECT ` + occurrenceColumns + `, ` + publishedCondition("m") + occurrenceTables + `
+        WHERE s.id = $1 AND ` + strings.Join(occurrenceConditions(true), " AND ")
+
+	var o Occurrence
+	err := scanOccurrence(db.DB.QueryRow(query, specimenID), &o)
+	if err == sql.ErrNoRows {
+		return nil, ErrSpecimenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// GetEarliestOccurrenceDatestamp returns the oldest datestamp a harvester can ask for, nil when there are no records.
+func (db *Database) GetEarliestOccurrenceDatestamp() (*time.Time, error) {
+	query := `
+        SELECT MIN(` + occurrenceModified + `)` + occurrenceTables + `
+        WHERE ` + strings.Join(occurrenceConditions(true), " AND ")
+
+	var earliest sql.NullTime
+	if err := db.DB.QueryRow(query).Scan(&earliest); err != nil {
+		return nil, err
+	}
+	return nullableTime(earliest), nil
+}
@@ -0,0 +1,274 @@
+// Queries for synonyms, varieties and relations between minerals.
+// The relation graph is walked breadth-first, one query per level, only through minerals the caller can see,
+// and stops at MaxGraphNodes minerals.
+
+package database
+
+import (
+	"backend/internal/models"
+	"github.com/lib/pq"
+	"strings"
+)
+
+const MaxGraphNodes = 200
+
+const mineralNameColumns = `id, mineral_id, name, lang, kind, COALESCE(description, ''), created_at`
+
+const relationColumns = `id, mineral_id, related_id, type, COALESCE(note, ''), created_at`
+
+func scanMineralName(row rowScanner, n *models.MineralName) error {
+	return row.Scan(&n.ID, &n.MineralID, &n.Name, &n.Lang, &n.Kind, &n.Description, &n.CreatedAt)
+}
+
+func scanRelation(row rowScanner, r *models.MineralRelation) error {
+	return row.Scan(&r.ID, &r.MineralID, &r.RelatedID, &r.Type, &r.Note, &r.CreatedAt)
+}
+
+func (db *Database) GetMineralNames(mineralID int) ([]models.MineralName, error) {
+	query := `
+        SELECT ` + mineralNameColumns + `
+        FROM mineral_names
+        WHERE mineral_id = $1
+        ORDER BY kind, lang, name
+    `
+	rows, err := db.DB.Query(query, mineralID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []models.MineralName{}
+	for rows.Next() {
+		var n models.MineralName
+		if err := scanMineralName(rows, &n); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+// FindMineralsByName matches synonyms and varieties by case-insensitive prefix
+// and returns the first matching name of each mineral.
+func (db *Database) FindMineralsByName(prefix string) (map[int]string, error) {
+	query := `
+        SELECT DISTINCT ON (mineral_id) mineral_id, name
+        FROM mineral_names
+        WHERE LOWER(name) LIKE $1
+        ORDER BY mineral_id, LENGTH(name), name
+    `
+	rows, err := db.DB.Query(query, likePrefix(prefix))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := map[int]string{}
+	for rows.Next() {
+		var mineralID int
+		var name string
+		if err := rows.Scan(&mineralID, &name); err != nil {
+			return nil, err
+		}
+		matches[mineralID] = name
+	}
+	return matches, rows.Err()
+}
+
+// likePrefix turns user input into a lower-case LIKE pattern that matches it literally as a prefix.
+func likePrefix(prefix string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(prefix))
+	return escaped + "%"
+}
+
+func (db *Database) CreateMineralName(name models.MineralName) (*models.MineralName, error) {
+	query := `
+        INSERT INTO mineral_names (mineral_id, name, lang, kind, description)
+        VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+        RETURNING ` + mineralNameColumns + `
+    `
+	var created models.MineralName
+	err := scanMineralName(db.DB.QueryRow(query, name.MineralID, name.Name, name.Lang, name.Kind, name.Description), &created)
+	if err != nil {
+		return nil, relationError(err, ErrMineralNameExists)
+	}
+	return &created, nil
+}
+
+func (db *Database) DeleteMineralName(id int) error {
+	result, err := db.DB.Exec(`DELETE FROM mineral_names WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrMineralNameNotFound
+	}
+	return nil
+}
+
+func (db *Database) CreateMineralRelation(relation models.MineralRelation) (*models.MineralRelation, error) {
+	query := `
+        INSERT INTO mineral_relations (mineral_id, related_id, type, note)
+        VALUES ($1, $2, $3, NULLIF($4, ''))
+        RETURNING ` + relationColumns + `
+    `
+	var created models.MineralRelation
+	err := scanRelation(db.DB.QueryRow(query, relation.MineralID, relation.RelatedID, relation.Type, relation.Note), &created)
+	if err != nil {
+		return nil, relationError(err, ErrRelationExists)
+	}
+	return &created, nil
+}
+
+func (db *Database) DeleteMineralRelation(id int) error {
+	result, err := db.DB.Exec(`DELETE FROM mineral_relations WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRelationNotFound
+	}
+	return nil
+}
+
+// GetRelationGraph collects the minerals within depth relations of a mineral and the relations between them.
+// The mineral itself is kept in any status, as the caller was already allowed to see it; other minerals must be published
+// unless includeUnpublished is set. The walk does not pass through minerals the caller cannot see.
+func (db *Database) GetRelationGraph(mineralID, depth int, includeUnpublished bool) (*models.RelationGraph, error) {
+	graph := &models.RelationGraph{MineralID: mineralID, Nodes: []models.RelatedMineral{}, Edges: []models.MineralRelation{}}
+	nodes, err := db.getRelatedMinerals([]int{mineralID}, true)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := nodes[mineralID]
+	if !ok {
+		return graph, nil
+	}
+	graph.Nodes = append(graph.Nodes, root)
+	// checked holds the minerals already looked up, visible or not, so each is loaded once.
+	checked := map[int]bool{mineralID: true}
+	seenEdges := map[int]bool{}
+	frontier := []int{mineralID}
+
+	for level := 1; level <= depth && len(frontier) > 0; level++ {
+		edges, err := db.getRelationsTouching(frontier)
+		if err != nil {
+			return nil, err
+		}
+		var candidates []int
+		for _, edge := range edges {
+			for _, id := range []int{edge.MineralID, edge.RelatedID} {
+				if !checked[id] {
+					checked[id] = true
+					candidates = append(candidates, id)
+				}
+			}
+		}
+		visible, err := db.getRelatedMinerals(candidates, includeUnpublished)
+		if err != nil {
+			return nil, err
+		}
+
+		var next []int
+		for _, edge := range edges {
+			for _, id := range []int{edge.MineralID, edge.RelatedID} {
+				node, ok := visible[id]
+				if _, seen := nodes[id]; seen || !ok {
+					continue
+				}
+				if len(graph.Nodes) >= MaxGraphNodes {
+					graph.Truncated = true
+					continue
+				}
+				node.Depth = level
+				nodes[id] = node
+				graph.Nodes = append(graph.Nodes, node)
+				next = append(next, id)
+			}
+			_, fromOk := nodes[edge.MineralID]
+			_, toOk := nodes[edge.RelatedID]
+			if !fromOk || !toOk || seenEdges[edge.ID] {
+				continue
+			}
+			seenEdges[edge.ID] = true
+			graph.Edges = append(graph.Edges, edge)
+		}
+		frontier = next
+	}
+	return graph, nil
+}
+
+func (db *Database) getRelationsTouching(ids []int) ([]models.MineralRelation, error) {
+	query := `
+        SELECT ` + relationColumns + `
+        FROM mineral_relations
+        WHERE mineral_id = ANY($1) OR related_id = ANY($1)
+        ORDER BY id
+    `
+	rows, err := db.DB.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var relations []models.MineralRelation
+	for rows.Next() {
+		var r models.MineralRelation
+		if err := scanRelation(rows, &r); err != nil {
+			return nil, err
+		}
+		relations = append(relations, r)
+	}
+	return relations, rows.Err()
+}
+
+// getRelatedMinerals loads the graph nodes among ids: published minerals, or every mineral outside the trash with includeUnpublished.
+func (db *Database) getRelatedMinerals(ids []int, includeUnpublished bool) (map[int]models.RelatedMineral, error) {
+	nodes := map[int]models.RelatedMineral{}
+	if len(ids) == 0 {
+		return nodes, nil
+	}
+	condition := publishedCondition("m")
+	if includeUnpublished {
+		condition = notDeletedCondition("m")
+	}
+	query := `
+        SELECT id, title, COALESCE(formula, ''), preview_image_path
+        FROM minerals m
+        WHERE id = ANY($1) AND ` + condition + `
+    `
+	rows, err := db.DB.Query(query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var node models.RelatedMineral
+		if err := rows.Scan(&node.ID, &node.Title, &node.Formula, &node.PreviewImagePath); err != nil {
+			return nil, err
+		}
+		nodes[node.ID] = node
+	}
+	return nodes, rows.Err()
+}
+
+func relationError(err error, exists error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case uniqueViolation:
+			return exists
+		case foreignKeyViolation:
+			return ErrMineralNotFound
+		}
+	}
+	return err
+}
@@ -0,0 +1,110 @@
+// Queries for the revision history of minerals. Revisions are written in the transaction that changes the mineral,
+// numbered per mineral, and are not removed together with it.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"encoding/json"
+)
+
+const revisionColumns = `id, mineral_id, revision, action, author_id, COALESCE(author_name, ''), restored_from, snapshot, created_at`
+
+func scanRevision(row rowScanner, r *models.MineralRevision) error {
+	var authorID, restoredFrom sql.NullInt64
+	var snapshot []byte
+	err := row.Scan(&r.ID, &r.MineralID, &r.Revision, &r.Action, &authorID, &r.AuthorName, &restoredFrom, &snapshot, &r.CreatedAt)
+	if err != nil {
+		return err
+	}
+	r.AuthorID = nullableInt(authorID)
+	r.RestoredFrom = nullableInt(restoredFrom)
+	return json.Unmarshal(snapshot, &r.Snapshot)
+}
+
+// GetMineralRevisions lists the revisions of a mineral, newest first.
+func (db *Database) GetMineralRevisions(mineralID int) ([]models.MineralRevision, error) {
+	query := `
+        SELECT ` + revisionColumns + `
+        FROM mineral_revisions
+        WHERE mineral_id = $1
+        ORDER BY revision DESC
+    `
+	rows, err := db.DB.Query(query, mineralID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.MineralRevision{}
+	for rows.Next() {
+		var r models.MineralRevision
+		if err := scanRevision(rows, &r); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func (db *Database) GetMineralRevision(mineralID, revision int) (*models.MineralRevision, error) {
+	query := `SELECT ` + revisionColumns + ` FROM mineral_revisions WHERE mineral_id = $1 AND revision = $2`
+	var r models.MineralRevision
+	err := scanRevision(db.DB.QueryRow(query, mineralID, revision), &r)
+	if err == sql.ErrNoRows {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateMineralRevision stores the next revision of a mineral. Callers hold the mineral row lock,
+// so revision numbers of one mineral never collide.
+func (t *Tx) CreateMineralRevision(revision models.MineralRevision) (*models.MineralRevision, error) {
+	snapshot, err := json.Marshal(revision.Snapshot)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        INSERT INTO mineral_revisions (mineral_id, revision, action, author_id, author_name, restored_from, snapshot)
+        VALUES (
+            $1,
+            COALESCE((SELECT MAX(revision) FROM mineral_revisions WHERE mineral_id = $1), 0) + 1,
+            $2, $3, NULLIF($4, ''), $5, $6
+        )
+        RETURNING ` + revisionColumns + `
+    `
+	var created models.MineralRevision
+	err = scanRevision(t.tx.QueryRow(
+		query,
+		revision.MineralID,
+		revision.Action,
+		revision.AuthorID,
+		revision.AuthorName,
+		revision.RestoredFrom,
+		string(snapshot),
+	), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// EnsureBaselineRevision records the current state of a mineral that has no history yet,
+// so the first change to a mineral created before revisions were kept can still be undone.
+func (t *Tx) EnsureBaselineRevision(mineral *models.Mineral) error {
+	var exists bool
+	err := t.tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM mineral_revisions WHERE mineral_id = $1)`, mineral.ID).Scan(&exists)
+	if err != nil || exists {
+		return err
+	}
+	_, err = t.CreateMineralRevision(models.MineralRevision{
+		MineralID: mineral.ID,
+		Action:    models.RevisionBaseline,
+		Snapshot:  models.SnapshotOf(mineral),
+	})
+	return err
+}
@@ -0,0 +1,225 @@
+// Queries for mineral slugs and UUID lookups.
+// Slugs are unique within a language. A mineral's current slug in the default language is also kept in minerals.slug
+// so that lists can link to it; replaced slugs stay in mineral_slugs until another mineral claims them.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"github.com/lib/pq"
+	"strconv"
+	"strings"
+)
+
+const slugColumns = `id, mineral_id, lang, slug, is_current, created_at`
+
+func scanMineralSlug(row rowScanner, s *models.MineralSlug) error {
+	return row.Scan(&s.ID, &s.MineralID, &s.Lang, &s.Slug, &s.Current, &s.CreatedAt)
+}
+
+// GetMineralSlugs lists every slug of a mineral, the current ones first.
+func (db *Database) GetMineralSlugs(mineralID int) ([]models.MineralSlug, error) {
+	query := `
+        SELECT ` + slugColumns + `
+        FROM mineral_slugs
+        WHERE mineral_id = $1
+        ORDER BY is_current DESC, lang, created_at DESC
+    `
+	rows, err := db.DB.Query(query, mineralID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slugs := []models.MineralSlug{}
+	for rows.Next() {
+		var s models.MineralSlug
+		if err := scanMineralSlug(rows, &s); err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, s)
+	}
+	return slugs, rows.Err()
+}
+
+// GetCurrentMineralSlugs maps each language to the mineral's current slug in it.
+func (db *Database) GetCurrentMineralSlugs(mineralID int) (map[string]string, error) {
+	rows, err := db.DB.Query(`SELECT lang, slug FROM mineral_slugs WHERE mineral_id = $1 AND is_current`, mineralID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slugs := map[string]string{}
+	for rows.Next() {
+		var lang, value string
+		if err := rows.Scan(&lang, &value); err != nil {
+			return nil, err
+		}
+		slugs[lang] = value
+	}
+	return slugs, rows.Err()
+}
+
+// FindMineralSlug looks a slug up in a language, or in any language when lang is empty.
+// A current slug wins over a replaced one, and the default language over the others.
+func (db *Database) FindMineralSlug(value, lang string) (*models.MineralSlug, error) {
+	query := `
+        SELECT ` + slugColumns + `
+        FROM mineral_slugs
+        WHERE slug = $1 AND ($2 = '' OR lang = $2)
+        ORDER BY is_current DESC, lang = $3 DESC, lang
+        LIMIT 1
+    `
+	var s models.MineralSlug
+	err := scanMineralSlug(db.DB.QueryRow(query, value, lang, models.DefaultLanguage), &s)
+	if err == sql.ErrNoRows {
+		return nil, ErrSlugNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetCurrentMineralSlug returns the slug a replaced slug redirects to.
+func (db *Database) GetCurrentMineralSlug(mineralID int, lang string) (string, error) {
+	var value string
+	err := db.DB.QueryRow(`SELECT slug FROM mineral_slugs WHERE mineral_id = $1 AND lang = $2 AND is_current`, mineralID, lang).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", ErrSlugNotFound
+	}
+	return value, err
+}
+
+func (db *Database) GetMineralIDByUUID(uuid string) (int, error) {
+	var id int
+	err := db.DB.QueryRow(`SELECT id FROM minerals WHERE uuid = $1 AND deleted_at IS NULL`, uuid).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, ErrMineralNotFound
+	}
+	return id, err
+}
+
+// SetMineralSlug makes value the mineral's current slug in a language. With exact set, value is used as is and a slug
+// that another mineral uses is an error; otherwise value is a base that gets a numeric suffix when it is taken.
+func (t *Tx) SetMineralSlug(mineralID int, lang, value string, exact bool) (string, error) {
+	return setMineralSlug(t.tx, mineralID, lang, value, exact)
+}
+
+// BackfillMineralSlugs gives a slug made from its title by makeSlug to every mineral created before slugs existed
+// and returns how many were updated.
+func (db *Database) BackfillMineralSlugs(makeSlug func(title string) string) (int, error) {
+	rows, err := db.DB.Query(`SELECT id, title FROM minerals WHERE slug IS NULL ORDER BY id`)
+	if err != nil {
+		return 0, err
+	}
+	titles := map[int]string{}
+	var ids []int
+	for rows.Next() {
+		var id int
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		titles[id] = title
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		tx, err := db.Begin()
+		if err != nil {
+			return i, err
+		}
+		if _, err := tx.SetMineralSlug(id, models.DefaultLanguage, makeSlug(titles[id]), false); err != nil {
+			tx.Rollback()
+			return i, err
+		}
+		if err := tx.Commit(); err != nil {
+			return i, err
+		}
+	}
+	return len(ids), nil
+}
+
+func setMineralSlug(q querier, mineralID int, lang, value string, exact bool) (string, error) {
+	current := ""
+	err := q.QueryRow(`SELECT slug FROM mineral_slugs WHERE mineral_id = $1 AND lang = $2 AND is_current`, mineralID, lang).Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	// A mineral keeps its current slug while the title still produces it, so slugs only change on renames.
+	chosen := ""
+	if current == value || !exact && isSlugVariant(current, value) {
+		chosen = current
+	}
+	for n := 1; chosen == ""; n++ {
+		candidate := value
+		if n > 1 {
+			candidate = models.SlugWithSuffix(value, n)
+		}
+		var ownerID int
+		var ownerCurrent bool
+		err := q.QueryRow(`SELECT mineral_id, is_current FROM mineral_slugs WHERE lang = $1 AND slug = $2`, lang, candidate).
+			Scan(&ownerID, &ownerCurrent)
+		switch {
+		case err == sql.ErrNoRows || err == nil && (ownerID == mineralID || !ownerCurrent):
+			chosen = candidate
+		case err != nil:
+			return "", err
+		case exact:
+			return "", ErrSlugExists
+		}
+	}
+
+	// A replaced slug of another mineral is given up: the new owner's page is the better target for that link.
+	_, err = q.Exec(`DELETE FROM mineral_slugs WHERE lang = $1 AND slug = $2 AND mineral_id <> $3 AND NOT is_current`, lang, chosen, mineralID)
+	if err != nil {
+		return "", err
+	}
+	_, err = q.Exec(`UPDATE mineral_slugs SET is_current = FALSE WHERE mineral_id = $1 AND lang = $2 AND slug <> $3 AND is_current`, mineralID, lang, chosen)
+	if err != nil {
+		return "", err
+	}
+	var id int
+	err = q.QueryRow(`
+        INSERT INTO mineral_slugs (mineral_id, lang, slug, is_current)
+        VALUES ($1, $2, $3, TRUE)
+        ON CONFLICT (lang, slug) DO UPDATE SET is_current = TRUE
+        WHERE mineral_slugs.mineral_id = EXCLUDED.mineral_id
+        RETURNING id
+    `, mineralID, lang, chosen).Scan(&id)
+	if err == sql.ErrNoRows {
+		// Another mineral took the slug concurrently.
+		return "", ErrSlugExists
+	}
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+			return "", ErrSlugExists
+		}
+		return "", err
+	}
+	if lang == models.DefaultLanguage {
+		if _, err := q.Exec(`UPDATE minerals SET slug = $2 WHERE id = $1`, mineralID, chosen); err != nil {
+			return "", err
+		}
+	}
+	return chosen, nil
+}
+
+// isSlugVariant reports whether s is base with a numeric suffix added by models.SlugWithSuffix.
+func isSlugVariant(s, base string) bool {
+	i := strings.LastIndexByte(s, '-')
+	if i < 0 {
+		return false
+	}
+	n, err := strconv.Atoi(s[i+1:])
+	return err == nil && n > 1 && models.SlugWithSuffix(base, n) == s
+}
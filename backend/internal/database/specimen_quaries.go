@@ -0,0 +1,219 @@
+// CRUD queries for the physical specimens of a mineral species.
+// Optional numeric and date columns are written as NULL when absent; a duplicate catalog number is reported as ErrCatalogNumberExists.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"github.com/lib/pq"
+	"time"
+)
+
+const specimenColumns = `id, mineral_id, catalog_number, COALESCE(locality, ''), locality_id, length_mm, width_mm, height_mm, mass_g,
+        COALESCE(to_char(acquired_on, 'YYYY-MM-DD'), ''), COALESCE(acquisition_source, ''),
+        COALESCE(storage_location, ''), COALESCE(notes, ''), created_at, updated_at`
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+func scanSpecimen(row rowScanner, s *models.Specimen) error {
+	var length, width, height, mass sql.NullFloat64
+	var localityID sql.NullInt64
+	err := row.Scan(
+		&s.ID,
+		&s.MineralID,
+		&s.CatalogNumber,
+		&s.Locality,
+		&localityID,
+		&length,
+		&width,
+		&height,
+		&mass,
+		&s.AcquiredOn,
+		&s.AcquisitionSource,
+		&s.StorageLocation,
+		&s.Notes,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	s.LocalityID = nullableInt(localityID)
+	s.LengthMM = nullableFloat(length)
+	s.WidthMM = nullableFloat(width)
+	s.HeightMM = nullableFloat(height)
+	s.MassG = nullableFloat(mass)
+	return nil
+}
+
+func nullableFloat(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
+
+func nullableInt(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	id := int(value.Int64)
+	return &id
+}
+
+func nullableTime(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+func (db *Database) GetMineralSpecimens(mineralID int) ([]models.Specimen, error) {
+	query := `
+        SELECT ` + specimenColumns + `
+        FROM specimens
+        WHERE mineral_id = $1
+        ORDER BY catalog_number
+    `
+	rows, err := db.DB.Query(query, mineralID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	specimens := []models.Specimen{}
+	for rows.Next() {
+		var s models.Specimen
+		if err := scanSpecimen(rows, &s); err != nil {
+			return nil, err
+		}
+		specimens = append(specimens, s)
+	}
+	return specimens, rows.Err()
+}
+
+func (db *Database) GetSpecimenByID(id int) (*models.Specimen, error) {
+	return getSpecimenByID(db.DB, id, false)
+}
+
+func (t *Tx) GetSpecimenForUpdate(id int) (*models.Specimen, error) {
+	return getSpecimenByID(t.tx, id, true)
+}
+
+func getSpecimenByID(q querier, id int, forUpdate bool) (*models.Specimen, error) {
+	query := `
+        SELECT ` + specimenColumns + `
+        FROM specimens
+        WHERE id = $1
+    `
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	var specimen models.Specimen
+	err := scanSpecimen(q.QueryRow(query, id), &specimen)
+	if err == sql.ErrNoRows {
+		return nil, ErrSpecimenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &specimen, nil
+}
+
+func (db *Database) CreateSpecimen(specimen models.Specimen) (*models.Specimen, error) {
+	query := `
+        INSERT INTO specimens (mineral_id, catalog_number, locality, length_mm, width_mm, height_mm, mass_g,
+                               acquired_on, acquisition_source, storage_location, notes, locality_id)
+        VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, NULLIF($8, '')::date, NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), $12)
+        RETURNING ` + specimenColumns + `
+    `
+	var created models.Specimen
+	err := scanSpecimen(db.DB.QueryRow(
+		query,
+		specimen.MineralID,
+		specimen.CatalogNumber,
+		specimen.Locality,
+		specimen.LengthMM,
+		specimen.WidthMM,
+		specimen.HeightMM,
+		specimen.MassG,
+		specimen.AcquiredOn,
+		specimen.AcquisitionSource,
+		specimen.StorageLocation,
+		specimen.Notes,
+		specimen.LocalityID,
+	), &created)
+	if err != nil {
+		return nil, specimenError(err)
+	}
+	return &created, nil
+}
+
+func (db *Database) UpdateSpecimen(specimen models.Specimen) (*models.Specimen, error) {
+	query := `
+        UPDATE specimens
+        SET catalog_number = $1, locality = NULLIF($2, ''), length_mm = $3, width_mm = $4, height_mm = $5,
+            mass_g = $6, acquired_on = NULLIF($7, '')::date, acquisition_source = NULLIF($8, ''),
+            storage_location = NULLIF($9, ''), notes = NULLIF($10, ''), locality_id = $11, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $12
+        RETURNING ` + specimenColumns + `
+    `
+	var updated models.Specimen
+	err := scanSpecimen(db.DB.QueryRow(
+		query,
+		specimen.CatalogNumber,
+		specimen.Locality,
+		specimen.LengthMM,
+		specimen.WidthMM,
+		specimen.HeightMM,
+		specimen.MassG,
+		specimen.AcquiredOn,
+		specimen.AcquisitionSource,
+		specimen.StorageLocation,
+		specimen.Notes,
+		specimen.LocalityID,
+		specimen.ID,
+	), &updated)
+	if err == sql.ErrNoRows {
+		return nil, ErrSpecimenNotFound
+	}
+	if err != nil {
+		return nil, specimenError(err)
+	}
+	return &updated, nil
+}
+
+func (t *Tx) DeleteSpecimen(id int) error {
+	result, err := t.tx.Exec(`DELETE FROM specimens WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSpecimenNotFound
+	}
+	return nil
+}
+
+func specimenError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok {
+		switch pqErr.Code {
+		case uniqueViolation:
+			return ErrCatalogNumberExists
+		case foreignKeyViolation:
+			if pqErr.Constraint == "specimens_locality_id_fkey" {
+				return ErrLocalityNotFound
+			}
+			return ErrMineralNotFound
+		}
+	}
+	return err
+}
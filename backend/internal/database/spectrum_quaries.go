@@ -0,0 +1,158 @@
+// Queries for reference spectra. Metadata lives in columns; the series is a JSONB array of [x, y] pairs,
+// read only when the data is requested or spectra are compared.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"encoding/json"
+)
+
+const spectrumColumns = `id, mineral_id, type, COALESCE(title, ''), COALESCE(instrument, ''), wavelength,
+        COALESCE(x_units, ''), COALESCE(y_units, ''), point_count, x_min, x_max, source_path, created_at`
+
+func scanSpectrum(row rowScanner, s *models.Spectrum, extra ...interface{}) error {
+	var wavelength sql.NullFloat64
+	dest := []interface{}{
+		&s.ID,
+		&s.MineralID,
+		&s.Type,
+		&s.Title,
+		&s.Instrument,
+		&wavelength,
+		&s.XUnits,
+		&s.YUnits,
+		&s.PointCount,
+		&s.XMin,
+		&s.XMax,
+		&s.SourcePath,
+		&s.CreatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	s.SetWavelength(nullableFloat(wavelength))
+	return nil
+}
+
+// GetMineralSpectra lists the spectra of a mineral without their data; an empty type lists all of them.
+func (db *Database) GetMineralSpectra(mineralID int, spectrumType string) ([]models.Spectrum, error) {
+	query := `
+        SELECT ` + spectrumColumns + `
+        FROM mineral_spectra
+        WHERE mineral_id = $1 AND ($2 = '' OR type = $2)
+        ORDER BY type, id
+    `
+	rows, err := db.DB.Query(query, mineralID, spectrumType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spectra := []models.Spectrum{}
+	for rows.Next() {
+		var s models.Spectrum
+		if err := scanSpectrum(rows, &s); err != nil {
+			return nil, err
+		}
+		spectra = append(spectra, s)
+	}
+	return spectra, rows.Err()
+}
+
+// GetSpectrumByID loads a spectrum; withData also loads its points.
+func (db *Database) GetSpectrumByID(id int, withData bool) (*models.Spectrum, error) {
+	query := `SELECT ` + spectrumColumns + `, CASE WHEN $2 THEN data END FROM mineral_spectra WHERE id = $1`
+	var s models.Spectrum
+	var data []byte
+	err := scanSpectrum(db.DB.QueryRow(query, id, withData), &s, &data)
+	if err == sql.ErrNoRows {
+		return nil, ErrSpectrumNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if withData {
+		if err := json.Unmarshal(data, &s.Points); err != nil {
+			return nil, err
+		}
+	}
+	return &s, nil
+}
+
+// GetReferenceSpectra loads every spectrum of a type attached to a published mineral, with its points, for comparison.
+func (db *Database) GetReferenceSpectra(spectrumType string) ([]models.Spectrum, error) {
+	query := `
+        SELECT ` + spectrumColumns + `, data
+        FROM mineral_spectra
+        WHERE type = $1 AND mineral_id IN (SELECT id FROM minerals m WHERE ` + publishedCondition("m") + `)
+        ORDER BY id
+    `
+	rows, err := db.DB.Query(query, spectrumType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	spectra := []models.Spectrum{}
+	for rows.Next() {
+		var s models.Spectrum
+		var data []byte
+		if err := scanSpectrum(rows, &s, &data); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &s.Points); err != nil {
+			return nil, err
+		}
+		spectra = append(spectra, s)
+	}
+	return spectra, rows.Err()
+}
+
+func (t *Tx) CreateSpectrum(spectrum models.Spectrum) (*models.Spectrum, error) {
+	points := spectrum.Points
+	if points == nil {
+		points = []models.SpectrumPoint{}
+	}
+	data, err := json.Marshal(points)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        INSERT INTO mineral_spectra (mineral_id, type, title, instrument, wavelength, x_units, y_units,
+            point_count, x_min, x_max, source_path, data)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10, $11, $12)
+        RETURNING ` + spectrumColumns + `
+    `
+	var created models.Spectrum
+	err = scanSpectrum(t.tx.QueryRow(
+		query,
+		spectrum.MineralID,
+		spectrum.Type,
+		spectrum.Title,
+		spectrum.Instrument,
+		spectrum.Wavelength,
+		spectrum.XUnits,
+		spectrum.YUnits,
+		spectrum.PointCount,
+		spectrum.XMin,
+		spectrum.XMax,
+		spectrum.SourcePath,
+		data,
+	), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// DeleteSpectrum removes a spectrum and returns the path of its source file.
+func (db *Database) DeleteSpectrum(id int) (string, error) {
+	var sourcePath string
+	err := db.DB.QueryRow(`DELETE FROM mineral_spectra WHERE id = $1 RETURNING source_path`, id).Scan(&sourcePath)
+	if err == sql.ErrNoRows {
+		return "", ErrSpectrumNotFound
+	}
+	return sourcePath, err
+}
@@ -0,0 +1,84 @@
+// Queries used by the storage consistency checker.
+// Collects every public /storage path referenced by a database record, so files on disk can be matched against them.
+
+package database
+
+// StorageReference is a single file path stored in a column of a database record.
+type StorageReference struct {
+	MineralID int    `json:"mineral_id"`
+	Field     string `json:"field"`
+	Path      string `json:"path"`
+}
+
+func (db *Database) GetStorageReferences() ([]StorageReference, error) {
+	query := `
+        SELECT id, 'model_path', model_path FROM minerals WHERE model_path <> ''
+        UNION ALL
+        SELECT id, 'preview_image_path', preview_image_path FROM minerals WHERE preview_image_path <> ''
+        UNION ALL
+        SELECT id, 'source_model_path', source_model_path FROM minerals WHERE COALESCE(source_model_path, '') <> ''
+        UNION ALL
+        SELECT mineral_id, 'asset_path', path FROM mineral_assets
+        UNION ALL
+        SELECT mineral_id, 'asset_source_path', source_path FROM mineral_assets WHERE COALESCE(source_path, '') <> ''
+        UNION ALL
+        SELECT mineral_id, 'cif_path', cif_path FROM crystal_structures
+        UNION ALL
+        SELECT mineral_id, 'spectrum_source_path', source_path FROM mineral_spectra
+        UNION ALL
+        SELECT r.mineral_id, 'revision_' || f.field, f.path
+        FROM mineral_revisions r
+        JOIN minerals m ON m.id = r.mineral_id
+        CROSS JOIN LATERAL (VALUES
+            ('model_path', r.snapshot->>'model_path'),
+            ('preview_image_path', r.snapshot->>'preview_image_path'),
+            ('source_model_path', r.snapshot->>'source_model_path')
+        ) AS f(field, path)
+        WHERE COALESCE(f.path, '') <> ''
+        ORDER BY 1, 2
+    `
+
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var references []StorageReference
+	for rows.Next() {
+		var ref StorageReference
+		if err := rows.Scan(&ref.MineralID, &ref.Field, &ref.Path); err != nil {
+			return nil, err
+		}
+		references = append(references, ref)
+	}
+	return references, rows.Err()
+}
+
+// IsStoragePathReferenced reports whether any record still points at the path.
+// Content-hashed file names let identical uploads share a file, so a file is only deleted once nothing references it.
+// Files named in the revisions of an existing mineral are kept so that those revisions can be restored.
+func (db *Database) IsStoragePathReferenced(path string) (bool, error) {
+	query := `
+        SELECT EXISTS (
+            SELECT 1 FROM minerals
+            WHERE model_path = $1 OR preview_image_path = $1 OR source_model_path = $1
+        ) OR EXISTS (
+            SELECT 1 FROM mineral_assets
+            WHERE path = $1 OR source_path = $1
+        ) OR EXISTS (
+            SELECT 1 FROM crystal_structures WHERE cif_path = $1
+        ) OR EXISTS (
+            SELECT 1 FROM mineral_spectra WHERE source_path = $1
+        ) OR EXISTS (
+            SELECT 1 FROM mineral_revisions r
+            JOIN minerals m ON m.id = r.mineral_id
+            WHERE r.snapshot->>'model_path' = $1
+               OR r.snapshot->>'preview_image_path' = $1
+               OR r.snapshot->>'source_model_path' = $1
+        )
+    `
+	var referenced bool
+	err := db.DB.QueryRow(query, path).Scan(&referenced)
+	return referenced, err
+}
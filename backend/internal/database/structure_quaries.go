@@ -0,0 +1,142 @@
+// Queries for crystal structures parsed from CIF files.
+// Unit-cell parameters are kept in columns so they can be filtered and sorted; atom sites are stored as a JSONB array.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"encoding/json"
+)
+
+const structureColumns = `id, mineral_id, cif_path, COALESCE(data_block, ''), COALESCE(space_group, ''),
+        space_group_number, crystal_system, a, b, c, alpha, beta, gamma, cell_volume, z, atom_sites, created_at`
+
+func scanStructure(row rowScanner, s *models.CrystalStructure) error {
+	var spaceGroupNumber, z sql.NullInt64
+	var atomSites []byte
+	err := row.Scan(
+		&s.ID,
+		&s.MineralID,
+		&s.CIFPath,
+		&s.DataBlock,
+		&s.SpaceGroup,
+		&spaceGroupNumber,
+		&s.CrystalSystem,
+		&s.Cell.A,
+		&s.Cell.B,
+		&s.Cell.C,
+		&s.Cell.Alpha,
+		&s.Cell.Beta,
+		&s.Cell.Gamma,
+		&s.Cell.Volume,
+		&z,
+		&atomSites,
+		&s.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	s.SpaceGroupNumber = nullableInt(spaceGroupNumber)
+	s.Z = nullableInt(z)
+	return json.Unmarshal(atomSites, &s.AtomSites)
+}
+
+func (db *Database) GetMineralStructures(mineralID int) ([]models.CrystalStructure, error) {
+	query := `
+        SELECT ` + structureColumns + `
+        FROM crystal_structures
+        WHERE mineral_id = $1
+        ORDER BY id
+    `
+	rows, err := db.DB.Query(query, mineralID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	structures := []models.CrystalStructure{}
+	for rows.Next() {
+		var s models.CrystalStructure
+		if err := scanStructure(rows, &s); err != nil {
+			return nil, err
+		}
+		structures = append(structures, s)
+	}
+	return structures, rows.Err()
+}
+
+func (db *Database) GetStructureByID(id int) (*models.CrystalStructure, error) {
+	query := `SELECT ` + structureColumns + ` FROM crystal_structures WHERE id = $1`
+	var s models.CrystalStructure
+	err := scanStructure(db.DB.QueryRow(query, id), &s)
+	if err == sql.ErrNoRows {
+		return nil, ErrStructureNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (t *Tx) CreateStructure(structure models.CrystalStructure) (*models.CrystalStructure, error) {
+	atomSites, err := json.Marshal(structure.AtomSites)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        INSERT INTO crystal_structures (mineral_id, cif_path, data_block, space_group, space_group_number, crystal_system,
+            a, b, c, alpha, beta, gamma, cell_volume, z, atom_sites)
+        VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+        RETURNING ` + structureColumns + `
+    `
+	var created models.CrystalStructure
+	err = scanStructure(t.tx.QueryRow(
+		query,
+		structure.MineralID,
+		structure.CIFPath,
+		structure.DataBlock,
+		structure.SpaceGroup,
+		structure.SpaceGroupNumber,
+		structure.CrystalSystem,
+		structure.Cell.A,
+		structure.Cell.B,
+		structure.Cell.C,
+		structure.Cell.Alpha,
+		structure.Cell.Beta,
+		structure.Cell.Gamma,
+		structure.Cell.Volume,
+		structure.Z,
+		atomSites,
+	), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// SetMineralCrystalSystem records the crystal system of a mineral, e.g. when the first CIF of a mineral without one is attached.
+func (t *Tx) SetMineralCrystalSystem(mineralID int, system string) error {
+	result, err := t.tx.Exec(`UPDATE minerals SET crystal_system = NULLIF($1, ''), updated_at = CURRENT_TIMESTAMP WHERE id = $2`, system, mineralID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrMineralNotFound
+	}
+	return nil
+}
+
+// DeleteStructure removes a structure and returns the path of its CIF file.
+func (db *Database) DeleteStructure(id int) (string, error) {
+	var cifPath string
+	err := db.DB.QueryRow(`DELETE FROM crystal_structures WHERE id = $1 RETURNING cif_path`, id).Scan(&cifPath)
+	if err == sql.ErrNoRows {
+		return "", ErrStructureNotFound
+	}
+	return cifPath, err
+}
@@ -0,0 +1,184 @@
+// Queries for tags and their assignment to minerals.
+// Counts only include minerals visible to visitors, so the tag cloud never reveals drafts or deleted minerals.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"encoding/json"
+	"github.com/lib/pq"
+)
+
+const tagColumns = `t.id, t.slug, t.names, t.created_at`
+
+func scanTag(row rowScanner, t *models.Tag, extra ...interface{}) error {
+	var names []byte
+	dest := append([]interface{}{&t.ID, &t.Slug, &names, &t.CreatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	t.Names = map[string]string{}
+	if len(names) > 0 {
+		return json.Unmarshal(names, &t.Names)
+	}
+	return nil
+}
+
+// GetTags lists every tag with the number of published minerals carrying it.
+func (db *Database) GetTags() ([]models.Tag, error) {
+	query := `
+        SELECT ` + tagColumns + `, COUNT(m.id)
+        FROM tags t
+        LEFT JOIN mineral_tags mt ON mt.tag_id = t.id
+        LEFT JOIN minerals m ON m.id = mt.mineral_id AND ` + publishedCondition("m") + `
+        GROUP BY t.id
+        ORDER BY t.slug
+    `
+	return queryTags(db.DB, query)
+}
+
+// GetTagCloud returns the limit most used tags that have at least one published mineral, most used first.
+func (db *Database) GetTagCloud(limit int) ([]models.Tag, error) {
+	query := `
+        SELECT ` + tagColumns + `, COUNT(*)
+        FROM tags t
+        JOIN mineral_tags mt ON mt.tag_id = t.id
+        JOIN minerals m ON m.id = mt.mineral_id
+        WHERE ` + publishedCondition("m") + `
+        GROUP BY t.id
+        ORDER BY COUNT(*) DESC, t.slug
+        LIMIT $1
+    `
+	return queryTags(db.DB, query, limit)
+}
+
+func (db *Database) GetMineralTags(mineralID int) ([]models.Tag, error) {
+	query := `
+        SELECT ` + tagColumns + `, 0
+        FROM tags t
+        JOIN mineral_tags mt ON mt.tag_id = t.id
+        WHERE mt.mineral_id = $1
+        ORDER BY t.slug
+    `
+	return queryTags(db.DB, query, mineralID)
+}
+
+func queryTags(q querier, query string, args ...interface{}) ([]models.Tag, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var t models.Tag
+		if err := scanTag(rows, &t, &t.MineralCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+	return tags, rows.Err()
+}
+
+func (db *Database) GetTagByID(id int) (*models.Tag, error) {
+	query := `SELECT ` + tagColumns + ` FROM tags t WHERE t.id = $1`
+	var tag models.Tag
+	err := scanTag(db.DB.QueryRow(query, id), &tag)
+	if err == sql.ErrNoRows {
+		return nil, ErrTagNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (db *Database) CreateTag(tag models.Tag) (*models.Tag, error) {
+	names, err := json.Marshal(tag.Names)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        INSERT INTO tags AS t (slug, names)
+        VALUES ($1, $2)
+        RETURNING ` + tagColumns + `
+    `
+	var created models.Tag
+	if err := scanTag(db.DB.QueryRow(query, tag.Slug, names), &created); err != nil {
+		return nil, tagError(err)
+	}
+	return &created, nil
+}
+
+func (db *Database) UpdateTag(tag models.Tag) (*models.Tag, error) {
+	names, err := json.Marshal(tag.Names)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+        UPDATE tags AS t
+        SET slug = $1, names = $2
+        WHERE t.id = $3
+        RETURNING ` + tagColumns + `
+    `
+	var updated models.Tag
+	err = scanTag(db.DB.QueryRow(query, tag.Slug, names, tag.ID), &updated)
+	if err == sql.ErrNoRows {
+		return nil, ErrTagNotFound
+	}
+	if err != nil {
+		return nil, tagError(err)
+	}
+	return &updated, nil
+}
+
+// DeleteTag deletes a tag together with its assignments.
+func (db *Database) DeleteTag(id int) error {
+	result, err := db.DB.Exec(`DELETE FROM tags WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+// SetMineralTags replaces the tags of a mineral.
+func (t *Tx) SetMineralTags(mineralID int, tagIDs []int) error {
+	tagIDs = uniqueIDs(tagIDs)
+	if _, err := t.tx.Exec(`DELETE FROM mineral_tags WHERE mineral_id = $1`, mineralID); err != nil {
+		return err
+	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	result, err := t.tx.Exec(`
+        INSERT INTO mineral_tags (mineral_id, tag_id)
+        SELECT $1, id FROM tags WHERE id = ANY($2)
+    `, mineralID, pq.Array(tagIDs))
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(inserted) != len(tagIDs) {
+		return ErrTagNotFound
+	}
+	return nil
+}
+
+func tagError(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		return ErrTagExists
+	}
+	return err
+}
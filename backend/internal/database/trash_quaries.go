@@ -0,0 +1,145 @@
+// Queries for the trash: minerals deleted by administrators keep their row with deleted_at set
+// until they are restored or purged. Purging removes the row, and with it the records that cascade from it.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"time"
+)
+
+// GetDeletedMinerals lists the minerals in the trash, most recently deleted first.
+func (db *Database) GetDeletedMinerals() ([]models.Mineral, error) {
+	query := `
+        SELECT ` + mineralColumns + `
+        FROM minerals
+        WHERE deleted_at IS NOT NULL
+        ORDER BY deleted_at DESC, id
+    `
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	minerals := []models.Mineral{}
+	for rows.Next() {
+		var m models.Mineral
+		if err := scanMineral(rows, &m); err != nil {
+			return nil, err
+		}
+		minerals = append(minerals, m)
+	}
+	return minerals, rows.Err()
+}
+
+// GetExpiredDeletedMineralIDs returns the minerals that were moved to the trash before the given time.
+func (db *Database) GetExpiredDeletedMineralIDs(before time.Time) ([]int, error) {
+	rows, err := db.DB.Query(`SELECT id FROM minerals WHERE deleted_at < $1 ORDER BY id`, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetDeletedMineralForUpdate loads a mineral from the trash and locks its row until the transaction ends.
+func (t *Tx) GetDeletedMineralForUpdate(id int) (*models.Mineral, error) {
+	query := `
+        SELECT ` + mineralColumns + `
+        FROM minerals
+        WHERE id = $1 AND deleted_at IS NOT NULL
+        FOR UPDATE
+    `
+	var mineral models.Mineral
+	err := scanMineral(t.tx.QueryRow(query, id), &mineral)
+	if err == sql.ErrNoRows {
+		return nil, ErrMineralNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mineral, nil
+}
+
+// RestoreMineral takes a mineral out of the trash.
+func (t *Tx) RestoreMineral(id int) (*models.Mineral, error) {
+	query := `
+        UPDATE minerals
+        SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+        WHERE id = $1 AND deleted_at IS NOT NULL
+        RETURNING ` + mineralColumns + `
+    `
+	var restored models.Mineral
+	err := scanMineral(t.tx.QueryRow(query, id), &restored)
+	if err == sql.ErrNoRows {
+		return nil, ErrMineralNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &restored, nil
+}
+
+// GetPurgedFilePaths returns the files of records that purging a mineral removes or leaves without a mineral:
+// CIF files of its crystal structures, source files of its spectra and the files its revisions refer to.
+// The revisions outlive the purge, but they no longer protect their files once the mineral row is gone.
+func (t *Tx) GetPurgedFilePaths(id int) ([]string, error) {
+	query := `
+        SELECT DISTINCT path FROM (
+            SELECT cif_path AS path FROM crystal_structures WHERE mineral_id = $1
+            UNION ALL
+            SELECT source_path FROM mineral_spectra WHERE mineral_id = $1
+            UNION ALL
+            SELECT v.path
+            FROM mineral_revisions r,
+                 LATERAL (VALUES (r.snapshot->>'model_path'), (r.snapshot->>'preview_image_path'),
+                                 (r.snapshot->>'source_model_path')) AS v(path)
+            WHERE r.mineral_id = $1
+        ) paths
+        WHERE path IS NOT NULL AND path <> ''
+        ORDER BY path
+    `
+	rows, err := t.tx.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// PurgeMineral permanently deletes a mineral from the trash. Its revisions are kept.
+func (t *Tx) PurgeMineral(id int) error {
+	result, err := t.tx.Exec(`DELETE FROM minerals WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrMineralNotFound
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+// Transaction support for operations that must change several records atomically.
+// Query helpers accept a querier, so the same SQL runs either directly on the pool or inside a transaction.
+
+package database
+
+import (
+	"database/sql"
+)
+
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type Tx struct {
+	tx *sql.Tx
+}
+
+func (db *Database) Begin() (*Tx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx}, nil
+}
+
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback aborts the transaction. It is safe to call after Commit, which makes it suitable for defer.
+func (t *Tx) Rollback() error {
+	if err := t.tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		return err
+	}
+	return nil
+}
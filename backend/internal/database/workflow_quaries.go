@@ -0,0 +1,97 @@
+// Queries for the publication workflow: status changes of minerals and the review log.
+// A scheduled mineral is stored as published with a future publish_at; publishedCondition hides it until then,
+// so no background job is needed to publish it.
+
+package database
+
+import (
+	"backend/internal/models"
+	"database/sql"
+	"time"
+)
+
+const reviewColumns = `id, mineral_id, author_id, COALESCE(author_name, ''), action, from_status, to_status, COALESCE(comment, ''), created_at`
+
+func scanReviewEntry(row rowScanner, e *models.ReviewEntry) error {
+	var authorID sql.NullInt64
+	err := row.Scan(&e.ID, &e.MineralID, &authorID, &e.AuthorName, &e.Action, &e.FromStatus, &e.ToStatus, &e.Comment, &e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	e.AuthorID = nullableInt(authorID)
+	return nil
+}
+
+// SetMineralStatus moves a mineral to a status. publishAt is kept only for published minerals;
+// published_at records when the mineral was approved or, when scheduled, when it goes public.
+func (t *Tx) SetMineralStatus(id int, status string, publishAt *time.Time) (*models.Mineral, error) {
+	if status != models.StatusPublished {
+		publishAt = nil
+	}
+	query := `
+        UPDATE minerals
+        SET status = $1::varchar,
+            publish_at = $2::timestamptz,
+            published_at = CASE WHEN $1::varchar = 'published' THEN COALESCE($2::timestamptz, NOW()) ELSE published_at END,
+            updated_at = CURRENT_TIMESTAMP
+        WHERE id = $3
+        RETURNING ` + mineralColumns + `
+    `
+	var updated models.Mineral
+	err := scanMineral(t.tx.QueryRow(query, status, publishAt, id), &updated)
+	if err == sql.ErrNoRows {
+		return nil, ErrMineralNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (t *Tx) CreateReviewEntry(entry models.ReviewEntry) (*models.ReviewEntry, error) {
+	query := `
+        INSERT INTO mineral_reviews (mineral_id, author_id, author_name, action, from_status, to_status, comment)
+        VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, NULLIF($7, ''))
+        RETURNING ` + reviewColumns + `
+    `
+	var created models.ReviewEntry
+	err := scanReviewEntry(t.tx.QueryRow(
+		query,
+		entry.MineralID,
+		entry.AuthorID,
+		entry.AuthorName,
+		entry.Action,
+		entry.FromStatus,
+		entry.ToStatus,
+		entry.Comment,
+	), &created)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// GetMineralReviews returns the review log of a mineral, oldest first.
+func (db *Database) GetMineralReviews(mineralID int) ([]models.ReviewEntry, error) {
+	query := `
+        SELECT ` + reviewColumns + `
+        FROM mineral_reviews
+        WHERE mineral_id = $1
+        ORDER BY created_at, id
+    `
+	rows, err := db.DB.Query(query, mineralID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.ReviewEntry{}
+	for rows.Next() {
+		var e models.ReviewEntry
+		if err := scanReviewEntry(rows, &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,72 @@
+// Queries for reference powder diffraction patterns: one row per peak with its d-spacing, relative intensity and hkl.
+// The search-match loads every reference pattern at once; patterns are short, so this stays small even for a large catalogue.
+
+package database
+
+import "backend/internal/models"
+
+func (db *Database) GetMineralXRDPeaks(mineralID int) ([]models.XRDPeak, error) {
+	rows, err := db.DB.Query(`
+        SELECT d_spacing, intensity, COALESCE(hkl, '')
+        FROM mineral_xrd_peaks
+        WHERE mineral_id = $1
+        ORDER BY position
+    `, mineralID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	peaks := []models.XRDPeak{}
+	for rows.Next() {
+		var p models.XRDPeak
+		if err := rows.Scan(&p.D, &p.Intensity, &p.HKL); err != nil {
+			return nil, err
+		}
+		peaks = append(peaks, p)
+	}
+	return peaks, rows.Err()
+}
+
+// SetMineralXRDPeaks replaces the reference pattern of a mineral; an empty list removes it.
+func (t *Tx) SetMineralXRDPeaks(mineralID int, peaks []models.XRDPeak) error {
+	if _, err := t.tx.Exec(`DELETE FROM mineral_xrd_peaks WHERE mineral_id = $1`, mineralID); err != nil {
+		return err
+	}
+	for position, p := range peaks {
+		_, err := t.tx.Exec(`
+            INSERT INTO mineral_xrd_peaks (mineral_id, d_spacing, intensity, hkl, position)
+            VALUES ($1, $2, $3, NULLIF($4, ''), $5)
+        `, mineralID, p.D, p.Intensity, p.HKL, position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetXRDReferences returns the reference patterns of all published minerals that have one, keyed by mineral id.
+func (db *Database) GetXRDReferences() (map[int][]models.XRDPeak, error) {
+	rows, err := db.DB.Query(`
+        SELECT p.mineral_id, p.d_spacing, p.intensity, COALESCE(p.hkl, '')
+        FROM mineral_xrd_peaks p
+        JOIN minerals m ON m.id = p.mineral_id
+        WHERE ` + publishedCondition("m") + `
+        ORDER BY p.mineral_id, p.position
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	references := map[int][]models.XRDPeak{}
+	for rows.Next() {
+		var mineralID int
+		var p models.XRDPeak
+		if err := rows.Scan(&mineralID, &p.D, &p.Intensity, &p.HKL); err != nil {
+			return nil, err
+		}
+		references[mineralID] = append(references[mineralID], p)
+	}
+	return references, rows.Err()
+}
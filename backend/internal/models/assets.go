@@ -0,0 +1,76 @@
+// Media assets attached to a mineral or one of its specimens: photo galleries, additional 3D models, videos and documents.
+// Each asset has a type, a display position, an optional primary flag (one per type and owner) and captions keyed by language code.
+
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	AssetImage    = "image"
+	AssetModel    = "model"
+	AssetVideo    = "video"
+	AssetDocument = "document"
+
+	MaxCaptionLength = 1000
+	DefaultLanguage  = "ru"
+)
+
+var (
+	ErrInvalidAssetType = errors.New("тип ресурса должен быть image, model, video или document")
+	ErrCaptionTooLong   = errors.New("подпись слишком длинная")
+	ErrInvalidLanguage  = errors.New("некорректный код языка подписи")
+)
+
+type MineralAsset struct {
+	ID         int               `json:"id"`
+	MineralID  int               `json:"mineral_id"`
+	SpecimenID int               `json:"specimen_id,omitempty"`
+	Type       string            `json:"type"`
+	Path       string            `json:"path"`
+	SourcePath string            `json:"source_path,omitempty"`
+	Captions   map[string]string `json:"captions"`
+	Caption    string            `json:"caption,omitempty"`
+	Position   int               `json:"position"`
+	IsPrimary  bool              `json:"is_primary"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+func IsValidAssetType(assetType string) bool {
+	switch assetType {
+	case AssetImage, AssetModel, AssetVideo, AssetDocument:
+		return true
+	}
+	return false
+}
+
+func (a *MineralAsset) Validate() error {
+	if !IsValidAssetType(a.Type) {
+		return ErrInvalidAssetType
+	}
+	return ValidateCaptions(a.Captions)
+}
+
+// ValidateCaptions checks that captions are keyed by short language codes such as "ru" or "en".
+func ValidateCaptions(captions map[string]string) error {
+	for lang, caption := range captions {
+		if len(lang) < 2 || len(lang) > 8 {
+			return ErrInvalidLanguage
+		}
+		if len(caption) > MaxCaptionLength {
+			return ErrCaptionTooLong
+		}
+	}
+	return nil
+}
+
+// LocalizeCaption fills Caption for lang, falling back to the default language.
+func (a *MineralAsset) LocalizeCaption(lang string) {
+	if caption, ok := a.Captions[lang]; ok {
+		a.Caption = caption
+		return
+	}
+	a.Caption = a.Captions[DefaultLanguage]
+}
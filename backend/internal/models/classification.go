@@ -0,0 +1,199 @@
+// The Nickel–Strunz classification tree: class → subclass → family → group, with optional Dana codes.
+// Node codes extend the code of their parent ("9" → "9.A" → "9.AB" → "9.AB.05"), names are keyed by language code,
+// and each node reports how many minerals are placed directly in it and in its whole subtree.
+
+package models
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	LevelClass    = "class"
+	LevelSubclass = "subclass"
+	LevelFamily   = "family"
+	LevelGroup    = "group"
+
+	MaxClassificationNameLength = 255
+)
+
+var (
+	ErrInvalidLevel              = errors.New("уровень должен быть class, subclass, family или group")
+	ErrInvalidLevelForParent     = errors.New("уровень узла должен быть на одну ступень ниже родительского")
+	ErrInvalidStrunzCode         = errors.New("код Штрунца не соответствует уровню или не продолжает код родителя")
+	ErrInvalidDanaCode           = errors.New("код Дэна должен иметь вид 2.8.1.1")
+	ErrEmptyClassificationName   = errors.New("название узла на языке по умолчанию не может быть пустым")
+	ErrClassificationNameTooLong = errors.New("название узла слишком длинное")
+)
+
+// levelOrder lists the levels from the root down.
+var levelOrder = []string{LevelClass, LevelSubclass, LevelFamily, LevelGroup}
+
+// strunzCodePatterns describe the code of each level: 9, 9.A, 9.AB, 9.AB.05 (groups may carry a letter suffix, 9.AB.05a).
+var strunzCodePatterns = map[string]*regexp.Regexp{
+	LevelClass:    regexp.MustCompile(`^\d{1,2}$`),
+	LevelSubclass: regexp.MustCompile(`^\d{1,2}\.[A-Z]$`),
+	LevelFamily:   regexp.MustCompile(`^\d{1,2}\.[A-Z]{2}$`),
+	LevelGroup:    regexp.MustCompile(`^\d{1,2}\.[A-Z]{2}\.\d{2,3}[a-z]?$`),
+}
+
+var danaCodePattern = regexp.MustCompile(`^\d{1,3}(\.\d{1,3}[a-z]?){0,3}$`)
+
+type ClassificationNode struct {
+	ID         int               `json:"id"`
+	ParentID   *int              `json:"parent_id,omitempty"`
+	Level      string            `json:"level"`
+	StrunzCode string            `json:"strunz_code"`
+	DanaCode   string            `json:"dana_code,omitempty"`
+	Names      map[string]string `json:"names"`
+	Name       string            `json:"name"`
+	// MineralCount counts minerals placed in this node itself, TotalCount those in the whole subtree.
+	MineralCount int                  `json:"mineral_count"`
+	TotalCount   int                  `json:"total_count"`
+	CreatedAt    time.Time            `json:"created_at"`
+	Children     []ClassificationNode `json:"children,omitempty"`
+}
+
+// Validate checks the node against its parent, which must be nil for classes.
+func (n *ClassificationNode) Validate(parent *ClassificationNode) error {
+	pattern, ok := strunzCodePatterns[n.Level]
+	if !ok {
+		return ErrInvalidLevel
+	}
+	if (parent == nil) != (n.Level == LevelClass) {
+		return ErrInvalidLevelForParent
+	}
+	n.StrunzCode = strings.TrimSpace(n.StrunzCode)
+	if !pattern.MatchString(n.StrunzCode) {
+		return ErrInvalidStrunzCode
+	}
+	if parent != nil {
+		if ChildLevel(parent.Level) != n.Level {
+			return ErrInvalidLevelForParent
+		}
+		if !strings.HasPrefix(n.StrunzCode, parent.StrunzCode) {
+			return ErrInvalidStrunzCode
+		}
+	}
+	return n.ValidateDetails()
+}
+
+// ValidateDetails checks the fields that may change after creation: names and the Dana code.
+func (n *ClassificationNode) ValidateDetails() error {
+	if err := ValidateDanaCode(n.DanaCode); err != nil {
+		return err
+	}
+	if strings.TrimSpace(n.Names[DefaultLanguage]) == "" {
+		return ErrEmptyClassificationName
+	}
+	for lang, name := range n.Names {
+		if len(lang) < 2 || len(lang) > 8 {
+			return ErrInvalidLanguage
+		}
+		if len(name) > MaxClassificationNameLength {
+			return ErrClassificationNameTooLong
+		}
+	}
+	return nil
+}
+
+func ValidateDanaCode(code string) error {
+	if code != "" && !danaCodePattern.MatchString(code) {
+		return ErrInvalidDanaCode
+	}
+	return nil
+}
+
+// ChildLevel returns the level directly below level, or "" for groups.
+func ChildLevel(level string) string {
+	for i, l := range levelOrder {
+		if l == level && i+1 < len(levelOrder) {
+			return levelOrder[i+1]
+		}
+	}
+	return ""
+}
+
+// Localize fills Name for lang, falling back to the default language.
+func (n *ClassificationNode) Localize(lang string) {
+	if name, ok := n.Names[lang]; ok && name != "" {
+		n.Name = name
+	} else {
+		n.Name = n.Names[DefaultLanguage]
+	}
+	for i := range n.Children {
+		n.Children[i].Localize(lang)
+	}
+}
+
+// BuildClassificationTree nests flat nodes under their parents, orders siblings by code and sums subtree counts.
+func BuildClassificationTree(nodes []ClassificationNode) []ClassificationNode {
+	children := make(map[int][]ClassificationNode)
+	var roots []ClassificationNode
+	for _, node := range nodes {
+		if node.ParentID == nil {
+			roots = append(roots, node)
+		} else {
+			children[*node.ParentID] = append(children[*node.ParentID], node)
+		}
+	}
+
+	var attach func(list []ClassificationNode) []ClassificationNode
+	attach = func(list []ClassificationNode) []ClassificationNode {
+		SortByStrunzCode(list)
+		for i := range list {
+			list[i].Children = attach(children[list[i].ID])
+			list[i].TotalCount = list[i].MineralCount
+			for _, child := range list[i].Children {
+				list[i].TotalCount += child.TotalCount
+			}
+		}
+		return list
+	}
+
+	tree := attach(roots)
+	if tree == nil {
+		tree = []ClassificationNode{}
+	}
+	return tree
+}
+
+// FindClassificationNode searches a tree built by BuildClassificationTree.
+func FindClassificationNode(tree []ClassificationNode, id int) *ClassificationNode {
+	for i := range tree {
+		if tree[i].ID == id {
+			return &tree[i]
+		}
+		if found := FindClassificationNode(tree[i].Children, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// SortByStrunzCode orders nodes naturally, so class 10 follows class 9 and group 9.AB.10 follows 9.AB.05.
+func SortByStrunzCode(nodes []ClassificationNode) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return compareStrunzCodes(nodes[i].StrunzCode, nodes[j].StrunzCode) < 0
+	})
+}
+
+func compareStrunzCodes(a, b string) int {
+	partsA, partsB := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numA, errA := strconv.Atoi(strings.TrimRight(partsA[i], "abcdefghijklmnopqrstuvwxyz"))
+		numB, errB := strconv.Atoi(strings.TrimRight(partsB[i], "abcdefghijklmnopqrstuvwxyz"))
+		if errA == nil && errB == nil && numA != numB {
+			return numA - numB
+		}
+		if partsA[i] != partsB[i] {
+			return strings.Compare(partsA[i], partsB[i])
+		}
+	}
+	return len(partsA) - len(partsB)
+}
@@ -0,0 +1,10 @@
+// Elemental composition of a mineral, computed from its chemical formula when the formula is set.
+// Each element keeps its count in the formula unit and its share of the molar mass in weight percent.
+
+package models
+
+type ElementAmount struct {
+	Symbol        string  `json:"symbol"`
+	Count         float64 `json:"count"`
+	WeightPercent float64 `json:"weight_percent"`
+}
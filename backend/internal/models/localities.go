@@ -0,0 +1,99 @@
+// A geographic locality where minerals occur or specimens were collected.
+// Stores the name, country and region, a reference point in WGS 84 degrees and an optional outline as GeoJSON polygon coordinates.
+// Minerals are linked to localities many-to-many; a specimen may point at the locality it was collected from.
+
+package models
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	MaxLocalityNameLength = 255
+	MaxCountryLength      = 100
+)
+
+var (
+	ErrEmptyLocalityName   = errors.New("название местонахождения не может быть пустым")
+	ErrLocalityTextTooLong = errors.New("значение поля местонахождения слишком длинное")
+	ErrInvalidPolygon      = errors.New("полигон должен состоять из замкнутых колец минимум из 4 точек [lon, lat]")
+	ErrOutOfRange          = errors.New("широта должна быть в пределах [-90, 90], долгота в пределах [-180, 180]")
+)
+
+type Locality struct {
+	ID        int           `json:"id"`
+	Name      string        `json:"name"`
+	Country   string        `json:"country,omitempty"`
+	Region    string        `json:"region,omitempty"`
+	Latitude  float64       `json:"latitude"`
+	Longitude float64       `json:"longitude"`
+	Polygon   [][][]float64 `json:"polygon,omitempty"`
+	// DistanceKM is set by radius queries.
+	DistanceKM *float64  `json:"distance_km,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// BBox is an axis-aligned box in degrees. When MinLon > MaxLon the box crosses the antimeridian.
+type BBox struct {
+	MinLon float64 `json:"min_lon"`
+	MinLat float64 `json:"min_lat"`
+	MaxLon float64 `json:"max_lon"`
+	MaxLat float64 `json:"max_lat"`
+}
+
+func (b BBox) CrossesAntimeridian() bool {
+	return b.MinLon > b.MaxLon
+}
+
+func (l *Locality) Validate() error {
+	l.Name = strings.TrimSpace(l.Name)
+	if l.Name == "" {
+		return ErrEmptyLocalityName
+	}
+	if len(l.Name) > MaxLocalityNameLength || len(l.Region) > MaxLocalityNameLength || len(l.Country) > MaxCountryLength {
+		return ErrLocalityTextTooLong
+	}
+	if err := ValidatePoint(l.Latitude, l.Longitude); err != nil {
+		return err
+	}
+	if l.Polygon != nil {
+		return ValidatePolygon(l.Polygon)
+	}
+	return nil
+}
+
+func ValidatePoint(lat, lon float64) error {
+	if math.IsNaN(lat) || math.IsNaN(lon) || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return ErrOutOfRange
+	}
+	return nil
+}
+
+// ValidatePolygon checks GeoJSON polygon coordinates: an outer ring followed by optional holes.
+func ValidatePolygon(rings [][][]float64) error {
+	if len(rings) == 0 {
+		return ErrInvalidPolygon
+	}
+	for _, ring := range rings {
+		if len(ring) < 4 {
+			return ErrInvalidPolygon
+		}
+		for _, position := range ring {
+			if len(position) != 2 {
+				return ErrInvalidPolygon
+			}
+			if err := ValidatePoint(position[1], position[0]); err != nil {
+				return err
+			}
+		}
+		first, last := ring[0], ring[len(ring)-1]
+		if first[0] != last[0] || first[1] != last[1] {
+			return ErrInvalidPolygon
+		}
+	}
+	return nil
+}
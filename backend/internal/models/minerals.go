@@ -1,5 +1,5 @@
 // A data structure for working with minerals, implemented with Go's type safety principles in mind.
-// Defines the Mineral model with fields: unique identifier, title, description, paths to preview and 3D model, and creation timestamp.
+// Defines the Mineral model: the catalogue record itself and the related data loaded together with it.
 // Uses struct tags for flexible serialization/deserialization between JSON and database formats.
 // Supports extensibility through optional fields and strict typing.
 
@@ -25,12 +25,48 @@ var (
 )
 
 type Mineral struct {
-	ID               int       `json:"id"`
-	Title            string    `json:"title"`
-	Description      string    `json:"description"`
-	ModelPath        string    `json:"model_path"`
-	PreviewImagePath string    `json:"preview_image_path"`
-	CreatedAt        time.Time `json:"created_at"`
+	ID int `json:"id"`
+	// UUID stays the same across exports and imports; ExternalID is the record in the catalogue it was imported from.
+	UUID       string `json:"uuid"`
+	ExternalID string `json:"external_id,omitempty"`
+	Title      string `json:"title"`
+	// Slug is the current URL slug, in the default language unless the mineral is translated; Slugs lists it per language.
+	Slug        string            `json:"slug,omitempty"`
+	Slugs       map[string]string `json:"slugs,omitempty"`
+	Tags        []Tag             `json:"tags,omitempty"`
+	Description string            `json:"description"`
+	ModelPath   string            `json:"model_path"`
+	// PreviewImagePath is the preview image; SourceModelPath is the archived source file the model was converted from.
+	PreviewImagePath string `json:"preview_image_path"`
+	SourceModelPath  string `json:"source_model_path,omitempty"`
+	// ClassificationID is the node of the Nickel–Strunz classification; DanaCode is the number in the Dana system.
+	ClassificationID *int   `json:"classification_id,omitempty"`
+	DanaCode         string `json:"dana_code,omitempty"`
+	// Formula is the chemical formula; MolarMass is computed from it in g/mol.
+	Formula       string   `json:"formula,omitempty"`
+	MolarMass     *float64 `json:"molar_mass,omitempty"`
+	CrystalSystem string   `json:"crystal_system,omitempty"`
+	// Status is the publication status (see workflow.go). PublishAt is set when a reviewer scheduled the publication;
+	// PublishedAt is when the mineral went or goes public.
+	Status      string     `json:"status"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	// DeletedAt is set while the mineral is in the trash.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	// Related records, filled in by the detail queries: media assets, physical specimens, localities where it occurs,
+	// the classification path, elemental composition, CIF structures, reference XRD peaks, spectra, synonyms and varieties.
+	Assets         []MineralAsset       `json:"assets,omitempty"`
+	Specimens      []Specimen           `json:"specimens,omitempty"`
+	Localities     []Locality           `json:"localities,omitempty"`
+	Classification []ClassificationNode `json:"classification,omitempty"`
+	Composition    []ElementAmount      `json:"composition,omitempty"`
+	Structures     []CrystalStructure   `json:"structures,omitempty"`
+	XRDPeaks       []XRDPeak            `json:"xrd_peaks,omitempty"`
+	Spectra        []Spectrum           `json:"spectra,omitempty"`
+	Names          []MineralName        `json:"names,omitempty"`
+	// MatchedName is the synonym or variety through which a search found the mineral.
+	MatchedName string `json:"matched_name,omitempty"`
 }
 
 func (m *Mineral) Validate() error {
@@ -46,5 +82,5 @@ func (m *Mineral) Validate() error {
 	if !strings.HasSuffix(m.ModelPath, AllowedModelExtension) {
 		return ErrInvalidModelPath
 	}
-	return nil
+	return ValidateCrystalSystem(m.CrystalSystem)
 }
@@ -0,0 +1,119 @@
+// Alternative names of a mineral and typed relations between mineral species.
+// A name is a synonym (another name of the species) or a variety (a named form such as amethyst for quartz), in a given language.
+// Relations form a graph: polymorphs, members of one series and common associates are linked both ways,
+// while "pseudomorph_after" points from the pseudomorph to the mineral whose shape it took.
+
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	NameSynonym = "synonym"
+	NameVariety = "variety"
+
+	RelationPolymorph   = "polymorph_of"
+	RelationPseudomorph = "pseudomorph_after"
+	RelationSeries      = "series_member"
+	RelationAssociated  = "associated_with"
+
+	MaxMineralNameLength  = 255
+	MaxRelationNoteLength = 1000
+)
+
+var (
+	ErrEmptyMineralName    = errors.New("название не может быть пустым")
+	ErrMineralNameTooLong  = errors.New("название слишком длинное")
+	ErrInvalidNameKind     = errors.New("вид названия должен быть synonym или variety")
+	ErrInvalidRelationType = errors.New("тип связи должен быть polymorph_of, pseudomorph_after, series_member или associated_with")
+	ErrSelfRelation        = errors.New("минерал не может быть связан сам с собой")
+	ErrRelationNoteTooLong = errors.New("примечание к связи слишком длинное")
+)
+
+type MineralName struct {
+	ID          int       `json:"id"`
+	MineralID   int       `json:"mineral_id"`
+	Name        string    `json:"name"`
+	Lang        string    `json:"lang"`
+	Kind        string    `json:"kind"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (n *MineralName) Validate() error {
+	n.Name = strings.TrimSpace(n.Name)
+	n.Lang = strings.ToLower(strings.TrimSpace(n.Lang))
+	if n.Lang == "" {
+		n.Lang = DefaultLanguage
+	}
+	if n.Kind == "" {
+		n.Kind = NameSynonym
+	}
+	switch {
+	case n.Name == "":
+		return ErrEmptyMineralName
+	case len(n.Name) > MaxMineralNameLength:
+		return ErrMineralNameTooLong
+	case n.Kind != NameSynonym && n.Kind != NameVariety:
+		return ErrInvalidNameKind
+	case len(n.Lang) < 2 || len(n.Lang) > 8:
+		return ErrInvalidLanguage
+	case len(n.Description) > MaxCaptionLength:
+		return ErrCaptionTooLong
+	}
+	return nil
+}
+
+// MineralRelation is an edge of the relation graph. For symmetric types the ids are stored in ascending order.
+type MineralRelation struct {
+	ID        int       `json:"id"`
+	MineralID int       `json:"source"`
+	RelatedID int       `json:"target"`
+	Type      string    `json:"type"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsSymmetricRelation reports whether a relation reads the same in both directions.
+func IsSymmetricRelation(relationType string) bool {
+	return relationType != RelationPseudomorph
+}
+
+func (r *MineralRelation) Validate() error {
+	switch r.Type {
+	case RelationPolymorph, RelationPseudomorph, RelationSeries, RelationAssociated:
+	default:
+		return ErrInvalidRelationType
+	}
+	if r.MineralID == r.RelatedID {
+		return ErrSelfRelation
+	}
+	r.Note = strings.TrimSpace(r.Note)
+	if len(r.Note) > MaxRelationNoteLength {
+		return ErrRelationNoteTooLong
+	}
+	if IsSymmetricRelation(r.Type) && r.MineralID > r.RelatedID {
+		r.MineralID, r.RelatedID = r.RelatedID, r.MineralID
+	}
+	return nil
+}
+
+// RelatedMineral is a node of the relation graph; Depth is the number of edges from the requested mineral.
+type RelatedMineral struct {
+	ID               int    `json:"id"`
+	Title            string `json:"title"`
+	Formula          string `json:"formula,omitempty"`
+	PreviewImagePath string `json:"preview_image_path"`
+	Depth            int    `json:"depth"`
+}
+
+type RelationGraph struct {
+	MineralID int               `json:"mineral_id"`
+	Nodes     []RelatedMineral  `json:"nodes"`
+	Edges     []MineralRelation `json:"edges"`
+	// Truncated is set when the graph was cut at the node limit.
+	Truncated bool `json:"truncated,omitempty"`
+}
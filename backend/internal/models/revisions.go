@@ -0,0 +1,112 @@
+// Revision history of minerals. Every create, update, restore and delete stores a numbered revision with its author
+// and a snapshot of the mineral record after the change; the snapshot of a delete is the record as it was deleted.
+// Revisions outlive the mineral, so the history of a deleted mineral can still be read.
+
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+const (
+	RevisionBaseline = "baseline"
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionRestore  = "restore"
+	RevisionDelete   = "delete"
+)
+
+// textSnapshotFields are the fields whose changes also get a word-level diff.
+var textSnapshotFields = map[string]bool{"title": true, "description": true}
+
+// MineralSnapshot holds the fields of a mineral record that revisions track and restore.
+type MineralSnapshot struct {
+	Title            string   `json:"title"`
+	Description      string   `json:"description"`
+	ModelPath        string   `json:"model_path"`
+	PreviewImagePath string   `json:"preview_image_path"`
+	SourceModelPath  string   `json:"source_model_path"`
+	ClassificationID *int     `json:"classification_id"`
+	DanaCode         string   `json:"dana_code"`
+	Formula          string   `json:"formula"`
+	MolarMass        *float64 `json:"molar_mass"`
+	CrystalSystem    string   `json:"crystal_system"`
+}
+
+type MineralRevision struct {
+	ID        int    `json:"id"`
+	MineralID int    `json:"mineral_id"`
+	Revision  int    `json:"revision"`
+	Action    string `json:"action"`
+	// AuthorID is empty for the baseline revision recorded for minerals created before history was kept.
+	AuthorID     *int            `json:"author_id,omitempty"`
+	AuthorName   string          `json:"author_name,omitempty"`
+	RestoredFrom *int            `json:"restored_from,omitempty"`
+	Snapshot     MineralSnapshot `json:"snapshot"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+// FieldChange is a field whose value differs between two revisions.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+	// Text is a word-level diff, set for text fields.
+	Text []TextOp `json:"text,omitempty"`
+}
+
+// TextOp is a run of words that is equal in both texts, or inserted or deleted.
+type TextOp struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+func SnapshotOf(m *Mineral) MineralSnapshot {
+	return MineralSnapshot{
+		Title:            m.Title,
+		Description:      m.Description,
+		ModelPath:        m.ModelPath,
+		PreviewImagePath: m.PreviewImagePath,
+		SourceModelPath:  m.SourceModelPath,
+		ClassificationID: m.ClassificationID,
+		DanaCode:         m.DanaCode,
+		Formula:          m.Formula,
+		MolarMass:        m.MolarMass,
+		CrystalSystem:    m.CrystalSystem,
+	}
+}
+
+// ApplyTo copies the snapshot into a mineral record.
+func (s MineralSnapshot) ApplyTo(m *Mineral) {
+	m.Title = s.Title
+	m.Description = s.Description
+	m.ModelPath = s.ModelPath
+	m.PreviewImagePath = s.PreviewImagePath
+	m.SourceModelPath = s.SourceModelPath
+	m.ClassificationID = s.ClassificationID
+	m.DanaCode = s.DanaCode
+	m.Formula = s.Formula
+	m.MolarMass = s.MolarMass
+	m.CrystalSystem = s.CrystalSystem
+}
+
+// DiffSnapshots lists the fields that differ between from and to, in declaration order. Text is left for the caller.
+func DiffSnapshots(from, to MineralSnapshot) []FieldChange {
+	changes := []FieldChange{}
+	a, b := reflect.ValueOf(from), reflect.ValueOf(to)
+	for i := 0; i < a.NumField(); i++ {
+		x, y := a.Field(i).Interface(), b.Field(i).Interface()
+		if reflect.DeepEqual(x, y) {
+			continue
+		}
+		field := a.Type().Field(i)
+		changes = append(changes, FieldChange{Field: field.Tag.Get("json"), From: x, To: y})
+	}
+	return changes
+}
+
+// IsText reports whether the change is to a text field that also gets a word-level diff.
+func (c FieldChange) IsText() bool {
+	return textSnapshotFields[c.Field]
+}
@@ -0,0 +1,59 @@
+// URL slugs of minerals. Every language has at most one current slug per mineral; a slug replaced after a rename
+// stays as a non-current entry so that old links redirect to the new one.
+
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const MaxSlugLength = 80
+
+// SlugFallback is the slug of a title without a single letter or digit to build a slug from.
+const SlugFallback = "mineral"
+
+var validSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var (
+	ErrInvalidSlug  = errors.New("адрес может содержать только строчные латинские буквы, цифры и дефисы")
+	ErrSlugRequired = errors.New("укажите slug или title")
+)
+
+type MineralSlug struct {
+	ID        int       `json:"id"`
+	MineralID int       `json:"mineral_id"`
+	Lang      string    `json:"lang"`
+	Slug      string    `json:"slug"`
+	Current   bool      `json:"current"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ValidSlug reports whether s can be used as a slug as is: lower-case ASCII words joined by hyphens.
+func ValidSlug(s string) bool {
+	return len(s) <= MaxSlugLength && validSlug.MatchString(s)
+}
+
+// SlugWithSuffix returns the n-th alternative of a slug taken by another mineral: "kvarts-2", "kvarts-3", ...
+func SlugWithSuffix(base string, n int) string {
+	suffix := "-" + strconv.Itoa(n)
+	return TruncateSlug(base, MaxSlugLength-len(suffix)) + suffix
+}
+
+// TruncateSlug shortens a slug to max characters, at a word boundary where possible.
+func TruncateSlug(s string, max int) string {
+	if len(s) > max {
+		s = s[:max]
+		if i := strings.LastIndexByte(s, '-'); i > max/2 {
+			s = s[:i]
+		}
+	}
+	s = strings.Trim(s, "-")
+	if s == "" {
+		return SlugFallback
+	}
+	return s
+}
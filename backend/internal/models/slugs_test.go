@@ -0,0 +1,32 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugWithSuffix(t *testing.T) {
+	long := strings.TrimSuffix(strings.Repeat("kvarts-", 11), "-")
+	tests := []struct {
+		base string
+		n    int
+		want string
+	}{
+		{"kvarts", 2, "kvarts-2"},
+		{"kvarts-2", 3, "kvarts-2-3"},
+		{long, 12, long + "-12"},
+		{long, 1000, strings.Repeat("kvarts-", 10) + "1000"},
+		{strings.Repeat("a", MaxSlugLength), 2, strings.Repeat("a", MaxSlugLength-2) + "-2"},
+		{"---", 2, SlugFallback + "-2"},
+	}
+
+	for _, tt := range tests {
+		got := SlugWithSuffix(tt.base, tt.n)
+		if got != tt.want {
+			t.Errorf("SlugWithSuffix(%q, %d) = %q, want %q", tt.base, tt.n, got, tt.want)
+		}
+		if !ValidSlug(got) {
+			t.Errorf("SlugWithSuffix(%q, %d) = %q is not a valid slug", tt.base, tt.n, got)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// A physical specimen of a mineral species held in the collection.
+// Stores catalog data: catalog number, locality (free text and an optional link to a geographic locality), dimensions in millimetres, mass in grams, acquisition date and source,
+// and the storage location. Scans and photos of the specimen are kept as assets owned by it.
+
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	MaxCatalogNumberLength = 64
+	MaxSpecimenTextLength  = 255
+	DateLayout             = "2006-01-02"
+)
+
+var (
+	ErrEmptyCatalogNumber   = errors.New("каталожный номер не может быть пустым")
+	ErrCatalogNumberTooLong = errors.New("каталожный номер слишком длинный")
+	ErrSpecimenTextTooLong  = errors.New("значение поля образца слишком длинное")
+	ErrInvalidDimension     = errors.New("размеры и масса образца должны быть положительными")
+	ErrInvalidAcquiredOn    = errors.New("дата поступления должна быть в формате ГГГГ-ММ-ДД")
+)
+
+type Specimen struct {
+	ID                int            `json:"id"`
+	MineralID         int            `json:"mineral_id"`
+	CatalogNumber     string         `json:"catalog_number"`
+	Locality          string         `json:"locality,omitempty"`
+	LocalityID        *int           `json:"locality_id,omitempty"`
+	LengthMM          *float64       `json:"length_mm,omitempty"`
+	WidthMM           *float64       `json:"width_mm,omitempty"`
+	HeightMM          *float64       `json:"height_mm,omitempty"`
+	MassG             *float64       `json:"mass_g,omitempty"`
+	AcquiredOn        string         `json:"acquired_on,omitempty"`
+	// AcquisitionSource and StorageLocation are inventory data for curators; Public leaves them out.
+	AcquisitionSource string         `json:"acquisition_source,omitempty"`
+	StorageLocation   string         `json:"storage_location,omitempty"`
+	Notes             string         `json:"notes,omitempty"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	Assets            []MineralAsset `json:"assets,omitempty"`
+}
+
+// Public returns the specimen as visitors see it, without the inventory fields.
+func (s Specimen) Public() Specimen {
+	s.AcquisitionSource = ""
+	s.StorageLocation = ""
+	return s
+}
+
+func (s *Specimen) Validate() error {
+	s.CatalogNumber = strings.TrimSpace(s.CatalogNumber)
+	if s.CatalogNumber == "" {
+		return ErrEmptyCatalogNumber
+	}
+	if len(s.CatalogNumber) > MaxCatalogNumberLength {
+		return ErrCatalogNumberTooLong
+	}
+	for _, text := range []string{s.Locality, s.AcquisitionSource, s.StorageLocation} {
+		if len(text) > MaxSpecimenTextLength {
+			return ErrSpecimenTextTooLong
+		}
+	}
+	for _, value := range []*float64{s.LengthMM, s.WidthMM, s.HeightMM, s.MassG} {
+		if value != nil && *value <= 0 {
+			return ErrInvalidDimension
+		}
+	}
+	if s.AcquiredOn != "" {
+		if _, err := time.Parse(DateLayout, s.AcquiredOn); err != nil {
+			return ErrInvalidAcquiredOn
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// A reference spectrum of a mineral: a Raman or FTIR spectrum or an XRD pattern kept as a numeric (x, y) series.
+// Stores the instrument, the excitation wavelength (laser in nm for Raman, X-ray in Å for XRD), axis units,
+// the range and number of points and the path of the uploaded file. Points are loaded only by the data endpoints.
+
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	SpectrumRaman = "raman"
+	SpectrumFTIR  = "ftir"
+	SpectrumXRD   = "xrd"
+
+	MaxSpectrumTextLength = 255
+	MaxSpectrumUnitLength = 50
+)
+
+var (
+	ErrInvalidSpectrumType   = errors.New("тип спектра должен быть raman, ftir или xrd")
+	ErrSpectrumTextTooLong   = errors.New("значение поля спектра слишком длинное")
+	ErrInvalidWavelength     = errors.New("длина волны лазера должна быть от 100 до 2000 нм, рентгеновского излучения от 0.1 до 5 Å")
+	ErrWavelengthNotExpected = errors.New("длина волны указывается только для спектров КР и дифрактограмм")
+)
+
+// SpectrumPoint is a pair [x, y], serialized as a two-element array to keep plotting payloads small.
+type SpectrumPoint [2]float64
+
+type Spectrum struct {
+	ID             int             `json:"id"`
+	MineralID      int             `json:"mineral_id"`
+	Type           string          `json:"type"`
+	Title          string          `json:"title,omitempty"`
+	Instrument     string          `json:"instrument,omitempty"`
+	Wavelength     *float64        `json:"wavelength,omitempty"`
+	WavelengthUnit string          `json:"wavelength_unit,omitempty"`
+	XUnits         string          `json:"x_units,omitempty"`
+	YUnits         string          `json:"y_units,omitempty"`
+	PointCount     int             `json:"point_count"`
+	XMin           float64         `json:"x_min"`
+	XMax           float64         `json:"x_max"`
+	SourcePath     string          `json:"source_path"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Points         []SpectrumPoint `json:"points,omitempty"`
+}
+
+func (s *Spectrum) Validate() error {
+	s.Title = strings.TrimSpace(s.Title)
+	s.Instrument = strings.TrimSpace(s.Instrument)
+	if err := ValidateSpectrumType(s.Type); err != nil {
+		return err
+	}
+	if len(s.Title) > MaxSpectrumTextLength || len(s.Instrument) > MaxSpectrumTextLength ||
+		len(s.XUnits) > MaxSpectrumUnitLength || len(s.YUnits) > MaxSpectrumUnitLength {
+		return ErrSpectrumTextTooLong
+	}
+	if s.XUnits == "" {
+		s.XUnits = DefaultXUnits(s.Type)
+	}
+	if s.Wavelength != nil {
+		switch {
+		case s.Type == SpectrumFTIR:
+			return ErrWavelengthNotExpected
+		case s.Type == SpectrumRaman && (*s.Wavelength < 100 || *s.Wavelength > 2000),
+			s.Type == SpectrumXRD && (*s.Wavelength < 0.1 || *s.Wavelength > 5):
+			return ErrInvalidWavelength
+		}
+	}
+	return nil
+}
+
+func ValidateSpectrumType(spectrumType string) error {
+	switch spectrumType {
+	case SpectrumRaman, SpectrumFTIR, SpectrumXRD:
+		return nil
+	}
+	return ErrInvalidSpectrumType
+}
+
+// DefaultXUnits returns the x units usual for the spectrum type.
+func DefaultXUnits(spectrumType string) string {
+	if spectrumType == SpectrumXRD {
+		return "2θ, °"
+	}
+	return "cm-1"
+}
+
+// SetPoints stores the series together with its range and size.
+func (s *Spectrum) SetPoints(points []SpectrumPoint) {
+	s.Points = points
+	s.PointCount = len(points)
+	if len(points) > 0 {
+		s.XMin = points[0][0]
+		s.XMax = points[len(points)-1][0]
+	}
+}
+
+// SetWavelength sets the excitation wavelength and its unit, which depends on the spectrum type.
+func (s *Spectrum) SetWavelength(wavelength *float64) {
+	s.Wavelength = wavelength
+	switch {
+	case wavelength == nil:
+		s.WavelengthUnit = ""
+	case s.Type == SpectrumXRD:
+		s.WavelengthUnit = "Å"
+	default:
+		s.WavelengthUnit = "nm"
+	}
+}
@@ -0,0 +1,87 @@
+// A crystal structure of a mineral, parsed from an uploaded Crystallographic Information File (CIF).
+// Keeps the path of the original file together with the space group, unit cell, Z and atom sites read from it.
+// A mineral may have several structures, e.g. refinements at different temperatures or pressures.
+
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	Triclinic    = "triclinic"
+	Monoclinic   = "monoclinic"
+	Orthorhombic = "orthorhombic"
+	Tetragonal   = "tetragonal"
+	Trigonal     = "trigonal"
+	Hexagonal    = "hexagonal"
+	Cubic        = "cubic"
+)
+
+// CrystalSystems lists the systems in order of increasing symmetry.
+var CrystalSystems = []string{Triclinic, Monoclinic, Orthorhombic, Tetragonal, Trigonal, Hexagonal, Cubic}
+
+var ErrInvalidCrystalSystem = errors.New("неизвестная сингония")
+
+// UnitCell holds the cell lengths in ångströms and the angles in degrees.
+type UnitCell struct {
+	A      float64 `json:"a"`
+	B      float64 `json:"b"`
+	C      float64 `json:"c"`
+	Alpha  float64 `json:"alpha"`
+	Beta   float64 `json:"beta"`
+	Gamma  float64 `json:"gamma"`
+	Volume float64 `json:"volume"`
+}
+
+type AtomSite struct {
+	Label      string   `json:"label"`
+	TypeSymbol string   `json:"type_symbol,omitempty"`
+	Element    string   `json:"element,omitempty"`
+	X          float64  `json:"x"`
+	Y          float64  `json:"y"`
+	Z          float64  `json:"z"`
+	Occupancy  float64  `json:"occupancy"`
+	UIso       *float64 `json:"u_iso,omitempty"`
+}
+
+type CrystalStructure struct {
+	ID               int        `json:"id"`
+	MineralID        int        `json:"mineral_id"`
+	CIFPath          string     `json:"cif_path"`
+	DataBlock        string     `json:"data_block,omitempty"`
+	SpaceGroup       string     `json:"space_group"`
+	SpaceGroupNumber *int       `json:"space_group_number,omitempty"`
+	CrystalSystem    string     `json:"crystal_system"`
+	Cell             UnitCell   `json:"cell"`
+	Z                *int       `json:"z,omitempty"`
+	AtomSites        []AtomSite `json:"atom_sites"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+func IsCrystalSystem(system string) bool {
+	for _, s := range CrystalSystems {
+		if s == system {
+			return true
+		}
+	}
+	return false
+}
+
+func ValidateCrystalSystem(system string) error {
+	if system != "" && !IsCrystalSystem(system) {
+		return ErrInvalidCrystalSystem
+	}
+	return nil
+}
+
+// CheckCrystalSystem compares the crystal system of a structure with the one declared for the mineral.
+// A mineral without a declared system accepts any structure.
+func (s *CrystalStructure) CheckCrystalSystem(declared string) error {
+	if declared != "" && declared != s.CrystalSystem {
+		return fmt.Errorf("сингония из CIF (%s) не совпадает с сингонией минерала (%s)", s.CrystalSystem, declared)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+// Free-form tags that group minerals across the classification: "fluorescent", "gemstone", "ore of copper".
+// A tag is addressed by its slug, carries a name per language and reports how many published minerals it has.
+// The tag cloud weighs tags from 1 to CloudWeights on a logarithmic scale of their counts.
+
+package models
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	MaxTagNameLength = 100
+	CloudWeights     = 5
+)
+
+var (
+	ErrEmptyTagName   = errors.New("название тега на языке по умолчанию не может быть пустым")
+	ErrTagNameTooLong = errors.New("название тега слишком длинное")
+	ErrInvalidTagSlug = errors.New("код тега может содержать только строчные латинские буквы, цифры и дефисы")
+)
+
+type Tag struct {
+	ID           int               `json:"id"`
+	Slug         string            `json:"slug"`
+	Names        map[string]string `json:"names"`
+	Name         string            `json:"name"`
+	MineralCount int               `json:"mineral_count"`
+	Weight       int               `json:"weight,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// Validate checks the names and the slug.
+func (t *Tag) Validate() error {
+	if strings.TrimSpace(t.Names[DefaultLanguage]) == "" {
+		return ErrEmptyTagName
+	}
+	for lang, name := range t.Names {
+		if len(lang) < 2 || len(lang) > 8 {
+			return ErrInvalidLanguage
+		}
+		if len(name) > MaxTagNameLength {
+			return ErrTagNameTooLong
+		}
+		t.Names[lang] = strings.TrimSpace(name)
+	}
+	t.Slug = strings.TrimSpace(t.Slug)
+	if !ValidSlug(t.Slug) {
+		return ErrInvalidTagSlug
+	}
+	return nil
+}
+
+// Localize fills Name for lang, falling back to the default language.
+func (t *Tag) Localize(lang string) {
+	if name, ok := t.Names[lang]; ok && name != "" {
+		t.Name = name
+	} else {
+		t.Name = t.Names[DefaultLanguage]
+	}
+}
+
+// AssignCloudWeights sets the weight of each tag, so that the most used tag gets CloudWeights and the least used gets 1.
+func AssignCloudWeights(tags []Tag) {
+	if len(tags) == 0 {
+		return
+	}
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, tag := range tags {
+		count := math.Log1p(float64(tag.MineralCount))
+		low = math.Min(low, count)
+		high = math.Max(high, count)
+	}
+	for i := range tags {
+		if high == low {
+			tags[i].Weight = 1
+			continue
+		}
+		scaled := (math.Log1p(float64(tags[i].MineralCount)) - low) / (high - low)
+		tags[i].Weight = 1 + int(math.Round(scaled*(CloudWeights-1)))
+	}
+}
@@ -0,0 +1,99 @@
+// Publication workflow of minerals: draft → in_review → published → archived.
+// An editor submits a draft for review, a reviewer approves it (optionally for a later publish time) or sends it back
+// with a comment; published minerals can be archived, and archived or published ones reopened as drafts.
+// Each step is kept as a review entry with its author and comment.
+
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+
+	ReviewSubmit  = "submit"
+	ReviewApprove = "approve"
+	ReviewReject  = "reject"
+	ReviewArchive = "archive"
+	ReviewReopen  = "reopen"
+	ReviewComment = "comment"
+
+	MaxReviewCommentLength = 2000
+)
+
+var (
+	ErrInvalidStatus         = errors.New("статус должен быть draft, in_review, published или archived")
+	ErrInvalidTransition     = errors.New("это действие недоступно в текущем статусе минерала")
+	ErrReviewCommentRequired = errors.New("для этого действия нужен комментарий")
+	ErrReviewCommentTooLong  = errors.New("комментарий слишком длинный")
+)
+
+type workflowTransition struct {
+	from []string
+	to   string
+}
+
+var workflowTransitions = map[string]workflowTransition{
+	ReviewSubmit:  {from: []string{StatusDraft}, to: StatusInReview},
+	ReviewApprove: {from: []string{StatusInReview}, to: StatusPublished},
+	ReviewReject:  {from: []string{StatusInReview}, to: StatusDraft},
+	ReviewArchive: {from: []string{StatusPublished}, to: StatusArchived},
+	ReviewReopen:  {from: []string{StatusPublished, StatusArchived}, to: StatusDraft},
+}
+
+// ReviewEntry is a step of the workflow or a plain reviewer comment (action "comment", status unchanged).
+type ReviewEntry struct {
+	ID         int       `json:"id"`
+	MineralID  int       `json:"mineral_id"`
+	AuthorID   *int      `json:"author_id,omitempty"`
+	AuthorName string    `json:"author_name,omitempty"`
+	Action     string    `json:"action"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (e *ReviewEntry) Validate() error {
+	e.Comment = strings.TrimSpace(e.Comment)
+	if len(e.Comment) > MaxReviewCommentLength {
+		return ErrReviewCommentTooLong
+	}
+	if e.Comment == "" && (e.Action == ReviewReject || e.Action == ReviewComment) {
+		return ErrReviewCommentRequired
+	}
+	return nil
+}
+
+func ValidateStatus(status string) error {
+	switch status {
+	case StatusDraft, StatusInReview, StatusPublished, StatusArchived:
+		return nil
+	}
+	return ErrInvalidStatus
+}
+
+// NextStatus returns the status a workflow action leads to from the current one.
+func NextStatus(action, current string) (string, error) {
+	transition, ok := workflowTransitions[action]
+	if !ok {
+		return "", ErrInvalidTransition
+	}
+	for _, from := range transition.from {
+		if from == current {
+			return transition.to, nil
+		}
+	}
+	return "", ErrInvalidTransition
+}
+
+// IsPublic reports whether visitors may see the mineral: it is published and its publish time, if any, has come.
+func (m *Mineral) IsPublic(now time.Time) bool {
+	return m.Status == StatusPublished && (m.PublishAt == nil || !m.PublishAt.After(now))
+}
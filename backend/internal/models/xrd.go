@@ -0,0 +1,10 @@
+// A peak of the reference powder X-ray diffraction pattern of a mineral.
+// The d-spacing is in ångströms and the intensity is relative, with the strongest peak at 100.
+
+package models
+
+type XRDPeak struct {
+	D         float64 `json:"d"`
+	Intensity float64 `json:"intensity"`
+	HKL       string  `json:"hkl,omitempty"`
+}
@@ -0,0 +1,109 @@
+// The ZIP archive that accompanies an import manifest and holds the model and preview files its records name.
+// An archive made by the export carries the manifest itself, so it can be imported without a separate one.
+// Entries are read one at a time when they are needed, so a large archive is never held in memory as a whole.
+
+package catalog
+
+import (
+	"archive/zip"
+	"backend/internal/service/file"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	ErrFileMissing = errors.New("файл не найден в архиве")
+	ErrNoManifest  = errors.New("в архиве нет манифеста (manifest.ndjson, manifest.json или manifest.csv)")
+)
+
+// manifestNames are the names under which an archive may carry its own manifest, as the export writes it.
+var manifestNames = []string{ManifestName, "manifest.json", "manifest.csv"}
+
+type Archive struct {
+	files map[string]*zip.File
+}
+
+// OpenArchive indexes the entries of a ZIP archive by their paths.
+func OpenArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный ZIP-архив: %v", err)
+	}
+	archive := &Archive{files: make(map[string]*zip.File, len(reader.File))}
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		archive.files[cleanName(f.Name)] = f
+	}
+	return archive, nil
+}
+
+// Manifest reads the manifest stored at the root of the archive.
+func (a *Archive) Manifest() ([]Record, error) {
+	for _, name := range manifestNames {
+		f, err := a.entry(name)
+		if err != nil {
+			continue
+		}
+		src, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		defer src.Close()
+		return ParseManifest(name, src)
+	}
+	return nil, ErrNoManifest
+}
+
+// Size returns the unpacked size of an entry. A nil archive has no entries.
+func (a *Archive) Size(name string) (int64, error) {
+	f, err := a.entry(name)
+	if err != nil {
+		return 0, err
+	}
+	return int64(f.UncompressedSize64), nil
+}
+
+// Read unpacks an entry, refusing entries larger than file.MaxFileSize.
+func (a *Archive) Read(name string) ([]byte, error) {
+	f, err := a.entry(name)
+	if err != nil {
+		return nil, err
+	}
+	if f.UncompressedSize64 > file.MaxFileSize {
+		return nil, fmt.Errorf("%s: файл больше %d МБ", name, file.MaxFileSize>>20)
+	}
+	src, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, file.MaxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(data) > file.MaxFileSize {
+		return nil, fmt.Errorf("%s: файл больше %d МБ", name, file.MaxFileSize>>20)
+	}
+	return data, nil
+}
+
+func (a *Archive) entry(name string) (*zip.File, error) {
+	if a == nil {
+		return nil, fmt.Errorf("%s: %w", name, ErrFileMissing)
+	}
+	f, ok := a.files[cleanName(name)]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", name, ErrFileMissing)
+	}
+	return f, nil
+}
+
+func cleanName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(name, "\\", "/")), "/")
+}
@@ -0,0 +1,268 @@
+// Export of the whole catalogue as NDJSON, CSV or a self-contained ZIP archive.
+// Records carry the import fields, so an export can be imported again, plus the data the import does not write:
+// slugs and names in every language, tag names, composition, classification codes and timestamps.
+// Minerals are read in batches and written as they arrive; the archive keeps only the manifest in a temporary file
+// until the files are written, because a ZIP entry cannot be interleaved with others.
+
+package catalog
+
+import (
+	"archive/zip"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/file"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ManifestName       = "manifest.ndjson"
+	ArchiveModelsDir   = "models"
+	ArchivePreviewsDir = "previews"
+)
+
+// ExportColumns lists the CSV columns the export writes after the import Columns.
+var ExportColumns = []string{"id", "slug", "slugs", "names", "molar_mass", "strunz_code", "dana_code",
+	"model_path", "preview_image_path", "published_at", "created_at"}
+
+type ExportName struct {
+	Name        string `json:"name"`
+	Lang        string `json:"lang"`
+	Kind        string `json:"kind"`
+	Description string `json:"description,omitempty"`
+}
+
+type ExportRecord struct {
+	Record
+	ID               int                    `json:"id"`
+	Slug             string                 `json:"slug,omitempty"`
+	Slugs            map[string]string      `json:"slugs,omitempty"`
+	Names            []ExportName           `json:"names,omitempty"`
+	MolarMass        *float64               `json:"molar_mass,omitempty"`
+	Composition      []models.ElementAmount `json:"composition,omitempty"`
+	StrunzCode       string                 `json:"strunz_code,omitempty"`
+	DanaCode         string                 `json:"dana_code,omitempty"`
+	ModelPath        string                 `json:"model_path"`
+	PreviewImagePath string                 `json:"preview_image_path"`
+	PublishAt        *time.Time             `json:"publish_at,omitempty"`
+	PublishedAt      *time.Time             `json:"published_at,omitempty"`
+	CreatedAt        time.Time              `json:"created_at"`
+}
+
+type Exporter struct {
+	db          *database.Database
+	fileService *file.FileService
+}
+
+func NewExporter(db *database.Database, fileService *file.FileService) *Exporter {
+	return &Exporter{db: db, fileService: fileService}
+}
+
+// WriteNDJSON writes one JSON record per mineral and returns how many were written.
+func (e *Exporter) WriteNDJSON(w io.Writer) (int, error) {
+	encoder := json.NewEncoder(w)
+	count := 0
+	err := e.each(func(m *database.ExportMineral) error {
+		count++
+		return encoder.Encode(exportRecord(m))
+	})
+	return count, err
+}
+
+// WriteCSV writes a header row and one row per mineral; tags, slugs and names are joined with semicolons.
+func (e *Exporter) WriteCSV(w io.Writer) (int, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append(append([]string{}, Columns...), ExportColumns...)); err != nil {
+		return 0, err
+	}
+	count := 0
+	err := e.each(func(m *database.ExportMineral) error {
+		count++
+		return writer.Write(exportRecord(m).csvRow())
+	})
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	return count, err
+}
+
+// WriteArchive writes a ZIP archive with the GLB model and the preview of every mineral and a manifest.ndjson
+// that names them, which the import accepts as it is. Files missing from the storage are left out with a warning.
+func (e *Exporter) WriteArchive(w io.Writer) (int, error) {
+	manifest, err := os.CreateTemp("", "export-manifest-*.ndjson")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(manifest.Name())
+	defer manifest.Close()
+
+	archive := zip.NewWriter(w)
+	encoder := json.NewEncoder(manifest)
+	written := map[string]bool{}
+	count := 0
+	err = e.each(func(m *database.ExportMineral) error {
+		record := exportRecord(m)
+		var err error
+		if record.Model, err = e.addFile(archive, written, ArchiveModelsDir, m.ModelPath); err != nil {
+			return err
+		}
+		if record.Preview, err = e.addFile(archive, written, ArchivePreviewsDir, m.PreviewImagePath); err != nil {
+			return err
+		}
+		count++
+		return encoder.Encode(record)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if _, err := manifest.Seek(0, io.SeekStart); err != nil {
+		return count, err
+	}
+	entry, err := archive.Create(ManifestName)
+	if err != nil {
+		return count, err
+	}
+	if _, err := io.Copy(entry, manifest); err != nil {
+		return count, err
+	}
+	return count, archive.Close()
+}
+
+// addFile copies a stored file into the archive once and returns its name there, or "" when the file is missing.
+func (e *Exporter) addFile(archive *zip.Writer, written map[string]bool, dir, publicPath string) (string, error) {
+	if publicPath == "" {
+		return "", nil
+	}
+	name := dir + "/" + path.Base(publicPath)
+	if written[name] {
+		return name, nil
+	}
+	fullPath, err := e.fileService.FullPath(publicPath)
+	if err != nil {
+		log.Printf("Экспорт: некорректный путь к файлу %q: %v", publicPath, err)
+		return "", nil
+	}
+	f, err := os.Open(fullPath)
+	if os.IsNotExist(err) {
+		log.Printf("Экспорт: файл %s не найден", fullPath)
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: info.ModTime()}
+	entry, err := archive.CreateHeader(header)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(entry, f); err != nil {
+		return "", err
+	}
+	written[name] = true
+	return name, nil
+}
+
+// each calls fn for every mineral outside the trash, in id order, one batch at a time.
+func (e *Exporter) each(fn func(m *database.ExportMineral) error) error {
+	afterID := 0
+	for {
+		batch, err := e.db.GetExportBatch(afterID, database.ExportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		for i := range batch {
+			if err := fn(&batch[i]); err != nil {
+				return err
+			}
+		}
+		afterID = batch[len(batch)-1].ID
+	}
+}
+
+func exportRecord(m *database.ExportMineral) ExportRecord {
+	record := ExportRecord{
+		Record: Record{
+			ExternalID:    m.ExternalID,
+			UUID:          m.UUID,
+			Title:         m.Title,
+			Description:   m.Description,
+			Formula:       m.Formula,
+			CrystalSystem: m.CrystalSystem,
+			Status:        m.Status,
+			Tags:          make([]string, 0, len(m.Tags)),
+		},
+		ID:               m.ID,
+		Slug:             m.Slug,
+		Slugs:            m.Slugs,
+		MolarMass:        m.MolarMass,
+		Composition:      m.Composition,
+		StrunzCode:       m.StrunzCode,
+		DanaCode:         m.DanaCode,
+		ModelPath:        m.ModelPath,
+		PreviewImagePath: m.PreviewImagePath,
+		PublishAt:        m.PublishAt,
+		PublishedAt:      m.PublishedAt,
+		CreatedAt:        m.CreatedAt,
+	}
+	if len(m.Tags) > 0 {
+		record.TagNames = make(map[string]map[string]string, len(m.Tags))
+	}
+	for _, tag := range m.Tags {
+		record.Tags = append(record.Tags, tag.Slug)
+		record.TagNames[tag.Slug] = tag.Names
+	}
+	for _, n := range m.Names {
+		record.Names = append(record.Names, ExportName{Name: n.Name, Lang: n.Lang, Kind: n.Kind, Description: n.Description})
+	}
+	return record
+}
+
+func (r ExportRecord) csvRow() []string {
+	langs := make([]string, 0, len(r.Slugs))
+	for lang := range r.Slugs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	slugs := make([]string, len(langs))
+	for i, lang := range langs {
+		slugs[i] = lang + "=" + r.Slugs[lang]
+	}
+	names := make([]string, len(r.Names))
+	for i, n := range r.Names {
+		names[i] = n.Lang + ":" + n.Kind + ":" + n.Name
+	}
+	molarMass := ""
+	if r.MolarMass != nil {
+		molarMass = strconv.FormatFloat(*r.MolarMass, 'f', -1, 64)
+	}
+	publishedAt := ""
+	if r.PublishedAt != nil {
+		publishedAt = r.PublishedAt.Format(time.RFC3339)
+	}
+
+	return []string{
+		r.ExternalID, r.UUID, r.Title, r.Description, r.Formula, r.CrystalSystem, r.Status,
+		strings.Join(r.Tags, TagSeparator), r.Model, r.Preview,
+		strconv.Itoa(r.ID), r.Slug, strings.Join(slugs, TagSeparator), strings.Join(names, TagSeparator), molarMass,
+		r.StrunzCode, r.DanaCode, r.ModelPath, r.PreviewImagePath, publishedAt, r.CreatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,444 @@
+// Bulk import of minerals from a manifest and a ZIP archive of model and preview files.
+// Every row is first checked the way CreateMineral and UpdateMineral check a form: Mineral.Validate, the formula,
+// the crystal system against attached structures, and the FileService rules for the files, including model conversion.
+// Rows are matched to existing minerals by external ID, then UUID, then title. Nothing is written unless every row
+// passes; the import is then applied in a single transaction with a single staging area for the files.
+// An import can only submit a draft for review: publishing and archiving stay with the reviewers.
+
+package catalog
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/database"
+	"backend/internal/models"
+	"backend/internal/service/chemistry"
+	"backend/internal/service/file"
+	"backend/internal/service/slug"
+	stderrors "errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionError  = "error"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+type Options struct {
+	DryRun     bool
+	AuthorID   *int
+	AuthorName string
+}
+
+type ReportItem struct {
+	Row       int      `json:"row"`
+	Title     string   `json:"title"`
+	Action    string   `json:"action"`
+	MineralID int      `json:"mineral_id,omitempty"`
+	MatchedBy string   `json:"matched_by,omitempty"`
+	Submitted bool     `json:"submitted,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// Report lists what an import does, or would do in a dry run, with each row. Applied is set once the import is committed.
+type Report struct {
+	DryRun  bool         `json:"dry_run"`
+	Applied bool         `json:"applied"`
+	Rows    int          `json:"rows"`
+	Creates int          `json:"creates"`
+	Updates int          `json:"updates"`
+	Errors  int          `json:"errors"`
+	Items   []ReportItem `json:"items"`
+}
+
+type rowPlan struct {
+	record      Record
+	existing    *models.Mineral
+	matchedBy   string
+	composition *chemistry.Composition
+	submit      bool
+}
+
+type Importer struct {
+	db          *database.Database
+	fileService *file.FileService
+	mu          sync.Mutex
+}
+
+func NewImporter(db *database.Database, fileService *file.FileService) *Importer {
+	return &Importer{db: db, fileService: fileService}
+}
+
+// Run checks every record and, unless opts.DryRun is set or a row has errors, applies the import.
+// The returned error is reserved for failures of the database or the storage; problems with rows are in the report.
+func (imp *Importer) Run(records []Record, archive *Archive, opts Options) (*Report, error) {
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+
+	report := &Report{DryRun: opts.DryRun, Rows: len(records), Items: make([]ReportItem, 0, len(records))}
+	plans := make([]*rowPlan, 0, len(records))
+	keys := map[string]int{}
+	targets := map[int]int{}
+	for _, record := range records {
+		plan, problems, err := imp.plan(record, archive)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range record.keys() {
+			if row, ok := keys[key]; ok {
+				problems = append(problems, fmt.Sprintf("повторяет строку %d", row))
+				continue
+			}
+			keys[key] = record.Row
+		}
+
+		item := ReportItem{Row: record.Row, Title: record.Title, Action: ActionCreate, Submitted: plan.submit}
+		if plan.existing != nil {
+			item.Action, item.MineralID, item.MatchedBy = ActionUpdate, plan.existing.ID, plan.matchedBy
+			if row, ok := targets[plan.existing.ID]; ok {
+				problems = append(problems, fmt.Sprintf("обновляет тот же минерал, что и строка %d", row))
+			}
+			targets[plan.existing.ID] = record.Row
+		}
+		if len(problems) > 0 {
+			item.Action, item.Errors = ActionError, problems
+		}
+
+		switch item.Action {
+		case ActionCreate:
+			report.Creates++
+		case ActionUpdate:
+			report.Updates++
+		default:
+			report.Errors++
+		}
+		report.Items = append(report.Items, item)
+		plans = append(plans, plan)
+	}
+
+	if opts.DryRun || report.Errors > 0 {
+		return report, nil
+	}
+	ids, err := imp.apply(plans, archive, opts)
+	if err != nil {
+		return nil, err
+	}
+	for i := range report.Items {
+		report.Items[i].MineralID = ids[i]
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// plan finds the mineral a record refers to and lists the problems that would stop it from being imported.
+func (imp *Importer) plan(r Record, archive *Archive) (*rowPlan, []string, error) {
+	plan := &rowPlan{record: r}
+	var problems []string
+
+	uuid := r.UUID
+	if uuid != "" && !uuidPattern.MatchString(uuid) {
+		problems = append(problems, "некорректный UUID")
+		uuid = ""
+	}
+	candidates, matchedBy, err := imp.db.FindImportCandidates(r.ExternalID, uuid, r.Title)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case len(candidates) > 1:
+		problems = append(problems, "название совпадает с несколькими минералами, укажите external_id или uuid")
+	case len(candidates) == 1 && candidates[0].DeletedAt != nil:
+		problems = append(problems, fmt.Sprintf("минерал %d находится в корзине", candidates[0].ID))
+	case len(candidates) == 1:
+		plan.existing, plan.matchedBy = &candidates[0], matchedBy
+	}
+	if existing := plan.existing; existing != nil {
+		// Files stored under the same content-hashed name, as in a re-imported export, are kept as they are.
+		if r.Model != "" && path.Base(r.Model) == path.Base(existing.ModelPath) {
+			r.Model = ""
+		}
+		if r.Preview != "" && path.Base(r.Preview) == path.Base(existing.PreviewImagePath) {
+			r.Preview = ""
+		}
+		plan.record = r
+		if r.ExternalID != "" && matchedBy != database.MatchByExternalID && existing.ExternalID != "" {
+			problems = append(problems, fmt.Sprintf("минерал %d уже связан с external_id %s", existing.ID, existing.ExternalID))
+		}
+		if uuid != "" && matchedBy != database.MatchByUUID && existing.UUID != uuid {
+			problems = append(problems, fmt.Sprintf("UUID не совпадает с UUID минерала %d", existing.ID))
+		}
+	}
+
+	if r.Status != "" {
+		current := models.StatusDraft
+		if plan.existing != nil {
+			current = plan.existing.Status
+		}
+		if err := models.ValidateStatus(r.Status); err != nil {
+			problems = append(problems, err.Error())
+		} else if r.Status != current {
+			if next, err := models.NextStatus(models.ReviewSubmit, current); err != nil || next != r.Status {
+				problems = append(problems, fmt.Sprintf("импорт может только отправить черновик на рецензию, статус %s → %s меняется через рецензирование", current, r.Status))
+			} else {
+				plan.submit = true
+			}
+		}
+	}
+	if r.Formula != "" {
+		plan.composition, err = chemistry.ParseFormula(r.Formula)
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if plan.existing != nil && r.CrystalSystem != "" {
+		structures, err := imp.db.GetMineralStructures(plan.existing.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, structure := range structures {
+			if err := structure.CheckCrystalSystem(r.CrystalSystem); err != nil {
+				problems = append(problems, err.Error())
+				break
+			}
+		}
+	}
+	for _, tag := range r.Tags {
+		if !models.ValidSlug(tag) {
+			problems = append(problems, fmt.Sprintf("тег %q: %v", tag, models.ErrInvalidTagSlug))
+		}
+	}
+
+	mineral := models.Mineral{}
+	if plan.existing != nil {
+		mineral = *plan.existing
+	}
+	r.applyTo(&mineral, plan.composition)
+	switch {
+	case r.Model != "":
+		mineral.ModelPath = file.PublicPrefix + file.ModelsDir + "/" + file.ModelFileName(r.Model)
+		if data, err := archive.Read(r.Model); err != nil {
+			problems = append(problems, err.Error())
+		} else if err := file.CheckModelData(r.Model, data); err != nil {
+			problems = append(problems, r.Model+": "+describe(err))
+		}
+	case plan.existing == nil:
+		problems = append(problems, "для нового минерала нужен файл модели (model)")
+	}
+	if r.Preview != "" {
+		if size, err := archive.Size(r.Preview); err != nil {
+			problems = append(problems, err.Error())
+		} else if err := file.CheckPreview(r.Preview, size); err != nil {
+			problems = append(problems, r.Preview+": "+describe(err))
+		}
+	}
+	if err := mineral.Validate(); err != nil && !(err == models.ErrInvalidModelPath && plan.existing == nil && r.Model == "") {
+		problems = append(problems, err.Error())
+	}
+	return plan, problems, nil
+}
+
+// apply writes the planned rows in one transaction and returns the ids of the minerals in row order.
+func (imp *Importer) apply(plans []*rowPlan, archive *Archive, opts Options) ([]int, error) {
+	staging, err := imp.fileService.NewStaging()
+	if err != nil {
+		return nil, err
+	}
+	defer staging.Rollback()
+	files := imp.fileService.WithStaging(staging)
+
+	tx, err := imp.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int, len(plans))
+	for i, plan := range plans {
+		ids[i], err = imp.applyRow(tx, files, plan, archive, opts)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %w", plan.record.Row, err)
+		}
+	}
+
+	if err := staging.Commit(); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		staging.Rollback()
+		return nil, err
+	}
+	staging.Release()
+	return ids, nil
+}
+
+func (imp *Importer) applyRow(tx *database.Tx, files *file.FileService, plan *rowPlan, archive *Archive, opts Options) (int, error) {
+	r := plan.record
+	mineral := &models.Mineral{UUID: r.UUID, Status: models.StatusDraft, CreatedAt: time.Now()}
+	var previous *models.Mineral
+	if plan.existing != nil {
+		current, err := tx.GetMineralForUpdate(plan.existing.ID)
+		if err != nil {
+			return 0, err
+		}
+		snapshot := *current
+		previous, mineral = &snapshot, current
+	}
+	r.applyTo(mineral, plan.composition)
+
+	if r.Model != "" {
+		data, err := archive.Read(r.Model)
+		if err != nil {
+			return 0, err
+		}
+		saved, err := files.SaveModelData(r.Model, data)
+		if err != nil {
+			return 0, err
+		}
+		mineral.ModelPath, mineral.SourceModelPath = saved.Path, saved.SourcePath
+	}
+	if r.Preview != "" {
+		data, err := archive.Read(r.Preview)
+		if err != nil {
+			return 0, err
+		}
+		if mineral.PreviewImagePath, err = files.SavePreviewData(r.Preview, data); err != nil {
+			return 0, err
+		}
+	} else if previous == nil {
+		rendered, err := files.RenderPreview(mineral.ModelPath)
+		if err != nil {
+			return 0, err
+		}
+		mineral.PreviewImagePath = rendered
+	}
+	if err := mineral.Validate(); err != nil {
+		return 0, errors.ErrInvalidInput(err.Error())
+	}
+
+	var saved *models.Mineral
+	var err error
+	action := models.RevisionCreate
+	if previous == nil {
+		saved, err = tx.CreateMineral(*mineral, slug.Make(mineral.Title))
+	} else {
+		action = models.RevisionUpdate
+		saved, err = tx.UpdateMineral(*mineral, slug.Make(mineral.Title))
+	}
+	if err != nil {
+		return 0, err
+	}
+	if plan.composition != nil {
+		if err := tx.SetMineralComposition(saved.ID, plan.composition.Elements); err != nil {
+			return 0, err
+		}
+	}
+	if r.Tags != nil {
+		tagIDs, err := tx.EnsureTags(r.importTags())
+		if err != nil {
+			return 0, err
+		}
+		if err := tx.SetMineralTags(saved.ID, tagIDs); err != nil {
+			return 0, err
+		}
+	}
+	if plan.submit {
+		if _, err := tx.SetMineralStatus(saved.ID, models.StatusInReview, nil); err != nil {
+			return 0, err
+		}
+		_, err := tx.CreateReviewEntry(models.ReviewEntry{
+			MineralID:  saved.ID,
+			AuthorID:   opts.AuthorID,
+			AuthorName: opts.AuthorName,
+			Action:     models.ReviewSubmit,
+			FromStatus: saved.Status,
+			ToStatus:   models.StatusInReview,
+			Comment:    "Отправлено на рецензию импортом каталога",
+		})
+		if err != nil {
+			return 0, err
+		}
+		saved.Status = models.StatusInReview
+	}
+
+	if previous != nil {
+		if err := tx.EnsureBaselineRevision(previous); err != nil {
+			return 0, err
+		}
+	}
+	_, err = tx.CreateMineralRevision(models.MineralRevision{
+		MineralID:  saved.ID,
+		Action:     action,
+		AuthorID:   opts.AuthorID,
+		AuthorName: opts.AuthorName,
+		Snapshot:   models.SnapshotOf(saved),
+	})
+	return saved.ID, err
+}
+
+// applyTo copies the non-empty fields of the record into a mineral.
+func (r Record) applyTo(m *models.Mineral, composition *chemistry.Composition) {
+	if r.ExternalID != "" {
+		m.ExternalID = r.ExternalID
+	}
+	if r.Title != "" {
+		m.Title = r.Title
+	}
+	if r.Description != "" {
+		m.Description = r.Description
+	}
+	if r.CrystalSystem != "" {
+		m.CrystalSystem = r.CrystalSystem
+	}
+	if composition != nil {
+		molarMass := composition.MolarMass
+		m.Formula = composition.Formula
+		m.MolarMass = &molarMass
+	}
+}
+
+// importTags builds the tags of the record with the names given in tag_names; a tag without valid names
+// is named after its slug. Only tags that do not exist yet are created.
+func (r Record) importTags() []models.Tag {
+	tags := make([]models.Tag, len(r.Tags))
+	for i, value := range r.Tags {
+		tag := models.Tag{Slug: value, Names: map[string]string{}}
+		for lang, name := range r.TagNames[value] {
+			tag.Names[lang] = name
+		}
+		if err := tag.Validate(); err != nil || tag.Slug != value {
+			tag.Names = map[string]string{models.DefaultLanguage: value}
+		}
+		tags[i] = tag
+	}
+	return tags
+}
+
+// keys identifies the mineral a record refers to within the manifest: by external ID or UUID when given, else by title.
+func (r Record) keys() []string {
+	var keys []string
+	if r.ExternalID != "" {
+		keys = append(keys, "external_id:"+r.ExternalID)
+	}
+	if r.UUID != "" {
+		keys = append(keys, "uuid:"+r.UUID)
+	}
+	if len(keys) == 0 && r.Title != "" {
+		keys = append(keys, "title:"+strings.ToLower(r.Title))
+	}
+	return keys
+}
+
+func describe(err error) string {
+	var apiErr *errors.APIError
+	if stderrors.As(err, &apiErr) && apiErr.Detail != "" {
+		return apiErr.Detail
+	}
+	return err.Error()
+}
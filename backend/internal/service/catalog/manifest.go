@@ -0,0 +1,199 @@
+// The manifest of a catalogue import: one record per mineral, read from CSV with a header row,
+// from a JSON array or from NDJSON with one object per line. Files named in a record are looked up in the import archive.
+// Empty fields leave the value of an existing mineral unchanged; in CSV, tags are separated with semicolons.
+
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	MaxManifestSize = 20 << 20
+	MaxManifestRows = 10000
+	TagSeparator    = ";"
+)
+
+var (
+	ErrUnknownFormat  = errors.New("манифест должен быть файлом .csv, .json, .ndjson или .jsonl")
+	ErrEmptyManifest  = errors.New("манифест не содержит ни одной записи")
+	ErrTooManyRows    = fmt.Errorf("манифест содержит больше %d записей", MaxManifestRows)
+	ErrNoTitleColumn  = errors.New("в заголовке CSV нет столбца title")
+	ErrManifestSyntax = errors.New("некорректный формат манифеста")
+)
+
+// Columns lists the CSV columns in the order the export writes them.
+var Columns = []string{"external_id", "uuid", "title", "description", "formula", "crystal_system", "status", "tags", "model", "preview"}
+
+type Record struct {
+	// Row is the line of the record in a CSV or NDJSON manifest, or its position in a JSON array.
+	Row           int      `json:"-"`
+	ExternalID    string   `json:"external_id,omitempty"`
+	UUID          string   `json:"uuid,omitempty"`
+	Title         string   `json:"title"`
+	Description   string   `json:"description,omitempty"`
+	Formula       string   `json:"formula,omitempty"`
+	CrystalSystem string   `json:"crystal_system,omitempty"`
+	Status        string   `json:"status,omitempty"`
+	Tags          []string `json:"tags"`
+	Model         string   `json:"model,omitempty"`
+	Preview       string   `json:"preview,omitempty"`
+	// TagNames gives the names of tags the import has to create, by slug and language. CSV manifests have none.
+	TagNames map[string]map[string]string `json:"tag_names,omitempty"`
+}
+
+// ParseManifest reads the records of a manifest, choosing the format by the file extension.
+func ParseManifest(filename string, r io.Reader) ([]Record, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxManifestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxManifestSize {
+		return nil, fmt.Errorf("манифест больше %d МБ", MaxManifestSize>>20)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var records []Record
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		records, err = parseCSV(data)
+	case ".json", ".ndjson", ".jsonl":
+		records, err = parseJSON(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, ErrEmptyManifest
+	}
+	if len(records) > MaxManifestRows {
+		return nil, ErrTooManyRows
+	}
+	for i := range records {
+		records[i].normalize()
+	}
+	return records, nil
+}
+
+func parseCSV(data []byte) ([]Record, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrEmptyManifest
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrManifestSyntax, err)
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := index["title"]; !ok {
+		return nil, ErrNoTitleColumn
+	}
+
+	var records []Record
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrManifestSyntax, err)
+		}
+		line, _ := reader.FieldPos(0)
+		cell := func(column string) string {
+			if i, ok := index[column]; ok && i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+		record := Record{
+			Row:           line,
+			ExternalID:    cell("external_id"),
+			UUID:          cell("uuid"),
+			Title:         cell("title"),
+			Description:   cell("description"),
+			Formula:       cell("formula"),
+			CrystalSystem: cell("crystal_system"),
+			Status:        cell("status"),
+			Model:         cell("model"),
+			Preview:       cell("preview"),
+		}
+		if tags := strings.TrimSpace(cell("tags")); tags != "" {
+			record.Tags = strings.Split(tags, TagSeparator)
+		}
+		records = append(records, record)
+		if len(records) > MaxManifestRows {
+			return nil, ErrTooManyRows
+		}
+	}
+	return records, nil
+}
+
+// parseJSON reads a JSON array of records or a stream of records, one object per line.
+func parseJSON(data []byte) ([]Record, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var records []Record
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrManifestSyntax, err)
+		}
+		for i := range records {
+			records[i].Row = i + 1
+		}
+		return records, nil
+	}
+
+	var records []Record
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, fmt.Errorf("%w: строка %d: %v", ErrManifestSyntax, i+1, err)
+		}
+		record.Row = i + 1
+		records = append(records, record)
+		if len(records) > MaxManifestRows {
+			return nil, ErrTooManyRows
+		}
+	}
+	return records, nil
+}
+
+func (r *Record) normalize() {
+	r.ExternalID = strings.TrimSpace(r.ExternalID)
+	r.UUID = strings.ToLower(strings.TrimSpace(r.UUID))
+	r.Title = strings.TrimSpace(r.Title)
+	r.Description = strings.TrimSpace(r.Description)
+	r.Formula = strings.TrimSpace(r.Formula)
+	r.CrystalSystem = strings.ToLower(strings.TrimSpace(r.CrystalSystem))
+	r.Status = strings.ToLower(strings.TrimSpace(r.Status))
+	r.Model = strings.TrimSpace(r.Model)
+	r.Preview = strings.TrimSpace(r.Preview)
+	if r.Tags != nil {
+		tags := make([]string, 0, len(r.Tags))
+		seen := map[string]bool{}
+		for _, tag := range r.Tags {
+			tag = strings.ToLower(strings.TrimSpace(tag))
+			if tag != "" && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+		r.Tags = tags
+	}
+}
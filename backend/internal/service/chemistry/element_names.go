@@ -0,0 +1,125 @@
+// Element names in the supported interface languages, indexed by atomic number - 1.
+// Columns: Russian, English, French, German, Spanish.
+
+package chemistry
+
+var elementNames = [][5]string{
+	{"Водород", "Hydrogen", "Hydrogène", "Wasserstoff", "Hidrógeno"},
+	{"Гелий", "Helium", "Hélium", "Helium", "Helio"},
+	{"Литий", "Lithium", "Lithium", "Lithium", "Litio"},
+	{"Бериллий", "Beryllium", "Béryllium", "Beryllium", "Berilio"},
+	{"Бор", "Boron", "Bore", "Bor", "Boro"},
+	{"Углерод", "Carbon", "Carbone", "Kohlenstoff", "Carbono"},
+	{"Азот", "Nitrogen", "Azote", "Stickstoff", "Nitrógeno"},
+	{"Кислород", "Oxygen", "Oxygène", "Sauerstoff", "Oxígeno"},
+	{"Фтор", "Fluorine", "Fluor", "Fluor", "Flúor"},
+	{"Неон", "Neon", "Néon", "Neon", "Neón"},
+	{"Натрий", "Sodium", "Sodium", "Natrium", "Sodio"},
+	{"Магний", "Magnesium", "Magnésium", "Magnesium", "Magnesio"},
+	{"Алюминий", "Aluminium", "Aluminium", "Aluminium", "Aluminio"},
+	{"Кремний", "Silicon", "Silicium", "Silicium", "Silicio"},
+	{"Фосфор", "Phosphorus", "Phosphore", "Phosphor", "Fósforo"},
+	{"Сера", "Sulfur", "Soufre", "Schwefel", "Azufre"},
+	{"Хлор", "Chlorine", "Chlore", "Chlor", "Cloro"},
+	{"Аргон", "Argon", "Argon", "Argon", "Argón"},
+	{"Калий", "Potassium", "Potassium", "Kalium", "Potasio"},
+	{"Кальций", "Calcium", "Calcium", "Calcium", "Calcio"},
+	{"Скандий", "Scandium", "Scandium", "Scandium", "Escandio"},
+	{"Титан", "Titanium", "Titane", "Titan", "Titanio"},
+	{"Ванадий", "Vanadium", "Vanadium", "Vanadium", "Vanadio"},
+	{"Хром", "Chromium", "Chrome", "Chrom", "Cromo"},
+	{"Марганец", "Manganese", "Manganèse", "Mangan", "Manganeso"},
+	{"Железо", "Iron", "Fer", "Eisen", "Hierro"},
+	{"Кобальт", "Cobalt", "Cobalt", "Cobalt", "Cobalto"},
+	{"Никель", "Nickel", "Nickel", "Nickel", "Níquel"},
+	{"Медь", "Copper", "Cuivre", "Kupfer", "Cobre"},
+	{"Цинк", "Zinc", "Zinc", "Zink", "Zinc"},
+	{"Галлий", "Gallium", "Gallium", "Gallium", "Galio"},
+	{"Германий", "Germanium", "Germanium", "Germanium", "Germanio"},
+	{"Мышьяк", "Arsenic", "Arsenic", "Arsen", "Arsénico"},
+	{"Селен", "Selenium", "Sélénium", "Selen", "Selenio"},
+	{"Бром", "Bromine", "Brome", "Brom", "Bromo"},
+	{"Криптон", "Krypton", "Krypton", "Krypton", "Kriptón"},
+	{"Рубидий", "Rubidium", "Rubidium", "Rubidium", "Rubidio"},
+	{"Стронций", "Strontium", "Strontium", "Strontium", "Estroncio"},
+	{"Иттрий", "Yttrium", "Yttrium", "Yttrium", "Itrio"},
+	{"Цирконий", "Zirconium", "Zirconium", "Zirconium", "Circonio"},
+	{"Ниобий", "Niobium", "Niobium", "Niob", "Niobio"},
+	{"Молибден", "Molybdenum", "Molybdène", "Molybdän", "Molibdeno"},
+	{"Технеций", "Technetium", "Technétium", "Technetium", "Tecnecio"},
+	{"Рутений", "Ruthenium", "Ruthénium", "Ruthenium", "Rutenio"},
+	{"Родий", "Rhodium", "Rhodium", "Rhodium", "Rodio"},
+	{"Палладий", "Palladium", "Palladium", "Palladium", "Paladio"},
+	{"Серебро", "Silver", "Argent", "Silber", "Plata"},
+	{"Кадмий", "Cadmium", "Cadmium", "Cadmium", "Cadmio"},
+	{"Индий", "Indium", "Indium", "Indium", "Indio"},
+	{"Олово", "Tin", "Étain", "Zinn", "Estaño"},
+	{"Сурьма", "Antimony", "Antimoine", "Antimon", "Antimonio"},
+	{"Теллур", "Tellurium", "Tellure", "Tellur", "Telurio"},
+	{"Иод", "Iodine", "Iode", "Iod", "Yodo"},
+	{"Ксенон", "Xenon", "Xénon", "Xenon", "Xenón"},
+	{"Цезий", "Caesium", "Césium", "Caesium", "Cesio"},
+	{"Барий", "Barium", "Baryum", "Barium", "Bario"},
+	{"Лантан", "Lanthanum", "Lanthane", "Lanthan", "Lantano"},
+	{"Церий", "Cerium", "Cérium", "Cer", "Cerio"},
+	{"Празеодим", "Praseodymium", "Praséodyme", "Praseodym", "Praseodimio"},
+	{"Неодим", "Neodymium", "Néodyme", "Neodym", "Neodimio"},
+	{"Прометий", "Promethium", "Prométhium", "Promethium", "Prometio"},
+	{"Самарий", "Samarium", "Samarium", "Samarium", "Samario"},
+	{"Европий", "Europium", "Europium", "Europium", "Europio"},
+	{"Гадолиний", "Gadolinium", "Gadolinium", "Gadolinium", "Gadolinio"},
+	{"Тербий", "Terbium", "Terbium", "Terbium", "Terbio"},
+	{"Диспрозий", "Dysprosium", "Dysprosium", "Dysprosium", "Disprosio"},
+	{"Гольмий", "Holmium", "Holmium", "Holmium", "Holmio"},
+	{"Эрбий", "Erbium", "Erbium", "Erbium", "Erbio"},
+	{"Тулий", "Thulium", "Thulium", "Thulium", "Tulio"},
+	{"Иттербий", "Ytterbium", "Ytterbium", "Ytterbium", "Iterbio"},
+	{"Лютеций", "Lutetium", "Lutécium", "Lutetium", "Lutecio"},
+	{"Гафний", "Hafnium", "Hafnium", "Hafnium", "Hafnio"},
+	{"Тантал", "Tantalum", "Tantale", "Tantal", "Tantalio"},
+	{"Вольфрам", "Tungsten", "Tungstène", "Wolfram", "Wolframio"},
+	{"Рений", "Rhenium", "Rhénium", "Rhenium", "Renio"},
+	{"Осмий", "Osmium", "Osmium", "Osmium", "Osmio"},
+	{"Иридий", "Iridium", "Iridium", "Iridium", "Iridio"},
+	{"Платина", "Platinum", "Platine", "Platin", "Platino"},
+	{"Золото", "Gold", "Or", "Gold", "Oro"},
+	{"Ртуть", "Mercury", "Mercure", "Quecksilber", "Mercurio"},
+	{"Таллий", "Thallium", "Thallium", "Thallium", "Talio"},
+	{"Свинец", "Lead", "Plomb", "Blei", "Plomo"},
+	{"Висмут", "Bismuth", "Bismuth", "Bismut", "Bismuto"},
+	{"Полоний", "Polonium", "Polonium", "Polonium", "Polonio"},
+	{"Астат", "Astatine", "Astate", "Astat", "Astato"},
+	{"Радон", "Radon", "Radon", "Radon", "Radón"},
+	{"Франций", "Francium", "Francium", "Francium", "Francio"},
+	{"Радий", "Radium", "Radium", "Radium", "Radio"},
+	{"Актиний", "Actinium", "Actinium", "Actinium", "Actinio"},
+	{"Торий", "Thorium", "Thorium", "Thorium", "Torio"},
+	{"Протактиний", "Protactinium", "Protactinium", "Protactinium", "Protactinio"},
+	{"Уран", "Uranium", "Uranium", "Uran", "Uranio"},
+	{"Нептуний", "Neptunium", "Neptunium", "Neptunium", "Neptunio"},
+	{"Плутоний", "Plutonium", "Plutonium", "Plutonium", "Plutonio"},
+	{"Америций", "Americium", "Américium", "Americium", "Americio"},
+	{"Кюрий", "Curium", "Curium", "Curium", "Curio"},
+	{"Берклий", "Berkelium", "Berkélium", "Berkelium", "Berkelio"},
+	{"Калифорний", "Californium", "Californium", "Californium", "Californio"},
+	{"Эйнштейний", "Einsteinium", "Einsteinium", "Einsteinium", "Einstenio"},
+	{"Фермий", "Fermium", "Fermium", "Fermium", "Fermio"},
+	{"Менделевий", "Mendelevium", "Mendélévium", "Mendelevium", "Mendelevio"},
+	{"Нобелий", "Nobelium", "Nobélium", "Nobelium", "Nobelio"},
+	{"Лоуренсий", "Lawrencium", "Lawrencium", "Lawrencium", "Lawrencio"},
+	{"Резерфордий", "Rutherfordium", "Rutherfordium", "Rutherfordium", "Rutherfordio"},
+	{"Дубний", "Dubnium", "Dubnium", "Dubnium", "Dubnio"},
+	{"Сиборгий", "Seaborgium", "Seaborgium", "Seaborgium", "Seaborgio"},
+	{"Борий", "Bohrium", "Bohrium", "Bohrium", "Bohrio"},
+	{"Хассий", "Hassium", "Hassium", "Hassium", "Hasio"},
+	{"Мейтнерий", "Meitnerium", "Meitnérium", "Meitnerium", "Meitnerio"},
+	{"Дармштадтий", "Darmstadtium", "Darmstadtium", "Darmstadtium", "Darmstatio"},
+	{"Рентгений", "Roentgenium", "Roentgenium", "Roentgenium", "Roentgenio"},
+	{"Коперниций", "Copernicium", "Copernicium", "Copernicium", "Copernicio"},
+	{"Нихоний", "Nihonium", "Nihonium", "Nihonium", "Nihonio"},
+	{"Флеровий", "Flerovium", "Flérovium", "Flerovium", "Flerovio"},
+	{"Московий", "Moscovium", "Moscovium", "Moscovium", "Moscovio"},
+	{"Ливерморий", "Livermorium", "Livermorium", "Livermorium", "Livermorio"},
+	{"Теннессин", "Tennessine", "Tennessine", "Tenness", "Teneso"},
+	{"Оганесон", "Oganesson", "Oganesson", "Oganesson", "Oganesón"},
+}
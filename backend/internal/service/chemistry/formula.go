@@ -0,0 +1,351 @@
+// A parser for mineral formulas that computes elemental composition, weight percentages and molar mass.
+// Supports nested groups in (), [] and {}, decimal and subscript counts, hydrate water after "·" (also "•" or "*"),
+// and substitution sites such as (Fe,Mg)2SiO4 or Ca5(PO4)3(F,Cl,OH). Site occupancy is unknown for substitutions,
+// so the alternatives are counted as equal shares and the result is marked approximate; the same applies to
+// variable water (·nH2O), whose elements are recorded with a zero count. Charges (Fe²⁺, Fe^3+) are ignored; a vacancy (□)
+// adds nothing but takes its share of a site, so (Na,□) counts half a sodium.
+
+package chemistry
+
+import (
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const MaxFormulaLength = 255
+
+var (
+	ErrEmptyFormula   = errors.New("формула не может быть пустой")
+	ErrInvalidFormula = errors.New("некорректная формула")
+)
+
+type Composition struct {
+	Formula     string                 `json:"formula"`
+	Elements    []models.ElementAmount `json:"elements"`
+	MolarMass   float64                `json:"molar_mass"`
+	Approximate bool                   `json:"approximate"`
+}
+
+// Symbols lists the elements of the composition in formula order.
+func (c *Composition) Symbols() []string {
+	symbols := make([]string, len(c.Elements))
+	for i, e := range c.Elements {
+		symbols[i] = e.Symbol
+	}
+	return symbols
+}
+
+// amounts keeps element counts in order of first appearance.
+type amounts struct {
+	order  []string
+	counts map[string]float64
+}
+
+func newAmounts() *amounts {
+	return &amounts{counts: map[string]float64{}}
+}
+
+func (a *amounts) add(symbol string, count float64) {
+	if _, ok := a.counts[symbol]; !ok {
+		a.order = append(a.order, symbol)
+	}
+	a.counts[symbol] += count
+}
+
+func (a *amounts) merge(other *amounts, factor float64) {
+	for _, symbol := range other.order {
+		a.add(symbol, other.counts[symbol]*factor)
+	}
+}
+
+type parser struct {
+	input       []rune
+	pos         int
+	approximate bool
+}
+
+// ParseFormula parses a formula and computes its composition.
+func ParseFormula(formula string) (*Composition, error) {
+	formula = strings.TrimSpace(formula)
+	if formula == "" {
+		return nil, ErrEmptyFormula
+	}
+	if len(formula) > MaxFormulaLength {
+		return nil, fmt.Errorf("%w: формула длиннее %d символов", ErrInvalidFormula, MaxFormulaLength)
+	}
+
+	p := &parser{input: normalizeFormula(formula)}
+	if strings.Trim(string(p.input), "□") == "" {
+		return nil, ErrEmptyFormula
+	}
+
+	total := newAmounts()
+	for {
+		part, err := p.parsePart()
+		if err != nil {
+			return nil, err
+		}
+		total.merge(part, 1)
+		if p.pos == len(p.input) {
+			break
+		}
+		if p.input[p.pos] != '·' {
+			return nil, p.errorf("неожиданный символ %q", p.input[p.pos])
+		}
+		p.pos++
+	}
+
+	return composition(formula, total, p.approximate), nil
+}
+
+// parsePart parses one component separated by "·": an optional multiplier followed by a sequence of groups.
+func (p *parser) parsePart() (*amounts, error) {
+	factor := 1.0
+	variable := false
+	switch {
+	case p.pos < len(p.input) && isDigit(p.input[p.pos]):
+		number, err := p.parseNumber()
+		if err != nil {
+			return nil, err
+		}
+		factor = number
+	case p.pos+1 < len(p.input) && (p.input[p.pos] == 'n' || p.input[p.pos] == 'x') && !unicode.IsLower(p.input[p.pos+1]):
+		p.pos++
+		variable = true
+	}
+
+	sequence, err := p.parseSequence()
+	if err != nil {
+		return nil, err
+	}
+	if len(sequence.order) == 0 {
+		return nil, p.errorf("ожидается химический элемент")
+	}
+
+	part := newAmounts()
+	if variable {
+		p.approximate = true
+		factor = 0
+	}
+	part.merge(sequence, factor)
+	return part, nil
+}
+
+func (p *parser) parseSequence() (*amounts, error) {
+	sequence := newAmounts()
+	for p.pos < len(p.input) && !strings.ContainsRune(")]},·", p.input[p.pos]) {
+		unit, err := p.parseUnit()
+		if err != nil {
+			return nil, err
+		}
+		sequence.merge(unit, 1)
+	}
+	return sequence, nil
+}
+
+// parseUnit parses an element, a vacancy or a bracketed group, each with an optional count.
+func (p *parser) parseUnit() (*amounts, error) {
+	r := p.input[p.pos]
+	if r == '□' {
+		p.pos++
+		if _, err := p.parseCount(); err != nil {
+			return nil, err
+		}
+		return newAmounts(), nil
+	}
+	if closer, ok := closingBracket(r); ok {
+		p.pos++
+		group, err := p.parseGroup(closer)
+		if err != nil {
+			return nil, err
+		}
+		count, err := p.parseCount()
+		if err != nil {
+			return nil, err
+		}
+		unit := newAmounts()
+		unit.merge(group, count)
+		return unit, nil
+	}
+
+	if !unicode.IsUpper(r) {
+		return nil, p.errorf("неожиданный символ %q", r)
+	}
+	symbol, err := p.parseSymbol()
+	if err != nil {
+		return nil, err
+	}
+	count, err := p.parseCount()
+	if err != nil {
+		return nil, err
+	}
+	unit := newAmounts()
+	unit.add(symbol, count)
+	return unit, nil
+}
+
+// parseGroup parses the contents of brackets. Comma-separated alternatives share the site equally;
+// a vacancy is an alternative without elements.
+func (p *parser) parseGroup(closer rune) (*amounts, error) {
+	var alternatives []*amounts
+	for {
+		start := p.pos
+		alternative, err := p.parseSequence()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos == start {
+			return nil, p.errorf("пустая группа в скобках")
+		}
+		alternatives = append(alternatives, alternative)
+
+		if p.pos == len(p.input) {
+			return nil, p.errorf("не закрыта скобка %q", closer)
+		}
+		r := p.input[p.pos]
+		p.pos++
+		if r == closer {
+			break
+		}
+		if r != ',' {
+			return nil, p.errorf("ожидается %q", closer)
+		}
+	}
+
+	if len(alternatives) > 1 {
+		p.approximate = true
+	}
+	group := newAmounts()
+	for _, alternative := range alternatives {
+		group.merge(alternative, 1/float64(len(alternatives)))
+	}
+	return group, nil
+}
+
+func (p *parser) parseSymbol() (string, error) {
+	start := p.pos
+	if p.pos+1 < len(p.input) && unicode.IsLower(p.input[p.pos+1]) {
+		symbol := string(p.input[p.pos : p.pos+2])
+		if _, ok := Lookup(symbol); ok {
+			p.pos += 2
+			return symbol, nil
+		}
+	}
+	symbol := string(p.input[p.pos])
+	_, ok := Lookup(symbol)
+	if !ok || (p.pos+1 < len(p.input) && unicode.IsLower(p.input[p.pos+1])) {
+		end := p.pos + 1
+		for end < len(p.input) && unicode.IsLower(p.input[end]) {
+			end++
+		}
+		return "", p.errorAt(start, "неизвестный элемент %q", string(p.input[start:end]))
+	}
+	p.pos++
+	return symbol, nil
+}
+
+func (p *parser) parseCount() (float64, error) {
+	if p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+		return p.parseNumber()
+	}
+	return 1, nil
+}
+
+// parseNumber reads a count or a multiplier; zero is rejected, variable amounts are written as n or x.
+func (p *parser) parseNumber() (float64, error) {
+	start := p.pos
+	for p.pos < len(p.input) && (isDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	number := string(p.input[start:p.pos])
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, p.errorAt(start, "некорректное число %q", number)
+	}
+	if value == 0 {
+		return 0, p.errorAt(start, "количество не может быть нулевым")
+	}
+	return value, nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return p.errorAt(p.pos, format, args...)
+}
+
+func (p *parser) errorAt(pos int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s (позиция %d)", ErrInvalidFormula, fmt.Sprintf(format, args...), pos+1)
+}
+
+func composition(formula string, total *amounts, approximate bool) *Composition {
+	result := &Composition{Formula: formula, Approximate: approximate, Elements: []models.ElementAmount{}}
+	for _, symbol := range total.order {
+		element, _ := Lookup(symbol)
+		result.MolarMass += total.counts[symbol] * element.Weight
+	}
+	for _, symbol := range total.order {
+		element, _ := Lookup(symbol)
+		amount := models.ElementAmount{Symbol: symbol, Count: round(total.counts[symbol], 6)}
+		if result.MolarMass > 0 {
+			amount.WeightPercent = round(total.counts[symbol]*element.Weight/result.MolarMass*100, 4)
+		}
+		result.Elements = append(result.Elements, amount)
+	}
+	result.MolarMass = round(result.MolarMass, 4)
+	return result
+}
+
+// normalizeFormula maps subscripts to digits and hydrate dots to "·", and drops whitespace and charges.
+func normalizeFormula(formula string) []rune {
+	var normalized []rune
+	runes := []rune(formula)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			continue
+		case r >= '₀' && r <= '₉':
+			normalized = append(normalized, '0'+(r-'₀'))
+		case strings.ContainsRune("⁰¹²³⁴⁵⁶⁷⁸⁹⁺⁻", r):
+			continue
+		case r == '^':
+			// A charge is digits followed by a sign ("^3+"); digits after the sign are the count.
+			for i+1 < len(runes) && isDigit(runes[i+1]) {
+				i++
+			}
+			if i+1 < len(runes) && (runes[i+1] == '+' || runes[i+1] == '-') {
+				i++
+			}
+		case strings.ContainsRune("•∙⋅*", r):
+			normalized = append(normalized, '·')
+		default:
+			normalized = append(normalized, r)
+		}
+	}
+	return normalized
+}
+
+func closingBracket(r rune) (rune, bool) {
+	switch r {
+	case '(':
+		return ')', true
+	case '[':
+		return ']', true
+	case '{':
+		return '}', true
+	}
+	return 0, false
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func round(value float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(value*scale) / scale
+}
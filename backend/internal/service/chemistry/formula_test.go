@@ -0,0 +1,212 @@
+package chemistry
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParseFormula(t *testing.T) {
+	tests := []struct {
+		name        string
+		formula     string
+		elements    map[string]float64
+		order       []string
+		molarMass   float64
+		approximate bool
+	}{
+		{
+			name:      "quartz",
+			formula:   "SiO2",
+			elements:  map[string]float64{"Si": 1, "O": 2},
+			order:     []string{"Si", "O"},
+			molarMass: 60.083,
+		},
+		{
+			name:      "subscript digits",
+			formula:   "SiO₂",
+			elements:  map[string]float64{"Si": 1, "O": 2},
+			order:     []string{"Si", "O"},
+			molarMass: 60.083,
+		},
+		{
+			name:      "gypsum hydrate",
+			formula:   "CaSO4·2H2O",
+			elements:  map[string]float64{"Ca": 1, "S": 1, "O": 6, "H": 4},
+			order:     []string{"Ca", "S", "O", "H"},
+			molarMass: 172.164,
+		},
+		{
+			name:      "hydrate with asterisk and spaces",
+			formula:   "CaSO4 * 2H2O",
+			elements:  map[string]float64{"Ca": 1, "S": 1, "O": 6, "H": 4},
+			order:     []string{"Ca", "S", "O", "H"},
+			molarMass: 172.164,
+		},
+		{
+			name:      "borax with hydrate water",
+			formula:   "Na2B4O5(OH)4·8H2O",
+			elements:  map[string]float64{"Na": 2, "B": 4, "O": 17, "H": 20},
+			order:     []string{"Na", "B", "O", "H"},
+			molarMass: 381.367,
+		},
+		{
+			name:        "olivine substitution site",
+			formula:     "(Mg,Fe)2SiO4",
+			elements:    map[string]float64{"Mg": 1, "Fe": 1, "Si": 1, "O": 4},
+			order:       []string{"Mg", "Fe", "Si", "O"},
+			molarMass:   172.237,
+			approximate: true,
+		},
+		{
+			name:        "apatite anion site",
+			formula:     "Ca5(PO4)3(F,Cl,OH)",
+			elements:    map[string]float64{"Ca": 5, "P": 3, "O": 12 + 1.0/3, "F": 1.0 / 3, "Cl": 1.0 / 3, "H": 1.0 / 3},
+			order:       []string{"Ca", "P", "O", "F", "Cl", "H"},
+			molarMass:   509.118,
+			approximate: true,
+		},
+		{
+			name:      "nested brackets",
+			formula:   "K{Al2[AlSi3O10](OH)2}",
+			elements:  map[string]float64{"K": 1, "Al": 3, "Si": 3, "O": 12, "H": 2},
+			order:     []string{"K", "Al", "Si", "O", "H"},
+			molarMass: 398.307,
+		},
+		{
+			name:        "variable water",
+			formula:     "Al2O3·nH2O",
+			elements:    map[string]float64{"Al": 2, "O": 3, "H": 0},
+			order:       []string{"Al", "O", "H"},
+			molarMass:   101.961,
+			approximate: true,
+		},
+		{
+			name:      "charges and vacancies are ignored",
+			formula:   "Fe²⁺Fe^3+2O4□",
+			elements:  map[string]float64{"Fe": 3, "O": 4},
+			order:     []string{"Fe", "O"},
+			molarMass: 231.531,
+		},
+		{
+			name:        "amphibole with a half-empty A site",
+			formula:     "(Na,□)2Ca2Mg5Si8O22(OH)2",
+			elements:    map[string]float64{"Na": 1, "Ca": 2, "Mg": 5, "Si": 8, "O": 24, "H": 2},
+			order:       []string{"Na", "Ca", "Mg", "Si", "O", "H"},
+			molarMass:   835.343,
+			approximate: true,
+		},
+		{
+			name:        "vacancy first in the site",
+			formula:     "(□,K)Al2(AlSi3O10)(OH)2",
+			elements:    map[string]float64{"K": 0.5, "Al": 3, "Si": 3, "O": 12, "H": 2},
+			order:       []string{"K", "Al", "Si", "O", "H"},
+			molarMass:   378.754,
+			approximate: true,
+		},
+		{
+			name:      "foitite with a vacant X site",
+			formula:   "□(Fe2Al)Al6(Si6O18)(BO3)3(OH)3(OH)",
+			elements:  map[string]float64{"Fe": 2, "Al": 7, "Si": 6, "O": 31, "B": 3, "H": 4},
+			order:     []string{"Fe", "Al", "Si", "O", "B", "H"},
+			molarMass: 1001.505,
+		},
+		{
+			name:      "decimal counts",
+			formula:   "Fe0.95S",
+			elements:  map[string]float64{"Fe": 0.95, "S": 1},
+			order:     []string{"Fe", "S"},
+			molarMass: 85.113,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			composition, err := ParseFormula(tt.formula)
+			if err != nil {
+				t.Fatalf("ParseFormula(%q): %v", tt.formula, err)
+			}
+			if got := composition.Symbols(); !equalStrings(got, tt.order) {
+				t.Errorf("symbols = %v, want %v", got, tt.order)
+			}
+			for _, element := range composition.Elements {
+				if want := tt.elements[element.Symbol]; math.Abs(element.Count-want) > 1e-6 {
+					t.Errorf("count of %s = %v, want %v", element.Symbol, element.Count, want)
+				}
+			}
+			if math.Abs(composition.MolarMass-tt.molarMass) > 0.01 {
+				t.Errorf("molar mass = %v, want %v", composition.MolarMass, tt.molarMass)
+			}
+			if composition.Approximate != tt.approximate {
+				t.Errorf("approximate = %v, want %v", composition.Approximate, tt.approximate)
+			}
+		})
+	}
+}
+
+func TestParseFormulaWeightPercent(t *testing.T) {
+	composition, err := ParseFormula("SiO2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0.0
+	for _, element := range composition.Elements {
+		total += element.WeightPercent
+	}
+	if math.Abs(composition.Elements[0].WeightPercent-46.744) > 0.01 {
+		t.Errorf("Si = %v wt%%, want 46.744", composition.Elements[0].WeightPercent)
+	}
+	if math.Abs(total-100) > 0.001 {
+		t.Errorf("weight percents add up to %v", total)
+	}
+}
+
+func TestParseFormulaErrors(t *testing.T) {
+	tests := []struct {
+		formula string
+		want    error
+	}{
+		{"", ErrEmptyFormula},
+		{"   ", ErrEmptyFormula},
+		{"□", ErrEmptyFormula},
+		{"SiO0", ErrInvalidFormula},
+		{"Si0O2", ErrInvalidFormula},
+		{"(Mg,Fe)0SiO4", ErrInvalidFormula},
+		{"CaSO4·0H2O", ErrInvalidFormula},
+		{"Fe0.0S", ErrInvalidFormula},
+		{"Fe1.2.3S", ErrInvalidFormula},
+		{"Xx2O", ErrInvalidFormula},
+		{"sio2", ErrInvalidFormula},
+		{"(Mg,Fe2SiO4", ErrInvalidFormula},
+		{"Mg,Fe)2SiO4", ErrInvalidFormula},
+		{"()SiO2", ErrInvalidFormula},
+		{"(Mg,)SiO4", ErrInvalidFormula},
+		{"(,□)SiO4", ErrInvalidFormula},
+		{"(Na,□0)AlSi3O8", ErrInvalidFormula},
+		{"(Mg]SiO4", ErrInvalidFormula},
+		{"CaSO4·", ErrInvalidFormula},
+		{"·H2O", ErrInvalidFormula},
+		{"SiO2-", ErrInvalidFormula},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.formula, func(t *testing.T) {
+			composition, err := ParseFormula(tt.formula)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ParseFormula(%q) = %+v, %v; want %v", tt.formula, composition, err, tt.want)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
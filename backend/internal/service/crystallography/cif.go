@@ -0,0 +1,415 @@
+// A reader for Crystallographic Information Files (CIF 1.1, with the dotted CIF 2 / DDLm tag names also accepted).
+// Takes the first data block and extracts the space group, unit cell, Z and the atom site loop.
+// Standard uncertainties in parentheses ("5.4307(3)") are dropped; "?" and "." mark unknown values.
+// The crystal system comes from the space group number or symbol, then from the declared cell setting, then from the cell metric.
+
+package crystallography
+
+import (
+	"backend/internal/models"
+	"backend/internal/service/chemistry"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const MaxAtomSites = 1000
+
+var ErrInvalidCIF = errors.New("некорректный CIF-файл")
+
+type cifToken struct {
+	value  string
+	quoted bool
+}
+
+type cifLoop struct {
+	tags []string
+	rows [][]string
+}
+
+// cifBlock holds the items and loops of one data block. Tags are lower-cased with dots replaced by underscores.
+type cifBlock struct {
+	name  string
+	items map[string]string
+	loops []cifLoop
+}
+
+// ParseCIF reads the first data block of a CIF file.
+func ParseCIF(data []byte) (*models.CrystalStructure, error) {
+	tokens, err := tokenizeCIF(string(data))
+	if err != nil {
+		return nil, err
+	}
+	block, err := readBlock(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return block.structure()
+}
+
+// tokenizeCIF splits the text into values, handling comments, quoted strings and semicolon-delimited text fields.
+func tokenizeCIF(text string) ([]cifToken, error) {
+	text = strings.TrimPrefix(text, "\uFEFF")
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var tokens []cifToken
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, ";") {
+			field := []string{strings.TrimSpace(line[1:])}
+			closed := false
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(lines[i], ";") {
+					closed = true
+					break
+				}
+				field = append(field, lines[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("%w: не закрыто текстовое поле", ErrInvalidCIF)
+			}
+			tokens = append(tokens, cifToken{value: strings.TrimSpace(strings.Join(field, "\n")), quoted: true})
+			continue
+		}
+
+		runes := []rune(line)
+		for pos := 0; pos < len(runes); {
+			r := runes[pos]
+			switch {
+			case unicode.IsSpace(r):
+				pos++
+			case r == '#':
+				pos = len(runes)
+			case r == '\'' || r == '"':
+				// A quote only closes the value when followed by whitespace or the end of the line.
+				end := pos + 1
+				for end < len(runes) && !(runes[end] == r && (end+1 == len(runes) || unicode.IsSpace(runes[end+1]))) {
+					end++
+				}
+				if end == len(runes) {
+					return nil, fmt.Errorf("%w: не закрыта кавычка в строке %d", ErrInvalidCIF, i+1)
+				}
+				tokens = append(tokens, cifToken{value: string(runes[pos+1 : end]), quoted: true})
+				pos = end + 1
+			default:
+				end := pos
+				for end < len(runes) && !unicode.IsSpace(runes[end]) {
+					end++
+				}
+				tokens = append(tokens, cifToken{value: string(runes[pos:end])})
+				pos = end
+			}
+		}
+	}
+	return tokens, nil
+}
+
+func readBlock(tokens []cifToken) (*cifBlock, error) {
+	var block *cifBlock
+	for pos := 0; pos < len(tokens); {
+		token := tokens[pos]
+		keyword := strings.ToLower(token.value)
+		switch {
+		case !token.quoted && strings.HasPrefix(keyword, "data_"):
+			if block != nil {
+				return block, nil
+			}
+			block = &cifBlock{name: token.value[len("data_"):], items: map[string]string{}}
+			pos++
+		case block == nil:
+			// Anything before the first data block, such as a global_ section, is skipped.
+			pos++
+		case !token.quoted && keyword == "loop_":
+			loop, next, err := readLoop(tokens, pos+1)
+			if err != nil {
+				return nil, err
+			}
+			block.loops = append(block.loops, loop)
+			pos = next
+		case !token.quoted && strings.HasPrefix(keyword, "save_"):
+			pos++
+		case isTag(token):
+			if pos+1 == len(tokens) || isTag(tokens[pos+1]) || isReserved(tokens[pos+1]) {
+				return nil, fmt.Errorf("%w: нет значения для %s", ErrInvalidCIF, token.value)
+			}
+			block.items[normalizeTag(token.value)] = tokens[pos+1].value
+			pos += 2
+		default:
+			return nil, fmt.Errorf("%w: неожиданное значение %q", ErrInvalidCIF, token.value)
+		}
+	}
+	if block == nil {
+		return nil, fmt.Errorf("%w: не найден блок data_", ErrInvalidCIF)
+	}
+	return block, nil
+}
+
+func readLoop(tokens []cifToken, pos int) (cifLoop, int, error) {
+	var loop cifLoop
+	for pos < len(tokens) && isTag(tokens[pos]) {
+		loop.tags = append(loop.tags, normalizeTag(tokens[pos].value))
+		pos++
+	}
+	if len(loop.tags) == 0 {
+		return loop, pos, fmt.Errorf("%w: loop_ без имен столбцов", ErrInvalidCIF)
+	}
+
+	var values []string
+	for pos < len(tokens) && !isTag(tokens[pos]) && !isReserved(tokens[pos]) {
+		values = append(values, tokens[pos].value)
+		pos++
+	}
+	if len(values)%len(loop.tags) != 0 {
+		return loop, pos, fmt.Errorf("%w: число значений в loop_ (%s) не кратно числу столбцов",
+			ErrInvalidCIF, loop.tags[0])
+	}
+	for i := 0; i < len(values); i += len(loop.tags) {
+		loop.rows = append(loop.rows, values[i:i+len(loop.tags)])
+	}
+	return loop, pos, nil
+}
+
+func isTag(token cifToken) bool {
+	return !token.quoted && strings.HasPrefix(token.value, "_")
+}
+
+func isReserved(token cifToken) bool {
+	if token.quoted {
+		return false
+	}
+	keyword := strings.ToLower(token.value)
+	return keyword == "loop_" || keyword == "stop_" || keyword == "global_" ||
+		strings.HasPrefix(keyword, "data_") || strings.HasPrefix(keyword, "save_")
+}
+
+func normalizeTag(tag string) string {
+	return strings.ReplaceAll(strings.ToLower(tag), ".", "_")
+}
+
+// item returns the first known value among the alternative tag names.
+func (b *cifBlock) item(tags ...string) (string, bool) {
+	for _, tag := range tags {
+		if value, ok := b.items[tag]; ok && !isUnknown(value) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// loop finds the loop that contains the tag.
+func (b *cifBlock) loop(tag string) (*cifLoop, bool) {
+	for i := range b.loops {
+		if b.loops[i].column(tag) >= 0 {
+			return &b.loops[i], true
+		}
+	}
+	return nil, false
+}
+
+func (l *cifLoop) column(tag string) int {
+	for i, t := range l.tags {
+		if t == tag {
+			return i
+		}
+	}
+	return -1
+}
+
+func (b *cifBlock) structure() (*models.CrystalStructure, error) {
+	s := &models.CrystalStructure{DataBlock: b.name, AtomSites: []models.AtomSite{}}
+
+	if symbol, ok := b.item("_space_group_name_h-m_alt", "_symmetry_space_group_name_h-m"); ok {
+		s.SpaceGroup = strings.Join(strings.Fields(symbol), " ")
+	}
+	if raw, ok := b.item("_space_group_it_number", "_symmetry_int_tables_number"); ok {
+		number, err := strconv.Atoi(raw)
+		if err != nil || number < 1 || number > 230 {
+			return nil, fmt.Errorf("%w: номер пространственной группы должен быть от 1 до 230", ErrInvalidCIF)
+		}
+		s.SpaceGroupNumber = &number
+	}
+	if s.SpaceGroup == "" && s.SpaceGroupNumber == nil {
+		return nil, fmt.Errorf("%w: не указана пространственная группа", ErrInvalidCIF)
+	}
+
+	cell := []struct {
+		tag   string
+		value *float64
+	}{
+		{"_cell_length_a", &s.Cell.A},
+		{"_cell_length_b", &s.Cell.B},
+		{"_cell_length_c", &s.Cell.C},
+		{"_cell_angle_alpha", &s.Cell.Alpha},
+		{"_cell_angle_beta", &s.Cell.Beta},
+		{"_cell_angle_gamma", &s.Cell.Gamma},
+	}
+	for _, parameter := range cell {
+		raw, ok := b.item(parameter.tag)
+		if !ok {
+			return nil, fmt.Errorf("%w: не указан параметр ячейки %s", ErrInvalidCIF, parameter.tag)
+		}
+		value, err := parseNumber(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidCIF, parameter.tag, err)
+		}
+		*parameter.value = value
+	}
+	if err := validateCell(s.Cell); err != nil {
+		return nil, err
+	}
+	s.Cell.Volume = round(CellVolume(s.Cell), 4)
+	if raw, ok := b.item("_cell_volume"); ok {
+		if volume, err := parseNumber(raw); err == nil && volume > 0 {
+			s.Cell.Volume = volume
+		}
+	}
+
+	if raw, ok := b.item("_cell_formula_units_z"); ok {
+		z, err := strconv.Atoi(raw)
+		if err != nil || z < 1 {
+			return nil, fmt.Errorf("%w: Z должно быть положительным целым числом", ErrInvalidCIF)
+		}
+		s.Z = &z
+	}
+
+	if err := b.detectSystem(s); err != nil {
+		return nil, err
+	}
+
+	sites, err := b.atomSites()
+	if err != nil {
+		return nil, err
+	}
+	s.AtomSites = sites
+	return s, nil
+}
+
+// detectSystem sets the crystal system and checks that the cell metric agrees with it.
+func (b *cifBlock) detectSystem(s *models.CrystalStructure) error {
+	if s.SpaceGroupNumber != nil {
+		s.CrystalSystem, _ = SystemForSpaceGroup(*s.SpaceGroupNumber)
+	} else if system, ok := SystemForSymbol(s.SpaceGroup); ok {
+		s.CrystalSystem = system
+	} else if setting, ok := b.item("_space_group_crystal_system", "_symmetry_cell_setting"); ok {
+		if system, ok := systemFromSetting(strings.ToLower(setting)); ok {
+			s.CrystalSystem = system
+		}
+	}
+	if s.CrystalSystem == "" {
+		s.CrystalSystem = MetricSystem(s.Cell)
+		return nil
+	}
+	if !Fits(s.Cell, s.CrystalSystem) {
+		return fmt.Errorf("%w: параметры ячейки не соответствуют сингонии %s", ErrInvalidCIF, s.CrystalSystem)
+	}
+	return nil
+}
+
+func (b *cifBlock) atomSites() ([]models.AtomSite, error) {
+	loop, ok := b.loop("_atom_site_fract_x")
+	if !ok {
+		return []models.AtomSite{}, nil
+	}
+	if len(loop.rows) > MaxAtomSites {
+		return nil, fmt.Errorf("%w: больше %d позиций атомов", ErrInvalidCIF, MaxAtomSites)
+	}
+
+	columns := map[string]int{}
+	for _, tag := range []string{"label", "type_symbol", "fract_x", "fract_y", "fract_z", "occupancy", "u_iso_or_equiv"} {
+		columns[tag] = loop.column("_atom_site_" + tag)
+	}
+	if columns["fract_y"] < 0 || columns["fract_z"] < 0 {
+		return nil, fmt.Errorf("%w: в таблице атомов нет координат y или z", ErrInvalidCIF)
+	}
+	value := func(row []string, tag string) (string, bool) {
+		if i := columns[tag]; i >= 0 && !isUnknown(row[i]) {
+			return row[i], true
+		}
+		return "", false
+	}
+
+	sites := make([]models.AtomSite, 0, len(loop.rows))
+	for n, row := range loop.rows {
+		site := models.AtomSite{Occupancy: 1}
+		site.Label, _ = value(row, "label")
+		site.TypeSymbol, _ = value(row, "type_symbol")
+		if site.Label == "" {
+			site.Label = fmt.Sprintf("%s%d", site.TypeSymbol, n+1)
+		}
+		site.Element = siteElement(site.TypeSymbol, site.Label)
+
+		coordinates := []struct {
+			tag   string
+			value *float64
+		}{{"fract_x", &site.X}, {"fract_y", &site.Y}, {"fract_z", &site.Z}}
+		for _, coordinate := range coordinates {
+			raw, ok := value(row, coordinate.tag)
+			if !ok {
+				return nil, fmt.Errorf("%w: у атома %s нет координаты %s", ErrInvalidCIF, site.Label, coordinate.tag)
+			}
+			parsed, err := parseNumber(raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: атом %s: %v", ErrInvalidCIF, site.Label, err)
+			}
+			*coordinate.value = parsed
+		}
+		if raw, ok := value(row, "occupancy"); ok {
+			occupancy, err := parseNumber(raw)
+			if err != nil || occupancy < 0 || occupancy > 1 {
+				return nil, fmt.Errorf("%w: заселенность атома %s должна быть от 0 до 1", ErrInvalidCIF, site.Label)
+			}
+			site.Occupancy = occupancy
+		}
+		if raw, ok := value(row, "u_iso_or_equiv"); ok {
+			if uIso, err := parseNumber(raw); err == nil {
+				site.UIso = &uIso
+			}
+		}
+		sites = append(sites, site)
+	}
+	return sites, nil
+}
+
+// siteElement takes the element from the type symbol ("Fe2+") or, failing that, from the label ("Fe1").
+func siteElement(typeSymbol, label string) string {
+	for _, source := range []string{typeSymbol, label} {
+		letters := strings.IndexFunc(source, func(r rune) bool { return !unicode.IsLetter(r) })
+		if letters < 0 {
+			letters = len(source)
+		}
+		for _, length := range []int{2, 1} {
+			if letters < length {
+				continue
+			}
+			candidate := strings.ToUpper(source[:1]) + strings.ToLower(source[1:length])
+			if _, ok := chemistry.Lookup(candidate); ok {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// parseNumber reads a CIF number, dropping the standard uncertainty in parentheses.
+func parseNumber(raw string) (float64, error) {
+	if i := strings.IndexByte(raw, '('); i >= 0 {
+		raw = raw[:i]
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("ожидается число, получено %q", raw)
+	}
+	return value, nil
+}
+
+func isUnknown(value string) bool {
+	return value == "?" || value == "."
+}
+
+func round(value float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(value*scale) / scale
+}
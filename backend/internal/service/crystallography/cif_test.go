@@ -0,0 +1,305 @@
+package crystallography
+
+import (
+	"backend/internal/models"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// quartzCIF is the AMCSD entry 0000789 for quartz (Levien, Prewitt and Weidner, 1980) as the database publishes it:
+// a data_global block, the space group given only by its symbol and an anisotropic loop after the atom sites.
+const quartzCIF = `data_global
+_chemical_name_mineral 'Quartz'
+loop_
+_publ_author_name
+'Levien L'
+'Prewitt C T'
+'Weidner D J'
+_journal_name_full 'American Mineralogist'
+_journal_volume 65
+_journal_year 1980
+_journal_page_first 920
+_journal_page_last 930
+_publ_section_title
+;
+Structure and elastic properties of quartz at pressure
+P = 1 atm
+;
+_database_code_amcsd 0000789
+_chemical_formula_sum 'Si O2'
+_cell_length_a 4.916
+_cell_length_b 4.916
+_cell_length_c 5.4054
+_cell_angle_alpha 90
+_cell_angle_beta 90
+_cell_angle_gamma 120
+_cell_volume 113.131
+_exptl_crystal_density_diffrn 2.646
+_symmetry_space_group_name_H-M 'P 32 2 1'
+loop_
+_space_group_symop_operation_xyz
+  'x,y,z'
+  'y,x,2/3-z'
+  '-y,x-y,2/3+z'
+  '-x,-x+y,1/3-z'
+  '-x+y,-x,1/3+z'
+  'x-y,-y,-z'
+loop_
+_atom_site_label
+_atom_site_fract_x
+_atom_site_fract_y
+_atom_site_fract_z
+Si   0.46970   0.00000   0.00000
+O   0.41350   0.26690   0.11910
+loop_
+_atom_site_aniso_label
+_atom_site_aniso_U_11
+_atom_site_aniso_U_22
+_atom_site_aniso_U_33
+_atom_site_aniso_U_12
+_atom_site_aniso_U_13
+_atom_site_aniso_U_23
+Si 0.00660 0.00520 0.00590 0.00260 -0.00010 -0.00020
+O  0.01360 0.00990 0.01100 0.00740 -0.00140 -0.00260
+`
+
+// haliteCIF uses the dotted CIF 2 tag names, Windows line endings, a byte order mark, standard uncertainties,
+// unknown values and a text field and quoted value that look like CIF syntax.
+const haliteCIF = "\uFEFF# written by hand\r\n" +
+	"data_halite\r\n" +
+	"_publ_section_comment\r\n" +
+	";\r\n" +
+	"loop_ and _cell.length_a inside a text field are plain text\r\n" +
+	"data_not_a_block\r\n" +
+	";\r\n" +
+	"_journal_coeditor_name 'O'Brien, M' # a quote inside a word does not close the value\r\n" +
+	"_space_group.IT_number 225\r\n" +
+	"_space_group.name_H-M_alt 'F m -3 m'\r\n" +
+	"_cell.length_a 5.6402(5)\r\n" +
+	"_cell.length_b 5.6402(5)\r\n" +
+	"_cell.length_c 5.6402(5)\r\n" +
+	"_cell.angle_alpha 90\r\n" +
+	"_cell.angle_beta 90\r\n" +
+	"_cell.angle_gamma 90\r\n" +
+	"_cell.volume ?\r\n" +
+	"_cell.formula_units_Z 4\r\n" +
+	"loop_\r\n" +
+	"_atom_site.label\r\n" +
+	"_atom_site.type_symbol\r\n" +
+	"_atom_site.fract_x\r\n" +
+	"_atom_site.fract_y\r\n" +
+	"_atom_site.fract_z\r\n" +
+	"_atom_site.occupancy\r\n" +
+	"_atom_site.U_iso_or_equiv\r\n" +
+	"Na1 Na+ 0 0 0 1.0 0.0123(4)\r\n" +
+	"Cl1 Cl- 0.5 0.5 0.5 . ?\r\n" +
+	"data_second\r\n" +
+	"_cell.length_a 1\r\n"
+
+func TestParseCIFQuartz(t *testing.T) {
+	s, err := ParseCIF([]byte(quartzCIF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DataBlock != "global" || s.SpaceGroup != "P 32 2 1" || s.SpaceGroupNumber != nil || s.Z != nil {
+		t.Errorf("block %q, space group %q (%v), Z %v", s.DataBlock, s.SpaceGroup, s.SpaceGroupNumber, s.Z)
+	}
+	if s.CrystalSystem != models.Trigonal {
+		t.Errorf("crystal system = %s, want trigonal", s.CrystalSystem)
+	}
+	want := models.UnitCell{A: 4.916, B: 4.916, C: 5.4054, Alpha: 90, Beta: 90, Gamma: 120, Volume: 113.131}
+	if s.Cell != want {
+		t.Errorf("cell = %+v, want %+v", s.Cell, want)
+	}
+	if len(s.AtomSites) != 2 {
+		t.Fatalf("atom sites = %+v", s.AtomSites)
+	}
+	si, o := s.AtomSites[0], s.AtomSites[1]
+	if si.Label != "Si" || si.Element != "Si" || si.X != 0.4697 || si.Occupancy != 1 || si.UIso != nil {
+		t.Errorf("Si site = %+v", si)
+	}
+	if o.Label != "O" || o.Element != "O" || o.X != 0.4135 || o.Y != 0.2669 || o.Z != 0.1191 {
+		t.Errorf("O site = %+v", o)
+	}
+}
+
+func TestParseCIFHalite(t *testing.T) {
+	s, err := ParseCIF([]byte(haliteCIF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.DataBlock != "halite" || s.SpaceGroup != "F m -3 m" || s.SpaceGroupNumber == nil || *s.SpaceGroupNumber != 225 {
+		t.Errorf("block %q, space group %q (%v)", s.DataBlock, s.SpaceGroup, s.SpaceGroupNumber)
+	}
+	if s.CrystalSystem != models.Cubic || s.Z == nil || *s.Z != 4 {
+		t.Errorf("crystal system %s, Z %v", s.CrystalSystem, s.Z)
+	}
+	if s.Cell.A != 5.6402 || s.Cell.C != 5.6402 {
+		t.Errorf("cell = %+v", s.Cell)
+	}
+	if math.Abs(s.Cell.Volume-179.4252) > 1e-3 {
+		t.Errorf("calculated volume = %v, want 179.4252", s.Cell.Volume)
+	}
+	if len(s.AtomSites) != 2 {
+		t.Fatalf("atom sites = %+v", s.AtomSites)
+	}
+	na, cl := s.AtomSites[0], s.AtomSites[1]
+	if na.Element != "Na" || na.TypeSymbol != "Na+" || na.UIso == nil || *na.UIso != 0.0123 {
+		t.Errorf("Na site = %+v", na)
+	}
+	if cl.Element != "Cl" || cl.X != 0.5 || cl.Occupancy != 1 || cl.UIso != nil {
+		t.Errorf("Cl site = %+v", cl)
+	}
+}
+
+func TestTokenizeCIF(t *testing.T) {
+	text := "_a 'it''s here' \"x y\" plain # comment\n" +
+		";first line\n" +
+		"_not_a_tag 'still text'\n" +
+		";\n" +
+		"'O'Brien'\n"
+	tokens, err := tokenizeCIF(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []cifToken{
+		{value: "_a"},
+		{value: "it''s here", quoted: true},
+		{value: "x y", quoted: true},
+		{value: "plain"},
+		{value: "first line\n_not_a_tag 'still text'", quoted: true},
+		{value: "O'Brien", quoted: true},
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("tokens = %+v, want %+v", tokens, want)
+	}
+	for i := range want {
+		if tokens[i] != want[i] {
+			t.Errorf("token %d = %+v, want %+v", i, tokens[i], want[i])
+		}
+	}
+}
+
+func TestParseCIFSystem(t *testing.T) {
+	cell := func(a, b, c, alpha, beta, gamma string) string {
+		return "_cell_length_a " + a + "\n_cell_length_b " + b + "\n_cell_length_c " + c +
+			"\n_cell_angle_alpha " + alpha + "\n_cell_angle_beta " + beta + "\n_cell_angle_gamma " + gamma + "\n"
+	}
+	tests := []struct {
+		name string
+		cif  string
+		want string
+	}{
+		{
+			name: "gypsum by full monoclinic symbol",
+			cif:  "data_gypsum\n_symmetry_space_group_name_H-M 'I 1 2/a 1'\n" + cell("5.679", "15.202", "6.522", "90", "118.43", "90"),
+			want: models.Monoclinic,
+		},
+		{
+			name: "calcite in the rhombohedral setting by number",
+			cif:  "data_calcite\n_symmetry_Int_Tables_number 167\n" + cell("6.375", "6.375", "6.375", "46.08", "46.08", "46.08"),
+			want: models.Trigonal,
+		},
+		{
+			name: "legacy cell setting",
+			cif:  "data_x\n_symmetry_space_group_name_H-M P3_221\n_symmetry_cell_setting rhombohedral\n" + cell("4.916", "4.916", "5.4054", "90", "90", "120"),
+			want: models.Trigonal,
+		},
+		{
+			name: "cell metric for a compact symbol",
+			cif:  "data_x\n_symmetry_space_group_name_H-M P6_3/mmc\n" + cell("2.464", "2.464", "6.711", "90", "90", "120"),
+			want: models.Hexagonal,
+		},
+		{
+			name: "orthorhombic metric",
+			cif:  "data_x\n_space_group_name_H-M_alt Pnma\n" + cell("4.756", "10.207", "5.980", "90", "90", "90"),
+			want: models.Orthorhombic,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCIF([]byte(tt.cif))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.CrystalSystem != tt.want {
+				t.Errorf("crystal system = %s, want %s", s.CrystalSystem, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCIFErrors(t *testing.T) {
+	const cell = "_cell_length_a 5\n_cell_length_b 5\n_cell_length_c 5\n" +
+		"_cell_angle_alpha 90\n_cell_angle_beta 90\n_cell_angle_gamma 90\n"
+	tests := []struct {
+		name    string
+		cif     string
+		message string
+	}{
+		{"no data block", "_cell_length_a 5\n", "data_"},
+		{"unclosed text field", "data_x\n_publ_section_title\n;\nQuartz\n", "текстовое поле"},
+		{"unclosed quote", "data_x\n_chemical_name_mineral 'Quartz\n", "кавычка"},
+		{"tag without value", "data_x\n_chemical_name_mineral\n_cell_length_a 5\n", "нет значения"},
+		{"loop without tags", "data_x\nloop_\n1 2\n", "без имен"},
+		{"ragged loop", "data_x\n_space_group_IT_number 221\n" + cell + "loop_\n_atom_site_label\n_atom_site_fract_x\nNa 0 0\n", "не кратно"},
+		{"no space group", "data_x\n" + cell, "пространственная группа"},
+		{"space group number out of range", "data_x\n_space_group_IT_number 231\n" + cell, "от 1 до 230"},
+		{"missing cell length", "data_x\n_space_group_IT_number 221\n_cell_length_a 5\n", "_cell_length_b"},
+		{"cell value is not a number", "data_x\n_space_group_IT_number 221\n" + strings.Replace(cell, "_cell_length_c 5", "_cell_length_c five", 1), "ожидается число"},
+		{"impossible angles", "data_x\n_space_group_IT_number 1\n" + strings.NewReplacer("alpha 90", "alpha 150", "beta 90", "beta 150").Replace(cell), "параллелепипед"},
+		{"cell does not fit the space group", "data_x\n_space_group_IT_number 225\n" + strings.Replace(cell, "_cell_length_c 5", "_cell_length_c 6", 1), "сингонии cubic"},
+		{"zero Z", "data_x\n_space_group_IT_number 221\n" + cell + "_cell_formula_units_Z 0\n", "Z"},
+		{"atom without y and z", "data_x\n_space_group_IT_number 221\n" + cell + "loop_\n_atom_site_label\n_atom_site_fract_x\nNa 0\n", "y или z"},
+		{"unknown coordinate", "data_x\n_space_group_IT_number 221\n" + cell + "loop_\n_atom_site_label\n_atom_site_fract_x\n_atom_site_fract_y\n_atom_site_fract_z\nNa 0 0 ?\n", "fract_z"},
+		{"occupancy above 1", "data_x\n_space_group_IT_number 221\n" + cell + "loop_\n_atom_site_label\n_atom_site_fract_x\n_atom_site_fract_y\n_atom_site_fract_z\n_atom_site_occupancy\nNa 0 0 0 1.5\n", "заселенность"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCIF([]byte(tt.cif))
+			if !errors.Is(err, ErrInvalidCIF) {
+				t.Fatalf("ParseCIF = %+v, %v; want ErrInvalidCIF", s, err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q does not mention %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestSystemForSymbol(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   string
+	}{
+		{"P 1", models.Triclinic},
+		{"P -1", models.Triclinic},
+		{"P 21/c", models.Monoclinic},
+		{"C 1 2/c 1", models.Monoclinic},
+		{"P 21 21 21", models.Orthorhombic},
+		{"P n m a", models.Orthorhombic},
+		{"I 41/a m d", models.Tetragonal},
+		{"P -4 21 m", models.Tetragonal},
+		{"P 31 2 1", models.Trigonal},
+		{"R -3 c", models.Trigonal},
+		{"P -3 m 1", models.Trigonal},
+		{"P 63/m m c", models.Hexagonal},
+		{"P -6 2 m", models.Hexagonal},
+		{"F d -3 m", models.Cubic},
+		{"P 21 3", models.Cubic},
+		{"I 41 3 2", models.Cubic},
+		{"I a -3 d", models.Cubic},
+	}
+	for _, tt := range tests {
+		if got, ok := SystemForSymbol(tt.symbol); !ok || got != tt.want {
+			t.Errorf("SystemForSymbol(%q) = %q, %v; want %q", tt.symbol, got, ok, tt.want)
+		}
+	}
+	for _, symbol := range []string{"", "Fd-3m", "P6_3/mmc", "Pnma", "P 1 1", "P - m"} {
+		if got, ok := SystemForSymbol(symbol); ok {
+			t.Errorf("SystemForSymbol(%q) = %q, want no result", symbol, got)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Crystal systems and unit cells: how a system follows from the space group number or symbol
+// and the metric constraints a unit cell has to satisfy in each system.
+// Lengths are in ångströms, angles in degrees.
+
+package crystallography
+
+import (
+	"backend/internal/models"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Relative tolerance for equal lengths and absolute tolerance in degrees for angles.
+const (
+	lengthTolerance = 1e-3
+	angleTolerance  = 0.01
+)
+
+// SystemForSpaceGroup maps an International Tables space group number (1-230) to its crystal system.
+func SystemForSpaceGroup(number int) (string, bool) {
+	switch {
+	case number < 1 || number > 230:
+		return "", false
+	case number <= 2:
+		return models.Triclinic, true
+	case number <= 15:
+		return models.Monoclinic, true
+	case number <= 74:
+		return models.Orthorhombic, true
+	case number <= 142:
+		return models.Tetragonal, true
+	case number <= 167:
+		return models.Trigonal, true
+	case number <= 194:
+		return models.Hexagonal, true
+	default:
+		return models.Cubic, true
+	}
+}
+
+// SystemForSymbol derives the crystal system from a Hermann–Mauguin symbol written with spaces between the
+// lattice and the symmetry directions ("P 32 2 1", "F d -3 m", "P 1 21/c 1"). Compact symbols are not recognised.
+func SystemForSymbol(symbol string) (string, bool) {
+	fields := strings.Fields(symbol)
+	if len(fields) < 2 || len(fields[0]) != 1 {
+		return "", false
+	}
+	if fields[0] == "R" {
+		return models.Trigonal, true
+	}
+	axis := func(i int) byte {
+		if i >= len(fields) || strings.TrimPrefix(fields[i], "-") == "" {
+			return 0
+		}
+		return strings.TrimPrefix(fields[i], "-")[0]
+	}
+	// Only cubic groups have a threefold axis along the second direction ("P 21 3", "I 41 3 2").
+	if axis(2) == '3' {
+		return models.Cubic, true
+	}
+	switch axis(1) {
+	case '6':
+		return models.Hexagonal, true
+	case '3':
+		return models.Trigonal, true
+	case '4':
+		return models.Tetragonal, true
+	}
+	switch len(fields) {
+	case 2:
+		if fields[1] == "1" || fields[1] == "-1" {
+			return models.Triclinic, true
+		}
+		return models.Monoclinic, true
+	case 4:
+		ones := 0
+		for _, field := range fields[1:] {
+			if field == "1" {
+				ones++
+			}
+		}
+		switch ones {
+		case 0:
+			return models.Orthorhombic, true
+		case 2:
+			return models.Monoclinic, true
+		}
+	}
+	return "", false
+}
+
+// systemFromSetting reads the legacy _symmetry_cell_setting and _space_group_crystal_system values.
+func systemFromSetting(setting string) (string, bool) {
+	if setting == "rhombohedral" {
+		return models.Trigonal, true
+	}
+	return setting, models.IsCrystalSystem(setting)
+}
+
+// validateCell checks that the lengths are positive and the angles describe a real parallelepiped.
+func validateCell(c models.UnitCell) error {
+	if c.A <= 0 || c.B <= 0 || c.C <= 0 {
+		return fmt.Errorf("%w: длины ребер ячейки должны быть положительными", ErrInvalidCIF)
+	}
+	for _, angle := range []float64{c.Alpha, c.Beta, c.Gamma} {
+		if angle <= 0 || angle >= 180 {
+			return fmt.Errorf("%w: углы ячейки должны быть в интервале (0, 180)", ErrInvalidCIF)
+		}
+	}
+	if volumeFactor(c) <= 0 {
+		return fmt.Errorf("%w: углы ячейки не образуют параллелепипед", ErrInvalidCIF)
+	}
+	return nil
+}
+
+// CellVolume computes the cell volume from its parameters.
+func CellVolume(c models.UnitCell) float64 {
+	return c.A * c.B * c.C * math.Sqrt(volumeFactor(c))
+}
+
+func volumeFactor(c models.UnitCell) float64 {
+	ca, cb, cg := cosDeg(c.Alpha), cosDeg(c.Beta), cosDeg(c.Gamma)
+	return 1 - ca*ca - cb*cb - cg*cg + 2*ca*cb*cg
+}
+
+// Fits reports whether the cell has the metric required by the crystal system.
+// Trigonal cells are accepted both in the hexagonal and in the rhombohedral setting.
+func Fits(c models.UnitCell, system string) bool {
+	right := func(angle float64) bool { return sameAngle(angle, 90) }
+	allRight := right(c.Alpha) && right(c.Beta) && right(c.Gamma)
+	hexagonalAxes := sameLength(c.A, c.B) && right(c.Alpha) && right(c.Beta) && sameAngle(c.Gamma, 120)
+
+	switch system {
+	case models.Triclinic:
+		return true
+	case models.Monoclinic:
+		rightAngles := 0
+		for _, angle := range []float64{c.Alpha, c.Beta, c.Gamma} {
+			if right(angle) {
+				rightAngles++
+			}
+		}
+		return rightAngles >= 2
+	case models.Orthorhombic:
+		return allRight
+	case models.Tetragonal:
+		return allRight && sameLength(c.A, c.B)
+	case models.Trigonal:
+		rhombohedral := sameLength(c.A, c.B) && sameLength(c.B, c.C) &&
+			sameAngle(c.Alpha, c.Beta) && sameAngle(c.Beta, c.Gamma)
+		return hexagonalAxes || rhombohedral
+	case models.Hexagonal:
+		return hexagonalAxes
+	case models.Cubic:
+		return allRight && sameLength(c.A, c.B) && sameLength(c.B, c.C)
+	}
+	return false
+}
+
+// MetricSystem infers the highest crystal system the cell metric allows. Hexagonal is checked before trigonal,
+// since the metric alone cannot tell them apart in the hexagonal setting.
+func MetricSystem(c models.UnitCell) string {
+	for i := len(models.CrystalSystems) - 1; i >= 0; i-- {
+		if Fits(c, models.CrystalSystems[i]) {
+			return models.CrystalSystems[i]
+		}
+	}
+	return models.Triclinic
+}
+
+func sameLength(x, y float64) bool {
+	return math.Abs(x-y) <= lengthTolerance*math.Max(x, y)
+}
+
+func sameAngle(x, y float64) bool {
+	return math.Abs(x-y) <= angleTolerance
+}
+
+func cosDeg(angle float64) float64 {
+	return math.Cos(angle * math.Pi / 180)
+}
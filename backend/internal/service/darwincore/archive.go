@@ -0,0 +1,149 @@
+// Darwin Core Archive of the collection: a ZIP with meta.xml describing the columns and occurrence.txt with one row per specimen.
+// Only the specimens of minerals visitors can see are written. Specimens are read in batches and written as they arrive,
+// and meta.xml comes first because the columns are known from the mapping before any record is read.
+
+package darwincore
+
+import (
+	"archive/zip"
+	"backend/internal/database"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const (
+	TextNamespace     = "http://rs.tdwg.org/dwc/text/"
+	OccurrenceRowType = DwCNamespace + "Occurrence"
+	MetaName          = "meta.xml"
+	OccurrenceName    = "occurrence.txt"
+)
+
+type Publisher struct {
+	db      *database.Database
+	mapping *Mapping
+}
+
+func NewPublisher(db *database.Database, mapping *Mapping) *Publisher {
+	return &Publisher{db: db, mapping: mapping}
+}
+
+func (p *Publisher) Mapping() *Mapping {
+	return p.mapping
+}
+
+type metaArchive struct {
+	XMLName xml.Name `xml:"archive"`
+	Xmlns   string   `xml:"xmlns,attr"`
+	Core    metaCore `xml:"core"`
+}
+
+type metaCore struct {
+	Encoding           string      `xml:"encoding,attr"`
+	FieldsTerminatedBy string      `xml:"fieldsTerminatedBy,attr"`
+	LinesTerminatedBy  string      `xml:"linesTerminatedBy,attr"`
+	FieldsEnclosedBy   string      `xml:"fieldsEnclosedBy,attr"`
+	IgnoreHeaderLines  int         `xml:"ignoreHeaderLines,attr"`
+	RowType            string      `xml:"rowType,attr"`
+	Location           string      `xml:"files>location"`
+	ID                 metaIndex   `xml:"id"`
+	Fields             []metaField `xml:"field"`
+}
+
+type metaIndex struct {
+	Index int `xml:"index,attr"`
+}
+
+type metaField struct {
+	Index int    `xml:"index,attr"`
+	Term  string `xml:"term,attr"`
+}
+
+// Meta returns the meta.xml of the archive for the mapping.
+func (m *Mapping) Meta() ([]byte, error) {
+	meta := metaArchive{
+		Xmlns: TextNamespace,
+		Core: metaCore{
+			Encoding:           "UTF-8",
+			FieldsTerminatedBy: ",",
+			LinesTerminatedBy:  `\n`,
+			FieldsEnclosedBy:   `"`,
+			IgnoreHeaderLines:  1,
+			RowType:            OccurrenceRowType,
+			Location:           OccurrenceName,
+			ID:                 metaIndex{Index: m.IDIndex()},
+		},
+	}
+	for i, term := range m.Terms() {
+		meta.Core.Fields = append(meta.Core.Fields, metaField{Index: i, Term: term.IRI()})
+	}
+	data, err := xml.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// WriteArchive writes the archive and returns the number of specimens in it.
+func (p *Publisher) WriteArchive(w io.Writer) (int, error) {
+	archive := zip.NewWriter(w)
+	now := time.Now()
+
+	meta, err := p.mapping.Meta()
+	if err != nil {
+		return 0, err
+	}
+	entry, err := archive.CreateHeader(&zip.FileHeader{Name: MetaName, Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := entry.Write(meta); err != nil {
+		return 0, err
+	}
+
+	entry, err = archive.CreateHeader(&zip.FileHeader{Name: OccurrenceName, Method: zip.Deflate, Modified: now})
+	if err != nil {
+		return 0, err
+	}
+	count, err := p.WriteOccurrences(entry)
+	if err != nil {
+		return count, err
+	}
+	return count, archive.Close()
+}
+
+// WriteOccurrences writes occurrence.txt: a header row with the term names and a row per visible specimen.
+func (p *Publisher) WriteOccurrences(w io.Writer) (int, error) {
+	writer := csv.NewWriter(w)
+	header := make([]string, len(p.mapping.Terms()))
+	for i, term := range p.mapping.Terms() {
+		header[i] = term.Name
+	}
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	filter := database.OccurrenceFilter{Limit: database.ExportBatchSize}
+	for {
+		batch, err := p.db.GetOccurrences(filter)
+		if err != nil {
+			return count, err
+		}
+		for i := range batch {
+			if err := writer.Write(p.mapping.Row(&batch[i])); err != nil {
+				return count, err
+			}
+			count++
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return count, err
+		}
+		if len(batch) < filter.Limit {
+			return count, nil
+		}
+		filter.AfterID = batch[len(batch)-1].ID
+	}
+}
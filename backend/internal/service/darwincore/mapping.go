@@ -0,0 +1,369 @@
+// The mapping from specimens, their minerals and localities to Darwin Core terms.
+// Every field of a record names a Darwin Core or Dublin Core term and either a source, one of the values the catalogue
+// knows about an occurrence, or a constant value. The default mapping can be replaced by a JSON file named by DWC_MAPPING_FILE.
+
+package darwincore
+
+import (
+	"backend/internal/database"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DwCNamespace     = "http://rs.tdwg.org/dwc/terms/"
+	DCTermsNamespace = "http://purl.org/dc/terms/"
+
+	DefaultInstitutionCode = "GazlinGO"
+	DefaultCollectionCode  = "minerals"
+	// OccurrenceIDTerm is the term that identifies a record; the archive uses its column as the record id.
+	OccurrenceIDTerm = "occurrenceID"
+)
+
+var (
+	ErrNoOccurrenceID = errors.New("в сопоставлении нет поля occurrenceID")
+	ErrEmptyCode      = errors.New("institution_code и collection_code не могут быть пустыми")
+)
+
+// dcTerms are the Dublin Core terms Darwin Core records use; a bare term name outside this list is a Darwin Core term.
+var dcTerms = map[string]bool{
+	"modified": true, "license": true, "rightsHolder": true, "accessRights": true,
+	"bibliographicCitation": true, "references": true, "language": true, "type": true,
+}
+
+// Term is a Darwin Core ("dwc") or Dublin Core ("dcterms") term.
+type Term struct {
+	Prefix string
+	Name   string
+}
+
+// ParseTerm accepts a bare term name, a prefixed name such as "dcterms:modified" or the full term IRI.
+func ParseTerm(raw string) (Term, error) {
+	raw = strings.TrimSpace(raw)
+	var term Term
+	switch {
+	case strings.HasPrefix(raw, DwCNamespace):
+		term = Term{"dwc", strings.TrimPrefix(raw, DwCNamespace)}
+	case strings.HasPrefix(raw, DCTermsNamespace):
+		term = Term{"dcterms", strings.TrimPrefix(raw, DCTermsNamespace)}
+	case strings.HasPrefix(raw, "dwc:"), strings.HasPrefix(raw, "dcterms:"):
+		parts := strings.SplitN(raw, ":", 2)
+		term = Term{parts[0], parts[1]}
+	case dcTerms[raw]:
+		term = Term{"dcterms", raw}
+	default:
+		term = Term{"dwc", raw}
+	}
+	if term.Name == "" || strings.ContainsAny(term.Name, ":/# ") {
+		return Term{}, fmt.Errorf("некорректный термин %q", raw)
+	}
+	return term, nil
+}
+
+// IRI returns the full identifier of the term, as meta.xml names it.
+func (t Term) IRI() string {
+	if t.Prefix == "dcterms" {
+		return DCTermsNamespace + t.Name
+	}
+	return DwCNamespace + t.Name
+}
+
+// QName returns the prefixed name of the term, as XML records name it.
+func (t Term) QName() string {
+	return t.Prefix + ":" + t.Name
+}
+
+// Field maps one term either to a source or to a constant value.
+type Field struct {
+	Term   string `json:"term"`
+	Source string `json:"source,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+type Mapping struct {
+	InstitutionCode string  `json:"institution_code"`
+	CollectionCode  string  `json:"collection_code"`
+	Fields          []Field `json:"fields"`
+
+	terms []Term
+}
+
+// Value is a term of a record with its value.
+type Value struct {
+	Term  Term
+	Value string
+}
+
+// sources are the values a field may take from an occurrence. The archive and the OAI-PMH records are public,
+// so the inventory fields of specimens (acquisition source, storage location) are not offered.
+var sources = map[string]func(m *Mapping, o *database.Occurrence) string{
+	"occurrence_id": func(m *Mapping, o *database.Occurrence) string {
+		return m.OccurrenceID(o.CatalogNumber)
+	},
+	"catalog_number":   func(m *Mapping, o *database.Occurrence) string { return o.CatalogNumber },
+	"institution_code": func(m *Mapping, o *database.Occurrence) string { return m.InstitutionCode },
+	"collection_code":  func(m *Mapping, o *database.Occurrence) string { return m.CollectionCode },
+	"mineral_title":    func(m *Mapping, o *database.Occurrence) string { return o.MineralTitle },
+	"mineral_uuid":     func(m *Mapping, o *database.Occurrence) string { return o.MineralUUID },
+	"formula":          func(m *Mapping, o *database.Occurrence) string { return o.Formula },
+	"crystal_system":   func(m *Mapping, o *database.Occurrence) string { return o.CrystalSystem },
+	"strunz_code":      func(m *Mapping, o *database.Occurrence) string { return o.StrunzCode },
+	"dana_code":        func(m *Mapping, o *database.Occurrence) string { return o.DanaCode },
+	// locality is the free-text locality of the specimen, else the name of the linked locality.
+	"locality": func(m *Mapping, o *database.Occurrence) string {
+		if o.Locality == "" && o.Place != nil {
+			return o.Place.Name
+		}
+		return o.Locality
+	},
+	"locality_name": func(m *Mapping, o *database.Occurrence) string {
+		return placeValue(o, func() string { return o.Place.Name })
+	},
+	"country": func(m *Mapping, o *database.Occurrence) string {
+		return placeValue(o, func() string { return o.Place.Country })
+	},
+	"region": func(m *Mapping, o *database.Occurrence) string {
+		return placeValue(o, func() string { return o.Place.Region })
+	},
+	"latitude": func(m *Mapping, o *database.Occurrence) string {
+		return placeValue(o, func() string { return formatFloat(o.Place.Latitude) })
+	},
+	"longitude": func(m *Mapping, o *database.Occurrence) string {
+		return placeValue(o, func() string { return formatFloat(o.Place.Longitude) })
+	},
+	// geodetic_datum is WGS84 for specimens with coordinates, the datum localities are stored in.
+	"geodetic_datum": func(m *Mapping, o *database.Occurrence) string {
+		return placeValue(o, func() string { return "WGS84" })
+	},
+	"mass_g": func(m *Mapping, o *database.Occurrence) string {
+		if o.MassG == nil {
+			return ""
+		}
+		return formatFloat(*o.MassG)
+	},
+	"dimensions":         func(m *Mapping, o *database.Occurrence) string { return dimensions(o) },
+	"acquired_on":        func(m *Mapping, o *database.Occurrence) string { return o.AcquiredOn },
+	"notes":              func(m *Mapping, o *database.Occurrence) string { return o.Notes },
+	"modified":           func(m *Mapping, o *database.Occurrence) string { return o.Modified.UTC().Format(time.RFC3339) },
+	"dynamic_properties": func(m *Mapping, o *database.Occurrence) string { return dynamicProperties(o) },
+}
+
+// Sources lists the source names a field may use.
+func Sources() []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultMapping returns the mapping used without DWC_MAPPING_FILE.
+func DefaultMapping() *Mapping {
+	m := &Mapping{
+		InstitutionCode: DefaultInstitutionCode,
+		CollectionCode:  DefaultCollectionCode,
+		Fields: []Field{
+			{Term: OccurrenceIDTerm, Source: "occurrence_id"},
+			{Term: "basisOfRecord", Value: "PreservedSpecimen"},
+			{Term: "institutionCode", Source: "institution_code"},
+			{Term: "collectionCode", Source: "collection_code"},
+			{Term: "catalogNumber", Source: "catalog_number"},
+			{Term: "scientificName", Source: "mineral_title"},
+			{Term: "locality", Source: "locality"},
+			{Term: "country", Source: "country"},
+			{Term: "stateProvince", Source: "region"},
+			{Term: "decimalLatitude", Source: "latitude"},
+			{Term: "decimalLongitude", Source: "longitude"},
+			{Term: "geodeticDatum", Source: "geodetic_datum"},
+			{Term: "occurrenceRemarks", Source: "notes"},
+			{Term: "dynamicProperties", Source: "dynamic_properties"},
+			{Term: "dcterms:modified", Source: "modified"},
+		},
+	}
+	if err := m.Validate(); err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// LoadMapping reads a mapping from a JSON file. The codes the file leaves out keep their defaults; the fields are
+// replaced as a whole, and only a file without "fields" keeps the default ones.
+func LoadMapping(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := DefaultMapping()
+	defaults := m.Fields
+	// Decoding into the default fields would merge the file's fields into them element by element.
+	m.Fields = nil
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if m.Fields == nil {
+		m.Fields = defaults
+	}
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// MappingFromEnv loads the mapping named by DWC_MAPPING_FILE, or returns the default mapping when it is not set.
+func MappingFromEnv() (*Mapping, error) {
+	if path := os.Getenv("DWC_MAPPING_FILE"); path != "" {
+		return LoadMapping(path)
+	}
+	return DefaultMapping(), nil
+}
+
+// Validate checks the terms and sources of the fields and resolves their terms.
+func (m *Mapping) Validate() error {
+	m.InstitutionCode = strings.TrimSpace(m.InstitutionCode)
+	m.CollectionCode = strings.TrimSpace(m.CollectionCode)
+	if m.InstitutionCode == "" || m.CollectionCode == "" {
+		return ErrEmptyCode
+	}
+
+	terms := make([]Term, len(m.Fields))
+	seen := make(map[Term]bool, len(m.Fields))
+	hasID := false
+	for i, field := range m.Fields {
+		term, err := ParseTerm(field.Term)
+		if err != nil {
+			return fmt.Errorf("поле %d: %w", i+1, err)
+		}
+		if seen[term] {
+			return fmt.Errorf("поле %d: термин %s указан дважды", i+1, term.QName())
+		}
+		seen[term] = true
+		switch {
+		case field.Source != "" && field.Value != "":
+			return fmt.Errorf("поле %s: нужен либо source, либо value", term.QName())
+		case field.Source == "" && field.Value == "":
+			return fmt.Errorf("поле %s: не указан source или value", term.QName())
+		case field.Source != "" && sources[field.Source] == nil:
+			return fmt.Errorf("поле %s: неизвестный источник %q, доступны: %s", term.QName(), field.Source, strings.Join(Sources(), ", "))
+		}
+		if term == (Term{"dwc", OccurrenceIDTerm}) {
+			hasID = true
+		}
+		terms[i] = term
+	}
+	if !hasID {
+		return ErrNoOccurrenceID
+	}
+	m.terms = terms
+	return nil
+}
+
+// Terms returns the terms of the fields in their order.
+func (m *Mapping) Terms() []Term {
+	return m.terms
+}
+
+// IDIndex returns the position of the occurrenceID field.
+func (m *Mapping) IDIndex() int {
+	for i, term := range m.terms {
+		if term == (Term{"dwc", OccurrenceIDTerm}) {
+			return i
+		}
+	}
+	return 0
+}
+
+// OccurrenceID builds the globally unique identifier of a specimen from the institution and collection codes and its catalog number.
+func (m *Mapping) OccurrenceID(catalogNumber string) string {
+	return "urn:catalog:" + m.InstitutionCode + ":" + m.CollectionCode + ":" + catalogNumber
+}
+
+// Row returns the values of the fields for an occurrence, in the order of the fields.
+func (m *Mapping) Row(o *database.Occurrence) []string {
+	row := make([]string, len(m.Fields))
+	for i, field := range m.Fields {
+		if field.Source == "" {
+			row[i] = field.Value
+			continue
+		}
+		row[i] = sources[field.Source](m, o)
+	}
+	return row
+}
+
+// Record returns the non-empty values of the fields for an occurrence.
+func (m *Mapping) Record(o *database.Occurrence) []Value {
+	row := m.Row(o)
+	values := make([]Value, 0, len(row))
+	for i, value := range row {
+		if value != "" {
+			values = append(values, Value{Term: m.terms[i], Value: value})
+		}
+	}
+	return values
+}
+
+func placeValue(o *database.Occurrence, value func() string) string {
+	if o.Place == nil {
+		return ""
+	}
+	return value()
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// dimensions formats the measured dimensions of a specimen as "40 x 30 x 20 mm".
+func dimensions(o *database.Occurrence) string {
+	var parts []string
+	for _, value := range []*float64{o.LengthMM, o.WidthMM, o.HeightMM} {
+		if value != nil {
+			parts = append(parts, formatFloat(*value))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, " x ") + " mm"
+}
+
+// dynamicProperties collects the mineralogical data Darwin Core has no terms for as a JSON object.
+func dynamicProperties(o *database.Occurrence) string {
+	properties := map[string]interface{}{}
+	for key, value := range map[string]string{
+		"formula":       o.Formula,
+		"crystalSystem": o.CrystalSystem,
+		"strunzCode":    o.StrunzCode,
+		"danaCode":      o.DanaCode,
+		"mineralUUID":   o.MineralUUID,
+	} {
+		if value != "" {
+			properties[key] = value
+		}
+	}
+	for key, value := range map[string]*float64{
+		"massG":    o.MassG,
+		"lengthMM": o.LengthMM,
+		"widthMM":  o.WidthMM,
+		"heightMM": o.HeightMM,
+	} {
+		if value != nil {
+			properties[key] = *value
+		}
+	}
+	if len(properties) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(properties)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
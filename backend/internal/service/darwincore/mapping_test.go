@@ -0,0 +1,107 @@
+package darwincore
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadMapping(t *testing.T) {
+	defaults := DefaultMapping()
+	tests := []struct {
+		name        string
+		file        string
+		institution string
+		fields      []Field
+	}{
+		{
+			name: "reordered and shortened fields",
+			file: `{"fields": [
+				{"term": "occurrenceID", "source": "occurrence_id"},
+				{"term": "scientificName", "source": "mineral_title"}
+			]}`,
+			institution: DefaultInstitutionCode,
+			fields: []Field{
+				{Term: "occurrenceID", Source: "occurrence_id"},
+				{Term: "scientificName", Source: "mineral_title"},
+			},
+		},
+		{
+			name: "constant value in place of a default source",
+			file: `{"institution_code": "MinMus", "fields": [
+				{"term": "dwc:basisOfRecord", "source": "catalog_number"},
+				{"term": "http://rs.tdwg.org/dwc/terms/occurrenceID", "source": "occurrence_id"},
+				{"term": "license", "value": "CC-BY-4.0"}
+			]}`,
+			institution: "MinMus",
+			fields: []Field{
+				{Term: "dwc:basisOfRecord", Source: "catalog_number"},
+				{Term: "http://rs.tdwg.org/dwc/terms/occurrenceID", Source: "occurrence_id"},
+				{Term: "license", Value: "CC-BY-4.0"},
+			},
+		},
+		{
+			name:        "codes only",
+			file:        `{"institution_code": "MinMus", "collection_code": "specimens"}`,
+			institution: "MinMus",
+			fields:      defaults.Fields,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := LoadMapping(writeMapping(t, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.InstitutionCode != tt.institution || m.CollectionCode == "" {
+				t.Errorf("codes = %q, %q", m.InstitutionCode, m.CollectionCode)
+			}
+			if !reflect.DeepEqual(m.Fields, tt.fields) {
+				t.Errorf("fields = %+v, want %+v", m.Fields, tt.fields)
+			}
+			if len(m.Terms()) != len(tt.fields) {
+				t.Errorf("%d terms for %d fields", len(m.Terms()), len(tt.fields))
+			}
+		})
+	}
+}
+
+func TestLoadMappingErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		message string
+	}{
+		{"empty fields", `{"fields": []}`, "occurrenceID"},
+		{"no occurrenceID", `{"fields": [{"term": "scientificName", "source": "mineral_title"}]}`, "occurrenceID"},
+		{"source and value", `{"fields": [{"term": "occurrenceID", "source": "occurrence_id", "value": "x"}]}`, "либо source, либо value"},
+		{"unknown source", `{"fields": [{"term": "occurrenceID", "source": "storage_location"}]}`, "неизвестный источник"},
+		{"repeated term", `{"fields": [{"term": "occurrenceID", "source": "occurrence_id"}, {"term": "dwc:occurrenceID", "value": "x"}]}`, "дважды"},
+		{"empty code", `{"collection_code": " "}`, "collection_code"},
+		{"broken JSON", `{"fields": [`, "mapping.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := LoadMapping(writeMapping(t, tt.file))
+			if err == nil {
+				t.Fatalf("LoadMapping = %+v, want an error", m)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q does not mention %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func writeMapping(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "mapping.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
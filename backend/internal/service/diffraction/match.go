@@ -0,0 +1,139 @@
+// Search-match of a measured powder pattern against reference patterns.
+// Reference lines are paired with measured peaks strongest first, within a relative d-spacing tolerance.
+// The score combines how much of the reference intensity was found, how much of the measured intensity
+// the reference explains, the position error and the agreement of relative intensities.
+
+package diffraction
+
+import (
+	"backend/internal/models"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	DefaultTolerance = 0.005
+	MaxTolerance     = 0.05
+	// Reference lines weaker than this are not required to be present in the measured pattern.
+	minReferenceIntensity = 5.0
+	// Candidates scoring below this are not reported.
+	minCandidateScore = 10.0
+)
+
+type PeakMatch struct {
+	Reference models.XRDPeak  `json:"reference"`
+	Measured  *models.XRDPeak `json:"measured,omitempty"`
+}
+
+type MatchResult struct {
+	Score             float64     `json:"score"`
+	ReferenceCoverage float64     `json:"reference_coverage"`
+	MeasuredCoverage  float64     `json:"measured_coverage"`
+	PositionError     float64     `json:"position_error"`
+	MatchedPeaks      int         `json:"matched_peaks"`
+	ReferencePeaks    int         `json:"reference_peaks"`
+	Matches           []PeakMatch `json:"matches"`
+}
+
+type Candidate struct {
+	MineralID int `json:"mineral_id"`
+	MatchResult
+}
+
+func ValidateTolerance(tolerance float64) error {
+	if tolerance <= 0 || tolerance > MaxTolerance {
+		return fmt.Errorf("%w: допуск должен быть от 0 до %.2f", ErrInvalidPeak, MaxTolerance)
+	}
+	return nil
+}
+
+// Match compares measured peaks with a reference pattern. tolerance is the allowed relative difference |Δd|/d.
+func Match(measured, reference []models.XRDPeak, tolerance float64) MatchResult {
+	considered := make([]models.XRDPeak, 0, len(reference))
+	for _, p := range reference {
+		if p.Intensity >= minReferenceIntensity {
+			considered = append(considered, p)
+		}
+	}
+	if len(considered) < 3 {
+		considered = append(considered[:0], reference...)
+	}
+	sort.SliceStable(considered, func(i, j int) bool { return considered[i].Intensity > considered[j].Intensity })
+
+	result := MatchResult{ReferencePeaks: len(considered), Matches: make([]PeakMatch, 0, len(considered))}
+	used := make([]bool, len(measured))
+	var referenceTotal, referenceFound, measuredFound, positionError, intensityDiff, intensitySum float64
+	for _, ref := range considered {
+		referenceTotal += ref.Intensity
+		best := -1
+		bestError := tolerance
+		for i, m := range measured {
+			if used[i] {
+				continue
+			}
+			if relError := math.Abs(m.D-ref.D) / ref.D; relError <= bestError {
+				best, bestError = i, relError
+			}
+		}
+
+		match := PeakMatch{Reference: ref}
+		if best >= 0 {
+			used[best] = true
+			found := measured[best]
+			match.Measured = &found
+			result.MatchedPeaks++
+			referenceFound += ref.Intensity
+			measuredFound += found.Intensity
+			positionError += bestError
+			intensityDiff += math.Abs(found.Intensity - ref.Intensity)
+			intensitySum += found.Intensity + ref.Intensity
+		}
+		result.Matches = append(result.Matches, match)
+	}
+	if result.MatchedPeaks == 0 || referenceTotal == 0 {
+		return result
+	}
+
+	measuredTotal := 0.0
+	for _, m := range measured {
+		measuredTotal += m.Intensity
+	}
+	result.ReferenceCoverage = round(referenceFound/referenceTotal, 4)
+	if measuredTotal > 0 {
+		result.MeasuredCoverage = round(measuredFound/measuredTotal, 4)
+	}
+	result.PositionError = round(positionError/float64(result.MatchedPeaks), 6)
+
+	positionFactor := 1 - 0.5*result.PositionError/tolerance
+	intensityFactor := 1.0
+	if intensitySum > 0 {
+		intensityFactor = 0.8 + 0.2*(1-intensityDiff/intensitySum)
+	}
+	coverage := 0.6*result.ReferenceCoverage + 0.4*result.MeasuredCoverage
+	result.Score = round(100*coverage*positionFactor*intensityFactor, 2)
+	return result
+}
+
+// Rank matches the measured peaks against every reference pattern, keyed by mineral id,
+// and returns up to limit candidates ordered by decreasing score.
+func Rank(measured []models.XRDPeak, references map[int][]models.XRDPeak, tolerance float64, limit int) []Candidate {
+	measured = Normalize(measured)
+	candidates := []Candidate{}
+	for mineralID, reference := range references {
+		result := Match(measured, reference, tolerance)
+		if result.Score >= minCandidateScore {
+			candidates = append(candidates, Candidate{MineralID: mineralID, MatchResult: result})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Score != candidates[j].Score {
+			return candidates[i].Score > candidates[j].Score
+		}
+		return candidates[i].MineralID < candidates[j].MineralID
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
@@ -0,0 +1,246 @@
+// Powder X-ray diffraction patterns: conversion between 2θ and d-spacing, X-ray tube wavelengths,
+// reading two-column 2θ/intensity files and picking peaks from a measured profile.
+// d-spacings are in ångströms, 2θ in degrees; intensities are relative, with the strongest peak at 100.
+
+package diffraction
+
+import (
+	"backend/internal/models"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxPatternPoints = 50000
+	MaxPeaks         = 200
+	// MinPeakIntensity is the relative intensity below which picked peaks are discarded as noise.
+	MinPeakIntensity = 2.0
+)
+
+var (
+	ErrInvalidPeak       = errors.New("некорректный пик")
+	ErrInvalidPattern    = errors.New("некорректная дифрактограмма")
+	ErrInvalidWavelength = errors.New("некорректная длина волны")
+)
+
+// Wavelengths of the Kα1 lines of common X-ray tube anodes.
+var Wavelengths = map[string]float64{
+	"cu": 1.54056,
+	"co": 1.78897,
+	"fe": 1.93604,
+	"cr": 2.28970,
+	"mo": 0.70930,
+	"ag": 0.55941,
+}
+
+// Point is a sample of a measured profile.
+type Point struct {
+	TwoTheta  float64
+	Intensity float64
+}
+
+// ParseWavelength accepts a value in ångströms ("1.5406") or an anode ("Cu", "CuKa", "Cu Kα1").
+func ParseWavelength(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("%w: не указана длина волны", ErrInvalidWavelength)
+	}
+	if wavelength, err := strconv.ParseFloat(value, 64); err == nil {
+		if wavelength < 0.1 || wavelength > 5 {
+			return 0, fmt.Errorf("%w: ожидается значение от 0.1 до 5 Å", ErrInvalidWavelength)
+		}
+		return wavelength, nil
+	}
+	anode := strings.ToLower(value)
+	if len(anode) > 2 {
+		anode = anode[:2]
+	}
+	if wavelength, ok := Wavelengths[anode]; ok {
+		return wavelength, nil
+	}
+	return 0, fmt.Errorf("%w: неизвестный анод %q", ErrInvalidWavelength, value)
+}
+
+// DSpacing converts a 2θ angle to a d-spacing with Bragg's law.
+func DSpacing(twoTheta, wavelength float64) (float64, error) {
+	if twoTheta <= 0 || twoTheta >= 180 {
+		return 0, fmt.Errorf("%w: 2θ должен быть в интервале (0, 180)", ErrInvalidPeak)
+	}
+	return wavelength / (2 * math.Sin(twoTheta/2*math.Pi/180)), nil
+}
+
+// ValidatePeaks checks the d-spacings and intensities of a peak list. Intensities may be in any units; see Normalize.
+func ValidatePeaks(peaks []models.XRDPeak) error {
+	if len(peaks) == 0 {
+		return fmt.Errorf("%w: список пиков пуст", ErrInvalidPeak)
+	}
+	if len(peaks) > MaxPeaks {
+		return fmt.Errorf("%w: не больше %d пиков", ErrInvalidPeak, MaxPeaks)
+	}
+	strongest := 0.0
+	for _, p := range peaks {
+		if !(p.D > 0 && p.D <= 100) {
+			return fmt.Errorf("%w: межплоскостное расстояние должно быть от 0 до 100 Å", ErrInvalidPeak)
+		}
+		if !(p.Intensity >= 0) || math.IsInf(p.Intensity, 0) {
+			return fmt.Errorf("%w: интенсивность не может быть отрицательной", ErrInvalidPeak)
+		}
+		if len(p.HKL) > 20 {
+			return fmt.Errorf("%w: слишком длинные индексы hkl", ErrInvalidPeak)
+		}
+		strongest = math.Max(strongest, p.Intensity)
+	}
+	if strongest == 0 {
+		return fmt.Errorf("%w: все интенсивности равны нулю", ErrInvalidPeak)
+	}
+	return nil
+}
+
+// Normalize scales intensities so that the strongest peak is 100 and sorts the peaks by decreasing d.
+func Normalize(peaks []models.XRDPeak) []models.XRDPeak {
+	normalized := append([]models.XRDPeak(nil), peaks...)
+	strongest := 0.0
+	for _, p := range normalized {
+		strongest = math.Max(strongest, p.Intensity)
+	}
+	if strongest > 0 {
+		for i := range normalized {
+			normalized[i].Intensity = math.Round(normalized[i].Intensity/strongest*1000) / 10
+		}
+	}
+	sort.SliceStable(normalized, func(i, j int) bool { return normalized[i].D > normalized[j].D })
+	return normalized
+}
+
+// ParsePattern reads a two-column 2θ/intensity file. Columns may be separated by whitespace, commas or semicolons;
+// header and comment lines (starting with #, ; or !) and extra columns are ignored.
+func ParsePattern(data []byte) ([]Point, error) {
+	var points []Point
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.ContainsAny(text[:1], "#;!") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ',' || r == ';'
+		})
+		if len(fields) < 2 {
+			continue
+		}
+		twoTheta, errX := strconv.ParseFloat(fields[0], 64)
+		intensity, errY := strconv.ParseFloat(fields[1], 64)
+		if errX != nil || errY != nil {
+			if len(points) == 0 {
+				// A header line before the data.
+				continue
+			}
+			return nil, fmt.Errorf("%w: строка %d не содержит двух чисел", ErrInvalidPattern, line)
+		}
+		if twoTheta <= 0 || twoTheta >= 180 {
+			return nil, fmt.Errorf("%w: строка %d: 2θ должен быть в интервале (0, 180)", ErrInvalidPattern, line)
+		}
+		points = append(points, Point{TwoTheta: twoTheta, Intensity: intensity})
+		if len(points) > MaxPatternPoints {
+			return nil, fmt.Errorf("%w: больше %d точек", ErrInvalidPattern, MaxPatternPoints)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPattern, err)
+	}
+	if len(points) < 10 {
+		return nil, fmt.Errorf("%w: слишком мало точек", ErrInvalidPattern)
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].TwoTheta < points[j].TwoTheta })
+	return points, nil
+}
+
+// Half-widths in degrees 2θ of the neighbourhood a peak must dominate and of the background estimate.
+const (
+	peakHalfWidth       = 0.1
+	backgroundHalfWidth = 2.0
+)
+
+// pointsWithin converts an angular half-width to a number of points, clamped to [lower, upper].
+func pointsWithin(width, step float64, lower, upper int) int {
+	return min(max(int(math.Round(width/step)), lower), upper)
+}
+
+// FindPeaks picks peaks from a measured profile: the background is estimated as a rolling minimum,
+// the net profile is smoothed, and local maxima above MinPeakIntensity percent of the strongest one are kept.
+func FindPeaks(points []Point, wavelength float64) ([]models.XRDPeak, error) {
+	n := len(points)
+	step := (points[n-1].TwoTheta - points[0].TwoTheta) / float64(n-1)
+	if step <= 0 {
+		return nil, fmt.Errorf("%w: все точки имеют одинаковый угол 2θ", ErrInvalidPattern)
+	}
+	window := pointsWithin(peakHalfWidth, step, 1, 50)
+	backgroundWindow := pointsWithin(backgroundHalfWidth, step, 10, 500)
+
+	net := make([]float64, n)
+	for i := range points {
+		background := points[i].Intensity
+		for j := max(0, i-backgroundWindow); j <= min(n-1, i+backgroundWindow); j++ {
+			background = math.Min(background, points[j].Intensity)
+		}
+		net[i] = points[i].Intensity - background
+	}
+
+	smoothed := make([]float64, n)
+	for i := range net {
+		sum, count := 0.0, 0
+		for j := max(0, i-1); j <= min(n-1, i+1); j++ {
+			sum += net[j]
+			count++
+		}
+		smoothed[i] = sum / float64(count)
+	}
+
+	strongest := 0.0
+	for _, v := range smoothed {
+		strongest = math.Max(strongest, v)
+	}
+	if strongest <= 0 {
+		return nil, fmt.Errorf("%w: не найдено ни одного пика", ErrInvalidPattern)
+	}
+
+	var peaks []models.XRDPeak
+	for i := range smoothed {
+		if smoothed[i] < strongest*MinPeakIntensity/100 {
+			continue
+		}
+		isMaximum := true
+		for j := max(0, i-window); j <= min(n-1, i+window); j++ {
+			if smoothed[j] > smoothed[i] || (smoothed[j] == smoothed[i] && j < i) {
+				isMaximum = false
+				break
+			}
+		}
+		if !isMaximum {
+			continue
+		}
+		d, err := DSpacing(points[i].TwoTheta, wavelength)
+		if err != nil {
+			return nil, err
+		}
+		peaks = append(peaks, models.XRDPeak{D: round(d, 5), Intensity: smoothed[i]})
+	}
+
+	if len(peaks) > MaxPeaks {
+		sort.Slice(peaks, func(i, j int) bool { return peaks[i].Intensity > peaks[j].Intensity })
+		peaks = peaks[:MaxPeaks]
+	}
+	return Normalize(peaks), nil
+}
+
+func round(value float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,106 @@
+// Storage of mineral media assets: gallery images, additional 3D models, videos and documents.
+// Every type has its own directory, extension list and size limit. Besides the extension, the leading bytes of
+// the file are checked, so a renamed file of another type is rejected. Models go through the regular model import.
+
+package file
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/models"
+	"bytes"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ImagesDir           = "/images"
+	VideosDir           = "/videos"
+	DocumentsDir        = "/documents"
+	AllowedVideoExts    = ".mp4,.webm,.mov"
+	AllowedDocumentExts = ".pdf"
+	MaxVideoSize        = 100 << 20
+)
+
+// SaveAsset validates and stores an asset of the given type. For models the result has the same meaning as
+// SaveModel's; for other types SourcePath is empty.
+func (fs *FileService) SaveAsset(file *multipart.FileHeader, assetType string) (*SavedModel, error) {
+	if assetType == models.AssetModel {
+		return fs.SaveModel(file)
+	}
+
+	var dir, allowed string
+	maxSize := int64(MaxFileSize)
+	switch assetType {
+	case models.AssetImage:
+		dir, allowed = ImagesDir, AllowedImageExts
+	case models.AssetVideo:
+		dir, allowed, maxSize = VideosDir, AllowedVideoExts, MaxVideoSize
+	case models.AssetDocument:
+		dir, allowed = DocumentsDir, AllowedDocumentExts
+	default:
+		return nil, errors.ErrInvalidInput(models.ErrInvalidAssetType.Error())
+	}
+
+	if file.Size > maxSize {
+		return nil, errors.ErrFileTooBig("файл слишком большой")
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !hasExtension(allowed, ext) {
+		return nil, errors.ErrInvalidTypeFile("можно загружать только файлы " + allowed)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		log.Printf("Ошибка открытия файла: %v", err)
+		return nil, errors.ErrFileOperation("не удалось открыть файл")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxSize+1))
+	if err != nil {
+		log.Printf("Ошибка чтения файла: %v", err)
+		return nil, errors.ErrFileOperation("не удалось прочитать файл")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errors.ErrFileTooBig("файл слишком большой")
+	}
+	if !matchesContent(assetType, data) {
+		return nil, errors.ErrInvalidTypeFile("содержимое файла не соответствует расширению " + ext)
+	}
+
+	path, err := fs.writeFile(data, dir, filepath.Base(file.Filename))
+	if err != nil {
+		return nil, err
+	}
+	return &SavedModel{Path: path}, nil
+}
+
+func hasExtension(list, ext string) bool {
+	for _, allowed := range strings.Split(list, ",") {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesContent checks the magic bytes of images, PDF documents and MP4/QuickTime or WebM videos.
+func matchesContent(assetType string, data []byte) bool {
+	switch assetType {
+	case models.AssetImage:
+		contentType := http.DetectContentType(data)
+		return contentType == "image/jpeg" || contentType == "image/png"
+	case models.AssetDocument:
+		return bytes.HasPrefix(data, []byte("%PDF-"))
+	case models.AssetVideo:
+		if len(data) >= 12 && string(data[4:8]) == "ftyp" {
+			return true
+		}
+		return bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3})
+	}
+	return false
+}
@@ -6,26 +6,40 @@ package file
 
 import (
 	"backend/internal/api/errors"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"mime/multipart"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 )
 
 const (
-	StoragePath      = "/app/storage"
-	ModelsDir        = "/models"
-	PreviewsDir      = "/previews"
-	MaxFileSize      = 50 << 20
-	AllowedModelExt  = ".glb"
-	AllowedImageExts = ".jpg,.jpeg,.png"
+	StoragePath       = "/app/storage"
+	ModelsDir         = "/models"
+	PreviewsDir       = "/previews"
+	SourcesDir        = "/sources"
+	MaxFileSize       = 50 << 20
+	AllowedModelExt   = ".glb"
+	AllowedSourceExts = ".glb,.gltf,.obj,.stl,.ply,.zip"
+	AllowedImageExts  = ".jpg,.jpeg,.png"
+	ContentHashLength = 16
+	PublicPrefix      = "/storage"
 )
 
+// SavedModel describes a stored model: the GLB served to clients and, for converted uploads, the archived original.
+type SavedModel struct {
+	Path       string `json:"path"`
+	SourcePath string `json:"source_path,omitempty"`
+}
+
 type FileService struct {
 	basePath string
+	staging  *Staging
 }
 
 func NewFileService(basePath string) (*FileService, error) {
@@ -34,6 +48,12 @@ func NewFileService(basePath string) (*FileService, error) {
 	dirs := []string{
 		filepath.Join(StoragePath, ModelsDir),
 		filepath.Join(StoragePath, PreviewsDir),
+		filepath.Join(StoragePath, SourcesDir),
+		filepath.Join(StoragePath, ImagesDir),
+		filepath.Join(StoragePath, VideosDir),
+		filepath.Join(StoragePath, DocumentsDir),
+		filepath.Join(StoragePath, StructuresDir),
+		filepath.Join(StoragePath, SpectraDir),
 	}
 
 	for _, dir := range dirs {
@@ -44,67 +64,235 @@ func NewFileService(basePath string) (*FileService, error) {
 		}
 	}
 
-	return &FileService{basePath: StoragePath}, nil
+	fs := &FileService{basePath: StoragePath}
+	fs.CleanupStaging(StagingMaxAge)
+	return fs, nil
 }
 
-func (fs *FileService) SaveModel(file *multipart.FileHeader) (string, error) {
+// SaveModel stores an uploaded model. Files in other supported formats are converted to GLB,
+// and the original upload is kept under the sources directory.
+func (fs *FileService) SaveModel(file *multipart.FileHeader) (*SavedModel, error) {
 	if file.Size > MaxFileSize {
-		return "", errors.ErrFileTooBig("файл слишком большой")
+		return nil, errors.ErrFileTooBig("файл слишком большой")
+	}
+
+	if !IsAllowedModelFile(file.Filename) {
+		return nil, errors.ErrInvalidTypeFile("можно загружать только файлы " + AllowedSourceExts)
 	}
 
-	if !strings.HasSuffix(strings.ToLower(file.Filename), AllowedModelExt) {
-		return "", errors.ErrInvalidTypeFile("можно загружать только .glb файлы")
+	src, err := file.Open()
+	if err != nil {
+		log.Printf("Ошибка открытия файла: %v", err)
+		return nil, errors.ErrFileOperation("не удалось открыть файл")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, MaxFileSize+1))
+	if err != nil {
+		log.Printf("Ошибка чтения файла: %v", err)
+		return nil, errors.ErrFileOperation("не удалось прочитать файл")
 	}
 
-	return fs.saveFile(file, ModelsDir)
+	return fs.storeModel(file.Filename, data)
 }
 
-func (fs *FileService) SavePreview(file *multipart.FileHeader) (string, error) {
-	if file.Size > MaxFileSize {
-		return "", errors.ErrFileTooBig("файл слишком большой")
+// SaveModelFile stores a model that has already been written to disk, such as a finalized resumable upload.
+// It applies the same size and format validation as SaveModel.
+func (fs *FileService) SaveModelFile(filename, path string) (*SavedModel, error) {
+	if !IsAllowedModelFile(filename) {
+		return nil, errors.ErrInvalidTypeFile("можно загружать только файлы " + AllowedSourceExts)
 	}
 
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	if !strings.Contains(AllowedImageExts, ext) {
-		return "", errors.ErrInvalidTypeFile("можно загружать только jpg и png")
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Printf("Ошибка доступа к файлу %s: %v", path, err)
+		return nil, errors.ErrFileOperation("не удалось открыть файл")
+	}
+	if info.Size() > MaxFileSize {
+		return nil, errors.ErrFileTooBig("файл слишком большой")
 	}
 
-	return fs.saveFile(file, PreviewsDir)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("Ошибка чтения файла %s: %v", path, err)
+		return nil, errors.ErrFileOperation("не удалось прочитать файл")
+	}
+
+	return fs.storeModel(filename, data)
 }
 
-func (fs *FileService) saveFile(file *multipart.FileHeader, dir string) (string, error) {
-	src, err := file.Open()
+// SaveModelData stores a model read from somewhere other than an upload, such as an import archive,
+// with the same validation as SaveModel.
+func (fs *FileService) SaveModelData(filename string, data []byte) (*SavedModel, error) {
+	if !IsAllowedModelFile(filename) {
+		return nil, errors.ErrInvalidTypeFile("можно загружать только файлы " + AllowedSourceExts)
+	}
+	return fs.storeModel(filename, data)
+}
+
+// CheckModelData applies the checks of SaveModelData, including the conversion to GLB, without storing anything.
+func CheckModelData(filename string, data []byte) error {
+	if !IsAllowedModelFile(filename) {
+		return errors.ErrInvalidTypeFile("можно загружать только файлы " + AllowedSourceExts)
+	}
+	if len(data) > MaxFileSize {
+		return errors.ErrFileTooBig("файл слишком большой")
+	}
+	if _, err := convertModel(filepath.Base(filename), data); err != nil {
+		return errors.ErrInvalidTypeFile(err.Error())
+	}
+	return nil
+}
+
+func (fs *FileService) storeModel(filename string, data []byte) (*SavedModel, error) {
+	if len(data) > MaxFileSize {
+		return nil, errors.ErrFileTooBig("файл слишком большой")
+	}
+	filename = filepath.Base(filename)
+
+	glb, err := convertModel(filename, data)
 	if err != nil {
-		log.Printf("Ошибка открытия файла: %v", err)
-		return "", errors.ErrFileOperation("не удалось открыть файл")
+		log.Printf("Ошибка конвертации модели %s: %v", filename, err)
+		return nil, errors.ErrInvalidTypeFile(err.Error())
 	}
-	defer src.Close()
 
-	fullPath := filepath.Join(StoragePath, dir, file.Filename)
-	log.Printf("Attempting to save file at: %s", fullPath)
+	saved := &SavedModel{}
+	if !strings.EqualFold(filepath.Ext(filename), AllowedModelExt) {
+		saved.SourcePath, err = fs.writeFile(data, SourcesDir, filename)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Модель %s сконвертирована в GLB (%d -> %d байт)", filename, len(data), len(glb))
+	}
+
+	saved.Path, err = fs.writeFile(glb, ModelsDir, ModelFileName(filename))
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// ContentHashedName inserts a short SHA-256 digest of data before the extension: "quartz.glb" becomes "quartz.<hash>.glb".
+func ContentHashedName(name string, data []byte) string {
+	sum := sha256.Sum256(data)
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:])[:ContentHashLength] + ext
+}
+
+// IsAllowedModelFile reports whether a model upload has one of the importable extensions.
+func IsAllowedModelFile(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, allowed := range strings.Split(AllowedSourceExts, ",") {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelFileName returns the name under which the converted GLB of an upload is stored.
+func ModelFileName(filename string) string {
+	base := filepath.Base(filename)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + AllowedModelExt
+}
+
+// writeFile stores data under a content-hashed name, so identical uploads share a file, different uploads
+// with the same name never overwrite each other, and the result can be cached as immutable.
+func (fs *FileService) writeFile(data []byte, dir, name string) (string, error) {
+	name = ContentHashedName(name, data)
+	fullPath := filepath.Join(fs.basePath, dir, name)
+	publicPath := filepath.Join(PublicPrefix, dir, name)
 
-	dirPath := filepath.Dir(fullPath)
-	if err := os.MkdirAll(dirPath, 0755); err != nil {
+	if fs.staging != nil {
+		if err := fs.staging.stage(publicPath, fullPath, data); err != nil {
+			log.Printf("Ошибка промежуточной записи файла %s: %v", fullPath, err)
+			return "", errors.ErrFileOperation(fmt.Sprintf("не удалось сохранить файл: %v", err))
+		}
+		return publicPath, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 		log.Printf("Ошибка создания директории: %v", err)
 		return "", errors.ErrFileOperation(fmt.Sprintf("не удалось создать директорию: %v", err))
 	}
+	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+		log.Printf("Ошибка записи файла %s: %v", fullPath, err)
+		return "", errors.ErrFileOperation(fmt.Sprintf("не удалось сохранить файл: %v", err))
+	}
+	log.Printf("Successfully written %d bytes to %s", len(data), fullPath)
 
-	dst, err := os.Create(fullPath)
+	return publicPath, nil
+}
+
+// Root returns the directory on disk that backs the public /storage prefix.
+func (fs *FileService) Root() string {
+	return fs.basePath
+}
+
+// FullPath maps a public /storage path to its location on disk. Paths that would leave the storage directory are rejected.
+func (fs *FileService) FullPath(urlPath string) (string, error) {
+	if strings.Contains(urlPath, "\\") || strings.Contains(urlPath, "\x00") {
+		return "", errors.ErrInvalidInput("некорректный путь к файлу")
+	}
+	for _, segment := range strings.Split(urlPath, "/") {
+		if segment == ".." {
+			return "", errors.ErrInvalidInput("некорректный путь к файлу")
+		}
+	}
+	cleaned := path.Clean("/" + urlPath)
+	if !strings.HasPrefix(cleaned, PublicPrefix+"/") {
+		return "", errors.ErrInvalidInput("путь должен начинаться с " + PublicPrefix)
+	}
+	if fs.staging != nil {
+		if stagedPath, ok := fs.staging.byPath[cleaned]; ok {
+			return stagedPath, nil
+		}
+	}
+	return filepath.Join(fs.basePath, filepath.FromSlash(strings.TrimPrefix(cleaned, PublicPrefix))), nil
+}
+
+func (fs *FileService) SavePreview(file *multipart.FileHeader) (string, error) {
+	if err := CheckPreview(file.Filename, file.Size); err != nil {
+		return "", err
+	}
+
+	return fs.saveFile(file, PreviewsDir)
+}
+
+// SavePreviewData stores a preview image read from somewhere other than an upload, with the same validation as SavePreview.
+func (fs *FileService) SavePreviewData(filename string, data []byte) (string, error) {
+	if err := CheckPreview(filename, int64(len(data))); err != nil {
+		return "", err
+	}
+	return fs.writeFile(data, PreviewsDir, filepath.Base(filename))
+}
+
+// CheckPreview checks the name and size of a preview image.
+func CheckPreview(filename string, size int64) error {
+	if size > MaxFileSize {
+		return errors.ErrFileTooBig("файл слишком большой")
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !hasExtension(AllowedImageExts, ext) {
+		return errors.ErrInvalidTypeFile("можно загружать только jpg и png")
+	}
+	return nil
+}
+
+func (fs *FileService) saveFile(file *multipart.FileHeader, dir string) (string, error) {
+	src, err := file.Open()
 	if err != nil {
-		log.Printf("Ошибка создания файла: %v", err)
-		return "", errors.ErrFileOperation(fmt.Sprintf("не удалось создать файл: %v", err))
+		log.Printf("Ошибка открытия файла: %v", err)
+		return "", errors.ErrFileOperation("не удалось открыть файл")
 	}
-	defer dst.Close()
+	defer src.Close()
 
-	written, err := io.Copy(dst, src)
+	data, err := io.ReadAll(src)
 	if err != nil {
 		log.Printf("Ошибка копирования файла: %v", err)
 		return "", errors.ErrFileOperation(fmt.Sprintf("не удалось сохранить файл: %v", err))
 	}
-	log.Printf("Successfully written %d bytes to %s", written, fullPath)
-
-	urlPath := filepath.Join("/storage", dir, file.Filename)
-	log.Printf("URL path for file: %s", urlPath)
 
-	return urlPath, nil
+	return fs.writeFile(data, dir, filepath.Base(file.Filename))
 }
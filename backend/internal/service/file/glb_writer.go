@@ -0,0 +1,231 @@
+// Serialization of imported geometry into a single self-contained binary glTF (GLB) file.
+// Every mesh becomes a primitive with float positions, optional normals, texture coordinates and vertex colors, and 32-bit indices.
+// Textures are embedded into the binary chunk, so the resulting file can be served and rendered without any companion files.
+
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+)
+
+const (
+	targetArrayBuffer        = 34962
+	targetElementArrayBuffer = 34963
+	glbGenerator             = "GazlinGO importer"
+)
+
+// importedMaterial is a base color material collected by the format importers.
+type importedMaterial struct {
+	Name        string
+	Color       [4]float32
+	Texture     []byte
+	TextureMime string
+}
+
+// importedMesh is an indexed triangle list with flat attribute arrays.
+type importedMesh struct {
+	Positions []float32
+	Normals   []float32
+	UVs       []float32
+	Colors    []float32
+	Indices   []uint32
+	Material  int
+}
+
+type importedScene struct {
+	Meshes    []importedMesh
+	Materials []importedMaterial
+}
+
+func (s *importedScene) vertexCount() int {
+	total := 0
+	for _, mesh := range s.Meshes {
+		total += len(mesh.Positions) / 3
+	}
+	return total
+}
+
+type glbBuilder struct {
+	bin         bytes.Buffer
+	bufferViews []map[string]interface{}
+	accessors   []map[string]interface{}
+}
+
+func (b *glbBuilder) align() {
+	for b.bin.Len()%4 != 0 {
+		b.bin.WriteByte(0)
+	}
+}
+
+func (b *glbBuilder) addView(data []byte, target int) int {
+	b.align()
+	view := map[string]interface{}{
+		"buffer":     0,
+		"byteOffset": b.bin.Len(),
+		"byteLength": len(data),
+	}
+	if target != 0 {
+		view["target"] = target
+	}
+	b.bin.Write(data)
+	b.bufferViews = append(b.bufferViews, view)
+	return len(b.bufferViews) - 1
+}
+
+func (b *glbBuilder) addFloats(values []float32, components int, accessorType string, withBounds bool) int {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, values)
+	view := b.addView(buf.Bytes(), targetArrayBuffer)
+
+	accessor := map[string]interface{}{
+		"bufferView":    view,
+		"componentType": 5126,
+		"count":         len(values) / components,
+		"type":          accessorType,
+	}
+	if withBounds {
+		min := make([]float64, components)
+		max := make([]float64, components)
+		for c := 0; c < components; c++ {
+			min[c], max[c] = math.Inf(1), math.Inf(-1)
+		}
+		for i, v := range values {
+			c := i % components
+			min[c] = math.Min(min[c], float64(v))
+			max[c] = math.Max(max[c], float64(v))
+		}
+		accessor["min"] = min
+		accessor["max"] = max
+	}
+	b.accessors = append(b.accessors, accessor)
+	return len(b.accessors) - 1
+}
+
+func (b *glbBuilder) addIndices(indices []uint32) int {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, indices)
+	view := b.addView(buf.Bytes(), targetElementArrayBuffer)
+	b.accessors = append(b.accessors, map[string]interface{}{
+		"bufferView":    view,
+		"componentType": 5125,
+		"count":         len(indices),
+		"type":          "SCALAR",
+	})
+	return len(b.accessors) - 1
+}
+
+// writeGLB packs the imported scene into a GLB container.
+func writeGLB(scene *importedScene) ([]byte, error) {
+	b := &glbBuilder{}
+
+	var images, textures, materials []map[string]interface{}
+	for _, m := range scene.Materials {
+		pbr := map[string]interface{}{
+			"baseColorFactor": []float32{m.Color[0], m.Color[1], m.Color[2], m.Color[3]},
+			"metallicFactor":  0,
+			"roughnessFactor": 0.8,
+		}
+		if len(m.Texture) > 0 {
+			view := b.addView(m.Texture, 0)
+			images = append(images, map[string]interface{}{"bufferView": view, "mimeType": m.TextureMime})
+			textures = append(textures, map[string]interface{}{"source": len(images) - 1, "sampler": 0})
+			pbr["baseColorTexture"] = map[string]interface{}{"index": len(textures) - 1}
+		}
+		material := map[string]interface{}{
+			"pbrMetallicRoughness": pbr,
+			"doubleSided":          true,
+		}
+		if m.Name != "" {
+			material["name"] = m.Name
+		}
+		if m.Color[3] < 1 {
+			material["alphaMode"] = "BLEND"
+		}
+		materials = append(materials, material)
+	}
+
+	var primitives []map[string]interface{}
+	for _, mesh := range scene.Meshes {
+		if len(mesh.Indices) == 0 || len(mesh.Positions) == 0 {
+			continue
+		}
+		attributes := map[string]int{
+			"POSITION": b.addFloats(mesh.Positions, 3, "VEC3", true),
+		}
+		if len(mesh.Normals) == len(mesh.Positions) {
+			attributes["NORMAL"] = b.addFloats(mesh.Normals, 3, "VEC3", false)
+		}
+		if len(mesh.UVs)/2 == len(mesh.Positions)/3 && len(mesh.UVs) > 0 {
+			attributes["TEXCOORD_0"] = b.addFloats(mesh.UVs, 2, "VEC2", false)
+		}
+		if len(mesh.Colors)/4 == len(mesh.Positions)/3 && len(mesh.Colors) > 0 {
+			attributes["COLOR_0"] = b.addFloats(mesh.Colors, 4, "VEC4", false)
+		}
+		primitive := map[string]interface{}{
+			"attributes": attributes,
+			"indices":    b.addIndices(mesh.Indices),
+			"mode":       4,
+		}
+		if mesh.Material >= 0 && mesh.Material < len(materials) {
+			primitive["material"] = mesh.Material
+		}
+		primitives = append(primitives, primitive)
+	}
+	if len(primitives) == 0 {
+		return nil, errEmptyImport
+	}
+	b.align()
+
+	doc := map[string]interface{}{
+		"asset":       map[string]interface{}{"version": "2.0", "generator": glbGenerator},
+		"scene":       0,
+		"scenes":      []interface{}{map[string]interface{}{"nodes": []int{0}}},
+		"nodes":       []interface{}{map[string]interface{}{"mesh": 0}},
+		"meshes":      []interface{}{map[string]interface{}{"primitives": primitives}},
+		"accessors":   b.accessors,
+		"bufferViews": b.bufferViews,
+		"buffers":     []interface{}{map[string]interface{}{"byteLength": b.bin.Len()}},
+	}
+	if len(materials) > 0 {
+		doc["materials"] = materials
+	}
+	if len(images) > 0 {
+		doc["images"] = images
+		doc["textures"] = textures
+		doc["samplers"] = []interface{}{map[string]interface{}{}}
+	}
+
+	jsonChunk, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return packGLB(jsonChunk, b.bin.Bytes()), nil
+}
+
+// packGLB wraps a JSON document and a binary buffer into the GLB container format.
+func packGLB(jsonChunk, binChunk []byte) []byte {
+	for len(jsonChunk)%4 != 0 {
+		jsonChunk = append(jsonChunk, ' ')
+	}
+	binPadding := (4 - len(binChunk)%4) % 4
+
+	total := 12 + 8 + len(jsonChunk)
+	if len(binChunk) > 0 {
+		total += 8 + len(binChunk) + binPadding
+	}
+
+	var out bytes.Buffer
+	out.Grow(total)
+	binary.Write(&out, binary.LittleEndian, []uint32{0x46546C67, 2, uint32(total)})
+	binary.Write(&out, binary.LittleEndian, []uint32{uint32(len(jsonChunk)), 0x4E4F534A})
+	out.Write(jsonChunk)
+	if len(binChunk) > 0 {
+		binary.Write(&out, binary.LittleEndian, []uint32{uint32(len(binChunk) + binPadding), 0x004E4942})
+		out.Write(binChunk)
+		out.Write(make([]byte, binPadding))
+	}
+	return out.Bytes()
+}
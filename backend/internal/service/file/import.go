@@ -0,0 +1,189 @@
+// Conversion of uploaded 3D models in foreign formats (OBJ+MTL, STL, PLY, glTF) into the GLB format served by the application.
+// Accepts single files as well as ZIP archives that bundle a model with its materials, textures and external buffers.
+// Companion files are looked up relative to the main model, with case-insensitive and base-name fallbacks for files exported on other systems.
+// Archive extraction is bounded in size and file count to protect the server from decompression bombs.
+
+package file
+
+import (
+	"archive/zip"
+	"bytes"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	MaxArchiveSize  = 4 * MaxFileSize
+	MaxArchiveFiles = 2000
+)
+
+var (
+	errEmptyImport    = stderrors.New("файл не содержит треугольной геометрии")
+	errMissingFile    = stderrors.New("связанный файл не найден")
+	errNoModelInZip   = stderrors.New("в архиве не найден файл модели")
+	errArchiveTooBig  = stderrors.New("архив слишком большой после распаковки")
+	errInvalidGLBFile = stderrors.New("файл не является корректным GLB")
+
+	// mainModelPriority defines which file of an archive is treated as the model when several candidates are present.
+	mainModelPriority = []string{".gltf", ".glb", ".obj", ".ply", ".stl"}
+)
+
+// fileResolver loads a companion file referenced by a model, such as an MTL library, a texture or a glTF buffer.
+type fileResolver func(name string) ([]byte, error)
+
+func noResolver(name string) ([]byte, error) {
+	return nil, fmt.Errorf("%w: %s", errMissingFile, name)
+}
+
+// convertModel turns an uploaded model into GLB bytes. GLB input is returned unchanged after a header check.
+func convertModel(filename string, data []byte) ([]byte, error) {
+	if strings.ToLower(path.Ext(filename)) != ".zip" {
+		return convertWith(filename, data, noResolver)
+	}
+
+	files, err := readArchive(data)
+	if err != nil {
+		return nil, err
+	}
+	main, err := findMainModel(files)
+	if err != nil {
+		return nil, err
+	}
+	return convertWith(main, files[main], archiveResolver(files, path.Dir(main)))
+}
+
+func convertWith(name string, data []byte, resolve fileResolver) ([]byte, error) {
+	var scene *importedScene
+	var err error
+
+	switch strings.ToLower(path.Ext(name)) {
+	case ".glb":
+		if len(data) < 12 || string(data[:4]) != "glTF" {
+			return nil, errInvalidGLBFile
+		}
+		return data, nil
+	case ".gltf":
+		return repackGLTF(data, resolve)
+	case ".obj":
+		scene, err = importOBJ(data, resolve)
+	case ".stl":
+		scene, err = importSTL(data)
+	case ".ply":
+		scene, err = importPLY(data, resolve)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат модели: %s", path.Ext(name))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return writeGLB(scene)
+}
+
+func readArchive(data []byte) (map[string][]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать zip архив: %v", err)
+	}
+	if len(reader.File) > MaxArchiveFiles {
+		return nil, fmt.Errorf("в архиве слишком много файлов: %d", len(reader.File))
+	}
+
+	files := make(map[string][]byte)
+	var total int64
+	for _, f := range reader.File {
+		name := path.Clean(strings.ReplaceAll(f.Name, "\\", "/"))
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "._") {
+			continue
+		}
+		if strings.HasPrefix(name, "../") || strings.HasPrefix(name, "/") {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("не удалось распаковать %s: %v", f.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, MaxArchiveSize-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("не удалось распаковать %s: %v", f.Name, err)
+		}
+		total += int64(len(content))
+		if total > MaxArchiveSize {
+			return nil, errArchiveTooBig
+		}
+		files[name] = content
+	}
+	return files, nil
+}
+
+func findMainModel(files map[string][]byte) (string, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		di, dj := strings.Count(names[i], "/"), strings.Count(names[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return names[i] < names[j]
+	})
+
+	for _, ext := range mainModelPriority {
+		for _, name := range names {
+			if strings.ToLower(path.Ext(name)) == ext {
+				return name, nil
+			}
+		}
+	}
+	return "", errNoModelInZip
+}
+
+func archiveResolver(files map[string][]byte, baseDir string) fileResolver {
+	return func(name string) ([]byte, error) {
+		if decoded, err := url.PathUnescape(name); err == nil {
+			name = decoded
+		}
+		name = strings.ReplaceAll(strings.TrimSpace(name), "\\", "/")
+
+		candidate := path.Clean(path.Join(baseDir, name))
+		if data, ok := files[candidate]; ok {
+			return data, nil
+		}
+		for stored, data := range files {
+			if strings.EqualFold(stored, candidate) {
+				return data, nil
+			}
+		}
+		base := path.Base(name)
+		for stored, data := range files {
+			if strings.EqualFold(path.Base(stored), base) {
+				return data, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s", errMissingFile, name)
+	}
+}
+
+// loadTexture resolves an image referenced by a material. Only PNG and JPEG can be embedded into glTF.
+func loadTexture(resolve fileResolver, name string) ([]byte, string) {
+	if name == "" {
+		return nil, ""
+	}
+	data, err := resolve(name)
+	if err != nil {
+		return nil, ""
+	}
+	mime := http.DetectContentType(data)
+	if mime != "image/png" && mime != "image/jpeg" {
+		return nil, ""
+	}
+	return data, mime
+}
@@ -0,0 +1,112 @@
+// Repackaging of multi-file glTF 2.0 models (a .gltf JSON document with external or data-URI buffers and images) into a single GLB.
+// The scene description is kept intact, so animations, extensions and PBR materials survive the conversion unchanged.
+// All buffers are concatenated into the binary chunk and images are moved into buffer views with an explicit MIME type.
+
+package file
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func repackGLTF(data []byte, resolve fileResolver) ([]byte, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("некорректный glTF документ: %v", err)
+	}
+
+	var bin bytes.Buffer
+	align := func() {
+		for bin.Len()%4 != 0 {
+			bin.WriteByte(0)
+		}
+	}
+
+	buffers, _ := doc["buffers"].([]interface{})
+	offsets := make([]int, len(buffers))
+	for i, raw := range buffers {
+		buffer, _ := raw.(map[string]interface{})
+		uri, _ := buffer["uri"].(string)
+		content, err := loadURI(uri, resolve)
+		if err != nil {
+			return nil, fmt.Errorf("буфер %d: %v", i, err)
+		}
+		align()
+		offsets[i] = bin.Len()
+		bin.Write(content)
+	}
+
+	views, _ := doc["bufferViews"].([]interface{})
+	for i, raw := range views {
+		view, _ := raw.(map[string]interface{})
+		index, _ := view["buffer"].(float64)
+		if int(index) < 0 || int(index) >= len(offsets) {
+			return nil, fmt.Errorf("bufferView %d ссылается на несуществующий буфер", i)
+		}
+		byteOffset, _ := view["byteOffset"].(float64)
+		view["buffer"] = 0
+		view["byteOffset"] = offsets[int(index)] + int(byteOffset)
+	}
+
+	images, _ := doc["images"].([]interface{})
+	for i, raw := range images {
+		image, _ := raw.(map[string]interface{})
+		uri, ok := image["uri"].(string)
+		if !ok {
+			continue
+		}
+		content, err := loadURI(uri, resolve)
+		if err != nil {
+			return nil, fmt.Errorf("изображение %d: %v", i, err)
+		}
+		align()
+		views = append(views, map[string]interface{}{
+			"buffer":     0,
+			"byteOffset": bin.Len(),
+			"byteLength": len(content),
+		})
+		bin.Write(content)
+		delete(image, "uri")
+		image["bufferView"] = len(views) - 1
+		if _, ok := image["mimeType"]; !ok {
+			image["mimeType"] = http.DetectContentType(content)
+		}
+	}
+	align()
+
+	if len(views) > 0 {
+		doc["bufferViews"] = views
+	}
+	if bin.Len() > 0 {
+		doc["buffers"] = []interface{}{map[string]interface{}{"byteLength": bin.Len()}}
+	} else {
+		delete(doc, "buffers")
+	}
+
+	jsonChunk, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return packGLB(jsonChunk, bin.Bytes()), nil
+}
+
+func loadURI(uri string, resolve fileResolver) ([]byte, error) {
+	if uri == "" {
+		return nil, fmt.Errorf("%w: пустой uri", errMissingFile)
+	}
+	if strings.HasPrefix(uri, "data:") {
+		comma := strings.Index(uri, ",")
+		if comma < 0 || !strings.Contains(uri[:comma], ";base64") {
+			return nil, fmt.Errorf("неподдерживаемый data uri")
+		}
+		return base64.StdEncoding.DecodeString(uri[comma+1:])
+	}
+	if strings.Contains(uri, "://") {
+		return nil, fmt.Errorf("внешние ссылки не поддерживаются: %s", uri)
+	}
+	return resolve(uri)
+}
@@ -0,0 +1,274 @@
+// Importer for Wavefront OBJ files with MTL material libraries, the usual export format of photogrammetry and scanning software.
+// Polygons are fan-triangulated, negative (relative) indices are supported, and vertices are deduplicated per material.
+// Diffuse colors, opacity and diffuse texture maps are taken from the material library when it can be resolved.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// mtlOptionArgs lists map_Kd options and the maximum number of arguments each of them takes.
+var mtlOptionArgs = map[string]int{
+	"-blendu": 1, "-blendv": 1, "-bm": 1, "-boost": 1, "-cc": 1, "-clamp": 1,
+	"-imfchan": 1, "-mm": 2, "-o": 3, "-s": 3, "-t": 3, "-texres": 1, "-type": 1,
+}
+
+type objBuilder struct {
+	mesh          importedMesh
+	vertices      map[[3]int]uint32
+	missingNormal bool
+	missingUV     bool
+}
+
+type objImporter struct {
+	resolve   fileResolver
+	scene     importedScene
+	materials map[string]int
+	builders  map[int]*objBuilder
+	order     []int
+	positions [][3]float32
+	normals   [][3]float32
+	uvs       [][2]float32
+}
+
+func importOBJ(data []byte, resolve fileResolver) (*importedScene, error) {
+	imp := &objImporter{
+		resolve:   resolve,
+		materials: make(map[string]int),
+		builders:  make(map[int]*objBuilder),
+	}
+	current := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(text)
+
+		switch fields[0] {
+		case "v":
+			v, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("OBJ строка %d: %v", line, err)
+			}
+			imp.positions = append(imp.positions, [3]float32{v[0], v[1], v[2]})
+		case "vn":
+			v, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return nil, fmt.Errorf("OBJ строка %d: %v", line, err)
+			}
+			imp.normals = append(imp.normals, [3]float32{v[0], v[1], v[2]})
+		case "vt":
+			v, err := parseFloats(fields[1:], 2)
+			if err != nil {
+				return nil, fmt.Errorf("OBJ строка %d: %v", line, err)
+			}
+			imp.uvs = append(imp.uvs, [2]float32{v[0], 1 - v[1]})
+		case "f":
+			if current < 0 {
+				current = imp.material("")
+			}
+			if err := imp.face(current, fields[1:]); err != nil {
+				return nil, fmt.Errorf("OBJ строка %d: %v", line, err)
+			}
+		case "usemtl":
+			current = imp.material(strings.TrimSpace(strings.TrimPrefix(text, "usemtl")))
+		case "mtllib":
+			imp.loadLibrary(strings.TrimSpace(strings.TrimPrefix(text, "mtllib")))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("не удалось прочитать OBJ: %v", err)
+	}
+
+	for _, material := range imp.order {
+		b := imp.builders[material]
+		if b.missingNormal {
+			b.mesh.Normals = nil
+		}
+		if b.missingUV {
+			b.mesh.UVs = nil
+		}
+		if len(b.mesh.Indices) > 0 {
+			imp.scene.Meshes = append(imp.scene.Meshes, b.mesh)
+		}
+	}
+	if len(imp.scene.Meshes) == 0 {
+		return nil, errEmptyImport
+	}
+	return &imp.scene, nil
+}
+
+func (imp *objImporter) material(name string) int {
+	if index, ok := imp.materials[name]; ok {
+		return index
+	}
+	material := importedMaterial{Name: name, Color: [4]float32{1, 1, 1, 1}}
+	if name == "" {
+		material.Color = [4]float32{0.8, 0.8, 0.8, 1}
+	}
+	imp.scene.Materials = append(imp.scene.Materials, material)
+	imp.materials[name] = len(imp.scene.Materials) - 1
+	return imp.materials[name]
+}
+
+func (imp *objImporter) face(material int, tokens []string) error {
+	if len(tokens) < 3 {
+		return fmt.Errorf("грань содержит меньше трёх вершин")
+	}
+
+	b, ok := imp.builders[material]
+	if !ok {
+		b = &objBuilder{mesh: importedMesh{Material: material}, vertices: make(map[[3]int]uint32)}
+		imp.builders[material] = b
+		imp.order = append(imp.order, material)
+	}
+
+	corners := make([]uint32, len(tokens))
+	for i, token := range tokens {
+		key := [3]int{-1, -1, -1}
+		parts := strings.Split(token, "/")
+		lengths := []int{len(imp.positions), len(imp.uvs), len(imp.normals)}
+		for k := 0; k < len(parts) && k < 3; k++ {
+			if parts[k] == "" {
+				continue
+			}
+			index, err := strconv.Atoi(parts[k])
+			if err != nil {
+				return fmt.Errorf("некорректный индекс %q", token)
+			}
+			if index < 0 {
+				index += lengths[k]
+			} else {
+				index--
+			}
+			if index < 0 || index >= lengths[k] {
+				return fmt.Errorf("индекс вне диапазона %q", token)
+			}
+			key[k] = index
+		}
+		if key[0] < 0 {
+			return fmt.Errorf("вершина без позиции %q", token)
+		}
+
+		if existing, ok := b.vertices[key]; ok {
+			corners[i] = existing
+			continue
+		}
+		p := imp.positions[key[0]]
+		b.mesh.Positions = append(b.mesh.Positions, p[0], p[1], p[2])
+		if key[1] >= 0 {
+			uv := imp.uvs[key[1]]
+			b.mesh.UVs = append(b.mesh.UVs, uv[0], uv[1])
+		} else {
+			b.missingUV = true
+			b.mesh.UVs = append(b.mesh.UVs, 0, 0)
+		}
+		if key[2] >= 0 {
+			n := imp.normals[key[2]]
+			b.mesh.Normals = append(b.mesh.Normals, n[0], n[1], n[2])
+		} else {
+			b.missingNormal = true
+			b.mesh.Normals = append(b.mesh.Normals, 0, 0, 0)
+		}
+		index := uint32(len(b.mesh.Positions)/3 - 1)
+		b.vertices[key] = index
+		corners[i] = index
+	}
+
+	for i := 2; i < len(corners); i++ {
+		b.mesh.Indices = append(b.mesh.Indices, corners[0], corners[i-1], corners[i])
+	}
+	return nil
+}
+
+func (imp *objImporter) loadLibrary(name string) {
+	data, err := imp.resolve(name)
+	if err != nil {
+		return
+	}
+
+	current := -1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		fields := strings.Fields(text)
+
+		if fields[0] == "newmtl" {
+			current = imp.material(strings.TrimSpace(strings.TrimPrefix(text, "newmtl")))
+			continue
+		}
+		if current < 0 {
+			continue
+		}
+		material := &imp.scene.Materials[current]
+
+		switch fields[0] {
+		case "Kd":
+			if v, err := parseFloats(fields[1:], 3); err == nil && material.Texture == nil {
+				material.Color[0], material.Color[1], material.Color[2] = v[0], v[1], v[2]
+			}
+		case "d":
+			if v, err := parseFloats(fields[1:], 1); err == nil {
+				material.Color[3] = v[0]
+			}
+		case "Tr":
+			if v, err := parseFloats(fields[1:], 1); err == nil {
+				material.Color[3] = 1 - v[0]
+			}
+		case "map_Kd":
+			texture, mime := loadTexture(imp.resolve, textureName(fields[1:]))
+			if texture != nil {
+				material.Texture, material.TextureMime = texture, mime
+				material.Color[0], material.Color[1], material.Color[2] = 1, 1, 1
+			}
+		}
+	}
+}
+
+// textureName strips map_Kd options and returns the remaining file name, which may contain spaces.
+func textureName(tokens []string) string {
+	i := 0
+	for i < len(tokens) {
+		args, ok := mtlOptionArgs[tokens[i]]
+		if !ok {
+			break
+		}
+		i++
+		for n := 0; n < args && i < len(tokens)-1; n++ {
+			if _, err := strconv.ParseFloat(tokens[i], 64); err != nil && tokens[i] != "on" && tokens[i] != "off" {
+				break
+			}
+			i++
+		}
+	}
+	return strings.Join(tokens[i:], " ")
+}
+
+func parseFloats(tokens []string, want int) ([]float32, error) {
+	if len(tokens) < want {
+		return nil, fmt.Errorf("ожидалось %d чисел", want)
+	}
+	out := make([]float32, want)
+	for i := 0; i < want; i++ {
+		v, err := strconv.ParseFloat(tokens[i], 32)
+		if err != nil {
+			return nil, fmt.Errorf("некорректное число %q", tokens[i])
+		}
+		out[i] = float32(v)
+	}
+	return out, nil
+}
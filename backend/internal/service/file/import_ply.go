@@ -0,0 +1,326 @@
+// Importer for Stanford PLY meshes in ASCII and binary (little and big endian) encodings, common for structured-light scanners.
+// Reads positions, normals, per-vertex colors and texture coordinates, as well as per-face texture coordinates written by MeshLab.
+// A texture referenced through a "comment TextureFile" header line is embedded when it can be resolved.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type plyProperty struct {
+	name      string
+	valueType string
+	countType string
+	list      bool
+}
+
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+type plyHeader struct {
+	format      string
+	elements    []plyElement
+	textureFile string
+}
+
+// plyReader reads scalar values from the body of a PLY file regardless of its encoding.
+type plyReader struct {
+	format string
+	order  binary.ByteOrder
+	bin    *bytes.Reader
+	ascii  *bufio.Scanner
+}
+
+func importPLY(data []byte, resolve fileResolver) (*importedScene, error) {
+	header, body, err := parsePLYHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &plyReader{format: header.format}
+	switch header.format {
+	case "ascii":
+		reader.ascii = bufio.NewScanner(bytes.NewReader(body))
+		reader.ascii.Buffer(make([]byte, 64*1024), 1024*1024)
+		reader.ascii.Split(bufio.ScanWords)
+	case "binary_little_endian":
+		reader.order, reader.bin = binary.LittleEndian, bytes.NewReader(body)
+	case "binary_big_endian":
+		reader.order, reader.bin = binary.BigEndian, bytes.NewReader(body)
+	default:
+		return nil, fmt.Errorf("неподдерживаемый формат PLY: %s", header.format)
+	}
+
+	var vertices []map[string]float64
+	var faces [][]uint32
+	var faceUVs [][]float32
+	for _, element := range header.elements {
+		for i := 0; i < element.count; i++ {
+			values := make(map[string]float64, len(element.properties))
+			var indices []uint32
+			var texcoords []float32
+			for _, prop := range element.properties {
+				if !prop.list {
+					v, err := reader.value(prop.valueType)
+					if err != nil {
+						return nil, fmt.Errorf("PLY %s %d: %v", element.name, i, err)
+					}
+					values[prop.name] = v
+					continue
+				}
+
+				count, err := reader.value(prop.countType)
+				if err != nil || count < 0 || count > 1<<16 {
+					return nil, fmt.Errorf("PLY %s %d: некорректная длина списка", element.name, i)
+				}
+				for k := 0; k < int(count); k++ {
+					v, err := reader.value(prop.valueType)
+					if err != nil {
+						return nil, fmt.Errorf("PLY %s %d: %v", element.name, i, err)
+					}
+					switch prop.name {
+					case "vertex_indices", "vertex_index":
+						indices = append(indices, uint32(v))
+					case "texcoord":
+						texcoords = append(texcoords, float32(v))
+					}
+				}
+			}
+
+			switch element.name {
+			case "vertex":
+				vertices = append(vertices, values)
+			case "face":
+				faces = append(faces, indices)
+				faceUVs = append(faceUVs, texcoords)
+			}
+		}
+	}
+
+	mesh := buildPLYMesh(header, vertices, faces, faceUVs)
+	if len(mesh.Indices) == 0 {
+		return nil, errEmptyImport
+	}
+
+	material := importedMaterial{Color: [4]float32{1, 1, 1, 1}}
+	if mesh.UVs != nil {
+		material.Texture, material.TextureMime = loadTexture(resolve, header.textureFile)
+	}
+	if material.Texture == nil && mesh.Colors == nil {
+		material.Color = [4]float32{0.8, 0.8, 0.8, 1}
+	}
+	return &importedScene{Meshes: []importedMesh{mesh}, Materials: []importedMaterial{material}}, nil
+}
+
+func parsePLYHeader(data []byte) (*plyHeader, []byte, error) {
+	end := bytes.Index(data, []byte("end_header"))
+	if !bytes.HasPrefix(data, []byte("ply")) || end < 0 {
+		return nil, nil, fmt.Errorf("файл не является PLY")
+	}
+	bodyStart := end + len("end_header")
+	if bodyStart < len(data) && data[bodyStart] == '\r' {
+		bodyStart++
+	}
+	if bodyStart < len(data) && data[bodyStart] == '\n' {
+		bodyStart++
+	}
+
+	header := &plyHeader{}
+	for _, line := range strings.Split(string(data[:end]), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "format":
+			if len(fields) >= 2 {
+				header.format = fields[1]
+			}
+		case "comment", "obj_info":
+			if len(fields) >= 3 && strings.EqualFold(fields[1], "TextureFile") {
+				header.textureFile = strings.TrimSpace(strings.SplitN(strings.TrimSpace(line), fields[1], 2)[1])
+			}
+		case "element":
+			if len(fields) < 3 {
+				return nil, nil, fmt.Errorf("некорректный заголовок PLY: %s", line)
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil || count < 0 {
+				return nil, nil, fmt.Errorf("некорректный заголовок PLY: %s", line)
+			}
+			header.elements = append(header.elements, plyElement{name: fields[1], count: count})
+		case "property":
+			if len(header.elements) == 0 {
+				return nil, nil, fmt.Errorf("свойство PLY вне элемента")
+			}
+			element := &header.elements[len(header.elements)-1]
+			switch {
+			case len(fields) == 5 && fields[1] == "list":
+				element.properties = append(element.properties, plyProperty{name: fields[4], countType: fields[2], valueType: fields[3], list: true})
+			case len(fields) == 3:
+				element.properties = append(element.properties, plyProperty{name: fields[2], valueType: fields[1]})
+			default:
+				return nil, nil, fmt.Errorf("некорректный заголовок PLY: %s", line)
+			}
+		}
+	}
+	return header, data[bodyStart:], nil
+}
+
+func (r *plyReader) value(valueType string) (float64, error) {
+	if r.ascii != nil {
+		if !r.ascii.Scan() {
+			return 0, io.ErrUnexpectedEOF
+		}
+		return strconv.ParseFloat(r.ascii.Text(), 64)
+	}
+
+	switch valueType {
+	case "char", "int8":
+		var v int8
+		err := binary.Read(r.bin, r.order, &v)
+		return float64(v), err
+	case "uchar", "uint8":
+		var v uint8
+		err := binary.Read(r.bin, r.order, &v)
+		return float64(v), err
+	case "short", "int16":
+		var v int16
+		err := binary.Read(r.bin, r.order, &v)
+		return float64(v), err
+	case "ushort", "uint16":
+		var v uint16
+		err := binary.Read(r.bin, r.order, &v)
+		return float64(v), err
+	case "int", "int32":
+		var v int32
+		err := binary.Read(r.bin, r.order, &v)
+		return float64(v), err
+	case "uint", "uint32":
+		var v uint32
+		err := binary.Read(r.bin, r.order, &v)
+		return float64(v), err
+	case "float", "float32":
+		var v float32
+		err := binary.Read(r.bin, r.order, &v)
+		return float64(v), err
+	case "double", "float64":
+		var v float64
+		err := binary.Read(r.bin, r.order, &v)
+		return v, err
+	}
+	return 0, fmt.Errorf("неизвестный тип PLY: %s", valueType)
+}
+
+func buildPLYMesh(header *plyHeader, vertices []map[string]float64, faces [][]uint32, faceUVs [][]float32) importedMesh {
+	var colorScale float64 = 255
+	for _, element := range header.elements {
+		for _, prop := range element.properties {
+			if element.name == "vertex" && prop.name == "red" && (prop.valueType == "float" || prop.valueType == "float32" || prop.valueType == "double") {
+				colorScale = 1
+			}
+		}
+	}
+
+	_, hasNormals := firstVertex(vertices)["nx"]
+	_, hasColors := firstVertex(vertices)["red"]
+	uKey, vKey := uvKeys(firstVertex(vertices))
+	perFaceUV := false
+	for _, uv := range faceUVs {
+		if len(uv) > 0 {
+			perFaceUV = true
+			break
+		}
+	}
+
+	var mesh importedMesh
+	emit := func(v map[string]float64) {
+		mesh.Positions = append(mesh.Positions, float32(v["x"]), float32(v["y"]), float32(v["z"]))
+		if hasNormals {
+			mesh.Normals = append(mesh.Normals, float32(v["nx"]), float32(v["ny"]), float32(v["nz"]))
+		}
+		if hasColors {
+			alpha := colorScale
+			if a, ok := v["alpha"]; ok {
+				alpha = a
+			}
+			mesh.Colors = append(mesh.Colors,
+				float32(math.Min(1, v["red"]/colorScale)),
+				float32(math.Min(1, v["green"]/colorScale)),
+				float32(math.Min(1, v["blue"]/colorScale)),
+				float32(math.Min(1, alpha/colorScale)))
+		}
+		if uKey != "" && !perFaceUV {
+			mesh.UVs = append(mesh.UVs, float32(v[uKey]), float32(1-v[vKey]))
+		}
+	}
+
+	if !perFaceUV {
+		for _, v := range vertices {
+			emit(v)
+		}
+	}
+
+	for f, face := range faces {
+		valid := len(face) >= 3
+		for _, index := range face {
+			if int(index) >= len(vertices) {
+				valid = false
+			}
+		}
+		if !valid {
+			continue
+		}
+
+		if !perFaceUV {
+			for i := 2; i < len(face); i++ {
+				mesh.Indices = append(mesh.Indices, face[0], face[i-1], face[i])
+			}
+			continue
+		}
+
+		uvs := faceUVs[f]
+		base := uint32(len(mesh.Positions) / 3)
+		for k, index := range face {
+			emit(vertices[index])
+			if len(uvs) >= (k+1)*2 {
+				mesh.UVs = append(mesh.UVs, uvs[k*2], 1-uvs[k*2+1])
+			} else {
+				mesh.UVs = append(mesh.UVs, 0, 0)
+			}
+		}
+		for i := 2; i < len(face); i++ {
+			mesh.Indices = append(mesh.Indices, base, base+uint32(i-1), base+uint32(i))
+		}
+	}
+	return mesh
+}
+
+func firstVertex(vertices []map[string]float64) map[string]float64 {
+	if len(vertices) == 0 {
+		return map[string]float64{}
+	}
+	return vertices[0]
+}
+
+func uvKeys(vertex map[string]float64) (string, string) {
+	for _, pair := range [][2]string{{"s", "t"}, {"u", "v"}, {"texture_u", "texture_v"}} {
+		_, hasU := vertex[pair[0]]
+		_, hasV := vertex[pair[1]]
+		if hasU && hasV {
+			return pair[0], pair[1]
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,125 @@
+// Importer for STL meshes in both binary and ASCII flavours.
+// STL has no shared vertices or materials, so every facet becomes three vertices carrying its face normal.
+// STL files come from CAD and 3D printing tools that use a Z-up convention, so geometry is rotated into glTF's Y-up space.
+
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const stlHeaderSize = 84
+
+func importSTL(data []byte) (*importedScene, error) {
+	var mesh importedMesh
+	var err error
+	if isBinarySTL(data) {
+		mesh, err = readBinarySTL(data)
+	} else {
+		mesh, err = readASCIISTL(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(mesh.Indices) == 0 {
+		return nil, errEmptyImport
+	}
+
+	return &importedScene{
+		Meshes:    []importedMesh{mesh},
+		Materials: []importedMaterial{{Color: [4]float32{0.8, 0.8, 0.8, 1}}},
+	}, nil
+}
+
+// isBinarySTL relies on the declared facet count, because many binary files also start with "solid".
+func isBinarySTL(data []byte) bool {
+	if len(data) < stlHeaderSize {
+		return false
+	}
+	count := binary.LittleEndian.Uint32(data[80:84])
+	return int64(len(data)) == stlHeaderSize+int64(count)*50
+}
+
+func readBinarySTL(data []byte) (importedMesh, error) {
+	count := int(binary.LittleEndian.Uint32(data[80:84]))
+	mesh := importedMesh{
+		Positions: make([]float32, 0, count*9),
+		Normals:   make([]float32, 0, count*9),
+		Indices:   make([]uint32, 0, count*3),
+	}
+
+	for i := 0; i < count; i++ {
+		offset := stlHeaderSize + i*50
+		var values [12]float32
+		for k := range values {
+			values[k] = math.Float32frombits(binary.LittleEndian.Uint32(data[offset+k*4:]))
+		}
+		addSTLFacet(&mesh, values[3:6], values[6:9], values[9:12])
+	}
+	return mesh, nil
+}
+
+func readASCIISTL(data []byte) (importedMesh, error) {
+	var mesh importedMesh
+	var vertices [][]float32
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToLower(fields[0]) {
+		case "facet":
+			vertices = vertices[:0]
+		case "vertex":
+			v, err := parseFloats(fields[1:], 3)
+			if err != nil {
+				return mesh, fmt.Errorf("STL строка %d: %v", line, err)
+			}
+			vertices = append(vertices, v)
+		case "endfacet":
+			for i := 2; i < len(vertices); i++ {
+				addSTLFacet(&mesh, vertices[0], vertices[i-1], vertices[i])
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return mesh, fmt.Errorf("не удалось прочитать STL: %v", err)
+	}
+	return mesh, nil
+}
+
+// addSTLFacet appends a triangle converted from Z-up to Y-up with a freshly computed face normal.
+// Declared STL normals are ignored because exporters frequently leave them zeroed.
+func addSTLFacet(mesh *importedMesh, a, b, c []float32) {
+	corners := [3][3]float32{
+		{a[0], a[2], -a[1]},
+		{b[0], b[2], -b[1]},
+		{c[0], c[2], -c[1]},
+	}
+
+	ux, uy, uz := corners[1][0]-corners[0][0], corners[1][1]-corners[0][1], corners[1][2]-corners[0][2]
+	vx, vy, vz := corners[2][0]-corners[0][0], corners[2][1]-corners[0][1], corners[2][2]-corners[0][2]
+	nx, ny, nz := uy*vz-uz*vy, uz*vx-ux*vz, ux*vy-uy*vx
+	length := float32(math.Sqrt(float64(nx*nx + ny*ny + nz*nz)))
+	if length == 0 {
+		return
+	}
+	nx, ny, nz = nx/length, ny/length, nz/length
+
+	base := uint32(len(mesh.Positions) / 3)
+	for _, p := range corners {
+		mesh.Positions = append(mesh.Positions, p[0], p[1], p[2])
+		mesh.Normals = append(mesh.Normals, nx, ny, nz)
+	}
+	mesh.Indices = append(mesh.Indices, base, base+1, base+2)
+}
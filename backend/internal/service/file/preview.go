@@ -0,0 +1,99 @@
+// Preview generation for stored 3D models, built on top of the software rasterizer from the render package.
+// Resolves public /storage paths to files on disk, renders a lit turntable thumbnail and optionally a sprite sheet,
+// and writes the resulting PNG images next to the uploaded previews so they are served like any other preview.
+
+package file
+
+import (
+	"backend/internal/api/errors"
+	"backend/internal/service/render"
+	stderrors "errors"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	GeneratedPreviewExt = ".png"
+	SpriteSuffix        = "_turntable"
+)
+
+type Turntable struct {
+	Path      string `json:"path"`
+	Frames    int    `json:"frames"`
+	Columns   int    `json:"columns"`
+	FrameSize int    `json:"frame_size"`
+}
+
+// RenderPreview draws a thumbnail of the model stored at modelPath and returns the public path of the saved image.
+func (fs *FileService) RenderPreview(modelPath string) (string, error) {
+	scene, err := fs.loadScene(modelPath)
+	if err != nil {
+		return "", err
+	}
+
+	img := render.NewRenderer(render.Options{}).Thumbnail(scene, render.DefaultYaw)
+	return fs.writePNG(img, previewName(modelPath, ""))
+}
+
+// RenderTurntable draws a full revolution of the model into a sprite sheet and returns its public path and layout.
+func (fs *FileService) RenderTurntable(modelPath string, frames int) (*Turntable, error) {
+	scene, err := fs.loadScene(modelPath)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := render.NewRenderer(render.Options{}).Turntable(scene, frames, render.SpriteFrameSize)
+	path, err := fs.writePNG(sheet.Image, previewName(modelPath, SpriteSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Turntable{
+		Path:      path,
+		Frames:    sheet.Frames,
+		Columns:   sheet.Columns,
+		FrameSize: sheet.FrameSize,
+	}, nil
+}
+
+func (fs *FileService) loadScene(modelPath string) (*render.Scene, error) {
+	if !strings.HasSuffix(strings.ToLower(modelPath), AllowedModelExt) {
+		return nil, errors.ErrInvalidTypeFile("превью можно построить только по .glb модели")
+	}
+
+	fullPath, err := fs.FullPath(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	scene, err := render.LoadGLB(fullPath)
+	if err != nil {
+		log.Printf("Ошибка чтения модели %s: %v", fullPath, err)
+		if os.IsNotExist(err) {
+			return nil, errors.ErrNotFound("файл модели не найден")
+		}
+		if stderrors.Is(err, render.ErrInvalidGLB) || stderrors.Is(err, render.ErrEmptyScene) ||
+			stderrors.Is(err, render.ErrBadAccessor) || stderrors.Is(err, render.ErrUnsupported) {
+			return nil, errors.ErrInvalidTypeFile(err.Error())
+		}
+		return nil, errors.ErrFileOperation("не удалось прочитать файл модели")
+	}
+	return scene, nil
+}
+
+func (fs *FileService) writePNG(img image.Image, name string) (string, error) {
+	data, err := render.EncodePNG(img)
+	if err != nil {
+		log.Printf("Ошибка кодирования превью: %v", err)
+		return "", errors.ErrFileOperation("не удалось закодировать превью")
+	}
+
+	return fs.writeFile(data, PreviewsDir, name)
+}
+
+func previewName(modelPath, suffix string) string {
+	base := filepath.Base(modelPath)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + suffix + GeneratedPreviewExt
+}
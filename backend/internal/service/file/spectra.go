@@ -0,0 +1,39 @@
+// Storage of uploaded spectrum files (delimited text or JCAMP-DX). Like CIFs, a spectrum is read and parsed
+// before it is written; the parsed series lives in the database and the original file is kept for download.
+
+package file
+
+import (
+	"backend/internal/api/errors"
+	"bytes"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	SpectraDir          = "/spectra"
+	AllowedSpectrumExts = ".csv,.txt,.dat,.xy,.jdx,.dx,.jcamp"
+	MaxSpectrumSize     = 20 << 20
+)
+
+// ReadSpectrum checks the extension and size of an uploaded spectrum and returns its contents.
+func (fs *FileService) ReadSpectrum(file *multipart.FileHeader) ([]byte, error) {
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !hasExtension(AllowedSpectrumExts, ext) {
+		return nil, errors.ErrInvalidTypeFile("можно загружать только файлы " + AllowedSpectrumExts)
+	}
+	data, err := ReadUpload(file, MaxSpectrumSize)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return nil, errors.ErrInvalidTypeFile("спектр должен быть текстовым файлом")
+	}
+	return data, nil
+}
+
+// SaveSpectrum stores the contents of a spectrum file that has already been read and parsed.
+func (fs *FileService) SaveSpectrum(data []byte, filename string) (string, error) {
+	return fs.writeFile(data, SpectraDir, filepath.Base(filename))
+}
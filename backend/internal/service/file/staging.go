@@ -0,0 +1,147 @@
+// Staged file writes that become visible only when the surrounding operation commits.
+// A FileService bound to a Staging writes new files into a private directory under .staging, which is never served.
+// Commit moves the files to their public locations with atomic renames; Rollback removes staged files and any file Commit has already placed.
+// Together with a database transaction this ensures that a failed create or update neither leaks files nor exposes a half-written record.
+
+package file
+
+import (
+	"backend/internal/api/errors"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	StagingDir    = "/.staging"
+	StagingMaxAge = time.Hour
+)
+
+type stagedFile struct {
+	publicPath string
+	stagedPath string
+	finalPath  string
+}
+
+type Staging struct {
+	dir    string
+	files  []stagedFile
+	byPath map[string]string
+	placed []string
+	done   bool
+}
+
+// NewStaging creates an empty staging area for one operation.
+func (fs *FileService) NewStaging() (*Staging, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, errors.ErrFileOperation("не удалось создать промежуточную директорию")
+	}
+	dir := filepath.Join(fs.basePath, StagingDir, hex.EncodeToString(buf))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("Ошибка создания промежуточной директории %s: %v", dir, err)
+		return nil, errors.ErrFileOperation("не удалось создать промежуточную директорию")
+	}
+	return &Staging{dir: dir, byPath: make(map[string]string)}, nil
+}
+
+// WithStaging returns a FileService whose writes go to the staging area. Paths of staged files
+// resolve to their staged copies, so a staged model can be rendered before it is committed.
+func (fs *FileService) WithStaging(staging *Staging) *FileService {
+	staged := *fs
+	staged.staging = staging
+	return &staged
+}
+
+// stage writes data into the staging area under the public path it will have after Commit.
+func (s *Staging) stage(publicPath, finalPath string, data []byte) error {
+	if s.done {
+		return fmt.Errorf("промежуточная запись уже завершена")
+	}
+	if _, ok := s.byPath[publicPath]; ok {
+		return nil
+	}
+	stagedPath := filepath.Join(s.dir, fmt.Sprintf("%03d_%s", len(s.files), filepath.Base(finalPath)))
+	if err := os.WriteFile(stagedPath, data, 0644); err != nil {
+		return err
+	}
+	s.files = append(s.files, stagedFile{publicPath: publicPath, stagedPath: stagedPath, finalPath: finalPath})
+	s.byPath[publicPath] = stagedPath
+	return nil
+}
+
+// Commit moves all staged files to their final locations. On failure the files already moved are removed again.
+func (s *Staging) Commit() error {
+	for _, f := range s.files {
+		if err := os.MkdirAll(filepath.Dir(f.finalPath), 0755); err != nil {
+			s.Rollback()
+			return errors.ErrFileOperation(fmt.Sprintf("не удалось создать директорию: %v", err))
+		}
+		_, statErr := os.Stat(f.finalPath)
+		existed := statErr == nil
+		if err := os.Rename(f.stagedPath, f.finalPath); err != nil {
+			log.Printf("Ошибка перемещения %s в %s: %v", f.stagedPath, f.finalPath, err)
+			s.Rollback()
+			return errors.ErrFileOperation("не удалось сохранить файл")
+		}
+		// Content-hashed names mean an existing file has identical contents and may be used elsewhere,
+		// so only newly created files are removed on rollback.
+		if !existed {
+			s.placed = append(s.placed, f.finalPath)
+		}
+	}
+	return nil
+}
+
+// Rollback discards staged files and removes files placed by Commit. It is a no-op after Release.
+func (s *Staging) Rollback() {
+	if s.done {
+		return
+	}
+	for _, path := range s.placed {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Ошибка при откате файла %s: %v", path, err)
+		}
+	}
+	s.placed = nil
+	s.cleanup()
+}
+
+// Release finishes a successful operation: committed files stay in place and the staging area is removed.
+func (s *Staging) Release() {
+	if s.done {
+		return
+	}
+	s.placed = nil
+	s.cleanup()
+}
+
+func (s *Staging) cleanup() {
+	s.done = true
+	if err := os.RemoveAll(s.dir); err != nil {
+		log.Printf("Ошибка удаления промежуточной директории %s: %v", s.dir, err)
+	}
+}
+
+// CleanupStaging removes staging areas left behind by interrupted operations.
+func (fs *FileService) CleanupStaging(maxAge time.Duration) {
+	root := filepath.Join(fs.basePath, StagingDir)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			log.Printf("Ошибка удаления промежуточной директории %s: %v", entry.Name(), err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Storage of Crystallographic Information Files. A CIF is read into memory first, so the handler can parse it
+// and reject an invalid file before anything is written; the original is then kept for download.
+
+package file
+
+import (
+	"backend/internal/api/errors"
+	"bytes"
+	"io"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	StructuresDir        = "/structures"
+	AllowedStructureExts = ".cif"
+	MaxStructureSize     = 10 << 20
+)
+
+// ReadStructure checks the extension and size of an uploaded CIF and returns its contents.
+func (fs *FileService) ReadStructure(file *multipart.FileHeader) ([]byte, error) {
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !hasExtension(AllowedStructureExts, ext) {
+		return nil, errors.ErrInvalidTypeFile("можно загружать только файлы " + AllowedStructureExts)
+	}
+	data, err := ReadUpload(file, MaxStructureSize)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.IndexByte(data, 0) >= 0 {
+		return nil, errors.ErrInvalidTypeFile("CIF должен быть текстовым файлом")
+	}
+	return data, nil
+}
+
+// SaveStructure stores the contents of a CIF that has already been read and parsed.
+func (fs *FileService) SaveStructure(data []byte, filename string) (string, error) {
+	return fs.writeFile(data, StructuresDir, filepath.Base(filename))
+}
+
+// ReadUpload reads an uploaded file, rejecting it when it is larger than maxSize.
+func ReadUpload(file *multipart.FileHeader, maxSize int64) ([]byte, error) {
+	if file.Size > maxSize {
+		return nil, errors.ErrFileTooBig("файл слишком большой")
+	}
+	src, err := file.Open()
+	if err != nil {
+		log.Printf("Ошибка открытия файла: %v", err)
+		return nil, errors.ErrFileOperation("не удалось открыть файл")
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, maxSize+1))
+	if err != nil {
+		log.Printf("Ошибка чтения файла: %v", err)
+		return nil, errors.ErrFileOperation("не удалось прочитать файл")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errors.ErrFileTooBig("файл слишком большой")
+	}
+	return data, nil
+}
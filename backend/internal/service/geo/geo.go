@@ -0,0 +1,105 @@
+// Geographic helpers for localities: bounding boxes, great-circle distances, query parameters and GeoJSON output.
+// Coordinates follow GeoJSON conventions: positions are [longitude, latitude] in degrees (WGS 84).
+// Spatial filtering runs in plain SQL, so the database needs no spatial extension; this package supplies the
+// bounds stored next to every locality and parses the query parameters used by the API.
+
+package geo
+
+import (
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	EarthRadiusKM = 6371.0
+	MaxRadiusKM   = 20000.0
+)
+
+var (
+	ErrInvalidBBox   = errors.New("bbox должен иметь вид minLon,minLat,maxLon,maxLat")
+	ErrInvalidPoint  = errors.New("координаты должны иметь вид lat,lon")
+	ErrInvalidRadius = errors.New("radius_km должен быть положительным числом")
+)
+
+// ParseBBox parses the GeoJSON-style "minLon,minLat,maxLon,maxLat" parameter.
+func ParseBBox(raw string) (models.BBox, error) {
+	values, err := parseFloats(raw, 4)
+	if err != nil {
+		return models.BBox{}, ErrInvalidBBox
+	}
+	box := models.BBox{MinLon: values[0], MinLat: values[1], MaxLon: values[2], MaxLat: values[3]}
+	if models.ValidatePoint(box.MinLat, box.MinLon) != nil || models.ValidatePoint(box.MaxLat, box.MaxLon) != nil {
+		return models.BBox{}, models.ErrOutOfRange
+	}
+	if box.MinLat > box.MaxLat {
+		return models.BBox{}, ErrInvalidBBox
+	}
+	return box, nil
+}
+
+// ParsePoint parses the "lat,lon" parameter used by radius queries.
+func ParsePoint(raw string) (lat, lon float64, err error) {
+	values, err := parseFloats(raw, 2)
+	if err != nil {
+		return 0, 0, ErrInvalidPoint
+	}
+	if err := models.ValidatePoint(values[0], values[1]); err != nil {
+		return 0, 0, err
+	}
+	return values[0], values[1], nil
+}
+
+func ParseRadius(raw string) (float64, error) {
+	radius, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || radius <= 0 || radius > MaxRadiusKM || math.IsNaN(radius) {
+		return 0, ErrInvalidRadius
+	}
+	return radius, nil
+}
+
+// Bounds returns the box covering a point and, if present, a polygon.
+func Bounds(lat, lon float64, polygon [][][]float64) models.BBox {
+	box := models.BBox{MinLon: lon, MinLat: lat, MaxLon: lon, MaxLat: lat}
+	for _, ring := range polygon {
+		for _, position := range ring {
+			box.MinLon = math.Min(box.MinLon, position[0])
+			box.MaxLon = math.Max(box.MaxLon, position[0])
+			box.MinLat = math.Min(box.MinLat, position[1])
+			box.MaxLat = math.Max(box.MaxLat, position[1])
+		}
+	}
+	return box
+}
+
+// DistanceKM returns the great-circle distance between two points using the haversine formula.
+func DistanceKM(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLon := radians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusKM * math.Asin(math.Sqrt(math.Min(1, a)))
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
+
+func parseFloats(raw string, count int) ([]float64, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != count {
+		return nil, fmt.Errorf("ожидается %d чисел", count)
+	}
+	values := make([]float64, count)
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,67 @@
+// Minimal GeoJSON (RFC 7946) types for returning localities as map features.
+
+package geo
+
+import "backend/internal/models"
+
+const ContentType = "application/geo+json"
+
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates,omitempty"`
+	Geometries  []Geometry  `json:"geometries,omitempty"`
+}
+
+type Feature struct {
+	Type       string                 `json:"type"`
+	ID         interface{}            `json:"id,omitempty"`
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+func NewFeatureCollection(features []Feature) FeatureCollection {
+	if features == nil {
+		features = []Feature{}
+	}
+	return FeatureCollection{Type: "FeatureCollection", Features: features}
+}
+
+// PointGeometry builds a point; GeoJSON positions are [longitude, latitude].
+func PointGeometry(lat, lon float64) Geometry {
+	return Geometry{Type: "Point", Coordinates: []float64{lon, lat}}
+}
+
+// LocationGeometry returns the point alone, or the point together with the outline when a polygon is known.
+func LocationGeometry(lat, lon float64, polygon [][][]float64) Geometry {
+	point := PointGeometry(lat, lon)
+	if len(polygon) == 0 {
+		return point
+	}
+	return Geometry{
+		Type:       "GeometryCollection",
+		Geometries: []Geometry{point, {Type: "Polygon", Coordinates: polygon}},
+	}
+}
+
+// LocalityFeature converts a locality into a GeoJSON feature for map display.
+func LocalityFeature(l models.Locality) Feature {
+	properties := map[string]interface{}{
+		"name":    l.Name,
+		"country": l.Country,
+		"region":  l.Region,
+	}
+	if l.DistanceKM != nil {
+		properties["distance_km"] = *l.DistanceKM
+	}
+	return Feature{
+		Type:       "Feature",
+		ID:         l.ID,
+		Geometry:   LocationGeometry(l.Latitude, l.Longitude, l.Polygon),
+		Properties: properties,
+	}
+}
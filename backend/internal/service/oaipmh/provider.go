@@ -0,0 +1,554 @@
+// OAI-PMH 2.0 data provider for the specimens of the collection, so harvesters can pull the records changed since their last visit.
+// Records are offered as Dublin Core (oai_dc) and as Simple Darwin Core (dwc) through the Darwin Core mapping of the export.
+// Specimens of minerals that were published and later hidden or trashed are reported as deleted; deleted specimens are
+// not remembered, so the repository declares transient deletion support. Lists are paged by stateless resumption tokens.
+
+package oaipmh
+
+import (
+	"backend/internal/database"
+	"backend/internal/service/darwincore"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Namespace       = "http://www.openarchives.org/OAI/2.0/"
+	PageSize        = 100
+	DateLayout      = "2006-01-02"
+	DatestampLayout = "2006-01-02T15:04:05Z"
+
+	xsiNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// Error codes of the protocol.
+const (
+	ErrBadArgument             = "badArgument"
+	ErrBadResumptionToken      = "badResumptionToken"
+	ErrBadVerb                 = "badVerb"
+	ErrCannotDisseminateFormat = "cannotDisseminateFormat"
+	ErrIDDoesNotExist          = "idDoesNotExist"
+	ErrNoRecordsMatch          = "noRecordsMatch"
+	ErrNoSetHierarchy          = "noSetHierarchy"
+)
+
+type MetadataFormat struct {
+	Prefix    string
+	Schema    string
+	Namespace string
+}
+
+var formats = []MetadataFormat{
+	{"oai_dc", "http://www.openarchives.org/OAI/2.0/oai_dc.xsd", "http://www.openarchives.org/OAI/2.0/oai_dc/"},
+	{"dwc", "http://rs.tdwg.org/dwc/xsd/tdwg_dwc_simple.xsd", "http://rs.tdwg.org/dwc/xsd/simpledarwincore/"},
+}
+
+// arguments lists the arguments every verb accepts, required ones marked true.
+var arguments = map[string]map[string]bool{
+	"Identify":            {},
+	"ListMetadataFormats": {"identifier": false},
+	"ListSets":            {"resumptionToken": false},
+	"GetRecord":           {"identifier": true, "metadataPrefix": true},
+	"ListIdentifiers":     {"metadataPrefix": true, "from": false, "until": false, "set": false, "resumptionToken": false},
+	"ListRecords":         {"metadataPrefix": true, "from": false, "until": false, "set": false, "resumptionToken": false},
+}
+
+type Config struct {
+	RepositoryName string
+	// RepositoryID is the namespace of the record identifiers, oai:<RepositoryID>:specimen/<id>.
+	RepositoryID string
+	AdminEmail   string
+	// BaseURL is the address harvesters use; when empty the handler derives it from the request.
+	BaseURL string
+}
+
+// ConfigFromEnv reads OAI_REPOSITORY_NAME, OAI_REPOSITORY_ID, OAI_ADMIN_EMAIL and OAI_BASE_URL.
+func ConfigFromEnv() Config {
+	config := Config{
+		RepositoryName: os.Getenv("OAI_REPOSITORY_NAME"),
+		RepositoryID:   os.Getenv("OAI_REPOSITORY_ID"),
+		AdminEmail:     os.Getenv("OAI_ADMIN_EMAIL"),
+		BaseURL:        os.Getenv("OAI_BASE_URL"),
+	}
+	if config.RepositoryName == "" {
+		config.RepositoryName = "GazlinGO"
+	}
+	if config.RepositoryID == "" {
+		config.RepositoryID = "gazlingo"
+	}
+	if config.AdminEmail == "" {
+		config.AdminEmail = "admin@localhost"
+	}
+	return config
+}
+
+type Provider struct {
+	db      *database.Database
+	mapping *darwincore.Mapping
+	config  Config
+}
+
+func NewProvider(db *database.Database, mapping *darwincore.Mapping, config Config) *Provider {
+	return &Provider{db: db, mapping: mapping, config: config}
+}
+
+func (p *Provider) BaseURL() string {
+	return p.config.BaseURL
+}
+
+// protocolError is an OAI-PMH error, reported inside a normal response.
+type protocolError struct {
+	code    string
+	message string
+}
+
+func (e *protocolError) Error() string {
+	return e.code + ": " + e.message
+}
+
+func fail(code, format string, args ...interface{}) *protocolError {
+	return &protocolError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// Handle answers a request with the given arguments. The error is returned only when the database fails;
+// protocol errors are part of the response.
+func (p *Provider) Handle(baseURL string, args url.Values) ([]byte, error) {
+	r := &response{}
+	r.buf.WriteString(xml.Header)
+	r.open("OAI-PMH", "xmlns", Namespace, "xmlns:xsi", xsiNamespace,
+		"xsi:schemaLocation", Namespace+" http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd")
+	r.element("responseDate", time.Now().UTC().Format(DatestampLayout))
+
+	verb := args.Get("verb")
+	body := &response{}
+	err := p.check(verb, args)
+	if err == nil {
+		err = p.dispatch(body, baseURL, verb, args)
+	}
+
+	protoErr, isProtocol := err.(*protocolError)
+	switch {
+	case err != nil && !isProtocol:
+		return nil, err
+	case isProtocol && (protoErr.code == ErrBadVerb || protoErr.code == ErrBadArgument):
+		r.element("request", baseURL)
+	default:
+		r.element("request", baseURL, requestAttrs(args)...)
+	}
+	if isProtocol {
+		r.element("error", protoErr.message, "code", protoErr.code)
+	} else {
+		r.buf.Write(body.buf.Bytes())
+	}
+	r.close("OAI-PMH")
+	return r.buf.Bytes(), nil
+}
+
+// check validates the verb and the presence of its arguments.
+func (p *Provider) check(verb string, args url.Values) error {
+	allowed, ok := arguments[verb]
+	if !ok {
+		if verb == "" {
+			return fail(ErrBadVerb, "не указан verb")
+		}
+		return fail(ErrBadVerb, "неизвестный verb %q", verb)
+	}
+	for name, values := range args {
+		if name == "verb" {
+			if len(values) > 1 {
+				return fail(ErrBadVerb, "verb указан несколько раз")
+			}
+			continue
+		}
+		if _, ok := allowed[name]; !ok {
+			return fail(ErrBadArgument, "недопустимый аргумент %s", name)
+		}
+		if len(values) > 1 {
+			return fail(ErrBadArgument, "аргумент %s указан несколько раз", name)
+		}
+	}
+	if _, ok := args["resumptionToken"]; ok {
+		if len(args) > 2 {
+			return fail(ErrBadArgument, "resumptionToken должен быть единственным аргументом")
+		}
+		return nil
+	}
+	for name, required := range allowed {
+		if required && args.Get(name) == "" {
+			return fail(ErrBadArgument, "не указан аргумент %s", name)
+		}
+	}
+	return nil
+}
+
+func (p *Provider) dispatch(r *response, baseURL, verb string, args url.Values) error {
+	switch verb {
+	case "Identify":
+		return p.identify(r, baseURL)
+	case "ListMetadataFormats":
+		return p.listMetadataFormats(r, args.Get("identifier"))
+	case "ListSets":
+		if args.Get("resumptionToken") != "" {
+			return fail(ErrBadResumptionToken, "некорректный resumptionToken")
+		}
+		return fail(ErrNoSetHierarchy, "репозиторий не поддерживает наборы")
+	case "GetRecord":
+		return p.getRecord(r, args.Get("identifier"), args.Get("metadataPrefix"))
+	default:
+		return p.list(r, verb, args)
+	}
+}
+
+func (p *Provider) identify(r *response, baseURL string) error {
+	earliest, err := p.db.GetEarliestOccurrenceDatestamp()
+	if err != nil {
+		return err
+	}
+	if earliest == nil {
+		now := time.Now()
+		earliest = &now
+	}
+	r.open("Identify")
+	r.element("repositoryName", p.config.RepositoryName)
+	r.element("baseURL", baseURL)
+	r.element("protocolVersion", "2.0")
+	r.element("adminEmail", p.config.AdminEmail)
+	r.element("earliestDatestamp", datestamp(*earliest))
+	r.element("deletedRecord", "transient")
+	r.element("granularity", "YYYY-MM-DDThh:mm:ssZ")
+	r.close("Identify")
+	return nil
+}
+
+func (p *Provider) listMetadataFormats(r *response, identifier string) error {
+	if identifier != "" {
+		if _, err := p.occurrence(identifier); err != nil {
+			return err
+		}
+	}
+	r.open("ListMetadataFormats")
+	for _, format := range formats {
+		r.open("metadataFormat")
+		r.element("metadataPrefix", format.Prefix)
+		r.element("schema", format.Schema)
+		r.element("metadataNamespace", format.Namespace)
+		r.close("metadataFormat")
+	}
+	r.close("ListMetadataFormats")
+	return nil
+}
+
+func (p *Provider) getRecord(r *response, identifier, prefix string) error {
+	if err := checkFormat(prefix); err != nil {
+		return err
+	}
+	occurrence, err := p.occurrence(identifier)
+	if err != nil {
+		return err
+	}
+	r.open("GetRecord")
+	p.writeRecord(r, occurrence, prefix)
+	r.close("GetRecord")
+	return nil
+}
+
+// token is the state of a list request carried from one page to the next.
+type token struct {
+	prefix  string
+	from    string
+	until   string
+	afterID int
+}
+
+func (t token) encode() string {
+	raw := strings.Join([]string{t.prefix, t.from, t.until, strconv.Itoa(t.afterID)}, "|")
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeToken(raw string) (token, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return token{}, fail(ErrBadResumptionToken, "некорректный resumptionToken")
+	}
+	parts := strings.Split(string(data), "|")
+	if len(parts) != 4 {
+		return token{}, fail(ErrBadResumptionToken, "некорректный resumptionToken")
+	}
+	afterID, err := strconv.Atoi(parts[3])
+	if err != nil || afterID < 0 {
+		return token{}, fail(ErrBadResumptionToken, "некорректный resumptionToken")
+	}
+	return token{prefix: parts[0], from: parts[1], until: parts[2], afterID: afterID}, nil
+}
+
+// list answers ListIdentifiers and ListRecords.
+func (p *Provider) list(r *response, verb string, args url.Values) error {
+	if args.Get("set") != "" {
+		return fail(ErrNoSetHierarchy, "репозиторий не поддерживает наборы")
+	}
+	resumed := args.Get("resumptionToken") != ""
+	state := token{prefix: args.Get("metadataPrefix"), from: args.Get("from"), until: args.Get("until")}
+	if resumed {
+		var err error
+		if state, err = decodeToken(args.Get("resumptionToken")); err != nil {
+			return err
+		}
+	}
+	if err := checkFormat(state.prefix); err != nil {
+		if resumed {
+			return fail(ErrBadResumptionToken, "некорректный resumptionToken")
+		}
+		return err
+	}
+	filter, err := dateFilter(state.from, state.until)
+	if err != nil {
+		if resumed {
+			return fail(ErrBadResumptionToken, "некорректный resumptionToken")
+		}
+		return err
+	}
+	filter.AfterID = state.afterID
+	filter.IncludeHidden = true
+	filter.Limit = PageSize + 1
+
+	occurrences, err := p.db.GetOccurrences(filter)
+	if err != nil {
+		return err
+	}
+	if len(occurrences) == 0 && !resumed {
+		return fail(ErrNoRecordsMatch, "нет записей для этих условий")
+	}
+	more := len(occurrences) > PageSize
+	if more {
+		occurrences = occurrences[:PageSize]
+	}
+
+	r.open(verb)
+	for i := range occurrences {
+		if verb == "ListIdentifiers" {
+			p.writeHeader(r, &occurrences[i])
+		} else {
+			p.writeRecord(r, &occurrences[i], state.prefix)
+		}
+	}
+	switch {
+	case more:
+		state.afterID = occurrences[len(occurrences)-1].ID
+		r.element("resumptionToken", state.encode())
+	case resumed:
+		r.element("resumptionToken", "")
+	}
+	r.close(verb)
+	return nil
+}
+
+// dateFilter parses from and until, which must have the same granularity. Both bounds are inclusive at the
+// granularity of the argument, so until=2024-05-01 takes in the whole day.
+func dateFilter(from, until string) (database.OccurrenceFilter, error) {
+	var filter database.OccurrenceFilter
+	fromTime, fromStep, err := parseDate(from)
+	if err != nil {
+		return filter, err
+	}
+	untilTime, untilStep, err := parseDate(until)
+	if err != nil {
+		return filter, err
+	}
+	if from != "" && until != "" {
+		if fromStep != untilStep {
+			return filter, fail(ErrBadArgument, "from и until должны иметь одинаковую точность")
+		}
+		if fromTime.After(untilTime) {
+			return filter, fail(ErrBadArgument, "from позже until")
+		}
+	}
+	if from != "" {
+		filter.From = &fromTime
+	}
+	if until != "" {
+		end := untilTime.Add(untilStep - time.Nanosecond)
+		filter.Until = &end
+	}
+	return filter, nil
+}
+
+// parseDate accepts a date or a UTC datestamp with seconds and returns the time with the length of its granularity.
+func parseDate(value string) (time.Time, time.Duration, error) {
+	if value == "" {
+		return time.Time{}, 0, nil
+	}
+	if t, err := time.Parse(DatestampLayout, value); err == nil {
+		return t, time.Second, nil
+	}
+	if t, err := time.Parse(DateLayout, value); err == nil {
+		return t, 24 * time.Hour, nil
+	}
+	return time.Time{}, 0, fail(ErrBadArgument, "некорректная дата %q", value)
+}
+
+func checkFormat(prefix string) error {
+	for _, format := range formats {
+		if format.Prefix == prefix {
+			return nil
+		}
+	}
+	return fail(ErrCannotDisseminateFormat, "формат %q не поддерживается", prefix)
+}
+
+// occurrence finds the specimen a record identifier names.
+func (p *Provider) occurrence(identifier string) (*database.Occurrence, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(identifier, p.identifierPrefix()))
+	if err != nil || !strings.HasPrefix(identifier, p.identifierPrefix()) {
+		return nil, fail(ErrIDDoesNotExist, "запись %q не найдена", identifier)
+	}
+	occurrence, err := p.db.GetOccurrence(id)
+	if err == database.ErrSpecimenNotFound {
+		return nil, fail(ErrIDDoesNotExist, "запись %q не найдена", identifier)
+	}
+	return occurrence, err
+}
+
+func (p *Provider) identifierPrefix() string {
+	return "oai:" + p.config.RepositoryID + ":specimen/"
+}
+
+// Identifier returns the OAI identifier of a specimen.
+func (p *Provider) Identifier(specimenID int) string {
+	return p.identifierPrefix() + strconv.Itoa(specimenID)
+}
+
+func (p *Provider) writeHeader(r *response, o *database.Occurrence) {
+	if o.Visible {
+		r.open("header")
+	} else {
+		r.open("header", "status", "deleted")
+	}
+	r.element("identifier", p.Identifier(o.ID))
+	r.element("datestamp", datestamp(o.Modified))
+	r.close("header")
+}
+
+// writeRecord writes the header and, unless the record is deleted, its metadata in the format.
+func (p *Provider) writeRecord(r *response, o *database.Occurrence, prefix string) {
+	r.open("record")
+	p.writeHeader(r, o)
+	if o.Visible {
+		r.open("metadata")
+		if prefix == "dwc" {
+			p.writeDarwinCore(r, o)
+		} else {
+			p.writeDublinCore(r, o)
+		}
+		r.close("metadata")
+	}
+	r.close("record")
+}
+
+func (p *Provider) writeDublinCore(r *response, o *database.Occurrence) {
+	r.open("oai_dc:dc", "xmlns:oai_dc", formats[0].Namespace, "xmlns:dc", "http://purl.org/dc/elements/1.1/",
+		"xsi:schemaLocation", formats[0].Namespace+" "+formats[0].Schema)
+	r.element("dc:title", o.MineralTitle+" ("+o.CatalogNumber+")")
+	r.element("dc:identifier", p.mapping.OccurrenceID(o.CatalogNumber))
+	r.element("dc:type", "PhysicalObject")
+	if o.Formula != "" {
+		r.element("dc:subject", o.Formula)
+	}
+	var coverage []string
+	if o.Locality != "" {
+		coverage = append(coverage, o.Locality)
+	}
+	if o.Place != nil {
+		for _, part := range []string{o.Place.Name, o.Place.Region, o.Place.Country} {
+			if part != "" && part != o.Locality {
+				coverage = append(coverage, part)
+			}
+		}
+	}
+	if len(coverage) > 0 {
+		r.element("dc:coverage", strings.Join(coverage, ", "))
+	}
+	if o.Notes != "" {
+		r.element("dc:description", o.Notes)
+	}
+	r.element("dc:publisher", p.config.RepositoryName)
+	r.element("dc:date", datestamp(o.Modified))
+	r.close("oai_dc:dc")
+}
+
+// writeDarwinCore writes the record as a Simple Darwin Core record with the terms of the mapping, Dublin Core terms first
+// as the Simple Darwin Core schema orders them.
+func (p *Provider) writeDarwinCore(r *response, o *database.Occurrence) {
+	values := p.mapping.Record(o)
+	sort.SliceStable(values, func(i, j int) bool {
+		return values[i].Term.Prefix == "dcterms" && values[j].Term.Prefix != "dcterms"
+	})
+
+	r.open("sdwc:SimpleDarwinRecordSet", "xmlns:sdwc", formats[1].Namespace, "xmlns:dwc", darwincore.DwCNamespace,
+		"xmlns:dcterms", darwincore.DCTermsNamespace, "xsi:schemaLocation", formats[1].Namespace+" "+formats[1].Schema)
+	r.open("sdwc:SimpleDarwinRecord")
+	for _, value := range values {
+		r.element(value.Term.QName(), value.Value)
+	}
+	r.close("sdwc:SimpleDarwinRecord")
+	r.close("sdwc:SimpleDarwinRecordSet")
+}
+
+func datestamp(t time.Time) string {
+	return t.UTC().Truncate(time.Second).Format(DatestampLayout)
+}
+
+// requestAttrs returns the arguments of a valid request as attributes of the request element, in a stable order.
+func requestAttrs(args url.Values) []string {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	attrs := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		attrs = append(attrs, name, args.Get(name))
+	}
+	return attrs
+}
+
+// response builds the XML of a response; attributes are given as name, value pairs.
+type response struct {
+	buf bytes.Buffer
+}
+
+func (r *response) start(name string, attrs []string) {
+	r.buf.WriteString("<" + name)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		r.buf.WriteString(" " + attrs[i] + `="`)
+		xml.EscapeText(&r.buf, []byte(attrs[i+1]))
+		r.buf.WriteString(`"`)
+	}
+}
+
+func (r *response) open(name string, attrs ...string) {
+	r.start(name, attrs)
+	r.buf.WriteString(">")
+}
+
+func (r *response) close(name string) {
+	r.buf.WriteString("</" + name + ">")
+}
+
+func (r *response) element(name, text string, attrs ...string) {
+	r.start(name, attrs)
+	if text == "" {
+		r.buf.WriteString("/>")
+		return
+	}
+	r.buf.WriteString(">")
+	xml.EscapeText(&r.buf, []byte(text))
+	r.close(name)
+}
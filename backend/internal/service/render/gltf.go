@@ -0,0 +1,629 @@
+// A minimal reader for binary glTF 2.0 (GLB) files, covering the subset of the format needed to draw a static preview.
+// Parses the container header, the JSON scene description and the binary buffer, then flattens the node hierarchy into world-space meshes.
+// Supports indexed and non-indexed triangle lists, strips and fans, node matrices and TRS transforms, vertex colors and embedded base color textures.
+// Skinning, morph targets, sparse accessors and compressed geometry extensions are deliberately ignored.
+
+package render
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	glbMagic     = 0x46546C67
+	glbChunkJSON = 0x4E4F534A
+	glbChunkBIN  = 0x004E4942
+
+	componentByte          = 5120
+	componentUnsignedByte  = 5121
+	componentShort         = 5122
+	componentUnsignedShort = 5123
+	componentUnsignedInt   = 5125
+	componentFloat         = 5126
+
+	modeTriangles     = 4
+	modeTriangleStrip = 5
+	modeTriangleFan   = 6
+)
+
+var (
+	ErrInvalidGLB   = errors.New("файл не является корректным GLB")
+	ErrEmptyScene   = errors.New("модель не содержит треугольной геометрии")
+	ErrBadAccessor  = errors.New("некорректный accessor в GLB")
+	ErrUnsupported  = errors.New("неподдерживаемая возможность glTF")
+	componentCounts = map[string]int{"SCALAR": 1, "VEC2": 2, "VEC3": 3, "VEC4": 4, "MAT4": 16}
+)
+
+type gltfDocument struct {
+	Scene  *int `json:"scene"`
+	Scenes []struct {
+		Nodes []int `json:"nodes"`
+	} `json:"scenes"`
+	Nodes []struct {
+		Mesh        *int      `json:"mesh"`
+		Children    []int     `json:"children"`
+		Matrix      []float64 `json:"matrix"`
+		Translation []float64 `json:"translation"`
+		Rotation    []float64 `json:"rotation"`
+		Scale       []float64 `json:"scale"`
+	} `json:"nodes"`
+	Meshes []struct {
+		Primitives []struct {
+			Attributes map[string]int `json:"attributes"`
+			Indices    *int           `json:"indices"`
+			Material   *int           `json:"material"`
+			Mode       *int           `json:"mode"`
+		} `json:"primitives"`
+	} `json:"meshes"`
+	Accessors []struct {
+		BufferView    *int   `json:"bufferView"`
+		ByteOffset    int    `json:"byteOffset"`
+		ComponentType int    `json:"componentType"`
+		Normalized    bool   `json:"normalized"`
+		Count         int    `json:"count"`
+		Type          string `json:"type"`
+	} `json:"accessors"`
+	BufferViews []struct {
+		Buffer     int `json:"buffer"`
+		ByteOffset int `json:"byteOffset"`
+		ByteLength int `json:"byteLength"`
+		ByteStride int `json:"byteStride"`
+	} `json:"bufferViews"`
+	Materials []struct {
+		PBR struct {
+			BaseColorFactor  []float64 `json:"baseColorFactor"`
+			BaseColorTexture *struct {
+				Index int `json:"index"`
+			} `json:"baseColorTexture"`
+		} `json:"pbrMetallicRoughness"`
+	} `json:"materials"`
+	Textures []struct {
+		Source *int `json:"source"`
+	} `json:"textures"`
+	Images []struct {
+		BufferView *int   `json:"bufferView"`
+		MimeType   string `json:"mimeType"`
+	} `json:"images"`
+}
+
+// Material is the flattened base color description of a glTF material.
+type Material struct {
+	Color   [4]float32
+	Texture *image.NRGBA
+}
+
+// Mesh is a single triangle list in world space.
+type Mesh struct {
+	Positions [][3]float32
+	Normals   [][3]float32
+	UVs       [][2]float32
+	Colors    [][4]float32
+	Indices   []uint32
+	Material  int
+}
+
+// Scene holds all drawable geometry of a model.
+type Scene struct {
+	Meshes    []Mesh
+	Materials []Material
+}
+
+func LoadGLB(path string) (*Scene, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return readGLB(f, info.Size())
+}
+
+func ReadGLB(r io.Reader) (*Scene, error) {
+	return readGLB(r, -1)
+}
+
+// readGLB parses a GLB of the given size, or of unknown size when size is negative.
+// Chunk lengths come from the file, so they are checked against the length in the header and the size before anything is allocated.
+func readGLB(r io.Reader, size int64) (*Scene, error) {
+	var header [3]uint32
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, ErrInvalidGLB
+	}
+	if header[0] != glbMagic || header[1] != 2 || header[2] < 12 || (size >= 0 && int64(header[2]) > size) {
+		return nil, ErrInvalidGLB
+	}
+
+	remaining := int64(header[2]) - 12
+	var jsonChunk, binChunk []byte
+	for remaining > 0 {
+		var chunkHeader [2]uint32
+		if remaining < 8 {
+			return nil, ErrInvalidGLB
+		}
+		if err := binary.Read(r, binary.LittleEndian, &chunkHeader); err != nil {
+			return nil, ErrInvalidGLB
+		}
+		remaining -= 8
+		if int64(chunkHeader[0]) > remaining {
+			return nil, ErrInvalidGLB
+		}
+		data, err := readChunk(r, int64(chunkHeader[0]))
+		if err != nil {
+			return nil, ErrInvalidGLB
+		}
+		remaining -= int64(chunkHeader[0])
+		switch chunkHeader[1] {
+		case glbChunkJSON:
+			jsonChunk = data
+		case glbChunkBIN:
+			if binChunk == nil {
+				binChunk = data
+			}
+		}
+	}
+	if jsonChunk == nil {
+		return nil, ErrInvalidGLB
+	}
+
+	var doc gltfDocument
+	if err := json.Unmarshal(jsonChunk, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidGLB, err)
+	}
+
+	loader := &gltfLoader{doc: &doc, bin: binChunk, textures: make(map[int]*image.NRGBA)}
+	return loader.load()
+}
+
+// readChunk reads length bytes, growing the buffer as the data arrives, so a truncated stream cannot claim a large allocation.
+func readChunk(r io.Reader, length int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, length); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type gltfLoader struct {
+	doc      *gltfDocument
+	bin      []byte
+	scene    Scene
+	textures map[int]*image.NRGBA
+}
+
+func (l *gltfLoader) load() (*Scene, error) {
+	l.loadMaterials()
+
+	var roots []int
+	switch {
+	case len(l.doc.Scenes) > 0:
+		sceneIndex := 0
+		if l.doc.Scene != nil && *l.doc.Scene < len(l.doc.Scenes) {
+			sceneIndex = *l.doc.Scene
+		}
+		roots = l.doc.Scenes[sceneIndex].Nodes
+	default:
+		isChild := make([]bool, len(l.doc.Nodes))
+		for _, node := range l.doc.Nodes {
+			for _, child := range node.Children {
+				if child >= 0 && child < len(isChild) {
+					isChild[child] = true
+				}
+			}
+		}
+		for i := range l.doc.Nodes {
+			if !isChild[i] {
+				roots = append(roots, i)
+			}
+		}
+	}
+
+	if len(l.doc.Nodes) == 0 {
+		for i := range l.doc.Meshes {
+			if err := l.loadMesh(i, identity()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	visited := make(map[int]bool)
+	for _, root := range roots {
+		if err := l.walk(root, identity(), visited); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(l.scene.Meshes) == 0 {
+		return nil, ErrEmptyScene
+	}
+	return &l.scene, nil
+}
+
+func (l *gltfLoader) walk(index int, parent mat4, visited map[int]bool) error {
+	if index < 0 || index >= len(l.doc.Nodes) || visited[index] {
+		return nil
+	}
+	visited[index] = true
+
+	node := l.doc.Nodes[index]
+	local := identity()
+	if len(node.Matrix) == 16 {
+		for i := 0; i < 16; i++ {
+			local[i] = node.Matrix[i]
+		}
+	} else {
+		local = composeTRS(node.Translation, node.Rotation, node.Scale)
+	}
+	world := parent.mul(local)
+
+	if node.Mesh != nil {
+		if err := l.loadMesh(*node.Mesh, world); err != nil {
+			return err
+		}
+	}
+	for _, child := range node.Children {
+		if err := l.walk(child, world, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *gltfLoader) loadMaterials() {
+	for _, m := range l.doc.Materials {
+		material := Material{Color: [4]float32{1, 1, 1, 1}}
+		if len(m.PBR.BaseColorFactor) == 4 {
+			for i := range material.Color {
+				material.Color[i] = float32(m.PBR.BaseColorFactor[i])
+			}
+		}
+		if m.PBR.BaseColorTexture != nil {
+			material.Texture = l.texture(m.PBR.BaseColorTexture.Index)
+		}
+		l.scene.Materials = append(l.scene.Materials, material)
+	}
+	l.scene.Materials = append(l.scene.Materials, Material{Color: [4]float32{0.8, 0.8, 0.8, 1}})
+}
+
+func (l *gltfLoader) texture(index int) *image.NRGBA {
+	if index < 0 || index >= len(l.doc.Textures) || l.doc.Textures[index].Source == nil {
+		return nil
+	}
+	source := *l.doc.Textures[index].Source
+	if cached, ok := l.textures[source]; ok {
+		return cached
+	}
+	l.textures[source] = nil
+
+	if source < 0 || source >= len(l.doc.Images) || l.doc.Images[source].BufferView == nil {
+		return nil
+	}
+	data, _, err := l.bufferView(*l.doc.Images[source].BufferView)
+	if err != nil {
+		return nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	nrgba := toNRGBA(img)
+	l.textures[source] = nrgba
+	return nrgba
+}
+
+func (l *gltfLoader) loadMesh(index int, world mat4) error {
+	if index < 0 || index >= len(l.doc.Meshes) {
+		return nil
+	}
+	normalMatrix := world.normalMatrix()
+	defaultMaterial := len(l.scene.Materials) - 1
+
+	for _, prim := range l.doc.Meshes[index].Primitives {
+		mode := modeTriangles
+		if prim.Mode != nil {
+			mode = *prim.Mode
+		}
+		if mode != modeTriangles && mode != modeTriangleStrip && mode != modeTriangleFan {
+			continue
+		}
+		positionAccessor, ok := prim.Attributes["POSITION"]
+		if !ok {
+			continue
+		}
+
+		positions, err := l.readFloats(positionAccessor, 3)
+		if err != nil {
+			return err
+		}
+		if len(positions) == 0 {
+			continue
+		}
+
+		mesh := Mesh{Material: defaultMaterial}
+		if prim.Material != nil && *prim.Material >= 0 && *prim.Material < defaultMaterial {
+			mesh.Material = *prim.Material
+		}
+
+		mesh.Positions = make([][3]float32, len(positions)/3)
+		for i := range mesh.Positions {
+			p := world.transformPoint(positions[i*3], positions[i*3+1], positions[i*3+2])
+			mesh.Positions[i] = [3]float32{float32(p[0]), float32(p[1]), float32(p[2])}
+		}
+
+		if normalAccessor, ok := prim.Attributes["NORMAL"]; ok {
+			normals, err := l.readFloats(normalAccessor, 3)
+			if err == nil && len(normals) == len(positions) {
+				mesh.Normals = make([][3]float32, len(normals)/3)
+				for i := range mesh.Normals {
+					n := normalMatrix.transformDirection(normals[i*3], normals[i*3+1], normals[i*3+2])
+					mesh.Normals[i] = normalize32(n)
+				}
+			}
+		}
+
+		if l.scene.Materials[mesh.Material].Texture != nil {
+			if uvAccessor, ok := prim.Attributes["TEXCOORD_0"]; ok {
+				uvs, err := l.readFloats(uvAccessor, 2)
+				if err == nil && len(uvs)/2 == len(mesh.Positions) {
+					mesh.UVs = make([][2]float32, len(uvs)/2)
+					for i := range mesh.UVs {
+						mesh.UVs[i] = [2]float32{float32(uvs[i*2]), float32(uvs[i*2+1])}
+					}
+				}
+			}
+		}
+
+		if colorAccessor, ok := prim.Attributes["COLOR_0"]; ok {
+			mesh.Colors = l.readColors(colorAccessor, len(mesh.Positions))
+		}
+
+		var indices []uint32
+		if prim.Indices != nil {
+			indices, err = l.readIndices(*prim.Indices)
+			if err != nil {
+				return err
+			}
+		} else {
+			indices = make([]uint32, len(mesh.Positions))
+			for i := range indices {
+				indices[i] = uint32(i)
+			}
+		}
+		mesh.Indices = triangulate(indices, mode, uint32(len(mesh.Positions)))
+		if len(mesh.Indices) == 0 {
+			continue
+		}
+
+		l.scene.Meshes = append(l.scene.Meshes, mesh)
+	}
+	return nil
+}
+
+func triangulate(indices []uint32, mode int, vertexCount uint32) []uint32 {
+	var out []uint32
+	emit := func(a, b, c uint32) {
+		if a < vertexCount && b < vertexCount && c < vertexCount {
+			out = append(out, a, b, c)
+		}
+	}
+	switch mode {
+	case modeTriangleStrip:
+		for i := 2; i < len(indices); i++ {
+			if i%2 == 0 {
+				emit(indices[i-2], indices[i-1], indices[i])
+			} else {
+				emit(indices[i-1], indices[i-2], indices[i])
+			}
+		}
+	case modeTriangleFan:
+		for i := 2; i < len(indices); i++ {
+			emit(indices[0], indices[i-1], indices[i])
+		}
+	default:
+		out = make([]uint32, 0, len(indices))
+		for i := 0; i+2 < len(indices); i += 3 {
+			emit(indices[i], indices[i+1], indices[i+2])
+		}
+	}
+	return out
+}
+
+func (l *gltfLoader) bufferView(index int) ([]byte, int, error) {
+	if index < 0 || index >= len(l.doc.BufferViews) {
+		return nil, 0, ErrBadAccessor
+	}
+	view := l.doc.BufferViews[index]
+	if view.Buffer != 0 {
+		return nil, 0, fmt.Errorf("%w: внешние буферы", ErrUnsupported)
+	}
+	end := view.ByteOffset + view.ByteLength
+	if view.ByteOffset < 0 || view.ByteLength < 0 || view.ByteStride < 0 || end > len(l.bin) || end < view.ByteOffset {
+		return nil, 0, ErrBadAccessor
+	}
+	return l.bin[view.ByteOffset:end], view.ByteStride, nil
+}
+
+// accessorData returns the raw bytes of an accessor together with its element stride and component layout.
+func (l *gltfLoader) accessorData(index int) (data []byte, stride, components, componentSize int, err error) {
+	if index < 0 || index >= len(l.doc.Accessors) {
+		return nil, 0, 0, 0, ErrBadAccessor
+	}
+	accessor := l.doc.Accessors[index]
+	components, ok := componentCounts[accessor.Type]
+	if !ok {
+		return nil, 0, 0, 0, ErrBadAccessor
+	}
+	switch accessor.ComponentType {
+	case componentByte, componentUnsignedByte:
+		componentSize = 1
+	case componentShort, componentUnsignedShort:
+		componentSize = 2
+	case componentUnsignedInt, componentFloat:
+		componentSize = 4
+	default:
+		return nil, 0, 0, 0, ErrBadAccessor
+	}
+	if accessor.Count < 0 || accessor.ByteOffset < 0 {
+		return nil, 0, 0, 0, ErrBadAccessor
+	}
+	if accessor.BufferView == nil {
+		return nil, 0, 0, 0, fmt.Errorf("%w: accessor без bufferView", ErrUnsupported)
+	}
+
+	view, viewStride, err := l.bufferView(*accessor.BufferView)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	stride = components * componentSize
+	if viewStride > 0 {
+		stride = viewStride
+	}
+	if accessor.Count == 0 {
+		return nil, stride, components, componentSize, nil
+	}
+	// Every element takes at least one byte, so a larger count cannot fit and would only overflow the size below.
+	if accessor.Count > len(view) || accessor.ByteOffset > len(view) {
+		return nil, 0, 0, 0, ErrBadAccessor
+	}
+	need := accessor.ByteOffset + stride*(accessor.Count-1) + components*componentSize
+	if need > len(view) {
+		return nil, 0, 0, 0, ErrBadAccessor
+	}
+	return view[accessor.ByteOffset:need], stride, components, componentSize, nil
+}
+
+func (l *gltfLoader) readFloats(index, want int) ([]float64, error) {
+	data, stride, components, _, err := l.accessorData(index)
+	if err != nil {
+		return nil, err
+	}
+	if components != want {
+		return nil, ErrBadAccessor
+	}
+	accessor := l.doc.Accessors[index]
+	out := make([]float64, 0, accessor.Count*components)
+	for i := 0; i < accessor.Count; i++ {
+		base := i * stride
+		for c := 0; c < components; c++ {
+			out = append(out, readComponent(data, base, c, accessor.ComponentType, accessor.Normalized))
+		}
+	}
+	return out, nil
+}
+
+// readColors reads a COLOR_0 accessor, which may hold RGB or RGBA values. Invalid data is ignored.
+func (l *gltfLoader) readColors(index, vertexCount int) [][4]float32 {
+	if index < 0 || index >= len(l.doc.Accessors) {
+		return nil
+	}
+	components := componentCounts[l.doc.Accessors[index].Type]
+	if components != 3 && components != 4 {
+		return nil
+	}
+	values, err := l.readFloats(index, components)
+	if err != nil || len(values)/components != vertexCount {
+		return nil
+	}
+	colors := make([][4]float32, vertexCount)
+	for i := range colors {
+		colors[i] = [4]float32{1, 1, 1, 1}
+		for c := 0; c < components; c++ {
+			colors[i][c] = float32(values[i*components+c])
+		}
+	}
+	return colors
+}
+
+func (l *gltfLoader) readIndices(index int) ([]uint32, error) {
+	data, stride, components, _, err := l.accessorData(index)
+	if err != nil {
+		return nil, err
+	}
+	if components != 1 {
+		return nil, ErrBadAccessor
+	}
+	accessor := l.doc.Accessors[index]
+	out := make([]uint32, accessor.Count)
+	for i := range out {
+		base := i * stride
+		switch accessor.ComponentType {
+		case componentUnsignedByte:
+			out[i] = uint32(data[base])
+		case componentUnsignedShort:
+			out[i] = uint32(binary.LittleEndian.Uint16(data[base:]))
+		case componentUnsignedInt:
+			out[i] = binary.LittleEndian.Uint32(data[base:])
+		default:
+			return nil, ErrBadAccessor
+		}
+	}
+	return out, nil
+}
+
+func readComponent(data []byte, base, component, componentType int, normalized bool) float64 {
+	switch componentType {
+	case componentFloat:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(data[base+component*4:])))
+	case componentByte:
+		v := float64(int8(data[base+component]))
+		if normalized {
+			return math.Max(v/127, -1)
+		}
+		return v
+	case componentUnsignedByte:
+		v := float64(data[base+component])
+		if normalized {
+			return v / 255
+		}
+		return v
+	case componentShort:
+		v := float64(int16(binary.LittleEndian.Uint16(data[base+component*2:])))
+		if normalized {
+			return math.Max(v/32767, -1)
+		}
+		return v
+	case componentUnsignedShort:
+		v := float64(binary.LittleEndian.Uint16(data[base+component*2:]))
+		if normalized {
+			return v / 65535
+		}
+		return v
+	case componentUnsignedInt:
+		return float64(binary.LittleEndian.Uint32(data[base+component*4:]))
+	}
+	return 0
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok {
+		return nrgba
+	}
+	bounds := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, a := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			i := out.PixOffset(x, y)
+			if a == 0 {
+				continue
+			}
+			out.Pix[i] = uint8((r * 0xffff / a) >> 8)
+			out.Pix[i+1] = uint8((g * 0xffff / a) >> 8)
+			out.Pix[i+2] = uint8((b * 0xffff / a) >> 8)
+			out.Pix[i+3] = uint8(a >> 8)
+		}
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+// Small linear algebra helpers used by the glTF loader and the rasterizer.
+// Matrices are stored in column-major order, matching the layout used by glTF.
+
+package render
+
+import "math"
+
+type vec3 [3]float64
+
+type mat4 [16]float64
+
+func identity() mat4 {
+	return mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+}
+
+func (a mat4) mul(b mat4) mat4 {
+	var out mat4
+	for col := 0; col < 4; col++ {
+		for row := 0; row < 4; row++ {
+			var sum float64
+			for k := 0; k < 4; k++ {
+				sum += a[k*4+row] * b[col*4+k]
+			}
+			out[col*4+row] = sum
+		}
+	}
+	return out
+}
+
+func (a mat4) transformPoint(x, y, z float64) vec3 {
+	return vec3{
+		a[0]*x + a[4]*y + a[8]*z + a[12],
+		a[1]*x + a[5]*y + a[9]*z + a[13],
+		a[2]*x + a[6]*y + a[10]*z + a[14],
+	}
+}
+
+func (a mat4) transformDirection(x, y, z float64) vec3 {
+	return vec3{
+		a[0]*x + a[4]*y + a[8]*z,
+		a[1]*x + a[5]*y + a[9]*z,
+		a[2]*x + a[6]*y + a[10]*z,
+	}
+}
+
+// normalMatrix returns the inverse transpose of the upper 3x3 block, embedded in a mat4.
+func (a mat4) normalMatrix() mat4 {
+	m00, m01, m02 := a[0], a[4], a[8]
+	m10, m11, m12 := a[1], a[5], a[9]
+	m20, m21, m22 := a[2], a[6], a[10]
+
+	c00 := m11*m22 - m12*m21
+	c01 := m12*m20 - m10*m22
+	c02 := m10*m21 - m11*m20
+	det := m00*c00 + m01*c01 + m02*c02
+	if math.Abs(det) < 1e-12 {
+		return a
+	}
+	inv := 1 / det
+
+	out := identity()
+	out[0] = c00 * inv
+	out[4] = c01 * inv
+	out[8] = c02 * inv
+	out[1] = (m02*m21 - m01*m22) * inv
+	out[5] = (m00*m22 - m02*m20) * inv
+	out[9] = (m01*m20 - m00*m21) * inv
+	out[2] = (m01*m12 - m02*m11) * inv
+	out[6] = (m02*m10 - m00*m12) * inv
+	out[10] = (m00*m11 - m01*m10) * inv
+	return out
+}
+
+func composeTRS(t, r, s []float64) mat4 {
+	tx, ty, tz := 0.0, 0.0, 0.0
+	if len(t) == 3 {
+		tx, ty, tz = t[0], t[1], t[2]
+	}
+	qx, qy, qz, qw := 0.0, 0.0, 0.0, 1.0
+	if len(r) == 4 {
+		qx, qy, qz, qw = r[0], r[1], r[2], r[3]
+	}
+	sx, sy, sz := 1.0, 1.0, 1.0
+	if len(s) == 3 {
+		sx, sy, sz = s[0], s[1], s[2]
+	}
+
+	return mat4{
+		(1 - 2*(qy*qy+qz*qz)) * sx, 2 * (qx*qy + qz*qw) * sx, 2 * (qx*qz - qy*qw) * sx, 0,
+		2 * (qx*qy - qz*qw) * sy, (1 - 2*(qx*qx+qz*qz)) * sy, 2 * (qy*qz + qx*qw) * sy, 0,
+		2 * (qx*qz + qy*qw) * sz, 2 * (qy*qz - qx*qw) * sz, (1 - 2*(qx*qx+qy*qy)) * sz, 0,
+		tx, ty, tz, 1,
+	}
+}
+
+func (v vec3) sub(o vec3) vec3 { return vec3{v[0] - o[0], v[1] - o[1], v[2] - o[2]} }
+
+func (v vec3) dot(o vec3) float64 { return v[0]*o[0] + v[1]*o[1] + v[2]*o[2] }
+
+func (v vec3) cross(o vec3) vec3 {
+	return vec3{v[1]*o[2] - v[2]*o[1], v[2]*o[0] - v[0]*o[2], v[0]*o[1] - v[1]*o[0]}
+}
+
+func (v vec3) normalize() vec3 {
+	length := math.Sqrt(v.dot(v))
+	if length == 0 {
+		return v
+	}
+	return vec3{v[0] / length, v[1] / length, v[2] / length}
+}
+
+func normalize32(v vec3) [3]float32 {
+	n := v.normalize()
+	return [3]float32{float32(n[0]), float32(n[1]), float32(n[2])}
+}
@@ -0,0 +1,330 @@
+// A pure-Go software rasterizer that turns a loaded glTF scene into preview images without a GPU or cgo.
+// Fits the model into the frame, rotates it around the vertical axis and shades it with a key/fill/ambient light rig.
+// Uses a depth buffer, two-sided lighting for scanned meshes with inconsistent winding, and supersampling for smooth edges.
+// Produces single thumbnails as well as turntable sprite sheets that the frontend can animate with CSS.
+
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+)
+
+const (
+	DefaultSize        = 512
+	DefaultSupersample = 2
+	DefaultElevation   = 20
+	DefaultYaw         = -30
+	SpriteFrameSize    = 128
+	SpriteFrames       = 12
+	MaxSpriteFrames    = 72
+	ambientLight       = 0.28
+	fitMargin          = 0.9
+)
+
+var (
+	keyLight  = vec3{-0.45, 0.65, 0.6}.normalize()
+	fillLight = vec3{0.6, -0.1, 0.45}.normalize()
+)
+
+type Options struct {
+	Size        int
+	Supersample int
+	Elevation   float64
+	Background  color.NRGBA
+}
+
+type Renderer struct {
+	opts Options
+}
+
+// SpriteSheet describes a turntable animation packed into a grid of equally sized frames.
+type SpriteSheet struct {
+	Image     *image.NRGBA
+	Frames    int
+	Columns   int
+	FrameSize int
+}
+
+func NewRenderer(opts Options) *Renderer {
+	if opts.Size <= 0 {
+		opts.Size = DefaultSize
+	}
+	if opts.Supersample <= 0 {
+		opts.Supersample = DefaultSupersample
+	}
+	if opts.Elevation == 0 {
+		opts.Elevation = DefaultElevation
+	}
+	return &Renderer{opts: opts}
+}
+
+// Thumbnail renders a single view of the scene rotated by yaw degrees around the vertical axis.
+func (r *Renderer) Thumbnail(scene *Scene, yaw float64) *image.NRGBA {
+	return r.renderFrame(scene, r.opts.Size, yaw)
+}
+
+// Turntable renders a full revolution of the scene into a sprite sheet.
+func (r *Renderer) Turntable(scene *Scene, frames, frameSize int) *SpriteSheet {
+	if frames <= 0 {
+		frames = SpriteFrames
+	}
+	if frames > MaxSpriteFrames {
+		frames = MaxSpriteFrames
+	}
+	if frameSize <= 0 {
+		frameSize = SpriteFrameSize
+	}
+
+	columns := int(math.Ceil(math.Sqrt(float64(frames))))
+	rows := (frames + columns - 1) / columns
+	sheet := image.NewNRGBA(image.Rect(0, 0, columns*frameSize, rows*frameSize))
+
+	for i := 0; i < frames; i++ {
+		frame := r.renderFrame(scene, frameSize, DefaultYaw+360*float64(i)/float64(frames))
+		ox, oy := (i%columns)*frameSize, (i/columns)*frameSize
+		for y := 0; y < frameSize; y++ {
+			copy(sheet.Pix[sheet.PixOffset(ox, oy+y):sheet.PixOffset(ox+frameSize, oy+y)], frame.Pix[frame.PixOffset(0, y):frame.PixOffset(frameSize, y)])
+		}
+	}
+
+	return &SpriteSheet{Image: sheet, Frames: frames, Columns: columns, FrameSize: frameSize}
+}
+
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type frameBuffer struct {
+	size  int
+	color []float32
+	depth []float32
+}
+
+func (r *Renderer) renderFrame(scene *Scene, size int, yaw float64) *image.NRGBA {
+	ss := r.opts.Supersample
+	fb := &frameBuffer{
+		size:  size * ss,
+		color: make([]float32, size*ss*size*ss*4),
+		depth: make([]float32, size*ss*size*ss),
+	}
+	for i := range fb.depth {
+		fb.depth[i] = float32(math.Inf(-1))
+	}
+
+	center, radius := bounds(scene)
+	view := viewRotation(yaw, r.opts.Elevation)
+	scale := float64(fb.size) / 2 * fitMargin / radius
+	half := float64(fb.size) / 2
+
+	for _, mesh := range scene.Meshes {
+		screen := make([]vec3, len(mesh.Positions))
+		for i, p := range mesh.Positions {
+			v := view.transformPoint(float64(p[0])-center[0], float64(p[1])-center[1], float64(p[2])-center[2])
+			screen[i] = vec3{half + v[0]*scale, half - v[1]*scale, v[2] * scale}
+		}
+		var normals []vec3
+		if mesh.Normals != nil {
+			normals = make([]vec3, len(mesh.Normals))
+			for i, n := range mesh.Normals {
+				normals[i] = view.transformDirection(float64(n[0]), float64(n[1]), float64(n[2]))
+			}
+		}
+		material := scene.Materials[mesh.Material]
+
+		for t := 0; t+2 < len(mesh.Indices); t += 3 {
+			fb.drawTriangle(&mesh, screen, normals, &material, mesh.Indices[t], mesh.Indices[t+1], mesh.Indices[t+2])
+		}
+	}
+
+	return fb.resolve(size, ss, r.opts.Background)
+}
+
+func (fb *frameBuffer) drawTriangle(mesh *Mesh, screen, normals []vec3, material *Material, i0, i1, i2 uint32) {
+	a, b, c := screen[i0], screen[i1], screen[i2]
+	area := (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	if math.Abs(area) < 1e-12 {
+		return
+	}
+
+	minX := int(math.Max(0, math.Floor(math.Min(a[0], math.Min(b[0], c[0])))))
+	maxX := int(math.Min(float64(fb.size-1), math.Ceil(math.Max(a[0], math.Max(b[0], c[0])))))
+	minY := int(math.Max(0, math.Floor(math.Min(a[1], math.Min(b[1], c[1])))))
+	maxY := int(math.Min(float64(fb.size-1), math.Ceil(math.Max(a[1], math.Max(b[1], c[1])))))
+	if minX > maxX || minY > maxY {
+		return
+	}
+
+	// Screen-space positions are uniformly scaled view-space positions with y flipped, so un-flipping y gives the face normal.
+	faceNormal := vec3{b[0] - a[0], -(b[1] - a[1]), b[2] - a[2]}.cross(vec3{c[0] - a[0], -(c[1] - a[1]), c[2] - a[2]}).normalize()
+
+	invArea := 1 / area
+	for y := minY; y <= maxY; y++ {
+		py := float64(y) + 0.5
+		for x := minX; x <= maxX; x++ {
+			px := float64(x) + 0.5
+			w0 := ((b[0]-px)*(c[1]-py) - (b[1]-py)*(c[0]-px)) * invArea
+			w1 := ((c[0]-px)*(a[1]-py) - (c[1]-py)*(a[0]-px)) * invArea
+			w2 := 1 - w0 - w1
+			if w0 < 0 || w1 < 0 || w2 < 0 {
+				continue
+			}
+
+			z := float32(w0*a[2] + w1*b[2] + w2*c[2])
+			idx := y*fb.size + x
+			if z <= fb.depth[idx] {
+				continue
+			}
+			fb.depth[idx] = z
+
+			n := faceNormal
+			if normals != nil {
+				na, nb, nc := normals[i0], normals[i1], normals[i2]
+				n = vec3{
+					w0*na[0] + w1*nb[0] + w2*nc[0],
+					w0*na[1] + w1*nb[1] + w2*nc[1],
+					w0*na[2] + w1*nb[2] + w2*nc[2],
+				}.normalize()
+			}
+			if n[2] < 0 {
+				n = vec3{-n[0], -n[1], -n[2]}
+			}
+
+			light := ambientLight + 0.75*math.Max(0, n.dot(keyLight)) + 0.3*math.Max(0, n.dot(fillLight))
+			specular := math.Pow(math.Max(0, n.dot(vec3{keyLight[0], keyLight[1], keyLight[2] + 1}.normalize())), 32) * 0.25
+
+			base := material.Color
+			if mesh.Colors != nil {
+				ca, cb, cc := mesh.Colors[i0], mesh.Colors[i1], mesh.Colors[i2]
+				for k := range base {
+					base[k] *= float32(w0)*ca[k] + float32(w1)*cb[k] + float32(w2)*cc[k]
+				}
+			}
+			if material.Texture != nil && mesh.UVs != nil {
+				ua, ub, uc := mesh.UVs[i0], mesh.UVs[i1], mesh.UVs[i2]
+				u := w0*float64(ua[0]) + w1*float64(ub[0]) + w2*float64(uc[0])
+				v := w0*float64(ua[1]) + w1*float64(ub[1]) + w2*float64(uc[1])
+				texel := sampleTexture(material.Texture, u, v)
+				for k := range base {
+					base[k] *= texel[k]
+				}
+			}
+
+			o := idx * 4
+			for k := 0; k < 3; k++ {
+				fb.color[o+k] = float32(math.Min(1, float64(base[k])*light+specular))
+			}
+			fb.color[o+3] = 1
+		}
+	}
+}
+
+func (fb *frameBuffer) resolve(size, ss int, background color.NRGBA) *image.NRGBA {
+	out := image.NewNRGBA(image.Rect(0, 0, size, size))
+	samples := float32(ss * ss)
+	bg := [4]float32{float32(background.R) / 255, float32(background.G) / 255, float32(background.B) / 255, float32(background.A) / 255}
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			var sum [4]float32
+			for sy := 0; sy < ss; sy++ {
+				for sx := 0; sx < ss; sx++ {
+					o := ((y*ss+sy)*fb.size + x*ss + sx) * 4
+					if fb.color[o+3] == 0 {
+						for k := 0; k < 3; k++ {
+							sum[k] += bg[k] * bg[3]
+						}
+						sum[3] += bg[3]
+						continue
+					}
+					for k := 0; k < 3; k++ {
+						sum[k] += fb.color[o+k]
+					}
+					sum[3]++
+				}
+			}
+
+			i := out.PixOffset(x, y)
+			if sum[3] == 0 {
+				continue
+			}
+			for k := 0; k < 3; k++ {
+				out.Pix[i+k] = uint8(math.Round(float64(sum[k]/sum[3]) * 255))
+			}
+			out.Pix[i+3] = uint8(math.Round(float64(sum[3]/samples) * 255))
+		}
+	}
+	return out
+}
+
+func sampleTexture(tex *image.NRGBA, u, v float64) [4]float32 {
+	w, h := tex.Rect.Dx(), tex.Rect.Dy()
+	u -= math.Floor(u)
+	v -= math.Floor(v)
+	x := int(u * float64(w))
+	y := int(v * float64(h))
+	if x >= w {
+		x = w - 1
+	}
+	if y >= h {
+		y = h - 1
+	}
+	i := tex.PixOffset(tex.Rect.Min.X+x, tex.Rect.Min.Y+y)
+	return [4]float32{
+		float32(tex.Pix[i]) / 255,
+		float32(tex.Pix[i+1]) / 255,
+		float32(tex.Pix[i+2]) / 255,
+		float32(tex.Pix[i+3]) / 255,
+	}
+}
+
+// bounds returns the center of the scene's bounding box and the radius of the sphere enclosing all vertices around it.
+func bounds(scene *Scene) (vec3, float64) {
+	min := vec3{math.Inf(1), math.Inf(1), math.Inf(1)}
+	max := vec3{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	for _, mesh := range scene.Meshes {
+		for _, p := range mesh.Positions {
+			for k := 0; k < 3; k++ {
+				min[k] = math.Min(min[k], float64(p[k]))
+				max[k] = math.Max(max[k], float64(p[k]))
+			}
+		}
+	}
+	center := vec3{(min[0] + max[0]) / 2, (min[1] + max[1]) / 2, (min[2] + max[2]) / 2}
+
+	radius := 0.0
+	for _, mesh := range scene.Meshes {
+		for _, p := range mesh.Positions {
+			d := vec3{float64(p[0]), float64(p[1]), float64(p[2])}.sub(center)
+			radius = math.Max(radius, d.dot(d))
+		}
+	}
+	radius = math.Sqrt(radius)
+	if radius == 0 {
+		radius = 1
+	}
+	return center, radius
+}
+
+func viewRotation(yawDeg, elevationDeg float64) mat4 {
+	yaw := yawDeg * math.Pi / 180
+	pitch := elevationDeg * math.Pi / 180
+
+	rotY := identity()
+	rotY[0], rotY[8] = math.Cos(yaw), math.Sin(yaw)
+	rotY[2], rotY[10] = -math.Sin(yaw), math.Cos(yaw)
+
+	rotX := identity()
+	rotX[5], rotX[9] = math.Cos(pitch), -math.Sin(pitch)
+	rotX[6], rotX[10] = math.Sin(pitch), math.Cos(pitch)
+
+	return rotX.mul(rotY)
+}
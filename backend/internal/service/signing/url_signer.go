@@ -0,0 +1,132 @@
+// Short-lived HMAC-signed URLs for storage assets that must not be publicly readable.
+// A signature covers the asset path and its expiry time, so a URL cannot be reused for another file or after it expires.
+// Which storage directories require a signature is configurable, which allows the feature to be enabled per deployment.
+
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultTTL     = 15 * time.Minute
+	ExpiresParam   = "expires"
+	SignatureParam = "signature"
+)
+
+var (
+	ErrMissingSignature = errors.New("требуется подписанная ссылка")
+	ErrInvalidSignature = errors.New("некорректная подпись ссылки")
+	ErrExpiredSignature = errors.New("срок действия ссылки истёк")
+)
+
+type URLSigner struct {
+	secret    []byte
+	ttl       time.Duration
+	protected map[string]bool
+}
+
+// NewURLSigner creates a signer. protectedDirs lists top-level storage directories (for example "models")
+// whose files are only served with a valid signature.
+func NewURLSigner(secret string, ttl time.Duration, protectedDirs []string) *URLSigner {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	protected := make(map[string]bool)
+	for _, dir := range protectedDirs {
+		dir = strings.Trim(strings.TrimSpace(dir), "/")
+		if dir != "" {
+			protected[dir] = true
+		}
+	}
+	return &URLSigner{secret: []byte(secret), ttl: ttl, protected: protected}
+}
+
+// NewURLSignerFromEnv reads STORAGE_SIGNED_DIRS (comma separated), STORAGE_URL_TTL (a Go duration)
+// and STORAGE_SIGNING_SECRET, which falls back to JWT_SECRET.
+func NewURLSignerFromEnv() *URLSigner {
+	secret := os.Getenv("STORAGE_SIGNING_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+
+	ttl := DefaultTTL
+	if raw := os.Getenv("STORAGE_URL_TTL"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			log.Printf("Warning: invalid STORAGE_URL_TTL %q, using %s", raw, DefaultTTL)
+		} else {
+			ttl = parsed
+		}
+	}
+
+	var dirs []string
+	if raw := os.Getenv("STORAGE_SIGNED_DIRS"); raw != "" {
+		dirs = strings.Split(raw, ",")
+	}
+	return NewURLSigner(secret, ttl, dirs)
+}
+
+// Enabled reports whether any directory requires signed access.
+func (s *URLSigner) Enabled() bool {
+	return len(s.protected) > 0
+}
+
+// Requires reports whether the storage path (relative to /storage, e.g. "/models/a.glb") needs a signature.
+func (s *URLSigner) Requires(storagePath string) bool {
+	dir := strings.SplitN(strings.TrimPrefix(storagePath, "/"), "/", 2)[0]
+	return s.protected[dir]
+}
+
+// Sign appends expiry and signature parameters to a public path such as "/storage/models/a.glb".
+// Paths outside protected directories are returned unchanged.
+func (s *URLSigner) Sign(publicPath string) string {
+	if publicPath == "" || !s.Requires(strings.TrimPrefix(publicPath, "/storage")) {
+		return publicPath
+	}
+	expires := strconv.FormatInt(time.Now().Add(s.ttl).Unix(), 10)
+	query := url.Values{}
+	query.Set(ExpiresParam, expires)
+	query.Set(SignatureParam, s.signature(publicPath, expires))
+	return publicPath + "?" + query.Encode()
+}
+
+// Verify checks the signature parameters of a request for a public path.
+func (s *URLSigner) Verify(publicPath, expires, signature string) error {
+	if expires == "" || signature == "" {
+		return ErrMissingSignature
+	}
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	given, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	expected, _ := base64.RawURLEncoding.DecodeString(s.signature(publicPath, expires))
+	if !hmac.Equal(given, expected) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expiresAt {
+		return ErrExpiredSignature
+	}
+	return nil
+}
+
+func (s *URLSigner) signature(publicPath, expires string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(publicPath))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,60 @@
+// URL slugs for mineral titles: lower-case ASCII words joined by hyphens.
+// Cyrillic is transliterated with the scheme of Russian passports ("Кварц" → "kvarts", "Шпинель" → "shpinel"),
+// and common Latin diacritics lose their marks, so a slug reads close to the title in any language.
+
+package slug
+
+import (
+	"backend/internal/models"
+	"strings"
+)
+
+const MaxLength = models.MaxSlugLength
+
+// Fallback is used for titles without a single letter or digit to build a slug from.
+const Fallback = models.SlugFallback
+
+var transliteration = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu",
+	'я': "ia",
+	// Ukrainian and Belarusian letters.
+	'є': "ie", 'і': "i", 'ї': "i", 'ґ': "g", 'ў': "u",
+	// Latin letters with diacritics.
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "ae", 'å': "a", 'æ': "ae", 'ç': "c",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i",
+	'ñ': "n", 'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "oe", 'ø': "o", 'ù': "u",
+	'ú': "u", 'û': "u", 'ü': "ue", 'ý': "y", 'ÿ': "y", 'ß': "ss", 'œ': "oe",
+	'č': "c", 'ć': "c", 'š': "s", 'ž': "z", 'ř': "r", 'ł': "l", 'ę': "e", 'ą': "a",
+	'ń': "n", 'ś': "s", 'ź': "z", 'ż': "z", 'ğ': "g", 'ş': "s", 'ı': "i",
+}
+
+// Make builds a slug from a title. The result is never empty and at most MaxLength characters long.
+func Make(title string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range strings.ToLower(title) {
+		var part string
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			part = string(r)
+		default:
+			part = transliteration[r]
+		}
+		if part == "" {
+			// Soft signs vanish inside a word; anything else separates words.
+			if r != 'ь' {
+				pendingHyphen = b.Len() > 0
+			}
+			continue
+		}
+		if pendingHyphen {
+			b.WriteByte('-')
+			pendingHyphen = false
+		}
+		b.WriteString(part)
+	}
+	return models.TruncateSlug(b.String(), MaxLength)
+}
@@ -0,0 +1,49 @@
+package slug
+
+import (
+	"backend/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	word := strings.Repeat("kvarts-", 11)
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"Quartz", "quartz"},
+		{"Кварц", "kvarts"},
+		{"Шпинель", "shpinel"},
+		{"Хризоберилл", "khrizoberill"},
+		{"Щёточный кальцит", "shchetochnyi-kaltsit"},
+		{"Соль каменная", "sol-kamennaia"},
+		{"Подъёмный Юрий", "podieemnyi-iurii"},
+		{"Їжак Ґрунт Єнісей", "izhak-grunt-ienisei"},
+		{"Gümbelite", "guembelite"},
+		{"Åkermanite, Straße", "akermanite-strasse"},
+		{"Nováčekite-I", "novacekite-i"},
+		{"Rosasite (Cu,Zn)", "rosasite-cu-zn"},
+		{"  --Кварц--  ", "kvarts"},
+		{"α-Quartz", "quartz"},
+		{"Malachite № 2", "malachite-2"},
+		{"", Fallback},
+		{"!!! ???", Fallback},
+		{"ъъъ", "ieieie"},
+		{"ь", Fallback},
+		{strings.Repeat("Кварц ", 20), strings.TrimSuffix(word, "-")},
+		{strings.Repeat("a", 100), strings.Repeat("a", MaxLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			got := Make(tt.title)
+			if got != tt.want {
+				t.Errorf("Make(%q) = %q, want %q", tt.title, got, tt.want)
+			}
+			if !models.ValidSlug(got) {
+				t.Errorf("Make(%q) = %q is not a valid slug", tt.title, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+// Scoring of a spectrum against references. Both are resampled on a common grid over the x range they share,
+// a rolling-minimum baseline is subtracted, and the Pearson correlation of the results is the similarity.
+// The score scales the correlation by how much of the query range the reference covers.
+
+package spectroscopy
+
+import (
+	"backend/internal/models"
+	"math"
+	"sort"
+)
+
+const (
+	compareGridSize = 1000
+	// References covering less than this share of the query range are not compared.
+	minOverlap = 0.2
+	// Half-width of the baseline window as a share of the grid.
+	baselineWindow = 0.05
+)
+
+type Comparison struct {
+	Score       float64 `json:"score"`
+	Correlation float64 `json:"correlation"`
+	Overlap     float64 `json:"overlap"`
+}
+
+type Match struct {
+	SpectrumID int `json:"spectrum_id"`
+	Comparison
+}
+
+// Compare scores the reference against the query; ok is false when their x ranges barely overlap.
+func Compare(query, reference []models.SpectrumPoint) (result Comparison, ok bool) {
+	if len(query) < 2 || len(reference) < 2 {
+		return result, false
+	}
+	from := math.Max(query[0][0], reference[0][0])
+	to := math.Min(query[len(query)-1][0], reference[len(reference)-1][0])
+	queryRange := query[len(query)-1][0] - query[0][0]
+	if to <= from || queryRange <= 0 {
+		return result, false
+	}
+	result.Overlap = round((to-from)/queryRange, 4)
+	if result.Overlap < minOverlap {
+		return result, false
+	}
+
+	q := removeBaseline(resample(query, from, to, compareGridSize))
+	r := removeBaseline(resample(reference, from, to, compareGridSize))
+	result.Correlation = round(pearson(q, r), 4)
+	result.Score = round(100*math.Max(0, result.Correlation)*math.Sqrt(result.Overlap), 2)
+	return result, true
+}
+
+// Rank compares the query with every reference, keyed by spectrum id, and returns up to limit best matches.
+func Rank(query []models.SpectrumPoint, references map[int][]models.SpectrumPoint, limit int) []Match {
+	matches := []Match{}
+	for id, reference := range references {
+		if comparison, ok := Compare(query, reference); ok {
+			matches = append(matches, Match{SpectrumID: id, Comparison: comparison})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].SpectrumID < matches[j].SpectrumID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// resample interpolates the series linearly at n evenly spaced x values from from to to.
+func resample(points []models.SpectrumPoint, from, to float64, n int) []float64 {
+	values := make([]float64, n)
+	j := 0
+	for i := range values {
+		x := from + (to-from)*float64(i)/float64(n-1)
+		for j < len(points)-2 && points[j+1][0] < x {
+			j++
+		}
+		a, b := points[j], points[j+1]
+		if b[0] == a[0] {
+			values[i] = a[1]
+			continue
+		}
+		t := math.Min(math.Max((x-a[0])/(b[0]-a[0]), 0), 1)
+		values[i] = a[1] + t*(b[1]-a[1])
+	}
+	return values
+}
+
+func removeBaseline(values []float64) []float64 {
+	window := max(1, int(float64(len(values))*baselineWindow))
+	corrected := make([]float64, len(values))
+	for i := range values {
+		baseline := values[i]
+		for j := max(0, i-window); j <= min(len(values)-1, i+window); j++ {
+			baseline = math.Min(baseline, values[j])
+		}
+		corrected[i] = values[i] - baseline
+	}
+	return corrected
+}
+
+func pearson(x, y []float64) float64 {
+	n := float64(len(x))
+	var meanX, meanY float64
+	for i := range x {
+		meanX += x[i]
+		meanY += y[i]
+	}
+	meanX, meanY = meanX/n, meanY/n
+
+	var cov, varX, varY float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX*varY)
+}
+
+func round(value float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,324 @@
+// A reader for JCAMP-DX 4.24 / 5.01 spectra. Reads the header records used for metadata and the first
+// ##XYDATA=(X++(Y..Y)) or ##XYPOINTS=(XY..XY) table. Tables may be plain (AFFN) or ASDF-compressed
+// (SQZ, DIF and DUP forms, with the DIF y-check value at line starts). Wavelengths in micrometres are converted to cm⁻¹.
+
+package spectroscopy
+
+import (
+	"backend/internal/models"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type jcampRecord struct {
+	label string
+	value string
+	lines []string
+}
+
+// ParseJCAMP reads the first spectrum of a JCAMP-DX file.
+func ParseJCAMP(data []byte) (*Spectrum, error) {
+	records := readJCAMPRecords(string(data))
+	header := map[string]string{}
+	spectrum := &Spectrum{}
+
+	for _, record := range records {
+		switch record.label {
+		case "XYDATA", "XYPOINTS":
+			points, err := readJCAMPTable(record, header)
+			if err != nil {
+				return nil, err
+			}
+			spectrum.Metadata = jcampMetadata(header)
+			if strings.Contains(strings.ToUpper(header["XUNITS"]), "MICROMETER") {
+				for i := range points {
+					points[i][0] = 10000 / points[i][0]
+				}
+				spectrum.XUnits = "cm-1"
+			}
+			spectrum.Points, err = SortPoints(points)
+			if err != nil {
+				return nil, err
+			}
+			return spectrum, nil
+		default:
+			if _, seen := header[record.label]; !seen {
+				header[record.label] = record.value
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: в файле JCAMP-DX нет таблицы XYDATA или XYPOINTS", ErrInvalidSpectrum)
+}
+
+// readJCAMPRecords splits the file into labelled records. Labels are upper-cased without spaces, dashes,
+// slashes and underscores, as the standard requires; "$$" comments are dropped.
+func readJCAMPRecords(text string) []jcampRecord {
+	var records []jcampRecord
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		if i := strings.Index(line, "$$"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if strings.HasPrefix(strings.TrimSpace(line), "##") {
+			line = strings.TrimSpace(line)[2:]
+			label, value, _ := strings.Cut(line, "=")
+			records = append(records, jcampRecord{label: normalizeLabel(label), value: strings.TrimSpace(value)})
+			continue
+		}
+		if len(records) > 0 && strings.TrimSpace(line) != "" {
+			records[len(records)-1].lines = append(records[len(records)-1].lines, line)
+		}
+	}
+	return records
+}
+
+func normalizeLabel(label string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' || r == '/' || r == '_' {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, label)
+}
+
+func jcampMetadata(header map[string]string) Metadata {
+	metadata := Metadata{
+		Title:      header["TITLE"],
+		Instrument: firstNonEmpty(header["SPECTROMETERDATASYSTEM"], header["INSTRUMENT"], header["$INSTRUMENT"]),
+		XUnits:     strings.ToLower(header["XUNITS"]),
+		YUnits:     strings.ToLower(header["YUNITS"]),
+	}
+
+	dataType := strings.ToUpper(header["DATATYPE"])
+	switch {
+	case strings.Contains(dataType, "RAMAN"):
+		metadata.Type = models.SpectrumRaman
+	case strings.Contains(dataType, "INFRARED"), strings.Contains(dataType, "IR SPECTRUM"):
+		metadata.Type = models.SpectrumFTIR
+	case strings.Contains(dataType, "X-RAY"), strings.Contains(dataType, "DIFFRACTION"):
+		metadata.Type = models.SpectrumXRD
+	}
+	if metadata.XUnits == "1/cm" {
+		metadata.XUnits = "cm-1"
+	}
+
+	for label, value := range header {
+		if strings.Contains(label, "LASERWAVELENGTH") || strings.Contains(label, "EXCITATIONWAVELENGTH") {
+			if wavelength, ok := leadingNumber(value); ok {
+				metadata.LaserWavelength = &wavelength
+			}
+		}
+	}
+	return metadata
+}
+
+// readJCAMPTable decodes the lines of an XYDATA or XYPOINTS record into points in real units.
+func readJCAMPTable(record jcampRecord, header map[string]string) ([]models.SpectrumPoint, error) {
+	xFactor := headerNumber(header, "XFACTOR", 1)
+	yFactor := headerNumber(header, "YFACTOR", 1)
+
+	if record.label == "XYPOINTS" {
+		var values []float64
+		for _, line := range record.lines {
+			decoded, err := decodeASDF(line)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, decoded.values...)
+		}
+		if len(values)%2 != 0 {
+			return nil, fmt.Errorf("%w: в таблице XYPOINTS нечетное число значений", ErrInvalidSpectrum)
+		}
+		points := make([]models.SpectrumPoint, 0, len(values)/2)
+		for i := 0; i < len(values); i += 2 {
+			points = append(points, models.SpectrumPoint{values[i] * xFactor, values[i+1] * yFactor})
+		}
+		return points, nil
+	}
+
+	if !strings.Contains(strings.ToUpper(strings.ReplaceAll(record.value, " ", "")), "X++(Y..Y)") {
+		return nil, fmt.Errorf("%w: неподдерживаемый формат таблицы %s", ErrInvalidSpectrum, record.value)
+	}
+	firstX, okFirst := header["FIRSTX"]
+	lastX, okLast := header["LASTX"]
+	if !okFirst || !okLast {
+		return nil, fmt.Errorf("%w: для XYDATA нужны FIRSTX и LASTX", ErrInvalidSpectrum)
+	}
+	from, errFrom := strconv.ParseFloat(strings.TrimSpace(firstX), 64)
+	to, errTo := strconv.ParseFloat(strings.TrimSpace(lastX), 64)
+	if errFrom != nil || errTo != nil {
+		return nil, fmt.Errorf("%w: некорректные FIRSTX или LASTX", ErrInvalidSpectrum)
+	}
+
+	var ys []float64
+	checkPending := false
+	for _, line := range record.lines {
+		decoded, err := decodeASDF(line)
+		if err != nil {
+			return nil, err
+		}
+		if len(decoded.values) < 2 {
+			continue
+		}
+		lineYs := decoded.values[1:]
+		// After a line ending in DIF form the first y repeats the last one as a check.
+		if checkPending && len(ys) > 0 {
+			if math.Abs(lineYs[0]-ys[len(ys)-1]) > 1e-6*math.Max(1, math.Abs(lineYs[0])) {
+				return nil, fmt.Errorf("%w: не совпадает контрольное значение y в строке %q", ErrInvalidSpectrum, line)
+			}
+			lineYs = lineYs[1:]
+		}
+		ys = append(ys, lineYs...)
+		checkPending = decoded.endsWithDIF
+		if len(ys) > MaxPoints {
+			return nil, fmt.Errorf("%w: больше %d точек", ErrInvalidSpectrum, MaxPoints)
+		}
+	}
+	if len(ys) < 2 {
+		return nil, fmt.Errorf("%w: слишком мало точек", ErrInvalidSpectrum)
+	}
+	if count, ok := leadingNumber(header["NPOINTS"]); ok && int(count) != len(ys) {
+		return nil, fmt.Errorf("%w: в таблице %d точек вместо %d из NPOINTS", ErrInvalidSpectrum, len(ys), int(count))
+	}
+
+	points := make([]models.SpectrumPoint, len(ys))
+	step := (to - from) / float64(len(ys)-1)
+	for i, y := range ys {
+		points[i] = models.SpectrumPoint{from + step*float64(i), y * yFactor}
+	}
+	return points, nil
+}
+
+type asdfLine struct {
+	values      []float64
+	endsWithDIF bool
+}
+
+type asdfToken struct {
+	kind   byte // 'a' absolute, 'd' difference, 'u' duplicate count
+	number string
+}
+
+// decodeASDF decodes one line of a table: the first value is x, the rest are y values.
+func decodeASDF(line string) (asdfLine, error) {
+	var tokens []asdfToken
+	var current *asdfToken
+	flush := func() {
+		if current != nil {
+			tokens = append(tokens, *current)
+			current = nil
+		}
+	}
+	start := func(kind byte, number string) {
+		flush()
+		current = &asdfToken{kind: kind, number: number}
+	}
+
+	runes := []rune(line)
+	for i, r := range runes {
+		switch {
+		case r >= '0' && r <= '9' || r == '.':
+			if current == nil {
+				start('a', "")
+			}
+			current.number += string(r)
+		case (r == 'E' || r == 'e') && current != nil && current.kind == 'a' && i+1 < len(runes) &&
+			(runes[i+1] == '+' || runes[i+1] == '-'):
+			current.number += "e"
+		case r == '+' || r == '-':
+			if current != nil && strings.HasSuffix(current.number, "e") {
+				current.number += string(r)
+				continue
+			}
+			start('a', string(r))
+		case r == ' ' || r == '\t' || r == ',' || r == ';':
+			flush()
+		case r == '?':
+			return asdfLine{}, fmt.Errorf("%w: пропущенные значения (?) не поддерживаются", ErrInvalidSpectrum)
+		case r == '@':
+			start('a', "0")
+		case r >= 'A' && r <= 'I':
+			start('a', string('1'+r-'A'))
+		case r >= 'a' && r <= 'i':
+			start('a', "-"+string('1'+r-'a'))
+		case r == '%':
+			start('d', "0")
+		case r >= 'J' && r <= 'R':
+			start('d', string('1'+r-'J'))
+		case r >= 'j' && r <= 'r':
+			start('d', "-"+string('1'+r-'j'))
+		case r >= 'S' && r <= 'Z':
+			start('u', string('1'+r-'S'))
+		case r == 's':
+			start('u', "9")
+		default:
+			return asdfLine{}, fmt.Errorf("%w: неожиданный символ %q в таблице данных", ErrInvalidSpectrum, r)
+		}
+	}
+	flush()
+
+	var result asdfLine
+	var lastKind byte
+	var lastDiff float64
+	for _, token := range tokens {
+		switch token.kind {
+		case 'u':
+			count, err := strconv.Atoi(token.number)
+			if err != nil || count < 1 || len(result.values) < 2 {
+				return asdfLine{}, fmt.Errorf("%w: некорректный повтор DUP", ErrInvalidSpectrum)
+			}
+			for n := 1; n < count; n++ {
+				if lastKind == 'd' {
+					result.values = append(result.values, result.values[len(result.values)-1]+lastDiff)
+				} else {
+					result.values = append(result.values, result.values[len(result.values)-1])
+				}
+			}
+			continue
+		}
+		value, err := strconv.ParseFloat(token.number, 64)
+		if err != nil {
+			return asdfLine{}, fmt.Errorf("%w: некорректное число %q", ErrInvalidSpectrum, token.number)
+		}
+		if token.kind == 'd' {
+			if len(result.values) < 2 {
+				return asdfLine{}, fmt.Errorf("%w: разность DIF без предыдущего значения", ErrInvalidSpectrum)
+			}
+			value += result.values[len(result.values)-1]
+			lastDiff = value - result.values[len(result.values)-1]
+		}
+		result.values = append(result.values, value)
+		lastKind = token.kind
+	}
+	result.endsWithDIF = lastKind == 'd'
+	return result, nil
+}
+
+func headerNumber(header map[string]string, label string, fallback float64) float64 {
+	if value, ok := leadingNumber(header[label]); ok && value != 0 {
+		return value
+	}
+	return fallback
+}
+
+// leadingNumber reads the number at the start of a value such as "532 nm".
+func leadingNumber(value string) (float64, bool) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(strings.TrimRightFunc(fields[0], unicode.IsLetter), 64)
+	return number, err == nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,245 @@
+package spectroscopy
+
+import (
+	"backend/internal/models"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// transmittance is the y series of the test spectra, in thousandths: a single absorption band with flat wings,
+// so that the compressed table has repeated values as well as repeated differences.
+var transmittance = []float64{
+	950, 950, 950, 948, 946, 944, 940, 931, 915, 890, 850, 800, 760, 741, 738, 738,
+	738, 740, 760, 800, 850, 890, 915, 931, 940, 944, 946, 948, 950, 950, 950,
+}
+
+// difdupJCAMP is laid out like the infrared spectra the NIST Chemistry WebBook publishes: a JCAMP-DX 4.24 header
+// and an XYDATA table in DIFDUP form, where every line after the first starts with the y-check value.
+const difdupJCAMP = `##TITLE=QUARTZ
+##JCAMP-DX=4.24
+##DATA TYPE=INFRARED SPECTRUM
+##ORIGIN=Mineral reference collection
+##OWNER=PUBLIC DOMAIN
+##$NIST SOURCE=TESTING
+##SPECTROMETER/DATA SYSTEM=Bruker IFS 66   $$ the instrument
+##XUNITS=1/CM
+##YUNITS=TRANSMITTANCE
+##XFACTOR=1.0
+##YFACTOR=0.001
+##FIRSTX=400
+##LASTX=430
+##FIRSTY=0.950
+##NPOINTS=31
+##XYDATA=(X++(Y..Y))
+400I50%TkUmrj6k5
+409H90m0n0m0j9l%TKK0M0
+419H00N0M0K5J6RMKU%
+429I50%
+##END=
+`
+
+func TestParseJCAMPDIFDUP(t *testing.T) {
+	spectrum, err := ParseJCAMP([]byte(difdupJCAMP))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spectrum.Title != "QUARTZ" || spectrum.Type != models.SpectrumFTIR || spectrum.Instrument != "Bruker IFS 66" {
+		t.Errorf("metadata = %+v", spectrum.Metadata)
+	}
+	if spectrum.XUnits != "cm-1" || spectrum.YUnits != "transmittance" {
+		t.Errorf("units = %q, %q", spectrum.XUnits, spectrum.YUnits)
+	}
+	checkPoints(t, spectrum.Points, 400, 1, 0.001)
+}
+
+func TestParseJCAMPForms(t *testing.T) {
+	affn := make([]string, 0, len(transmittance))
+	for _, y := range transmittance {
+		affn = append(affn, formatFloat(y))
+	}
+	tests := []struct {
+		name  string
+		table string
+	}{
+		{
+			name:  "AFFN with a line break inside the series",
+			table: "##XYDATA=(X++(Y..Y))\n400 " + strings.Join(affn[:16], " ") + "\n416 " + strings.Join(affn[16:], ",") + "\n",
+		},
+		{
+			name: "SQZ",
+			table: "##XYDATA=(X++(Y..Y))\n400I50I50I50I48I46I44I40I31I15H90\n" +
+				"410H50H00G60G41G38G38G38G40G60H00\n420H50H90I15I31I40I44I46I48I50I50I50\n",
+		},
+		{
+			name:  "DUP of absolute values and of differences",
+			table: "##XYDATA=(X++(Y..Y))\n400I50UkUmrj6k5m0n0m0j9l%T\n417G38KK0M0N0M0K5J6RMKU%T\n",
+		},
+		{
+			name: "XYPOINTS",
+			table: "##XYPOINTS=(XY..XY)\n" + func() string {
+				var b strings.Builder
+				for i, y := range transmittance {
+					b.WriteString(formatFloat(400+float64(i)) + ", " + formatFloat(y) + "; ")
+				}
+				return b.String()
+			}() + "\n",
+		},
+	}
+	header := "##TITLE=test\n##XFACTOR=1\n##YFACTOR=0.001\n##FIRSTX=400\n##LASTX=430\n##NPOINTS=31\n"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spectrum, err := ParseJCAMP([]byte(header + tt.table + "##END=\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkPoints(t, spectrum.Points, 400, 1, 0.001)
+		})
+	}
+}
+
+func TestParseJCAMPRaman(t *testing.T) {
+	data := "##TITLE= Quartz, Raman\r\n##JCAMP-DX=5.01\r\n##DATA TYPE=RAMAN SPECTRUM\r\n" +
+		"##$LASER WAVELENGTH=532 nm\r\n##XUNITS=1/CM\r\n##YUNITS=ARBITRARY UNITS\r\n" +
+		"##FIRSTX=1200\r\n##LASTX=100\r\n##XFACTOR=1\r\n##YFACTOR=1\r\n##NPOINTS=12\r\n" +
+		"##XYDATA=(X++(Y..Y))\r\n1200 5 6 7 8 9 10\r\n600 40 100 40 10 6 5\r\n##END=\r\n"
+	spectrum, err := ParseJCAMP([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spectrum.Type != models.SpectrumRaman || spectrum.Title != "Quartz, Raman" {
+		t.Errorf("metadata = %+v", spectrum.Metadata)
+	}
+	if spectrum.LaserWavelength == nil || *spectrum.LaserWavelength != 532 {
+		t.Errorf("laser wavelength = %v", spectrum.LaserWavelength)
+	}
+	points := spectrum.Points
+	if len(points) != 12 || points[0] != (models.SpectrumPoint{100, 5}) || points[11] != (models.SpectrumPoint{1200, 5}) {
+		t.Errorf("points are not sorted by x: %v", points)
+	}
+	if points[4] != (models.SpectrumPoint{500, 100}) {
+		t.Errorf("point 4 = %v, want [500 100]", points[4])
+	}
+}
+
+func TestParseJCAMPMicrometres(t *testing.T) {
+	data := "##TITLE=test\n##DATA TYPE=INFRARED SPECTRUM\n##XUNITS=MICROMETERS\n##YUNITS=ABSORBANCE\n" +
+		"##XYPOINTS=(XY..XY)\n2 1\n2.5 2\n4 3\n5 4\n8 5\n10 6\n16 7\n20 8\n25 9\n40 10\n##END=\n"
+	spectrum, err := ParseJCAMP([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.SpectrumPoint{
+		{250, 10}, {400, 9}, {500, 8}, {625, 7}, {1000, 6}, {1250, 5}, {2000, 4}, {2500, 3}, {4000, 2}, {5000, 1},
+	}
+	if spectrum.XUnits != "cm-1" || len(spectrum.Points) != len(want) {
+		t.Fatalf("units %q, points %v", spectrum.XUnits, spectrum.Points)
+	}
+	for i := range want {
+		if spectrum.Points[i] != want[i] {
+			t.Errorf("point %d = %v, want %v", i, spectrum.Points[i], want[i])
+		}
+	}
+}
+
+func TestParseJCAMPErrors(t *testing.T) {
+	header := "##TITLE=test\n##FIRSTX=400\n##LASTX=430\n"
+	tests := []struct {
+		name    string
+		data    string
+		message string
+	}{
+		{"no table", "##TITLE=test\n##END=\n", "нет таблицы"},
+		{"unsupported table", header + "##XYDATA=(X++(R..R))\n400 1 2\n", "неподдерживаемый формат"},
+		{"no FIRSTX", "##LASTX=430\n##XYDATA=(X++(Y..Y))\n400 1 2\n", "FIRSTX"},
+		{"y-check mismatch", header + "##XYDATA=(X++(Y..Y))\n400I50%TkUmrj6k5\n409H91m0n0m0j9l%TKK0M0\n", "контрольное значение"},
+		{"difference at the start of a line", header + "##XYDATA=(X++(Y..Y))\n400J5K\n", "DIF без предыдущего"},
+		{"duplicate without a value", header + "##XYDATA=(X++(Y..Y))\n400 T\n", "DUP"},
+		{"missing values", header + "##XYDATA=(X++(Y..Y))\n400 1 ? 3\n", "пропущенные"},
+		{"unexpected character", header + "##XYDATA=(X++(Y..Y))\n400 1 # 3\n", "неожиданный символ"},
+		{"point count differs from NPOINTS", header + "##NPOINTS=5\n##XYDATA=(X++(Y..Y))\n400 1 2 3 4\n", "NPOINTS"},
+		{"odd XYPOINTS", "##XYPOINTS=(XY..XY)\n1 2 3\n", "нечетное"},
+		{"single point", header + "##XYDATA=(X++(Y..Y))\n400 1\n", "мало точек"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spectrum, err := ParseJCAMP([]byte(tt.data))
+			if !errors.Is(err, ErrInvalidSpectrum) {
+				t.Fatalf("ParseJCAMP = %+v, %v; want ErrInvalidSpectrum", spectrum, err)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("error %q does not mention %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestDecodeASDF(t *testing.T) {
+	tests := []struct {
+		line        string
+		values      []float64
+		endsWithDIF bool
+	}{
+		{"400 950 -12.5 1.5E+03 2e-3", []float64{400, 950, -12.5, 1500, 0.002}, false},
+		{"400@A1b2", []float64{400, 0, 11, -22}, false},
+		{"400A%J1j2", []float64{400, 1, 1, 12, 0}, true},
+		{"400A%JU", []float64{400, 1, 1, 2, 3, 4}, true},
+		{"400AS2", []float64{400, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, false},
+		{"400AJA", []float64{400, 1, 2, 1}, false},
+		{"-5.5e+1;+3", []float64{-55, 3}, false},
+	}
+	for _, tt := range tests {
+		decoded, err := decodeASDF(tt.line)
+		if err != nil {
+			t.Errorf("decodeASDF(%q): %v", tt.line, err)
+			continue
+		}
+		if !equalValues(decoded.values, tt.values) || decoded.endsWithDIF != tt.endsWithDIF {
+			t.Errorf("decodeASDF(%q) = %v, %v; want %v, %v", tt.line, decoded.values, decoded.endsWithDIF, tt.values, tt.endsWithDIF)
+		}
+	}
+}
+
+func TestReadJCAMPRecords(t *testing.T) {
+	records := readJCAMPRecords("##Spectrometer/Data System= Raman 1 $$ comment\n##$Laser_Wavelength =785\n 1 2\n\n3 4\n")
+	if len(records) != 2 {
+		t.Fatalf("records = %+v", records)
+	}
+	if records[0].label != "SPECTROMETERDATASYSTEM" || records[0].value != "Raman 1" {
+		t.Errorf("record 0 = %+v", records[0])
+	}
+	if records[1].label != "$LASERWAVELENGTH" || records[1].value != "785" || len(records[1].lines) != 2 {
+		t.Errorf("record 1 = %+v", records[1])
+	}
+}
+
+func checkPoints(t *testing.T, points []models.SpectrumPoint, firstX, step, yFactor float64) {
+	t.Helper()
+	if len(points) != len(transmittance) {
+		t.Fatalf("%d points, want %d", len(points), len(transmittance))
+	}
+	for i, y := range transmittance {
+		want := models.SpectrumPoint{firstX + step*float64(i), y * yFactor}
+		if math.Abs(points[i][0]-want[0]) > 1e-9 || math.Abs(points[i][1]-want[1]) > 1e-9 {
+			t.Fatalf("point %d = %v, want %v", i, points[i], want)
+		}
+	}
+}
+
+func equalValues(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
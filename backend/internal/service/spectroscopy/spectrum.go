@@ -0,0 +1,143 @@
+// Numeric spectra: Raman and FTIR spectra and XRD patterns stored as (x, y) series.
+// Provides parsing of delimited two-column text, downsampling for plotting and scoring of a spectrum against references.
+// x is the Raman shift or wavenumber in cm⁻¹, or 2θ in degrees for XRD; y is in arbitrary units.
+
+package spectroscopy
+
+import (
+	"backend/internal/models"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MaxPoints = 100000
+	MinPoints = 10
+)
+
+var ErrInvalidSpectrum = errors.New("некорректный спектр")
+
+// Metadata is read from the file header where the format provides it.
+type Metadata struct {
+	Title           string
+	Type            string
+	Instrument      string
+	LaserWavelength *float64
+	XUnits          string
+	YUnits          string
+}
+
+type Spectrum struct {
+	Metadata
+	Points []models.SpectrumPoint
+}
+
+// Parse reads a JCAMP-DX file when the data starts with a "##" record, and delimited columns otherwise.
+func Parse(data []byte) (*Spectrum, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("##")) {
+		return ParseJCAMP(data)
+	}
+	points, err := ParseColumns(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Spectrum{Points: points}, nil
+}
+
+// ParseColumns reads two-column text. Columns may be separated by whitespace, commas or semicolons;
+// header and comment lines before the data and extra columns are ignored. Points are sorted by x.
+func ParseColumns(data []byte) ([]models.SpectrumPoint, error) {
+	var points []models.SpectrumPoint
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.ContainsAny(text[:1], "#;!%") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ',' || r == ';'
+		})
+		if len(fields) < 2 {
+			continue
+		}
+		x, errX := strconv.ParseFloat(fields[0], 64)
+		y, errY := strconv.ParseFloat(fields[1], 64)
+		if errX != nil || errY != nil {
+			if len(points) == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("%w: строка %d не содержит двух чисел", ErrInvalidSpectrum, line)
+		}
+		points = append(points, models.SpectrumPoint{x, y})
+		if len(points) > MaxPoints {
+			return nil, fmt.Errorf("%w: больше %d точек", ErrInvalidSpectrum, MaxPoints)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSpectrum, err)
+	}
+	return SortPoints(points)
+}
+
+// SortPoints sorts the points by x and checks that they form a usable series.
+func SortPoints(points []models.SpectrumPoint) ([]models.SpectrumPoint, error) {
+	if len(points) < MinPoints {
+		return nil, fmt.Errorf("%w: слишком мало точек", ErrInvalidSpectrum)
+	}
+	for _, p := range points {
+		if math.IsNaN(p[0]) || math.IsInf(p[0], 0) || math.IsNaN(p[1]) || math.IsInf(p[1], 0) {
+			return nil, fmt.Errorf("%w: значения должны быть конечными числами", ErrInvalidSpectrum)
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i][0] < points[j][0] })
+	if points[0][0] == points[len(points)-1][0] {
+		return nil, fmt.Errorf("%w: все точки имеют одинаковое значение x", ErrInvalidSpectrum)
+	}
+	return points, nil
+}
+
+// Downsample reduces the series to at most threshold points with the largest-triangle-three-buckets algorithm,
+// which keeps peaks visible. Series that are already small enough are returned unchanged.
+func Downsample(points []models.SpectrumPoint, threshold int) []models.SpectrumPoint {
+	if threshold >= len(points) || threshold < 3 {
+		return points
+	}
+
+	sampled := make([]models.SpectrumPoint, 0, threshold)
+	sampled = append(sampled, points[0])
+	bucketSize := float64(len(points)-2) / float64(threshold-2)
+	selected := 0
+	for bucket := 0; bucket < threshold-2; bucket++ {
+		start := int(float64(bucket)*bucketSize) + 1
+		end := int(float64(bucket+1)*bucketSize) + 1
+
+		// The average of the next bucket is the third vertex of the triangle.
+		nextStart, nextEnd := end, min(int(float64(bucket+2)*bucketSize)+1, len(points))
+		var avgX, avgY float64
+		for _, p := range points[nextStart:nextEnd] {
+			avgX += p[0]
+			avgY += p[1]
+		}
+		count := float64(nextEnd - nextStart)
+		avgX, avgY = avgX/count, avgY/count
+
+		best, bestArea := start, -1.0
+		a := points[selected]
+		for i := start; i < end; i++ {
+			area := math.Abs((a[0]-avgX)*(points[i][1]-a[1]) - (a[0]-points[i][0])*(avgY-a[1]))
+			if area > bestArea {
+				best, bestArea = i, area
+			}
+		}
+		sampled = append(sampled, points[best])
+		selected = best
+	}
+	return append(sampled, points[len(points)-1])
+}
@@ -0,0 +1,197 @@
+// A consistency checker and garbage collector for uploaded files.
+// Compares the files in the storage directories with the paths referenced from the database and reports
+// orphaned files as well as records that point at missing files.
+// In collect mode orphans older than a grace period are deleted; younger files are kept, because they may belong to an upload that is still in progress.
+
+package storagecheck
+
+import (
+	"backend/internal/database"
+	"backend/internal/service/file"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultGracePeriod = 24 * time.Hour
+
+// Directories scanned for orphans, relative to the storage root.
+var managedDirs = []string{file.ModelsDir, file.PreviewsDir, file.SourcesDir, file.ImagesDir, file.VideosDir, file.DocumentsDir, file.StructuresDir, file.SpectraDir}
+
+type OrphanFile struct {
+	Path          string    `json:"path"`
+	Size          int64     `json:"size"`
+	ModifiedAt    time.Time `json:"modified_at"`
+	InGracePeriod bool      `json:"in_grace_period"`
+	Deleted       bool      `json:"deleted"`
+	Error         string    `json:"error,omitempty"`
+}
+
+type Report struct {
+	StartedAt     time.Time                   `json:"started_at"`
+	FinishedAt    time.Time                   `json:"finished_at"`
+	DryRun        bool                        `json:"dry_run"`
+	GracePeriod   string                      `json:"grace_period"`
+	FilesScanned  int                         `json:"files_scanned"`
+	References    int                         `json:"references"`
+	Orphans       []OrphanFile                `json:"orphans"`
+	MissingFiles  []database.StorageReference `json:"missing_files"`
+	DeletedFiles  int                         `json:"deleted_files"`
+	ReclaimedSize int64                       `json:"reclaimed_bytes"`
+}
+
+type Checker struct {
+	db          *database.Database
+	fileService *file.FileService
+	grace       time.Duration
+	mu          sync.Mutex
+}
+
+func NewChecker(db *database.Database, fileService *file.FileService, grace time.Duration) *Checker {
+	if grace < 0 {
+		grace = DefaultGracePeriod
+	}
+	return &Checker{db: db, fileService: fileService, grace: grace}
+}
+
+// GracePeriod returns the default grace period used by scheduled runs.
+func (c *Checker) GracePeriod() time.Duration {
+	return c.grace
+}
+
+// Run checks storage consistency. Unless dryRun is set, orphans older than grace are deleted.
+// Only one run executes at a time.
+func (c *Checker) Run(dryRun bool, grace time.Duration) (*Report, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	report := &Report{
+		StartedAt:    time.Now(),
+		DryRun:       dryRun,
+		GracePeriod:  grace.String(),
+		Orphans:      []OrphanFile{},
+		MissingFiles: []database.StorageReference{},
+	}
+
+	references, err := c.db.GetStorageReferences()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось получить ссылки на файлы: %w", err)
+	}
+	report.References = len(references)
+
+	referenced := make(map[string]bool, len(references))
+	modelStems := make(map[string]bool)
+	for _, ref := range references {
+		clean := path.Clean(ref.Path)
+		referenced[clean] = true
+		if strings.HasPrefix(clean, file.PublicPrefix+file.ModelsDir+"/") {
+			modelStems[stem(clean)] = true
+		}
+	}
+
+	for _, ref := range references {
+		fullPath, err := c.fileService.FullPath(ref.Path)
+		if err == nil {
+			if _, err = os.Stat(fullPath); err == nil {
+				continue
+			}
+		}
+		report.MissingFiles = append(report.MissingFiles, ref)
+	}
+
+	root := c.fileService.Root()
+	cutoff := report.StartedAt.Add(-grace)
+	for _, dir := range managedDirs {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(fullPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if entry.IsDir() || !entry.Type().IsRegular() {
+				return nil
+			}
+			report.FilesScanned++
+
+			rel, err := filepath.Rel(root, fullPath)
+			if err != nil {
+				return err
+			}
+			publicPath := file.PublicPrefix + "/" + filepath.ToSlash(rel)
+			if referenced[publicPath] || isDerivedPreview(publicPath, modelStems) {
+				return nil
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return nil
+			}
+			orphan := OrphanFile{
+				Path:          publicPath,
+				Size:          info.Size(),
+				ModifiedAt:    info.ModTime(),
+				InGracePeriod: info.ModTime().After(cutoff),
+			}
+			if !dryRun && !orphan.InGracePeriod {
+				if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+					log.Printf("Ошибка при удалении осиротевшего файла %s: %v", fullPath, err)
+					orphan.Error = err.Error()
+				} else {
+					orphan.Deleted = true
+					report.DeletedFiles++
+					report.ReclaimedSize += orphan.Size
+				}
+			}
+			report.Orphans = append(report.Orphans, orphan)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("не удалось просканировать %s: %w", dir, err)
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// StartSchedule runs the collector in the background at the given interval.
+func (c *Checker) StartSchedule(interval time.Duration, dryRun bool) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			report, err := c.Run(dryRun, c.grace)
+			if err != nil {
+				log.Printf("Ошибка проверки хранилища: %v", err)
+				continue
+			}
+			log.Printf("Проверка хранилища: файлов %d, осиротевших %d, удалено %d (%d байт), отсутствующих файлов %d",
+				report.FilesScanned, len(report.Orphans), report.DeletedFiles, report.ReclaimedSize, len(report.MissingFiles))
+		}
+	}()
+}
+
+// isDerivedPreview keeps turntable sprite sheets, which are not stored in the database but are named after their model.
+func isDerivedPreview(publicPath string, modelStems map[string]bool) bool {
+	if !strings.HasPrefix(publicPath, file.PublicPrefix+file.PreviewsDir+"/") {
+		return false
+	}
+	base := path.Base(publicPath)
+	idx := strings.LastIndex(base, file.SpriteSuffix)
+	if idx <= 0 {
+		return false
+	}
+	return modelStems[base[:idx]]
+}
+
+func stem(publicPath string) string {
+	base := path.Base(publicPath)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
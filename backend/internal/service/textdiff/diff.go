@@ -0,0 +1,126 @@
+// Word-level text diff used to compare revisions of mineral descriptions.
+// Text is split into words and the whitespace between them, so joining the equal and deleted parts gives the old text
+// and joining the equal and inserted parts gives the new one. The middle part that differs is aligned by the longest common subsequence.
+
+package textdiff
+
+import (
+	"backend/internal/models"
+	"unicode"
+)
+
+const (
+	OpEqual  = "equal"
+	OpInsert = "insert"
+	OpDelete = "delete"
+
+	// Above this many table cells the differing middle is reported as replaced as a whole.
+	maxCells = 4_000_000
+)
+
+// Words returns the operations that turn a into b.
+func Words(a, b string) []models.TextOp {
+	x, y := tokenize(a), tokenize(b)
+
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(x)-prefix && suffix < len(y)-prefix && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+
+	var ops []models.TextOp
+	ops = appendTokens(ops, OpEqual, x[:prefix])
+	ops = append(ops, lcs(x[prefix:len(x)-suffix], y[prefix:len(y)-suffix])...)
+	ops = appendTokens(ops, OpEqual, x[len(x)-suffix:])
+	return merge(ops)
+}
+
+// Changed reports whether the operations contain any insertion or deletion.
+func Changed(ops []models.TextOp) bool {
+	for _, op := range ops {
+		if op.Type != OpEqual {
+			return true
+		}
+	}
+	return false
+}
+
+func lcs(x, y []string) []models.TextOp {
+	if len(x)*len(y) > maxCells {
+		return appendTokens(appendTokens(nil, OpDelete, x), OpInsert, y)
+	}
+
+	// lengths[i][j] is the LCS length of x[i:] and y[j:].
+	width := len(y) + 1
+	lengths := make([]int32, (len(x)+1)*width)
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lengths[i*width+j] = lengths[(i+1)*width+j+1] + 1
+			} else {
+				lengths[i*width+j] = max(lengths[(i+1)*width+j], lengths[i*width+j+1])
+			}
+		}
+	}
+
+	var ops []models.TextOp
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			ops = append(ops, models.TextOp{Type: OpEqual, Text: x[i]})
+			i++
+			j++
+		case lengths[(i+1)*width+j] >= lengths[i*width+j+1]:
+			ops = append(ops, models.TextOp{Type: OpDelete, Text: x[i]})
+			i++
+		default:
+			ops = append(ops, models.TextOp{Type: OpInsert, Text: y[j]})
+			j++
+		}
+	}
+	ops = appendTokens(ops, OpDelete, x[i:])
+	return appendTokens(ops, OpInsert, y[j:])
+}
+
+// tokenize splits text into alternating runs of whitespace and non-whitespace.
+func tokenize(text string) []string {
+	var tokens []string
+	start := 0
+	var previousSpace bool
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if i > 0 && space != previousSpace {
+			tokens = append(tokens, text[start:i])
+			start = i
+		}
+		previousSpace = space
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+func appendTokens(ops []models.TextOp, opType string, tokens []string) []models.TextOp {
+	for _, token := range tokens {
+		ops = append(ops, models.TextOp{Type: opType, Text: token})
+	}
+	return ops
+}
+
+// merge joins neighbouring operations of the same type.
+func merge(ops []models.TextOp) []models.TextOp {
+	merged := []models.TextOp{}
+	for _, op := range ops {
+		if n := len(merged); n > 0 && merged[n-1].Type == op.Type {
+			merged[n-1].Text += op.Text
+			continue
+		}
+		merged = append(merged, op)
+	}
+	return merged
+}
@@ -0,0 +1,146 @@
+// Permanent removal of minerals from the trash.
+// Purge deletes a trashed mineral and then the files only it referenced, including those of its structures, spectra
+// and revisions; the schedule purges every mineral that has been in the trash longer than the retention period.
+
+package trash
+
+import (
+	"backend/internal/database"
+	"backend/internal/service/file"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRetention     = 30 * 24 * time.Hour
+	DefaultPurgeInterval = time.Hour
+)
+
+type Purger struct {
+	db          *database.Database
+	fileService *file.FileService
+	retention   time.Duration
+	mu          sync.Mutex
+}
+
+func NewPurger(db *database.Database, fileService *file.FileService, retention time.Duration) *Purger {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Purger{db: db, fileService: fileService, retention: retention}
+}
+
+// Retention returns how long minerals stay in the trash before they are purged automatically.
+func (p *Purger) Retention() time.Duration {
+	return p.retention
+}
+
+// Purge permanently deletes a mineral from the trash and removes its files.
+func (p *Purger) Purge(id int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.purge(id)
+}
+
+// PurgeExpired purges the minerals deleted more than the retention period ago and returns how many were purged.
+func (p *Purger) PurgeExpired() (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ids, err := p.db.GetExpiredDeletedMineralIDs(time.Now().Add(-p.retention))
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, id := range ids {
+		if err := p.purge(id); err != nil {
+			if err == database.ErrMineralNotFound {
+				continue
+			}
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartSchedule purges expired minerals in the background at the given interval.
+func (p *Purger) StartSchedule(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purged, err := p.PurgeExpired()
+			if err != nil {
+				log.Printf("Ошибка очистки корзины: %v", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Очистка корзины: удалено минералов %d", purged)
+			}
+		}
+	}()
+}
+
+func (p *Purger) purge(id int) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	mineral, err := tx.GetDeletedMineralForUpdate(id)
+	if err != nil {
+		return err
+	}
+	assets, err := tx.GetAllMineralAssetsForUpdate(id)
+	if err != nil {
+		return err
+	}
+	attached, err := tx.GetPurgedFilePaths(id)
+	if err != nil {
+		return err
+	}
+	if err := tx.PurgeMineral(id); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	paths := []string{mineral.ModelPath, mineral.PreviewImagePath, mineral.SourceModelPath}
+	for _, asset := range assets {
+		paths = append(paths, asset.Path, asset.SourcePath)
+	}
+	paths = append(paths, attached...)
+	p.removeUnreferencedFiles(paths)
+	log.Printf("Минерал %d удалён из корзины", id)
+	return nil
+}
+
+// removeUnreferencedFiles skips paths that another record still uses; failures are left to the storage checker.
+func (p *Purger) removeUnreferencedFiles(publicPaths []string) {
+	for _, publicPath := range publicPaths {
+		if publicPath == "" {
+			continue
+		}
+		referenced, err := p.db.IsStoragePathReferenced(publicPath)
+		if err != nil {
+			log.Printf("Ошибка проверки ссылок на файл %s: %v", publicPath, err)
+			continue
+		}
+		if referenced {
+			continue
+		}
+		fullPath, err := p.fileService.FullPath(publicPath)
+		if err != nil {
+			log.Printf("Некорректный путь к файлу %q: %v", publicPath, err)
+			continue
+		}
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("Ошибка при удалении файла %s: %v", fullPath, err)
+		}
+	}
+}
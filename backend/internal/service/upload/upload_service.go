@@ -0,0 +1,361 @@
+// A service for resumable, chunked uploads of large files, modelled on the tus 1.0 protocol.
+// Keeps every upload as a data file plus a JSON descriptor outside the public storage directory, so transfers survive server restarts.
+// Implements offset negotiation, per-chunk checksum verification (sha1, sha256, md5) and expiry of abandoned uploads.
+// Completed uploads are handed over to the file service, which validates and stores them like regular multipart uploads.
+
+package upload
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	UploadsPath        = "/app/uploads"
+	DefaultExpiry      = 24 * time.Hour
+	CleanupInterval    = time.Hour
+	ChecksumAlgorithms = "sha1,sha256,md5"
+)
+
+var (
+	ErrUploadNotFound      = errors.New("загрузка не найдена")
+	ErrUploadExpired       = errors.New("срок действия загрузки истёк")
+	ErrOffsetMismatch      = errors.New("смещение не совпадает с текущим размером загрузки")
+	ErrChecksumMismatch    = errors.New("контрольная сумма фрагмента не совпадает")
+	ErrUnsupportedChecksum = errors.New("неподдерживаемый алгоритм контрольной суммы")
+	ErrUploadTooLarge      = errors.New("размер загрузки превышает допустимый")
+	ErrUploadIncomplete    = errors.New("загрузка ещё не завершена")
+)
+
+type Upload struct {
+	ID        string            `json:"id"`
+	Length    int64             `json:"length"`
+	Offset    int64             `json:"offset"`
+	Filename  string            `json:"filename"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+func (u *Upload) Complete() bool {
+	return u.Offset == u.Length
+}
+
+type UploadService struct {
+	dir     string
+	maxSize int64
+	expiry  time.Duration
+	mu      sync.Mutex
+}
+
+func NewUploadService(dir string, maxSize int64, expiry time.Duration) (*UploadService, error) {
+	log.Printf("Инициализация UploadService с путем: %s", dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("не удалось создать директорию %s: %w", dir, err)
+	}
+	if expiry <= 0 {
+		expiry = DefaultExpiry
+	}
+	return &UploadService{dir: dir, maxSize: maxSize, expiry: expiry}, nil
+}
+
+func (s *UploadService) MaxSize() int64 {
+	return s.maxSize
+}
+
+func (s *UploadService) Create(length int64, filename string, metadata map[string]string) (*Upload, error) {
+	if length < 0 || length > s.maxSize {
+		return nil, ErrUploadTooLarge
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	upload := &Upload{
+		ID:        id,
+		Length:    length,
+		Filename:  filepath.Base(filename),
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.expiry),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Create(s.dataPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("не удалось создать файл загрузки: %w", err)
+	}
+	f.Close()
+
+	if err := s.writeInfo(upload); err != nil {
+		os.Remove(s.dataPath(id))
+		return nil, err
+	}
+	return upload, nil
+}
+
+func (s *UploadService) Get(id string) (*Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(id)
+}
+
+// Append writes a chunk at the given offset. The checksum header value has the tus form "<algorithm> <base64 digest>" and may be empty.
+func (s *UploadService) Append(id string, offset int64, chunk []byte, checksum string) (*Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != upload.Offset {
+		return upload, ErrOffsetMismatch
+	}
+	if upload.Offset+int64(len(chunk)) > upload.Length {
+		return upload, ErrUploadTooLarge
+	}
+	if checksum != "" {
+		if err := verifyChecksum(chunk, checksum); err != nil {
+			return upload, err
+		}
+	}
+
+	f, err := os.OpenFile(s.dataPath(id), os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось открыть файл загрузки: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteAt(chunk, offset); err != nil {
+		return nil, fmt.Errorf("не удалось записать фрагмент: %w", err)
+	}
+	upload.Offset += int64(len(chunk))
+	upload.ExpiresAt = time.Now().Add(s.expiry)
+
+	if err := s.writeInfo(upload); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// DataPath returns the location of a completed upload's data on disk.
+func (s *UploadService) DataPath(id string) (string, error) {
+	upload, err := s.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if !upload.Complete() {
+		return "", ErrUploadIncomplete
+	}
+	return s.dataPath(id), nil
+}
+
+func (s *UploadService) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !validID(id) {
+		return ErrUploadNotFound
+	}
+	if _, err := os.Stat(s.infoPath(id)); os.IsNotExist(err) {
+		return ErrUploadNotFound
+	}
+	return s.remove(id)
+}
+
+// CleanupExpired removes uploads whose expiry time has passed and returns how many were deleted.
+func (s *UploadService) CleanupExpired() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	now := time.Now()
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		id := strings.TrimSuffix(name, ".json")
+		upload, err := s.readInfo(id)
+		if err != nil || now.After(upload.ExpiresAt) {
+			if err := s.remove(id); err != nil {
+				log.Printf("Ошибка при удалении загрузки %s: %v", id, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// StartCleanup periodically removes abandoned uploads in the background.
+func (s *UploadService) StartCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			removed, err := s.CleanupExpired()
+			if err != nil {
+				log.Printf("Ошибка очистки загрузок: %v", err)
+				continue
+			}
+			if removed > 0 {
+				log.Printf("Удалено просроченных загрузок: %d", removed)
+			}
+		}
+	}()
+}
+
+func (s *UploadService) load(id string) (*Upload, error) {
+	if !validID(id) {
+		return nil, ErrUploadNotFound
+	}
+	upload, err := s.readInfo(id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	if time.Now().After(upload.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	return upload, nil
+}
+
+func (s *UploadService) readInfo(id string) (*Upload, error) {
+	data, err := os.ReadFile(s.infoPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var upload Upload
+	if err := json.Unmarshal(data, &upload); err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// writeInfo replaces the descriptor atomically so a crash never leaves a truncated JSON file behind.
+func (s *UploadService) writeInfo(upload *Upload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("не удалось сохранить состояние загрузки: %w", err)
+	}
+	return os.Rename(tmp, s.infoPath(upload.ID))
+}
+
+func (s *UploadService) remove(id string) error {
+	if err := os.Remove(s.dataPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(s.infoPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *UploadService) dataPath(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+func (s *UploadService) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func newUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("не удалось сгенерировать идентификатор загрузки: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func validID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func verifyChecksum(chunk []byte, header string) error {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	if len(parts) != 2 {
+		return ErrUnsupportedChecksum
+	}
+
+	var h hash.Hash
+	switch strings.ToLower(parts[0]) {
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "md5":
+		h = md5.New()
+	default:
+		return ErrUnsupportedChecksum
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return ErrChecksumMismatch
+	}
+	h.Write(chunk)
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// ParseMetadata decodes the tus Upload-Metadata header: comma separated "key base64value" pairs.
+func ParseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.Fields(pair)
+		switch len(parts) {
+		case 1:
+			metadata[parts[0]] = ""
+		case 2:
+			value, err := base64.StdEncoding.DecodeString(parts[1])
+			if err != nil {
+				return nil, fmt.Errorf("некорректное значение метаданных %q", parts[0])
+			}
+			metadata[parts[0]] = string(value)
+		default:
+			return nil, fmt.Errorf("некорректные метаданные загрузки")
+		}
+	}
+	return metadata, nil
+}
@@ -16,4 +16,277 @@ CREATE TABLE IF NOT EXISTS users (
     role VARCHAR(50) NOT NULL DEFAULT 'user',
     favorites integer[] DEFAULT '{}',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-    );
\ No newline at end of file
+    );
+
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS source_model_path VARCHAR(255);
+
+CREATE TABLE IF NOT EXISTS mineral_assets (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    asset_type VARCHAR(20) NOT NULL CHECK (asset_type IN ('image', 'model', 'video', 'document')),
+    path VARCHAR(255) NOT NULL,
+    source_path VARCHAR(255),
+    captions JSONB NOT NULL DEFAULT '{}',
+    position INTEGER NOT NULL DEFAULT 0,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_mineral_assets_mineral ON mineral_assets(mineral_id, position);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mineral_assets_primary ON mineral_assets(mineral_id, asset_type) WHERE is_primary;
+
+CREATE TABLE IF NOT EXISTS specimens (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    catalog_number VARCHAR(64) NOT NULL UNIQUE,
+    locality VARCHAR(255),
+    length_mm NUMERIC(10, 2),
+    width_mm NUMERIC(10, 2),
+    height_mm NUMERIC(10, 2),
+    mass_g NUMERIC(12, 3),
+    acquired_on DATE,
+    acquisition_source VARCHAR(255),
+    storage_location VARCHAR(255),
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_specimens_mineral ON specimens(mineral_id);
+
+ALTER TABLE mineral_assets ADD COLUMN IF NOT EXISTS specimen_id INTEGER REFERENCES specimens(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS idx_mineral_assets_specimen ON mineral_assets(specimen_id);
+DROP INDEX IF EXISTS idx_mineral_assets_primary;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mineral_assets_owner_primary
+    ON mineral_assets(mineral_id, COALESCE(specimen_id, 0), asset_type) WHERE is_primary;
+
+CREATE TABLE IF NOT EXISTS localities (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    country VARCHAR(100),
+    region VARCHAR(255),
+    latitude DOUBLE PRECISION NOT NULL CHECK (latitude BETWEEN -90 AND 90),
+    longitude DOUBLE PRECISION NOT NULL CHECK (longitude BETWEEN -180 AND 180),
+    polygon JSONB,
+    min_lat DOUBLE PRECISION NOT NULL,
+    max_lat DOUBLE PRECISION NOT NULL,
+    min_lon DOUBLE PRECISION NOT NULL,
+    max_lon DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_localities_lat_lon ON localities(latitude, longitude);
+CREATE INDEX IF NOT EXISTS idx_localities_bounds ON localities(min_lat, max_lat, min_lon, max_lon);
+
+CREATE TABLE IF NOT EXISTS mineral_localities (
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    locality_id INTEGER NOT NULL REFERENCES localities(id) ON DELETE CASCADE,
+    PRIMARY KEY (mineral_id, locality_id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_mineral_localities_locality ON mineral_localities(locality_id);
+
+ALTER TABLE specimens ADD COLUMN IF NOT EXISTS locality_id INTEGER REFERENCES localities(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_specimens_locality ON specimens(locality_id);
+
+CREATE TABLE IF NOT EXISTS classification_nodes (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES classification_nodes(id) ON DELETE RESTRICT,
+    level VARCHAR(20) NOT NULL CHECK (level IN ('class', 'subclass', 'family', 'group')),
+    strunz_code VARCHAR(20) NOT NULL UNIQUE,
+    dana_code VARCHAR(20),
+    names JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_classification_nodes_parent ON classification_nodes(parent_id);
+
+INSERT INTO classification_nodes (level, strunz_code, names) VALUES
+    ('class', '1', '{"ru": "Самородные элементы", "en": "Elements"}'),
+    ('class', '2', '{"ru": "Сульфиды и сульфосоли", "en": "Sulfides and sulfosalts"}'),
+    ('class', '3', '{"ru": "Галогениды", "en": "Halides"}'),
+    ('class', '4', '{"ru": "Оксиды и гидроксиды", "en": "Oxides and hydroxides"}'),
+    ('class', '5', '{"ru": "Карбонаты и нитраты", "en": "Carbonates and nitrates"}'),
+    ('class', '6', '{"ru": "Бораты", "en": "Borates"}'),
+    ('class', '7', '{"ru": "Сульфаты, хроматы, молибдаты и вольфраматы", "en": "Sulfates, chromates, molybdates and tungstates"}'),
+    ('class', '8', '{"ru": "Фосфаты, арсенаты и ванадаты", "en": "Phosphates, arsenates and vanadates"}'),
+    ('class', '9', '{"ru": "Силикаты", "en": "Silicates"}'),
+    ('class', '10', '{"ru": "Органические соединения", "en": "Organic compounds"}')
+ON CONFLICT (strunz_code) DO NOTHING;
+
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS classification_id INTEGER REFERENCES classification_nodes(id) ON DELETE SET NULL;
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS dana_code VARCHAR(20);
+CREATE INDEX IF NOT EXISTS idx_minerals_classification ON minerals(classification_id);
+
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS formula VARCHAR(255);
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS molar_mass NUMERIC(12, 4);
+
+CREATE TABLE IF NOT EXISTS mineral_elements (
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    element VARCHAR(3) NOT NULL,
+    atom_count NUMERIC(14, 6) NOT NULL,
+    weight_percent NUMERIC(8, 4) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (mineral_id, element)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_mineral_elements_element ON mineral_elements(element);
+
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS crystal_system VARCHAR(20);
+
+CREATE TABLE IF NOT EXISTS crystal_structures (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    cif_path VARCHAR(255) NOT NULL,
+    data_block VARCHAR(255),
+    space_group VARCHAR(50),
+    space_group_number INTEGER,
+    crystal_system VARCHAR(20) NOT NULL,
+    a NUMERIC(12, 6) NOT NULL,
+    b NUMERIC(12, 6) NOT NULL,
+    c NUMERIC(12, 6) NOT NULL,
+    alpha NUMERIC(10, 4) NOT NULL,
+    beta NUMERIC(10, 4) NOT NULL,
+    gamma NUMERIC(10, 4) NOT NULL,
+    cell_volume NUMERIC(14, 4) NOT NULL,
+    z INTEGER,
+    atom_sites JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_crystal_structures_mineral ON crystal_structures(mineral_id);
+
+CREATE TABLE IF NOT EXISTS mineral_xrd_peaks (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    d_spacing NUMERIC(10, 5) NOT NULL,
+    intensity NUMERIC(5, 1) NOT NULL,
+    hkl VARCHAR(20),
+    position INTEGER NOT NULL DEFAULT 0
+    );
+
+CREATE INDEX IF NOT EXISTS idx_mineral_xrd_peaks_mineral ON mineral_xrd_peaks(mineral_id);
+
+CREATE TABLE IF NOT EXISTS mineral_spectra (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL,
+    title VARCHAR(255),
+    instrument VARCHAR(255),
+    wavelength NUMERIC(10, 5),
+    x_units VARCHAR(50),
+    y_units VARCHAR(50),
+    point_count INTEGER NOT NULL,
+    x_min DOUBLE PRECISION NOT NULL,
+    x_max DOUBLE PRECISION NOT NULL,
+    source_path VARCHAR(255) NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_mineral_spectra_mineral ON mineral_spectra(mineral_id);
+CREATE INDEX IF NOT EXISTS idx_mineral_spectra_type ON mineral_spectra(type);
+
+CREATE TABLE IF NOT EXISTS mineral_names (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    lang VARCHAR(8) NOT NULL DEFAULT 'ru',
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('synonym', 'variety')),
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mineral_names_unique ON mineral_names(mineral_id, lang, LOWER(name));
+CREATE INDEX IF NOT EXISTS idx_mineral_names_search ON mineral_names(LOWER(name) text_pattern_ops);
+
+CREATE TABLE IF NOT EXISTS mineral_relations (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    related_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('polymorph_of', 'pseudomorph_after', 'series_member', 'associated_with')),
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (mineral_id <> related_id),
+    UNIQUE (mineral_id, related_id, type)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_mineral_relations_related ON mineral_relations(related_id);
+
+CREATE TABLE IF NOT EXISTS mineral_revisions (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL,
+    revision INTEGER NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('baseline', 'create', 'update', 'restore', 'delete')),
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    author_name VARCHAR(255),
+    restored_from INTEGER,
+    snapshot JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (mineral_id, revision)
+    );
+
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'in_review', 'published', 'archived'));
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS publish_at TIMESTAMPTZ;
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS published_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_minerals_status ON minerals(status);
+
+CREATE TABLE IF NOT EXISTS mineral_reviews (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    author_name VARCHAR(255),
+    action VARCHAR(10) NOT NULL CHECK (action IN ('submit', 'approve', 'reject', 'archive', 'reopen', 'comment')),
+    from_status VARCHAR(20) NOT NULL,
+    to_status VARCHAR(20) NOT NULL,
+    comment TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX IF NOT EXISTS idx_mineral_reviews_mineral ON mineral_reviews(mineral_id);
+
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_minerals_deleted_at ON minerals(deleted_at) WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS uuid UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS slug VARCHAR(80);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_minerals_uuid ON minerals(uuid);
+
+CREATE TABLE IF NOT EXISTS mineral_slugs (
+    id SERIAL PRIMARY KEY,
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    lang VARCHAR(8) NOT NULL,
+    slug VARCHAR(80) NOT NULL,
+    is_current BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (lang, slug)
+    );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mineral_slugs_current ON mineral_slugs(mineral_id, lang) WHERE is_current;
+
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(80) NOT NULL UNIQUE,
+    names JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE TABLE IF NOT EXISTS mineral_tags (
+    mineral_id INTEGER NOT NULL REFERENCES minerals(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (mineral_id, tag_id)
+    );
+
+CREATE INDEX IF NOT EXISTS idx_mineral_tags_tag ON mineral_tags(tag_id);
+
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_minerals_external_id ON minerals(external_id);
+
+ALTER TABLE minerals ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;